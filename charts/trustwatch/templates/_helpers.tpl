@@ -39,6 +39,14 @@ app.kubernetes.io/name: {{ include "trustwatch.name" . }}
 app.kubernetes.io/instance: {{ .Release.Name }}
 {{- end }}
 
+{{/*
+Whether serve requires client certificates, so health checks move to the
+plain HTTP probe port.
+*/}}
+{{- define "trustwatch.probePort" -}}
+{{- if and .Values.tls.secretName .Values.tls.clientCA (eq .Values.tls.clientAuth "require") }}true{{- end }}
+{{- end }}
+
 {{/*
 ServiceAccount name.
 */}}
@@ -0,0 +1,275 @@
+// Package analytics computes certificate rotation SLOs from snapshot history.
+package analytics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/history"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// Defaults for Options fields left zero.
+const (
+	DefaultWindow       = 90 * 24 * time.Hour
+	DefaultNearMiss     = 72 * time.Hour
+	DefaultMinRemaining = 1.0 / 3
+)
+
+// Options selects the history analyzed and sets the SLO thresholds.
+type Options struct {
+	Source    string // only this source; empty for all
+	Namespace string // only this namespace; empty for all
+	// Window is how far back history is analyzed.
+	Window time.Duration
+	// NearMiss counts rotations with less lifetime than this left.
+	NearMiss time.Duration
+	// MinRemaining is the fraction of a certificate's lifetime that must be
+	// left when it is rotated to meet the SLO.
+	MinRemaining float64
+}
+
+func (o *Options) defaults() {
+	if o.Window <= 0 {
+		o.Window = DefaultWindow
+	}
+	if o.NearMiss <= 0 {
+		o.NearMiss = DefaultNearMiss
+	}
+	if o.MinRemaining <= 0 {
+		o.MinRemaining = DefaultMinRemaining
+	}
+}
+
+// Group aggregates rotations and open findings for one source, namespace,
+// and issuer. Ratios are nil when there is nothing to measure.
+type Group struct {
+	// SLORatio is the share of ended certificates rotated with at least
+	// MinRemaining of their lifetime left. Rotations of certificates with an
+	// unknown notBefore are not counted.
+	SLORatio *float64 `json:"sloRatio,omitempty"`
+	// SuccessRatio is the share of ended certificates rotated before expiry.
+	SuccessRatio *float64 `json:"successRatio,omitempty"`
+	// MeanRemaining is the mean fraction of lifetime left at rotation.
+	MeanRemaining *float64 `json:"meanRemaining,omitempty"`
+	// MinRemaining is the smallest fraction of lifetime left at rotation.
+	MinRemaining *float64         `json:"minRemaining,omitempty"`
+	Source       store.SourceKind `json:"source,omitempty"`
+	Namespace    string           `json:"namespace,omitempty"`
+	Issuer       string           `json:"issuer,omitempty"`
+	// Rotations counts certificates replaced before they expired.
+	Rotations int `json:"rotations"`
+	// Expired counts certificates seen past notAfter before being replaced.
+	Expired    int `json:"expired"`
+	SLOMet     int `json:"sloMet"`
+	SLOMissed  int `json:"sloMissed"`
+	NearMisses int `json:"nearMisses"`
+	// OpenFindings counts warn or critical findings still open.
+	OpenFindings     int `json:"openFindings"`
+	ResolvedFindings int `json:"resolvedFindings"`
+	// MeanTimeOpen is the mean time resolved findings stayed open.
+	MeanTimeOpen time.Duration `json:"meanTimeOpen,omitempty"`
+	// MaxTimeOpen is the longest time any finding stayed open, including open ones.
+	MaxTimeOpen time.Duration `json:"maxTimeOpen,omitempty"`
+
+	remainingSum   float64
+	remainingCount int
+	minRemaining   float64
+	openTotal      time.Duration
+}
+
+// Report is the rotation analytics for a window of history.
+type Report struct {
+	Generated    time.Time     `json:"generated"`
+	Since        time.Time     `json:"since"`
+	Groups       []Group       `json:"groups"`
+	Total        Group         `json:"total"`
+	NearMiss     time.Duration `json:"nearMiss"`
+	MinRemaining float64       `json:"minRemaining"`
+}
+
+// FromHistory loads the lineages and finding episodes in the window and computes the report.
+func FromHistory(hs *history.Store, opts Options, now time.Time) (*Report, error) {
+	opts.defaults()
+	since := now.Add(-opts.Window)
+	lineages, err := hs.Lineages(history.LineageFilter{Since: since, Source: opts.Source, Namespace: opts.Namespace})
+	if err != nil {
+		return nil, err
+	}
+	episodes, err := hs.Episodes(since)
+	if err != nil {
+		return nil, err
+	}
+	return Compute(lineages, episodes, opts, now), nil
+}
+
+// Compute aggregates lineages and episodes by source, namespace, and issuer.
+func Compute(lineages []history.Lineage, episodes []history.Episode, opts Options, now time.Time) *Report {
+	opts.defaults()
+	groups := make(map[[3]string]*Group)
+	group := func(source store.SourceKind, ns, issuer string) *Group {
+		key := [3]string{string(source), ns, issuer}
+		g, ok := groups[key]
+		if !ok {
+			g = &Group{Source: source, Namespace: ns, Issuer: issuer}
+			groups[key] = g
+		}
+		return g
+	}
+	match := func(source store.SourceKind, ns string) bool {
+		return (opts.Source == "" || string(source) == opts.Source) && (opts.Namespace == "" || ns == opts.Namespace)
+	}
+
+	for i := range lineages {
+		l := &lineages[i]
+		if !match(l.Source, l.Namespace) {
+			continue
+		}
+		for j := range l.Generations {
+			gen := &l.Generations[j]
+			g := group(l.Source, l.Namespace, gen.Issuer)
+			if gen.RotatedAt.IsZero() {
+				// The current certificate only counts once it is seen expired.
+				if !gen.NotAfter.IsZero() && gen.LastSeen.After(gen.NotAfter) {
+					g.Expired++
+				}
+				continue
+			}
+			g.addRotation(gen, &opts)
+		}
+	}
+
+	for i := range episodes {
+		e := &episodes[i]
+		if !match(e.Source, e.Namespace) {
+			continue
+		}
+		group(e.Source, e.Namespace, e.Issuer).addEpisode(e, now)
+	}
+
+	r := &Report{
+		Generated:    now,
+		Since:        now.Add(-opts.Window),
+		Groups:       make([]Group, 0, len(groups)),
+		NearMiss:     opts.NearMiss,
+		MinRemaining: opts.MinRemaining,
+	}
+	for _, g := range groups {
+		r.Total.merge(g)
+		g.finish()
+		r.Groups = append(r.Groups, *g)
+	}
+	r.Total.finish()
+	sort.Slice(r.Groups, func(i, j int) bool {
+		a, b := &r.Groups[i], &r.Groups[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Issuer < b.Issuer
+	})
+	return r
+}
+
+// Filter returns a copy of r with only the groups for source and namespace,
+// either of which may be empty to match all, and the total recomputed.
+func (r *Report) Filter(source, namespace string) *Report {
+	out := *r
+	out.Groups = make([]Group, 0, len(r.Groups))
+	out.Total = Group{}
+	for i := range r.Groups {
+		g := &r.Groups[i]
+		if (source != "" && string(g.Source) != source) || (namespace != "" && g.Namespace != namespace) {
+			continue
+		}
+		out.Groups = append(out.Groups, *g)
+		out.Total.merge(g)
+	}
+	out.Total.finish()
+	return &out
+}
+
+// addRotation counts one replaced certificate.
+func (g *Group) addRotation(gen *history.Generation, opts *Options) {
+	left := gen.RemainingAtRotation
+	if left <= 0 {
+		g.Expired++
+		return
+	}
+	g.Rotations++
+	if left < opts.NearMiss {
+		g.NearMisses++
+	}
+	lifetime := gen.NotAfter.Sub(gen.NotBefore)
+	if gen.NotBefore.IsZero() || lifetime <= 0 {
+		return
+	}
+	fraction := min(left.Seconds()/lifetime.Seconds(), 1)
+	if fraction >= opts.MinRemaining {
+		g.SLOMet++
+	} else {
+		g.SLOMissed++
+	}
+	g.addRemaining(fraction, 1, fraction)
+}
+
+func (g *Group) addRemaining(sum float64, count int, lowest float64) {
+	if count == 0 {
+		return
+	}
+	if g.remainingCount == 0 || lowest < g.minRemaining {
+		g.minRemaining = lowest
+	}
+	g.remainingSum += sum
+	g.remainingCount += count
+}
+
+// addEpisode counts one warn or critical episode.
+func (g *Group) addEpisode(e *history.Episode, now time.Time) {
+	end := e.Closed
+	if end.IsZero() {
+		g.OpenFindings++
+		end = now
+	} else {
+		g.ResolvedFindings++
+		g.openTotal += end.Sub(e.Opened)
+	}
+	g.MaxTimeOpen = max(g.MaxTimeOpen, end.Sub(e.Opened))
+}
+
+// merge adds the counts of other into g.
+func (g *Group) merge(other *Group) {
+	g.Rotations += other.Rotations
+	g.Expired += other.Expired
+	g.SLOMet += other.SLOMet
+	g.SLOMissed += other.SLOMissed
+	g.NearMisses += other.NearMisses
+	g.OpenFindings += other.OpenFindings
+	g.ResolvedFindings += other.ResolvedFindings
+	g.openTotal += other.openTotal
+	g.MaxTimeOpen = max(g.MaxTimeOpen, other.MaxTimeOpen)
+	g.addRemaining(other.remainingSum, other.remainingCount, other.minRemaining)
+}
+
+// finish derives the ratios and means from the counts.
+func (g *Group) finish() {
+	g.SLORatio = ratio(g.SLOMet, g.SLOMet+g.SLOMissed+g.Expired)
+	g.SuccessRatio = ratio(g.Rotations, g.Rotations+g.Expired)
+	if g.remainingCount > 0 {
+		mean, lowest := g.remainingSum/float64(g.remainingCount), g.minRemaining
+		g.MeanRemaining, g.MinRemaining = &mean, &lowest
+	}
+	if g.ResolvedFindings > 0 {
+		g.MeanTimeOpen = g.openTotal / time.Duration(g.ResolvedFindings)
+	}
+}
+
+func ratio(n, total int) *float64 {
+	if total == 0 {
+		return nil
+	}
+	r := float64(n) / float64(total)
+	return &r
+}
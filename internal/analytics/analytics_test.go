@@ -0,0 +1,153 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/history"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+const day = 24 * time.Hour
+
+// generation builds a 90-day certificate issued at start that was replaced
+// with left of its lifetime remaining; a zero left means it is current.
+func generation(start time.Time, issuer string, left time.Duration, current bool) history.Generation {
+	g := history.Generation{
+		NotBefore: start,
+		NotAfter:  start.Add(90 * day),
+		FirstSeen: start,
+		LastSeen:  start.Add(90*day - left - time.Hour),
+		Serial:    start.Format("20060102"),
+		Issuer:    issuer,
+	}
+	if !current {
+		g.RotatedAt = g.NotAfter.Add(-left)
+		g.RemainingAtRotation = left
+	}
+	return g
+}
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	start := now.Add(-200 * day)
+	lineages := []history.Lineage{
+		{
+			Source: store.SourceCertManager, Namespace: "payments", Name: "api-tls",
+			Generations: []history.Generation{
+				generation(start, "CN=LE", 40*day, false),            // SLO met
+				generation(start.Add(50*day), "CN=LE", 2*day, false), // near miss
+				generation(start.Add(138*day), "CN=LE", -day, false), // rotated a day after expiry
+				generation(start.Add(229*day), "CN=LE", 0, true),
+			},
+		},
+		{
+			Source: store.SourceTLSSecret, Namespace: "web", Name: "legacy",
+			Generations: []history.Generation{
+				{NotAfter: now.Add(-10 * day), FirstSeen: start, LastSeen: now, Serial: "1", Issuer: "CN=Internal"},
+			},
+		},
+	}
+	episodes := []history.Episode{
+		{Source: store.SourceCertManager, Namespace: "payments", Name: "api-tls", Issuer: "CN=LE", Opened: now.Add(-30 * day), Closed: now.Add(-26 * day)},
+		{Source: store.SourceCertManager, Namespace: "payments", Name: "api-tls", Issuer: "CN=LE", Opened: now.Add(-20 * day), Closed: now.Add(-18 * day)},
+		{Source: store.SourceTLSSecret, Namespace: "web", Name: "legacy", Issuer: "CN=Internal", Opened: now.Add(-40 * day)},
+	}
+
+	r := Compute(lineages, episodes, Options{}, now)
+	if len(r.Groups) != 2 {
+		t.Fatalf("groups = %d, want 2", len(r.Groups))
+	}
+	le := r.Groups[0]
+	if le.Source != store.SourceCertManager || le.Issuer != "CN=LE" {
+		t.Fatalf("first group = %+v", le)
+	}
+	if le.Rotations != 2 || le.Expired != 1 || le.SLOMet != 1 || le.SLOMissed != 1 || le.NearMisses != 1 {
+		t.Errorf("counts = %+v", le)
+	}
+	if *le.SuccessRatio != 2.0/3 || *le.SLORatio != 1.0/3 {
+		t.Errorf("ratios: success %v, slo %v", *le.SuccessRatio, *le.SLORatio)
+	}
+	wantMean := (40.0/90 + 2.0/90) / 2
+	if diff := *le.MeanRemaining - wantMean; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("mean remaining = %v, want %v", *le.MeanRemaining, wantMean)
+	}
+	if *le.MinRemaining != 2.0/90 {
+		t.Errorf("min remaining = %v", *le.MinRemaining)
+	}
+	if le.ResolvedFindings != 2 || le.MeanTimeOpen != 3*day || le.MaxTimeOpen != 4*day {
+		t.Errorf("episodes: resolved %d, mean %s, max %s", le.ResolvedFindings, le.MeanTimeOpen, le.MaxTimeOpen)
+	}
+
+	legacy := r.Groups[1]
+	if legacy.Expired != 1 || legacy.Rotations != 0 || *legacy.SuccessRatio != 0 || legacy.MeanRemaining != nil {
+		t.Errorf("legacy group = %+v", legacy)
+	}
+	if legacy.OpenFindings != 1 || legacy.MaxTimeOpen != 40*day {
+		t.Errorf("legacy episodes: open %d, max %s", legacy.OpenFindings, legacy.MaxTimeOpen)
+	}
+
+	if r.Total.Rotations != 2 || r.Total.Expired != 2 || *r.Total.SuccessRatio != 0.5 || *r.Total.MinRemaining != 2.0/90 {
+		t.Errorf("total = %+v", r.Total)
+	}
+
+	filtered := Compute(lineages, episodes, Options{Namespace: "web"}, now)
+	if len(filtered.Groups) != 1 || filtered.Groups[0].Namespace != "web" {
+		t.Errorf("namespace filter returned %+v", filtered.Groups)
+	}
+
+	// Filtering a computed report matches computing it filtered.
+	cached := r.Filter("", "web")
+	if len(cached.Groups) != 1 || cached.Total.Expired != filtered.Total.Expired ||
+		cached.Total.MaxTimeOpen != filtered.Total.MaxTimeOpen || *cached.Total.SuccessRatio != *filtered.Total.SuccessRatio {
+		t.Errorf("Filter total = %+v, want %+v", cached.Total, filtered.Total)
+	}
+	if le := r.Filter(string(store.SourceCertManager), "").Total; *le.MinRemaining != 2.0/90 || le.ResolvedFindings != 2 {
+		t.Errorf("source filter total = %+v", le)
+	}
+}
+
+func TestFromHistory(t *testing.T) {
+	hs, err := history.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hs.Close() //nolint:errcheck // test cleanup
+
+	now := time.Now().UTC().Truncate(time.Hour)
+	start := now.Add(-40 * day)
+	for d := range 40 {
+		issued := start
+		serial := "A"
+		if d >= 20 {
+			issued, serial = start.Add(20*day), "B"
+		}
+		sev := store.SeverityInfo
+		if d >= 15 && d < 20 {
+			sev = store.SeverityWarn // inside warnBefore until renewed
+		}
+		snap := store.Snapshot{At: start.Add(time.Duration(d) * day), Findings: []store.CertFinding{{
+			Name: "api-tls", Namespace: "payments", Source: store.SourceCertManager, Severity: sev, ProbeOK: true,
+			Serial: serial, Issuer: "CN=LE", NotBefore: issued, NotAfter: issued.Add(30 * day),
+		}}}
+		if err := hs.Save(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := FromHistory(hs, Options{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Groups) != 1 {
+		t.Fatalf("groups = %+v", r.Groups)
+	}
+	g := r.Groups[0]
+	// Rotated on day 20 of 30 with a third of the lifetime left.
+	if g.Rotations != 1 || g.SLOMet != 1 || g.NearMisses != 0 {
+		t.Errorf("group = %+v", g)
+	}
+	if g.ResolvedFindings != 1 || g.MeanTimeOpen != 5*day {
+		t.Errorf("episodes: resolved %d, mean %s", g.ResolvedFindings, g.MeanTimeOpen)
+	}
+}
@@ -0,0 +1,279 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/ppiankov/trustwatch/internal/history"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// Archive formats.
+const (
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// parquetMagic starts and ends every Parquet file.
+const parquetMagic = "PAR1"
+
+// parquetBatch is how many rows are buffered per Parquet write.
+const parquetBatch = 1024
+
+// Writer writes archive rows in one format. Close must be called to flush
+// the output; it does not close the underlying writer.
+type Writer interface {
+	Write(rows ...Row) error
+	Close() error
+}
+
+// NewWriter returns a Writer for the named format.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatNDJSON:
+		bw := bufio.NewWriter(w)
+		return &ndjsonWriter{buf: bw, enc: json.NewEncoder(bw)}, nil
+	case FormatParquet:
+		return &parquetWriter{w: parquet.NewGenericWriter[Row](w, parquet.Compression(&parquet.Zstd))}, nil
+	default:
+		return nil, fmt.Errorf("unknown archive format %q: must be %s or %s", format, FormatNDJSON, FormatParquet)
+	}
+}
+
+type ndjsonWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(rows ...Row) error {
+	for i := range rows {
+		if err := n.enc.Encode(&rows[i]); err != nil {
+			return fmt.Errorf("writing NDJSON row: %w", err)
+		}
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.buf.Flush()
+}
+
+type parquetWriter struct {
+	w       *parquet.GenericWriter[Row]
+	pending []Row
+}
+
+func (p *parquetWriter) Write(rows ...Row) error {
+	p.pending = append(p.pending, rows...)
+	if len(p.pending) < parquetBatch {
+		return nil
+	}
+	return p.flush()
+}
+
+func (p *parquetWriter) flush() error {
+	if _, err := p.w.Write(p.pending); err != nil {
+		return fmt.Errorf("writing Parquet rows: %w", err)
+	}
+	p.pending = p.pending[:0]
+	return nil
+}
+
+func (p *parquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	return p.w.Close()
+}
+
+// Stats counts what an export or import processed.
+type Stats struct {
+	Snapshots int `json:"snapshots"`
+	Findings  int `json:"findings"`
+	Skipped   int `json:"skipped,omitempty"` // imported snapshots already present
+}
+
+// Export writes every snapshot taken between from and to, oldest first,
+// loading one snapshot at a time. A zero from or to leaves that end open.
+func Export(hs *history.Store, w Writer, from, to time.Time) (Stats, error) {
+	var st Stats
+	ids, err := hs.SnapshotIDs(from, to)
+	if err != nil {
+		return st, err
+	}
+	for _, id := range ids {
+		snap, err := hs.GetSnapshot(id)
+		if err != nil {
+			return st, err
+		}
+		if snap == nil {
+			continue // deleted by retention since the IDs were listed
+		}
+		rows, err := SnapshotRows(id, snap)
+		if err != nil {
+			return st, err
+		}
+		if err := w.Write(rows...); err != nil {
+			return st, err
+		}
+		st.Snapshots++
+		st.Findings += len(snap.Findings)
+	}
+	return st, w.Close()
+}
+
+// Read calls fn for every row in an NDJSON or Parquet archive, detected from
+// its content. Parquet archives are read into memory first, since the format
+// keeps its index at the end of the file.
+func Read(r io.Reader, fn func(*Row) error) error {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(parquetMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading archive: %w", err)
+	}
+	if string(magic) != parquetMagic {
+		return readNDJSON(br, fn)
+	}
+
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return fmt.Errorf("reading archive: %w", err)
+	}
+	pr := parquet.NewGenericReader[Row](bytes.NewReader(data))
+	defer pr.Close() //nolint:errcheck // read-only
+	buf := make([]Row, parquetBatch)
+	for {
+		n, err := pr.Read(buf)
+		for i := range n {
+			if fnErr := fn(&buf[i]); fnErr != nil {
+				return fnErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading Parquet rows: %w", err)
+		}
+	}
+}
+
+func readNDJSON(r io.Reader, fn func(*Row) error) error {
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var row Row
+		err := dec.Decode(&row)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("parsing NDJSON row %d: %w", line, err)
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+}
+
+// ImportOptions controls how archived snapshots are merged into a history database.
+type ImportOptions struct {
+	// Cluster labels snapshots and findings that carry no cluster, e.g. when
+	// seeding a federation hub with a single cluster's archive.
+	Cluster string
+	DryRun  bool // count what would be imported without writing
+}
+
+// Importer rebuilds snapshots from archive rows and saves them. Snapshots
+// already present, by time and cluster, are skipped, so importing the same
+// archive twice is harmless.
+type Importer struct {
+	hs      *history.Store
+	current *store.Snapshot
+	opts    ImportOptions
+	stats   Stats
+	id      int64
+}
+
+// NewImporter returns an Importer writing to hs.
+func NewImporter(hs *history.Store, opts ImportOptions) *Importer {
+	return &Importer{hs: hs, opts: opts}
+}
+
+// Add consumes one row. Finding rows must follow their snapshot row.
+func (im *Importer) Add(r *Row) error {
+	switch r.Kind {
+	case KindSnapshot:
+		if err := im.flush(); err != nil {
+			return err
+		}
+		snap, err := r.snapshot()
+		if err != nil {
+			return err
+		}
+		im.current, im.id = &snap, r.SnapshotID
+	case KindFinding:
+		if im.current == nil || r.SnapshotID != im.id {
+			return fmt.Errorf("finding row for snapshot %d does not follow its snapshot row", r.SnapshotID)
+		}
+		im.current.Findings = append(im.current.Findings, r.finding())
+	default:
+		return fmt.Errorf("unknown row kind %q", r.Kind)
+	}
+	return nil
+}
+
+// Close saves the last snapshot and returns what was imported.
+func (im *Importer) Close() (Stats, error) {
+	err := im.flush()
+	return im.stats, err
+}
+
+// flush saves the snapshot being assembled unless it is already stored.
+func (im *Importer) flush() error {
+	snap := im.current
+	if snap == nil {
+		return nil
+	}
+	im.current = nil
+
+	if c := im.opts.Cluster; c != "" {
+		if snap.Metadata == nil {
+			snap.Metadata = &store.Metadata{}
+		}
+		if snap.Metadata.Cluster == "" {
+			snap.Metadata.Cluster = c
+		}
+		for i := range snap.Findings {
+			if snap.Findings[i].Cluster == "" {
+				snap.Findings[i].Cluster = c
+			}
+		}
+	}
+	var cluster string
+	if snap.Metadata != nil {
+		cluster = snap.Metadata.Cluster
+	}
+	exists, err := im.hs.HasSnapshot(snap.At, cluster)
+	if err != nil {
+		return err
+	}
+	if exists {
+		im.stats.Skipped++
+		return nil
+	}
+	if !im.opts.DryRun {
+		if err := im.hs.Save(*snap); err != nil {
+			return fmt.Errorf("saving snapshot taken at %s: %w", snap.At.Format(time.RFC3339), err)
+		}
+	}
+	im.stats.Snapshots++
+	im.stats.Findings += len(snap.Findings)
+	return nil
+}
@@ -0,0 +1,246 @@
+package archive
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/history"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func openMemory(t *testing.T) *history.Store {
+	t.Helper()
+	hs, err := history.Open(":memory:")
+	if err != nil {
+		t.Fatalf("opening in-memory history: %v", err)
+	}
+	t.Cleanup(func() { hs.Close() }) //nolint:errcheck // test cleanup
+	return hs
+}
+
+// fullFinding sets every exported JSON field of a finding to a non-zero value,
+// so a field added to store.CertFinding but not to Row fails the round trip.
+func fullFinding(t *testing.T, at time.Time) store.CertFinding {
+	t.Helper()
+	var f store.CertFinding
+	v := reflect.ValueOf(&f).Elem()
+	for i := range v.NumField() {
+		field, sf := v.Field(i), v.Type().Field(i)
+		if sf.Tag.Get("json") == "-" {
+			continue
+		}
+		switch field.Interface().(type) {
+		case time.Time:
+			field.Set(reflect.ValueOf(at.Add(time.Duration(i) * time.Hour)))
+		case time.Duration:
+			field.SetInt(int64(90 * 24 * time.Hour))
+		case *store.ObjectRef:
+			field.Set(reflect.ValueOf(&store.ObjectRef{APIVersion: "v1", Kind: "Secret", Namespace: "payments", Name: "api-tls", UID: "uid-1"}))
+		case []string:
+			field.Set(reflect.ValueOf([]string{sf.Name + "-1", sf.Name + "-2"}))
+		case bool:
+			field.SetBool(true)
+		case int:
+			field.SetInt(int64(i + 1))
+		default:
+			if field.Kind() != reflect.String {
+				t.Fatalf("fullFinding: no test value for %s (%s); add it to Row and here", sf.Name, sf.Type)
+			}
+			field.SetString(sf.Name + " value, with \"quotes\"")
+		}
+	}
+	return f
+}
+
+func seed(t *testing.T, hs *history.Store, base time.Time) {
+	t.Helper()
+	snaps := []store.Snapshot{
+		{
+			At:       base,
+			Metadata: &store.Metadata{Cluster: "prod", Context: "prod-admin"},
+			Errors:   map[string]string{"istio": "forbidden"},
+			Findings: []store.CertFinding{fullFinding(t, base), {Name: "down", Source: store.SourceWebhook, Severity: store.SeverityCritical}},
+		},
+		{At: base.Add(time.Hour), Metadata: &store.Metadata{Cluster: "prod"}},
+		{At: base.Add(2 * time.Hour), Metadata: &store.Metadata{Cluster: "prod"}, Findings: []store.CertFinding{{Name: "late", Source: store.SourceExternal}}},
+	}
+	for _, snap := range snaps {
+		if err := hs.Save(snap); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	base := time.Date(2026, 6, 1, 10, 0, 0, 123456789, time.UTC)
+	for _, format := range []string{FormatNDJSON, FormatParquet} {
+		t.Run(format, func(t *testing.T) {
+			src := openMemory(t)
+			seed(t, src, base)
+
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			st, err := Export(src, w, time.Time{}, base.Add(time.Hour))
+			if err != nil {
+				t.Fatalf("export: %v", err)
+			}
+			if st.Snapshots != 2 || st.Findings != 2 {
+				t.Fatalf("exported %+v, want 2 snapshots and 2 findings", st)
+			}
+
+			dst := openMemory(t)
+			archive := buf.Bytes()
+			for pass, want := range []Stats{{Snapshots: 2, Findings: 2}, {Skipped: 2}} {
+				im := NewImporter(dst, ImportOptions{})
+				if err := Read(bytes.NewReader(archive), im.Add); err != nil {
+					t.Fatalf("import pass %d: %v", pass, err)
+				}
+				got, err := im.Close()
+				if err != nil || got != want {
+					t.Fatalf("import pass %d = %+v, %v; want %+v", pass, got, err, want)
+				}
+			}
+
+			orig, err := src.GetAt(base, "prod")
+			if err != nil {
+				t.Fatal(err)
+			}
+			copied, err := dst.GetAt(base, "prod")
+			if err != nil || copied == nil {
+				t.Fatalf("imported snapshot = %v, %v", copied, err)
+			}
+			if !copied.At.Equal(orig.At) || copied.Metadata.Context != "prod-admin" || copied.Errors["istio"] != "forbidden" {
+				t.Errorf("imported snapshot = %+v", copied)
+			}
+			for i := range orig.Findings {
+				want, got := orig.Findings[i], copied.Findings[i]
+				for _, f := range []*store.CertFinding{&want, &got} {
+					f.NotAfter, f.NotBefore, f.RenewalTime = f.NotAfter.UTC(), f.NotBefore.UTC(), f.RenewalTime.UTC()
+				}
+				if !reflect.DeepEqual(want, got) {
+					t.Errorf("finding %d changed in the round trip:\n got %+v\nwant %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestImport_ClusterAndErrors(t *testing.T) {
+	base := time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)
+	ndjson := `{"kind":"snapshot","snapshotId":7,"snapshotAt":"2026-06-01T10:00:00Z"}
+{"kind":"finding","snapshotId":7,"snapshotAt":"2026-06-01T10:00:00Z","name":"api","source":"certmanager","severity":"warn"}
+`
+	hs := openMemory(t)
+	im := NewImporter(hs, ImportOptions{Cluster: "staging"})
+	if err := Read(strings.NewReader(ndjson), im.Add); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := im.Close(); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := hs.GetAt(base, "staging")
+	if err != nil || snap == nil {
+		t.Fatalf("GetAt = %v, %v", snap, err)
+	}
+	if snap.Metadata.Cluster != "staging" || snap.Findings[0].Cluster != "staging" {
+		t.Errorf("cluster not applied: %+v", snap)
+	}
+
+	dry := NewImporter(hs, ImportOptions{DryRun: true})
+	orphan := `{"kind":"finding","snapshotId":9,"snapshotAt":"2026-06-01T10:00:00Z","name":"api"}`
+	if err := Read(strings.NewReader(orphan), dry.Add); err == nil {
+		t.Error("expected an error for a finding without its snapshot row")
+	}
+	if err := Read(strings.NewReader("{not json"), dry.Add); err == nil || !strings.Contains(err.Error(), "row 1") {
+		t.Errorf("expected a parse error naming the row, got %v", err)
+	}
+	if _, err := NewWriter(&bytes.Buffer{}, "csv"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestImport_NonUTCSnapshotNotDuplicated(t *testing.T) {
+	hs := openMemory(t)
+	at := time.Date(2026, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	if err := hs.Save(store.Snapshot{At: at, Metadata: &store.Metadata{Cluster: "prod"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Parquet keeps snapshot times in UTC, so re-importing must still match.
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatParquet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Export(hs, w, time.Time{}, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	im := NewImporter(hs, ImportOptions{})
+	if err := Read(bytes.NewReader(buf.Bytes()), im.Add); err != nil {
+		t.Fatal(err)
+	}
+	if st, err := im.Close(); err != nil || st != (Stats{Skipped: 1}) {
+		t.Errorf("re-import = %+v, %v; want the snapshot skipped", st, err)
+	}
+}
+
+func TestImport_ClustersKeepSeparateStreams(t *testing.T) {
+	// Two edge clusters scanned ten minutes apart, each with a finding open
+	// in both of its snapshots.
+	archives := map[string]string{
+		"edge-1": `{"kind":"snapshot","snapshotId":1,"snapshotAt":"2026-06-01T10:00:00Z"}
+{"kind":"finding","snapshotId":1,"snapshotAt":"2026-06-01T10:00:00Z","name":"api","source":"certmanager","severity":"warn"}
+{"kind":"snapshot","snapshotId":2,"snapshotAt":"2026-06-01T10:20:00Z"}
+{"kind":"finding","snapshotId":2,"snapshotAt":"2026-06-01T10:20:00Z","name":"api","source":"certmanager","severity":"warn"}
+`,
+		"edge-2": `{"kind":"snapshot","snapshotId":1,"snapshotAt":"2026-06-01T10:10:00Z"}
+{"kind":"finding","snapshotId":1,"snapshotAt":"2026-06-01T10:10:00Z","name":"web","source":"k8s.ingressTLS","severity":"critical"}
+{"kind":"snapshot","snapshotId":2,"snapshotAt":"2026-06-01T10:30:00Z"}
+{"kind":"finding","snapshotId":2,"snapshotAt":"2026-06-01T10:30:00Z","name":"web","source":"k8s.ingressTLS","severity":"critical"}
+`,
+	}
+	hs := openMemory(t)
+	for cluster, ndjson := range archives {
+		im := NewImporter(hs, ImportOptions{Cluster: cluster})
+		if err := Read(strings.NewReader(ndjson), im.Add); err != nil {
+			t.Fatal(err)
+		}
+		if st, err := im.Close(); err != nil || st.Snapshots != 2 {
+			t.Fatalf("import %s = %+v, %v", cluster, st, err)
+		}
+	}
+
+	episodes, err := hs.Episodes(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(episodes) != 2 {
+		t.Fatalf("Episodes = %+v; want one per cluster", episodes)
+	}
+	for _, e := range episodes {
+		if !e.Closed.IsZero() {
+			t.Errorf("episode %s/%s closed at %s by another cluster's snapshot", e.Cluster, e.Name, e.Closed)
+		}
+	}
+
+	// A day later, daily retention keeps the first snapshot of each cluster.
+	res, err := hs.Compact(history.Retention{Full: time.Minute, Daily: 30 * 24 * time.Hour}, time.Date(2026, 6, 2, 12, 0, 0, 0, time.UTC))
+	if err != nil || res.Snapshots != 2 {
+		t.Fatalf("Compact = %+v, %v; want one snapshot per cluster removed", res, err)
+	}
+	for cluster, name := range map[string]string{"edge-1": "api", "edge-2": "web"} {
+		snap, err := hs.GetLatest(cluster)
+		if err != nil || snap == nil || len(snap.Findings) != 1 || snap.Findings[0].Name != name {
+			t.Errorf("GetLatest(%s) after compaction = %+v, %v", cluster, snap, err)
+		}
+	}
+	if snap, err := hs.GetLatest(""); err != nil || snap != nil {
+		t.Errorf("GetLatest(\"\") = %+v, %v; want imported clusters kept out of the local stream", snap, err)
+	}
+}
@@ -0,0 +1,172 @@
+// Package archive exports and imports snapshot history as NDJSON or Parquet.
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// Row kinds.
+const (
+	KindSnapshot = "snapshot"
+	KindFinding  = "finding"
+)
+
+// Row is one archived record: a snapshot, or one finding of the snapshot
+// row before it. Both kinds share a single flat schema, so NDJSON and
+// Parquet archives load into the same table. Metadata and Errors are set on
+// snapshot rows only; the finding columns on finding rows only.
+type Row struct {
+	SnapshotAt         time.Time       `json:"snapshotAt" parquet:"snapshot_at,timestamp(nanosecond)"`
+	NotAfter           *time.Time      `json:"notAfter,omitempty" parquet:"not_after,optional,timestamp(nanosecond)"`
+	NotBefore          *time.Time      `json:"notBefore,omitempty" parquet:"not_before,optional,timestamp(nanosecond)"`
+	RenewalTime        *time.Time      `json:"renewalTime,omitempty" parquet:"renewal_time,optional,timestamp(nanosecond)"`
+	Kind               string          `json:"kind" parquet:"kind,dict"`
+	Source             string          `json:"source,omitempty" parquet:"source,dict"`
+	Cluster            string          `json:"cluster,omitempty" parquet:"cluster,dict"`
+	Namespace          string          `json:"namespace,omitempty" parquet:"namespace,dict"`
+	Name               string          `json:"name,omitempty" parquet:"name"`
+	Severity           string          `json:"severity,omitempty" parquet:"severity,dict"`
+	FindingType        string          `json:"findingType,omitempty" parquet:"finding_type,dict"`
+	Target             string          `json:"target,omitempty" parquet:"target"`
+	SNI                string          `json:"sni,omitempty" parquet:"sni"`
+	Subject            string          `json:"subject,omitempty" parquet:"subject"`
+	Issuer             string          `json:"issuer,omitempty" parquet:"issuer,dict"`
+	Serial             string          `json:"serial,omitempty" parquet:"serial"`
+	SignatureAlgorithm string          `json:"signatureAlgorithm,omitempty" parquet:"signature_algorithm,dict"`
+	KeyAlgorithm       string          `json:"keyAlgorithm,omitempty" parquet:"key_algorithm,dict"`
+	TLSVersion         string          `json:"tlsVersion,omitempty" parquet:"tls_version,dict"`
+	CipherSuite        string          `json:"cipherSuite,omitempty" parquet:"cipher_suite,dict"`
+	ProbeErr           string          `json:"probeError,omitempty" parquet:"probe_error"`
+	PolicyName         string          `json:"policyName,omitempty" parquet:"policy_name,dict"`
+	Owner              string          `json:"owner,omitempty" parquet:"owner,dict"`
+	Notes              string          `json:"notes,omitempty" parquet:"notes"`
+	Remediation        string          `json:"remediation,omitempty" parquet:"remediation"`
+	ObjectAPIVersion   string          `json:"objectApiVersion,omitempty" parquet:"object_api_version,dict"`
+	ObjectKind         string          `json:"objectKind,omitempty" parquet:"object_kind,dict"`
+	ObjectNamespace    string          `json:"objectNamespace,omitempty" parquet:"object_namespace,dict"`
+	ObjectName         string          `json:"objectName,omitempty" parquet:"object_name"`
+	ObjectUID          string          `json:"objectUid,omitempty" parquet:"object_uid"`
+	Metadata           json.RawMessage `json:"metadata,omitempty" parquet:"metadata,optional,json"`
+	Errors             json.RawMessage `json:"errors,omitempty" parquet:"errors,optional,json"`
+	ChainErrors        []string        `json:"chainErrors,omitempty" parquet:"chain_errors,list"`
+	DNSNames           []string        `json:"dnsNames,omitempty" parquet:"dns_names,list"`
+	IssuerChain        []string        `json:"issuerChain,omitempty" parquet:"issuer_chain,list"`
+	RevocationIssues   []string        `json:"revocationIssues,omitempty" parquet:"revocation_issues,list"`
+	PostureIssues      []string        `json:"postureIssues,omitempty" parquet:"posture_issues,list"`
+	Notify             []string        `json:"notify,omitempty" parquet:"notify,list"`
+	// SnapshotID is the ID in the exporting database; imports assign new IDs.
+	SnapshotID  int64 `json:"snapshotId" parquet:"snapshot_id"`
+	CertSeconds int64 `json:"certDurationSeconds,omitempty" parquet:"cert_duration_seconds"`
+	ChainLen    int64 `json:"chainLen,omitempty" parquet:"chain_len"`
+	KeySize     int64 `json:"keySize,omitempty" parquet:"key_size"`
+	ProbeOK     bool  `json:"probeOk,omitempty" parquet:"probe_ok"`
+	SelfSigned  bool  `json:"selfSigned,omitempty" parquet:"self_signed"`
+	IsCA        bool  `json:"isCA,omitempty" parquet:"is_ca"`
+}
+
+// SnapshotRows returns the snapshot row followed by one row per finding.
+func SnapshotRows(id int64, snap *store.Snapshot) ([]Row, error) {
+	head := Row{Kind: KindSnapshot, SnapshotID: id, SnapshotAt: snap.At}
+	if snap.Metadata != nil {
+		data, err := json.Marshal(snap.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling snapshot metadata: %w", err)
+		}
+		head.Metadata = data
+	}
+	if len(snap.Errors) > 0 {
+		data, err := json.Marshal(snap.Errors)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling snapshot errors: %w", err)
+		}
+		head.Errors = data
+	}
+
+	rows := make([]Row, 0, len(snap.Findings)+1)
+	rows = append(rows, head)
+	for i := range snap.Findings {
+		rows = append(rows, findingRow(id, snap.At, &snap.Findings[i]))
+	}
+	return rows, nil
+}
+
+// findingRow flattens a finding into a row.
+func findingRow(id int64, at time.Time, f *store.CertFinding) Row {
+	r := Row{
+		Kind: KindFinding, SnapshotID: id, SnapshotAt: at,
+		NotAfter: timePtr(f.NotAfter), NotBefore: timePtr(f.NotBefore), RenewalTime: timePtr(f.RenewalTime),
+		ChainErrors: f.ChainErrors, DNSNames: f.DNSNames, IssuerChain: f.IssuerChain,
+		RevocationIssues: f.RevocationIssues, PostureIssues: f.PostureIssues, Notify: f.Notify,
+		Source: string(f.Source), Cluster: f.Cluster, Namespace: f.Namespace, Name: f.Name,
+		Severity: string(f.Severity), FindingType: f.FindingType, Target: f.Target, SNI: f.SNI,
+		Subject: f.Subject, Issuer: f.Issuer, Serial: f.Serial, SignatureAlgorithm: f.SignatureAlgorithm,
+		KeyAlgorithm: f.KeyAlgorithm, TLSVersion: f.TLSVersion, CipherSuite: f.CipherSuite,
+		ProbeErr: f.ProbeErr, PolicyName: f.PolicyName, Owner: f.Owner, Notes: f.Notes,
+		Remediation: f.Remediation, CertSeconds: int64(f.CertDuration / time.Second),
+		ChainLen: int64(f.ChainLen), KeySize: int64(f.KeySize),
+		ProbeOK: f.ProbeOK, SelfSigned: f.SelfSigned, IsCA: f.IsCA,
+	}
+	if o := f.Object; o != nil {
+		r.ObjectAPIVersion, r.ObjectKind, r.ObjectNamespace, r.ObjectName, r.ObjectUID = o.APIVersion, o.Kind, o.Namespace, o.Name, o.UID
+	}
+	return r
+}
+
+// finding rebuilds the finding stored in a finding row.
+func (r *Row) finding() store.CertFinding {
+	f := store.CertFinding{
+		NotAfter: timeVal(r.NotAfter), NotBefore: timeVal(r.NotBefore), RenewalTime: timeVal(r.RenewalTime),
+		ChainErrors: r.ChainErrors, DNSNames: r.DNSNames, IssuerChain: r.IssuerChain,
+		RevocationIssues: r.RevocationIssues, PostureIssues: r.PostureIssues, Notify: r.Notify,
+		Source: store.SourceKind(r.Source), Cluster: r.Cluster, Namespace: r.Namespace, Name: r.Name,
+		Severity: store.Severity(r.Severity), FindingType: r.FindingType, Target: r.Target, SNI: r.SNI,
+		Subject: r.Subject, Issuer: r.Issuer, Serial: r.Serial, SignatureAlgorithm: r.SignatureAlgorithm,
+		KeyAlgorithm: r.KeyAlgorithm, TLSVersion: r.TLSVersion, CipherSuite: r.CipherSuite,
+		ProbeErr: r.ProbeErr, PolicyName: r.PolicyName, Owner: r.Owner, Notes: r.Notes,
+		Remediation: r.Remediation, CertDuration: time.Duration(r.CertSeconds) * time.Second,
+		ChainLen: int(r.ChainLen), KeySize: int(r.KeySize),
+		ProbeOK: r.ProbeOK, SelfSigned: r.SelfSigned, IsCA: r.IsCA,
+	}
+	if r.ObjectKind != "" || r.ObjectName != "" {
+		f.Object = &store.ObjectRef{
+			APIVersion: r.ObjectAPIVersion, Kind: r.ObjectKind, Namespace: r.ObjectNamespace,
+			Name: r.ObjectName, UID: r.ObjectUID,
+		}
+	}
+	return f
+}
+
+// snapshot rebuilds the snapshot, without findings, stored in a snapshot row.
+func (r *Row) snapshot() (store.Snapshot, error) {
+	snap := store.Snapshot{At: r.SnapshotAt.UTC()}
+	if len(r.Metadata) > 0 && string(r.Metadata) != "null" {
+		snap.Metadata = &store.Metadata{}
+		if err := json.Unmarshal(r.Metadata, snap.Metadata); err != nil {
+			return snap, fmt.Errorf("parsing metadata of snapshot %d: %w", r.SnapshotID, err)
+		}
+	}
+	if len(r.Errors) > 0 && string(r.Errors) != "null" {
+		if err := json.Unmarshal(r.Errors, &snap.Errors); err != nil {
+			return snap, fmt.Errorf("parsing errors of snapshot %d: %w", r.SnapshotID, err)
+		}
+	}
+	return snap, nil
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func timeVal(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
@@ -0,0 +1,286 @@
+// Package auth authenticates and authorizes requests to the serve HTTP API and UI.
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+)
+
+// Authentication methods.
+const (
+	MethodToken      = "token"
+	MethodKubernetes = "kubernetes"
+	MethodOIDC       = "oidc"
+	MethodClientCert = "clientcert"
+)
+
+// Identity is an authenticated caller.
+type Identity struct {
+	extra  map[string][]string // Kubernetes user extra, passed on to SubjectAccessReviews
+	Name   string
+	Method string
+	uid    string
+	Groups []string
+}
+
+// bearerAuthenticator resolves the caller presenting a bearer token. It
+// returns nil when it does not recognize the token, and an error only when
+// the token could not be checked.
+type bearerAuthenticator interface {
+	authenticate(ctx context.Context, token string) (*Identity, error)
+}
+
+// publicPaths are open unless a route in the config says otherwise, so
+// kubelet probes keep working with auth enabled.
+var publicPaths = []string{"/healthz", "/readyz"}
+
+// route is a compiled config.AuthRoute.
+type route struct {
+	path   string
+	users  []string
+	groups []string
+	public bool
+}
+
+// matches reports whether the route covers path: an exact match, or any path
+// below a route ending in "/".
+func (rt *route) matches(path string) bool {
+	if strings.HasSuffix(rt.path, "/") {
+		return strings.HasPrefix(path, rt.path)
+	}
+	return path == rt.path
+}
+
+// allows reports whether id may call the route. Routes without users or
+// groups allow every authenticated caller.
+func (rt *route) allows(id *Identity) bool {
+	if len(rt.users) == 0 && len(rt.groups) == 0 {
+		return true
+	}
+	if slices.Contains(rt.users, id.Name) {
+		return true
+	}
+	for _, g := range id.Groups {
+		if slices.Contains(rt.groups, g) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware enforces the auth config in front of an HTTP handler.
+type Middleware struct {
+	kube        *kubernetesAuth
+	oidc        *oidcAuth
+	bearers     []bearerAuthenticator
+	routes      []route // most specific first
+	clientCerts bool
+}
+
+// Option configures a Middleware.
+type Option func(*options)
+
+type options struct {
+	kube        kubernetes.Interface
+	clientCerts bool
+}
+
+// WithKubernetes sets the client used for TokenReviews and
+// SubjectAccessReviews; required when auth.kubernetes is enabled.
+func WithKubernetes(client kubernetes.Interface) Option {
+	return func(o *options) {
+		o.kube = client
+	}
+}
+
+// WithClientCertificates accepts callers presenting a TLS client certificate
+// verified by the server. The certificate's common name is the user and its
+// organizations are the groups.
+func WithClientCertificates() Option {
+	return func(o *options) {
+		o.clientCerts = true
+	}
+}
+
+// New builds the middleware for cfg. With OIDC configured it fetches the
+// provider's discovery document, so ctx bounds that request.
+func New(ctx context.Context, cfg *config.AuthConfig, opts ...Option) (*Middleware, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	m := &Middleware{routes: compileRoutes(cfg.Routes), clientCerts: o.clientCerts}
+	if len(cfg.Tokens) > 0 {
+		m.bearers = append(m.bearers, newStaticTokens(cfg.Tokens))
+	}
+	if cfg.OIDC != nil {
+		oa, err := newOIDC(ctx, cfg.OIDC)
+		if err != nil {
+			return nil, err
+		}
+		m.oidc = oa
+		m.bearers = append(m.bearers, oa)
+	}
+	if cfg.Kubernetes.Enabled {
+		if o.kube == nil {
+			return nil, errors.New("auth.kubernetes requires a Kubernetes client")
+		}
+		m.kube = newKubernetesAuth(o.kube, &cfg.Kubernetes)
+		m.bearers = append(m.bearers, m.kube)
+	}
+	return m, nil
+}
+
+// compileRoutes adds the default public probe routes and orders the routes
+// so the longest, most specific path matches first.
+func compileRoutes(cfgRoutes []config.AuthRoute) []route {
+	routes := make([]route, 0, len(cfgRoutes)+len(publicPaths))
+	seen := make(map[string]bool, len(cfgRoutes))
+	for _, r := range cfgRoutes {
+		routes = append(routes, route{path: r.Path, public: r.Access == config.AccessPublic, users: r.Users, groups: r.Groups})
+		seen[r.Path] = true
+	}
+	for _, p := range publicPaths {
+		if !seen[p] {
+			routes = append(routes, route{path: p, public: true})
+		}
+	}
+	sort.SliceStable(routes, func(i, j int) bool { return len(routes[i].path) > len(routes[j].path) })
+	return routes
+}
+
+// routeFor returns the route for path. Paths without a route require an
+// authenticated caller.
+func (m *Middleware) routeFor(path string) *route {
+	for i := range m.routes {
+		if m.routes[i].matches(path) {
+			return &m.routes[i]
+		}
+	}
+	return &route{path: path}
+}
+
+// Handler wraps next so that only callers allowed by the route reach it.
+// With OIDC configured it also serves the sign-in, callback, and sign-out paths.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.oidc != nil && m.oidc.serves(r.URL.Path) {
+			m.oidc.ServeHTTP(w, r)
+			return
+		}
+		rt := m.routeFor(cleanPath(r.URL.Path))
+		if rt.public {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id, err := m.authenticate(r)
+		if err != nil {
+			slog.Error("authenticating request", "path", r.URL.Path, "err", err)
+			http.Error(w, "authentication unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if id == nil {
+			m.challenge(w, r)
+			return
+		}
+		if !rt.allows(id) {
+			slog.Debug("request denied by route", "path", r.URL.Path, "user", id.Name, "method", id.Method)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if id.Method == MethodKubernetes {
+			allowed, authzErr := m.kube.authorize(r.Context(), id, r.URL.Path, strings.ToLower(r.Method))
+			if authzErr != nil {
+				slog.Error("authorizing request", "path", r.URL.Path, "user", id.Name, "err", authzErr)
+				http.Error(w, "authorization unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			if !allowed {
+				slog.Debug("request denied by RBAC", "path", r.URL.Path, "user", id.Name)
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate returns the caller of r from its bearer token, its verified
+// client certificate or, with OIDC, its session cookie. It returns nil for
+// anonymous or unrecognized callers.
+func (m *Middleware) authenticate(r *http.Request) (*Identity, error) {
+	if token, ok := bearerToken(r); ok {
+		for _, b := range m.bearers {
+			id, err := b.authenticate(r.Context(), token)
+			if err != nil || id != nil {
+				return id, err
+			}
+		}
+		return nil, nil
+	}
+	if id := m.clientCert(r); id != nil {
+		return id, nil
+	}
+	if m.oidc != nil {
+		return m.oidc.session(r), nil
+	}
+	return nil, nil
+}
+
+// clientCert returns the caller of a verified TLS client certificate, or nil.
+func (m *Middleware) clientCert(r *http.Request) *Identity {
+	if !m.clientCerts || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	subject := r.TLS.VerifiedChains[0][0].Subject
+	if subject.CommonName == "" {
+		return nil
+	}
+	return &Identity{Name: subject.CommonName, Method: MethodClientCert, Groups: subject.Organization}
+}
+
+// challenge answers an unauthenticated request: browsers are sent to the
+// OIDC sign-in when it is configured, everything else gets a 401.
+func (m *Middleware) challenge(w http.ResponseWriter, r *http.Request) {
+	if m.oidc != nil && r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+		m.oidc.redirectToLogin(w, r)
+		return
+	}
+	w.Header().Set("WWW-Authenticate", `Bearer realm="trustwatch"`)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+// cleanPath resolves dot segments and repeated slashes the way ServeMux
+// does, keeping a trailing slash, so a route cannot be bypassed with "..".
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	clean := path.Clean(p)
+	if strings.HasSuffix(p, "/") && clean != "/" {
+		clean += "/"
+	}
+	return clean
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
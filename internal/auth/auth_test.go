@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authnv1 "k8s.io/api/authentication/v1"
+	authzv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func do(t *testing.T, h http.Handler, method, path, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, http.NoBody)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_TokensAndRoutes(t *testing.T) {
+	cfg := &config.AuthConfig{
+		Tokens: []config.TokenConfig{
+			{Name: "hub", Token: "hub-token-0123456789"},
+			{Name: "grafana", Token: "grafana-token-0123456789", Groups: []string{"dashboards"}},
+		},
+		Routes: []config.AuthRoute{
+			{Path: "/metrics", Access: config.AccessPublic},
+			{Path: "/api/v1/", Users: []string{"hub"}, Groups: []string{"dashboards"}},
+			{Path: "/api/v1/notifications", Users: []string{"hub"}},
+		},
+	}
+	m, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := m.Handler(okHandler)
+
+	tests := []struct {
+		name, method, path, token string
+		want                      int
+	}{
+		{"healthz stays open", http.MethodGet, "/healthz", "", http.StatusOK},
+		{"readyz stays open", http.MethodGet, "/readyz", "", http.StatusOK},
+		{"public metrics", http.MethodGet, "/metrics", "", http.StatusOK},
+		{"UI needs a token", http.MethodGet, "/", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "/", "hub-token-0123456780", http.StatusUnauthorized},
+		{"UI with any token", http.MethodGet, "/", "grafana-token-0123456789", http.StatusOK},
+		{"snapshot by user", http.MethodGet, "/api/v1/snapshot", "hub-token-0123456789", http.StatusOK},
+		{"snapshot by group", http.MethodGet, "/api/v1/snapshot", "grafana-token-0123456789", http.StatusOK},
+		{"more specific route wins", http.MethodGet, "/api/v1/notifications", "grafana-token-0123456789", http.StatusForbidden},
+		{"dot segments do not escape routes", http.MethodGet, "/metrics/../api/v1/notifications", "grafana-token-0123456789", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(t, h, tt.method, tt.path, tt.token); rec.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.want)
+			}
+		})
+	}
+	if rec := do(t, h, http.MethodGet, "/", ""); rec.Header().Get("WWW-Authenticate") == "" {
+		t.Error("401 response is missing WWW-Authenticate")
+	}
+}
+
+func TestMiddleware_ProbeRoutesCanBeProtected(t *testing.T) {
+	cfg := &config.AuthConfig{
+		Tokens: []config.TokenConfig{{Name: "ops", Token: "ops-token-0123456789"}},
+		Routes: []config.AuthRoute{{Path: "/readyz", Access: config.AccessAuthenticated}},
+	}
+	m, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := m.Handler(okHandler)
+	if rec := do(t, h, http.MethodGet, "/readyz", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("/readyz = %d, want 401 when configured as authenticated", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/healthz", ""); rec.Code != http.StatusOK {
+		t.Errorf("/healthz = %d, want 200", rec.Code)
+	}
+}
+
+func TestMiddleware_Kubernetes(t *testing.T) {
+	cs := fake.NewClientset()
+	var tokenReviews, accessReviews int
+	cs.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		tokenReviews++
+		tr := action.(k8stesting.CreateAction).GetObject().(*authnv1.TokenReview)
+		if tr.Spec.Token == "prometheus-sa-token" {
+			tr.Status = authnv1.TokenReviewStatus{Authenticated: true, User: authnv1.UserInfo{
+				Username: "system:serviceaccount:monitoring:prometheus",
+				Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:monitoring"},
+			}}
+		}
+		return true, tr, nil
+	})
+	cs.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		accessReviews++
+		sar := action.(k8stesting.CreateAction).GetObject().(*authzv1.SubjectAccessReview)
+		attrs := sar.Spec.NonResourceAttributes
+		sar.Status.Allowed = attrs != nil && attrs.Path == "/metrics" && attrs.Verb == "get" &&
+			sar.Spec.User == "system:serviceaccount:monitoring:prometheus"
+		return true, sar, nil
+	})
+
+	m, err := New(context.Background(), &config.AuthConfig{Kubernetes: config.KubernetesAuthConfig{Enabled: true}}, WithKubernetes(cs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := m.Handler(okHandler)
+
+	if rec := do(t, h, http.MethodGet, "/metrics", "prometheus-sa-token"); rec.Code != http.StatusOK {
+		t.Errorf("/metrics = %d, want 200 for the RBAC-allowed service account", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/api/v1/snapshot", "prometheus-sa-token"); rec.Code != http.StatusForbidden {
+		t.Errorf("/api/v1/snapshot = %d, want 403 without RBAC", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/metrics", "unknown-token"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("/metrics with an unknown token = %d, want 401", rec.Code)
+	}
+
+	before := [2]int{tokenReviews, accessReviews}
+	for range 3 {
+		do(t, h, http.MethodGet, "/metrics", "prometheus-sa-token")
+	}
+	if after := [2]int{tokenReviews, accessReviews}; after != before {
+		t.Errorf("reviews after cached requests = %v, want %v", after, before)
+	}
+
+	if _, err := New(context.Background(), &config.AuthConfig{Kubernetes: config.KubernetesAuthConfig{Enabled: true}}); err == nil {
+		t.Error("expected an error without a Kubernetes client")
+	}
+}
+
+func TestMiddleware_ClientCertificates(t *testing.T) {
+	cfg := &config.AuthConfig{Routes: []config.AuthRoute{{Path: "/api/v1/", Groups: []string{"federation"}}}}
+	m, err := New(context.Background(), cfg, WithClientCertificates())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := m.Handler(okHandler)
+	get := func(path string, subject *pkix.Name) int {
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		if subject != nil {
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: *subject}}}}
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	hub := &pkix.Name{CommonName: "hub", Organization: []string{"federation"}}
+	if code := get("/api/v1/snapshot", hub); code != http.StatusOK {
+		t.Errorf("client certificate in group federation = %d, want 200", code)
+	}
+	if code := get("/api/v1/snapshot", &pkix.Name{CommonName: "grafana"}); code != http.StatusForbidden {
+		t.Errorf("client certificate outside the group = %d, want 403", code)
+	}
+	if code := get("/", &pkix.Name{Organization: []string{"federation"}}); code != http.StatusUnauthorized {
+		t.Errorf("client certificate without a common name = %d, want 401", code)
+	}
+	if code := get("/", nil); code != http.StatusUnauthorized {
+		t.Errorf("no client certificate = %d, want 401", code)
+	}
+}
+
+func TestSafeRedirect(t *testing.T) {
+	for rd, want := range map[string]string{
+		"/?severity=critical":  "/?severity=critical",
+		"":                     "/",
+		"https://evil.example": "/",
+		"//evil.example":       "/",
+		"/\\evil.example":      "/",
+	} {
+		if got := safeRedirect(rd); got != want {
+			t.Errorf("safeRedirect(%q) = %q, want %q", rd, got, want)
+		}
+	}
+}
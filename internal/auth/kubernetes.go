@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	authnv1 "k8s.io/api/authentication/v1"
+	authzv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+)
+
+const (
+	defaultReviewCacheTTL = time.Minute
+	// maxReviewCache bounds the review cache; it is emptied when full.
+	maxReviewCache = 4096
+)
+
+// kubernetesAuth authenticates service account and user tokens with a
+// TokenReview and authorizes them with a SubjectAccessReview for the
+// non-resource URL being requested. Results are cached for the TTL so a
+// dashboard refresh does not hit the API server on every request.
+type kubernetesAuth struct {
+	client    kubernetes.Interface
+	cache     map[string]reviewResult
+	audiences []string
+	ttl       time.Duration
+	mu        sync.Mutex
+}
+
+type reviewResult struct {
+	expires time.Time
+	id      *Identity
+	allowed bool
+}
+
+func newKubernetesAuth(client kubernetes.Interface, cfg *config.KubernetesAuthConfig) *kubernetesAuth {
+	ttl := cfg.CacheTTL
+	if ttl == 0 {
+		ttl = defaultReviewCacheTTL
+	}
+	return &kubernetesAuth{client: client, audiences: cfg.Audiences, ttl: ttl, cache: make(map[string]reviewResult)}
+}
+
+func (k *kubernetesAuth) authenticate(ctx context.Context, token string) (*Identity, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := "tokenreview/" + hex.EncodeToString(sum[:])
+	if res, ok := k.cached(key); ok {
+		return res.id, nil
+	}
+
+	review, err := k.client.AuthenticationV1().TokenReviews().Create(ctx, &authnv1.TokenReview{
+		Spec: authnv1.TokenReviewSpec{Token: token, Audiences: k.audiences},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("creating TokenReview: %w", err)
+	}
+	var id *Identity
+	if st := review.Status; st.Authenticated {
+		id = &Identity{Name: st.User.Username, Method: MethodKubernetes, Groups: st.User.Groups, uid: st.User.UID}
+		if len(st.User.Extra) > 0 {
+			id.extra = make(map[string][]string, len(st.User.Extra))
+			for k, v := range st.User.Extra {
+				id.extra[k] = v
+			}
+		}
+	}
+	k.store(key, reviewResult{id: id})
+	return id, nil
+}
+
+// authorize asks the API server whether id may use verb on the non-resource
+// URL path, as RBAC rules with nonResourceURLs grant.
+func (k *kubernetesAuth) authorize(ctx context.Context, id *Identity, path, verb string) (bool, error) {
+	key := strings.Join([]string{"sar", id.Name, verb, path}, "\x00")
+	if res, ok := k.cached(key); ok {
+		return res.allowed, nil
+	}
+
+	spec := authzv1.SubjectAccessReviewSpec{
+		NonResourceAttributes: &authzv1.NonResourceAttributes{Path: path, Verb: verb},
+		User:                  id.Name,
+		Groups:                id.Groups,
+		UID:                   id.uid,
+	}
+	if len(id.extra) > 0 {
+		spec.Extra = make(map[string]authzv1.ExtraValue, len(id.extra))
+		for k, v := range id.extra {
+			spec.Extra[k] = v
+		}
+	}
+	review, err := k.client.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authzv1.SubjectAccessReview{Spec: spec}, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("creating SubjectAccessReview: %w", err)
+	}
+	allowed := review.Status.Allowed && !review.Status.Denied
+	k.store(key, reviewResult{allowed: allowed})
+	return allowed, nil
+}
+
+func (k *kubernetesAuth) cached(key string) (reviewResult, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	res, ok := k.cache[key]
+	if !ok || time.Now().After(res.expires) {
+		return reviewResult{}, false
+	}
+	return res, true
+}
+
+func (k *kubernetesAuth) store(key string, res reviewResult) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.cache) >= maxReviewCache {
+		clear(k.cache)
+	}
+	res.expires = time.Now().Add(k.ttl)
+	k.cache[key] = res
+}
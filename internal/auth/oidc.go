@@ -0,0 +1,346 @@
+package auth
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+)
+
+// Sign-in paths served by the middleware. The callback path is taken from
+// auth.oidc.redirectURL.
+const (
+	LoginPath  = "/auth/login"
+	LogoutPath = "/auth/logout"
+)
+
+const (
+	sessionCookie = "trustwatch_session"
+	stateCookie   = "trustwatch_oidc_state"
+	// stateTTL bounds how long a sign-in may take at the provider.
+	stateTTL = 10 * time.Minute
+	// signInFailed is all a failed callback reveals; the cause is logged.
+	signInFailed = "sign-in failed"
+)
+
+// oidcAuth signs UI users in with the authorization code flow and keeps them
+// signed in with an HMAC-signed session cookie. It also accepts ID tokens
+// issued to the client as bearer tokens.
+type oidcAuth struct {
+	verifier      *oidc.IDTokenVerifier
+	oauth         oauth2.Config
+	issuer        string
+	callbackPath  string
+	usernameClaim string
+	groupsClaim   string
+	key           []byte
+	ttl           time.Duration
+	secure        bool // HTTPS redirect URL: mark cookies Secure
+}
+
+// session is the payload of the session cookie.
+type session struct {
+	Expires time.Time `json:"exp"`
+	Name    string    `json:"name"`
+	Groups  []string  `json:"groups,omitempty"`
+}
+
+// loginState is the payload of the state cookie set for one sign-in.
+type loginState struct {
+	Expires  time.Time `json:"exp"`
+	State    string    `json:"state"`
+	Nonce    string    `json:"nonce"`
+	Redirect string    `json:"rd"`
+}
+
+func newOIDC(ctx context.Context, cfg *config.OIDCConfig) (*oidcAuth, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("discovering OIDC provider %s: %w", cfg.IssuerURL, err)
+	}
+	redirect, err := url.Parse(cfg.RedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing auth.oidc.redirectURL: %w", err)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	o := &oidcAuth{
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       scopes,
+		},
+		issuer:        cfg.IssuerURL,
+		callbackPath:  redirect.Path,
+		usernameClaim: cmp.Or(cfg.UsernameClaim, config.DefaultUsernameClaim),
+		groupsClaim:   cmp.Or(cfg.GroupsClaim, config.DefaultGroupsClaim),
+		key:           []byte(cfg.CookieSecret),
+		ttl:           cfg.SessionTTL,
+		secure:        redirect.Scheme == "https",
+	}
+	if o.ttl == 0 {
+		o.ttl = config.DefaultSessionTTL
+	}
+	if len(o.key) == 0 {
+		o.key = make([]byte, 32)
+		if _, err := rand.Read(o.key); err != nil {
+			return nil, fmt.Errorf("generating cookie secret: %w", err)
+		}
+		slog.Info("auth.oidc.cookieSecret not set: sessions are valid only on this replica until restart")
+	}
+	return o, nil
+}
+
+// serves reports whether path is one of the sign-in paths.
+func (o *oidcAuth) serves(path string) bool {
+	return path == LoginPath || path == LogoutPath || path == o.callbackPath
+}
+
+func (o *oidcAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case LoginPath:
+		o.login(w, r)
+	case LogoutPath:
+		o.setCookie(w, sessionCookie, "", -1)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "Signed out of trustwatch.") //nolint:errcheck // best-effort response
+	default:
+		o.callback(w, r)
+	}
+}
+
+// redirectToLogin sends the browser to the sign-in path, returning to the
+// requested page afterwards.
+func (o *oidcAuth) redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, LoginPath+"?rd="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+}
+
+// login starts the authorization code flow.
+func (o *oidcAuth) login(w http.ResponseWriter, r *http.Request) {
+	st := loginState{
+		Expires:  time.Now().Add(stateTTL),
+		State:    randomString(),
+		Nonce:    randomString(),
+		Redirect: safeRedirect(r.URL.Query().Get("rd")),
+	}
+	value, err := o.sign(stateCookie, st)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	o.setCookie(w, stateCookie, value, int(stateTTL/time.Second))
+	http.Redirect(w, r, o.oauth.AuthCodeURL(st.State, oidc.Nonce(st.Nonce)), http.StatusFound)
+}
+
+// callback completes the flow: it checks the state, exchanges the code,
+// verifies the ID token, and sets the session cookie.
+func (o *oidcAuth) callback(w http.ResponseWriter, r *http.Request) {
+	var st loginState
+	c, err := r.Cookie(stateCookie)
+	if err != nil || o.verify(stateCookie, c.Value, &st) != nil || time.Now().After(st.Expires) {
+		http.Error(w, "sign-in expired, please retry", http.StatusBadRequest)
+		return
+	}
+	o.setCookie(w, stateCookie, "", -1)
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, signInFailed+": "+e, http.StatusUnauthorized)
+		return
+	}
+	if !hmac.Equal([]byte(q.Get("state")), []byte(st.State)) {
+		http.Error(w, "sign-in state mismatch", http.StatusBadRequest)
+		return
+	}
+
+	tok, err := o.oauth.Exchange(r.Context(), q.Get("code"))
+	if err != nil {
+		slog.Warn("exchanging OIDC code", "err", err)
+		http.Error(w, signInFailed, http.StatusUnauthorized)
+		return
+	}
+	raw, _ := tok.Extra("id_token").(string)
+	idToken, err := o.verifier.Verify(r.Context(), raw)
+	if err != nil || idToken.Nonce != st.Nonce {
+		slog.Warn("verifying OIDC ID token", "err", err)
+		http.Error(w, signInFailed, http.StatusUnauthorized)
+		return
+	}
+	id, err := o.identity(idToken)
+	if err != nil {
+		slog.Warn("reading OIDC claims", "err", err)
+		http.Error(w, signInFailed, http.StatusUnauthorized)
+		return
+	}
+
+	value, err := o.sign(sessionCookie, session{Expires: time.Now().Add(o.ttl), Name: id.Name, Groups: id.Groups})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	o.setCookie(w, sessionCookie, value, int(o.ttl/time.Second))
+	slog.Info("OIDC sign-in", "user", id.Name)
+	http.Redirect(w, r, st.Redirect, http.StatusFound)
+}
+
+// session returns the caller of a valid session cookie, or nil.
+func (o *oidcAuth) session(r *http.Request) *Identity {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+	var s session
+	if o.verify(sessionCookie, c.Value, &s) != nil || s.Name == "" || time.Now().After(s.Expires) {
+		return nil
+	}
+	return &Identity{Name: s.Name, Method: MethodOIDC, Groups: s.Groups}
+}
+
+// authenticate accepts ID tokens from the configured issuer. Tokens from
+// other issuers, such as Kubernetes service account tokens, are left to the
+// next authenticator.
+func (o *oidcAuth) authenticate(ctx context.Context, token string) (*Identity, error) {
+	if tokenIssuer(token) != o.issuer {
+		return nil, nil
+	}
+	idToken, err := o.verifier.Verify(ctx, token)
+	if err != nil {
+		slog.Debug("rejecting OIDC bearer token", "err", err)
+		return nil, nil
+	}
+	id, err := o.identity(idToken)
+	if err != nil {
+		slog.Debug("rejecting OIDC bearer token", "err", err)
+		return nil, nil
+	}
+	return id, nil
+}
+
+// identity reads the username and groups claims of a verified ID token.
+func (o *oidcAuth) identity(idToken *oidc.IDToken) (*Identity, error) {
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("parsing claims: %w", err)
+	}
+	name, _ := claims[o.usernameClaim].(string)
+	if name == "" {
+		return nil, fmt.Errorf("ID token has no %q claim", o.usernameClaim)
+	}
+	if o.usernameClaim == "email" {
+		if verified, ok := claims["email_verified"].(bool); ok && !verified {
+			return nil, fmt.Errorf("email %s is not verified", name)
+		}
+	}
+	id := &Identity{Name: name, Method: MethodOIDC}
+	switch groups := claims[o.groupsClaim].(type) {
+	case string:
+		id.Groups = []string{groups}
+	case []any:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				id.Groups = append(id.Groups, s)
+			}
+		}
+	}
+	return id, nil
+}
+
+// sign encodes v as base64 JSON followed by its HMAC-SHA256. The MAC covers
+// the cookie name, so a value signed for one cookie does not verify as another.
+func (o *oidcAuth) sign(cookie string, v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("encoding cookie: %w", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(o.mac(cookie, payload)), nil
+}
+
+// verify checks a value produced by sign for the same cookie and decodes it into v.
+func (o *oidcAuth) verify(cookie, value string, v any) error {
+	payload, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return errors.New("malformed cookie")
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, o.mac(cookie, payload)) {
+		return errors.New("invalid cookie signature")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return fmt.Errorf("decoding cookie: %w", err)
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (o *oidcAuth) mac(cookie, payload string) []byte {
+	h := hmac.New(sha256.New, o.key)
+	h.Write([]byte(cookie + "\x00" + payload)) //nolint:errcheck // hash writes never fail
+	return h.Sum(nil)
+}
+
+func (o *oidcAuth) setCookie(w http.ResponseWriter, name, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   o.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// tokenIssuer returns the unverified "iss" claim of a JWT, or "" when token
+// is not a JWT.
+func tokenIssuer(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if json.Unmarshal(data, &claims) != nil {
+		return ""
+	}
+	return claims.Issuer
+}
+
+// safeRedirect keeps post-sign-in redirects on this server.
+func safeRedirect(rd string) string {
+	if !strings.HasPrefix(rd, "/") || strings.HasPrefix(rd, "//") || strings.HasPrefix(rd, "/\\") {
+		return "/"
+	}
+	return rd
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b) //nolint:errcheck // crypto/rand.Read never returns an error
+	return base64.RawURLEncoding.EncodeToString(b)
+}
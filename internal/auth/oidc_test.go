@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+)
+
+// fakeProvider is a minimal OpenID Connect provider that issues ID tokens
+// for the last nonce it was asked to authorize.
+type fakeProvider struct {
+	srv    *httptest.Server
+	signer jose.Signer
+	nonce  string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, (&jose.SignerOptions{}).WithHeader("kid", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProvider{signer: signer}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck // test server
+			"issuer":                                p.srv.URL,
+			"authorization_endpoint":                p.srv.URL + "/authorize",
+			"token_endpoint":                        p.srv.URL + "/token",
+			"jwks_uri":                              p.srv.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{ //nolint:errcheck // test server
+			{Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck // test server
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     p.idToken(t, map[string]any{"nonce": p.nonce}),
+		})
+	})
+	p.srv = httptest.NewServer(mux)
+	t.Cleanup(p.srv.Close)
+	return p
+}
+
+// idToken signs an ID token for alice, with extra claims merged in.
+func (p *fakeProvider) idToken(t *testing.T, extra map[string]any) string {
+	t.Helper()
+	claims := map[string]any{
+		"iss": p.srv.URL, "aud": "trustwatch", "sub": "alice-id",
+		"email": "alice@example.com", "email_verified": true, "groups": []string{"platform"},
+		"iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := p.signer.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := obj.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestOIDC_SignInFlow(t *testing.T) {
+	p := newFakeProvider(t)
+	cfg := &config.AuthConfig{
+		OIDC: &config.OIDCConfig{
+			IssuerURL: p.srv.URL, ClientID: "trustwatch", ClientSecret: "secret",
+			RedirectURL: "https://trustwatch.example.com/auth/callback",
+		},
+		Routes: []config.AuthRoute{{Path: "/api/v1/notifications", Groups: []string{"sre"}}},
+	}
+	m, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := m.Handler(okHandler)
+
+	get := func(path string, cookies []*http.Cookie, html bool) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		if html {
+			req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		}
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A browser is sent to sign in; an API client gets a 401.
+	rec := get("/?severity=critical", nil, true)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != LoginPath+"?rd=%2F%3Fseverity%3Dcritical" {
+		t.Fatalf("UI without session = %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := get("/api/v1/snapshot", nil, false); rec.Code != http.StatusUnauthorized {
+		t.Errorf("API without session = %d, want 401", rec.Code)
+	}
+
+	rec = get(rec.Header().Get("Location"), nil, true)
+	authURL, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(authURL.String(), p.srv.URL+"/authorize") {
+		t.Fatalf("login redirect = %q", rec.Header().Get("Location"))
+	}
+	state := authURL.Query().Get("state")
+	p.nonce = authURL.Query().Get("nonce")
+	stateCookies := rec.Result().Cookies()
+
+	// A state cookie replayed as a session cookie does not sign anyone in.
+	for _, c := range stateCookies {
+		replayed := *c
+		replayed.Name = sessionCookie
+		if rec := get("/api/v1/snapshot", []*http.Cookie{&replayed}, false); rec.Code != http.StatusUnauthorized {
+			t.Errorf("state cookie replayed as a session = %d, want 401", rec.Code)
+		}
+	}
+
+	if rec := get("/auth/callback?code=good-code&state=forged", stateCookies, true); rec.Code != http.StatusBadRequest {
+		t.Errorf("callback with a forged state = %d, want 400", rec.Code)
+	}
+	rec = get("/auth/callback?code=good-code&state="+state, stateCookies, true)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/?severity=critical" {
+		t.Fatalf("callback = %d %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body)
+	}
+	var sessionCookies []*http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == sessionCookie {
+			if !c.HttpOnly || !c.Secure {
+				t.Errorf("session cookie is not HttpOnly and Secure: %+v", c)
+			}
+			sessionCookies = append(sessionCookies, c)
+		}
+	}
+	if len(sessionCookies) != 1 {
+		t.Fatalf("callback set no session cookie")
+	}
+
+	if rec := get("/", sessionCookies, true); rec.Code != http.StatusOK {
+		t.Errorf("UI with session = %d, want 200", rec.Code)
+	}
+	if rec := get("/api/v1/notifications", sessionCookies, false); rec.Code != http.StatusForbidden {
+		t.Errorf("route restricted to group sre = %d, want 403", rec.Code)
+	}
+	tampered := *sessionCookies[0]
+	tampered.Value = "x" + tampered.Value
+	if rec := get("/api/v1/snapshot", []*http.Cookie{&tampered}, false); rec.Code != http.StatusUnauthorized {
+		t.Errorf("tampered session = %d, want 401", rec.Code)
+	}
+
+	// ID tokens from the provider also work as bearer tokens.
+	if rec := do(t, h, http.MethodGet, "/api/v1/notifications", p.idToken(t, map[string]any{"groups": []string{"sre"}})); rec.Code != http.StatusOK {
+		t.Errorf("bearer ID token in group sre = %d, want 200", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/", p.idToken(t, map[string]any{"aud": "other-client"})); rec.Code != http.StatusUnauthorized {
+		t.Errorf("ID token for another client = %d, want 401", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/", p.idToken(t, map[string]any{"email_verified": false})); rec.Code != http.StatusUnauthorized {
+		t.Errorf("ID token with an unverified email = %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,206 @@
+// Package calendar forecasts certificate renewals and expiries by week.
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/discovery"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// Grouping modes for Options.GroupBy.
+const (
+	GroupByOwner     = "owner"
+	GroupByNamespace = "namespace"
+)
+
+// DefaultHorizon is how far ahead the calendar looks when Options.Horizon is zero.
+const DefaultHorizon = 90 * 24 * time.Hour
+
+// unowned is the group key for findings without an owner.
+const unowned = "(unowned)"
+
+// Options selects the forecast window and grouping.
+type Options struct {
+	GroupBy string // owner or namespace (default)
+	Horizon time.Duration
+}
+
+// Entry is one certificate due for renewal or expiry within the horizon.
+type Entry struct {
+	// Due is the expected renewal date for cert-manager certificates and
+	// notAfter for everything else.
+	Due       time.Time        `json:"due"`
+	NotAfter  time.Time        `json:"notAfter"`
+	Cluster   string           `json:"cluster,omitempty"`
+	Source    store.SourceKind `json:"source"`
+	Namespace string           `json:"namespace,omitempty"`
+	Name      string           `json:"name"`
+	Owner     string           `json:"owner,omitempty"`
+	Issuer    string           `json:"issuer,omitempty"`
+	Severity  store.Severity   `json:"severity"`
+	Renewal   bool             `json:"renewal"`           // Due is a renewal rather than notAfter
+	Overdue   bool             `json:"overdue,omitempty"` // renewal date passed but the certificate has not expired
+}
+
+// Key identifies the certificate as cluster/source/namespace/name.
+func (e *Entry) Key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", e.Cluster, e.Source, e.Namespace, e.Name)
+}
+
+// Label is a short display name: namespace/name, prefixed with the cluster in federated snapshots.
+func (e *Entry) Label() string {
+	label := e.Name
+	if e.Namespace != "" {
+		label = e.Namespace + "/" + label
+	}
+	if e.Cluster != "" {
+		label = e.Cluster + ":" + label
+	}
+	return label
+}
+
+// Group is the entries of one owner or namespace within a week.
+type Group struct {
+	Key     string  `json:"key"`
+	Entries []Entry `json:"entries"`
+}
+
+// Week is the entries due in one ISO week, starting Monday 00:00 UTC.
+type Week struct {
+	Start  time.Time `json:"start"`
+	Groups []Group   `json:"groups"`
+	Count  int       `json:"count"`
+}
+
+// Calendar is the forecast of every week from now to the horizon.
+type Calendar struct {
+	Generated time.Time `json:"generated"`
+	Until     time.Time `json:"until"`
+	GroupBy   string    `json:"groupBy"`
+	Weeks     []Week    `json:"weeks"`
+	Total     int       `json:"total"`
+}
+
+// DueDate returns when a certificate is expected to be replaced and whether
+// that date is a renewal. cert-manager certificates renew at their recorded
+// renewal time, or with a third of their lifetime left by default; other
+// certificates are due at notAfter.
+func DueDate(f *store.CertFinding) (due time.Time, renewal bool) {
+	if !f.RenewalTime.IsZero() {
+		return f.RenewalTime, true
+	}
+	if f.Source == store.SourceCertManager {
+		lifetime := f.CertDuration
+		if !f.NotBefore.IsZero() {
+			lifetime = f.NotAfter.Sub(f.NotBefore)
+		}
+		if lifetime > 0 {
+			return f.NotAfter.Add(-lifetime / 3), true
+		}
+	}
+	return f.NotAfter, false
+}
+
+// Build buckets the certificates due between now and the horizon by week and
+// groups them by owner or namespace. Certificates already expired are left
+// out; those with an overdue renewal land in the current week. Secrets
+// managed by a cert-manager Certificate are represented by the Certificate,
+// and findings derived from the same certificate are counted once.
+func Build(findings []store.CertFinding, opts Options, now time.Time) *Calendar {
+	if opts.Horizon <= 0 {
+		opts.Horizon = DefaultHorizon
+	}
+	if opts.GroupBy == "" {
+		opts.GroupBy = GroupByNamespace
+	}
+	now = now.UTC()
+	cal := &Calendar{Generated: now, Until: now.Add(opts.Horizon), GroupBy: opts.GroupBy}
+
+	var entries []Entry
+	seen := make(map[string]int) // entry key → index in entries
+	for i := range findings {
+		f := &findings[i]
+		if !f.ProbeOK || f.NotAfter.IsZero() || !f.NotAfter.After(now) || f.FindingType == discovery.FindingManagedExpiry {
+			continue
+		}
+		due, renewal := DueDate(f)
+		if due.After(cal.Until) {
+			continue
+		}
+		e := Entry{
+			Due: due, NotAfter: f.NotAfter, Cluster: f.Cluster, Source: f.Source, Namespace: f.Namespace,
+			Name: f.Name, Owner: f.Owner, Issuer: f.Issuer, Severity: f.Severity, Renewal: renewal,
+			Overdue: due.Before(now),
+		}
+		// Policy, drift, and rotation findings repeat the certificate they are about;
+		// the plain discovery finding wins.
+		if j, ok := seen[e.Key()]; ok {
+			if f.FindingType == "" {
+				entries[j] = e
+			}
+			continue
+		}
+		seen[e.Key()] = len(entries)
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Due.Equal(entries[j].Due) {
+			return entries[i].Due.Before(entries[j].Due)
+		}
+		return entries[i].Key() < entries[j].Key()
+	})
+
+	weeks := make(map[time.Time]map[string][]Entry)
+	for i := range entries {
+		start := weekStart(entries[i].Due)
+		if entries[i].Overdue {
+			start = weekStart(now)
+		}
+		if weeks[start] == nil {
+			weeks[start] = make(map[string][]Entry)
+		}
+		key := groupKey(&entries[i], opts.GroupBy)
+		weeks[start][key] = append(weeks[start][key], entries[i])
+	}
+
+	for start := weekStart(now); !start.After(cal.Until); start = start.AddDate(0, 0, 7) {
+		w := Week{Start: start, Groups: []Group{}}
+		for key, es := range weeks[start] {
+			w.Groups = append(w.Groups, Group{Key: key, Entries: es})
+			w.Count += len(es)
+		}
+		sort.Slice(w.Groups, func(i, j int) bool { return w.Groups[i].Key < w.Groups[j].Key })
+		cal.Weeks = append(cal.Weeks, w)
+		cal.Total += w.Count
+	}
+	return cal
+}
+
+// groupKey returns the owner or namespace an entry is grouped under.
+// Namespaces are qualified by cluster in federated snapshots.
+func groupKey(e *Entry, groupBy string) string {
+	if groupBy == GroupByOwner {
+		if e.Owner == "" {
+			return unowned
+		}
+		return e.Owner
+	}
+	ns := e.Namespace
+	if ns == "" {
+		ns = "(cluster)"
+	}
+	if e.Cluster != "" {
+		return e.Cluster + "/" + ns
+	}
+	return ns
+}
+
+// weekStart returns Monday 00:00 UTC of the ISO week containing t.
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
@@ -0,0 +1,159 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/discovery"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// now is a Wednesday, so the current week starts on Monday 2026-10-12.
+var now = time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+
+func days(n int) time.Duration { return time.Duration(n) * 24 * time.Hour }
+
+func fleet() []store.CertFinding {
+	return []store.CertFinding{
+		// Renews at its recorded renewal time, three weeks out.
+		{
+			Name: "api-tls", Namespace: "payments", Cluster: "prod-eu", Owner: "team-payments",
+			Source: store.SourceCertManager, ProbeOK: true,
+			NotAfter: now.Add(days(40)), RenewalTime: now.Add(days(21)),
+		},
+		// No renewal time: cert-manager renews with a third of 90 days left.
+		{
+			Name: "web-tls", Namespace: "web", Cluster: "prod-us", Source: store.SourceCertManager, ProbeOK: true,
+			NotBefore: now.Add(-days(80)), NotAfter: now.Add(days(10)),
+		},
+		// The Secret behind web-tls is represented by the Certificate.
+		{
+			Name: "web-tls", Namespace: "web", Cluster: "prod-us", Source: store.SourceTLSSecret, ProbeOK: true,
+			NotAfter: now.Add(days(10)), FindingType: discovery.FindingManagedExpiry,
+		},
+		// Unmanaged certificates are due at notAfter; a derived finding is counted once.
+		{
+			Name: "ingress", Namespace: "payments", Cluster: "prod-eu", Owner: "team-payments",
+			Source: store.SourceIngressTLS, ProbeOK: true, NotAfter: now.Add(days(4)),
+		},
+		{
+			Name: "ingress", Namespace: "payments", Cluster: "prod-eu", Owner: "team-payments",
+			Source: store.SourceIngressTLS, ProbeOK: true, NotAfter: now.Add(days(4)), FindingType: "POLICY_VIOLATION",
+		},
+		// Outside the horizon, already expired, or never probed.
+		{Name: "far", Namespace: "web", Source: store.SourceIngressTLS, ProbeOK: true, NotAfter: now.Add(days(200))},
+		{Name: "expired", Namespace: "web", Source: store.SourceIngressTLS, ProbeOK: true, NotAfter: now.Add(-time.Hour)},
+		{Name: "down", Namespace: "web", Source: store.SourceAPIService, ProbeOK: false},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	cal := Build(fleet(), Options{}, now)
+
+	if cal.Total != 3 || cal.GroupBy != GroupByNamespace {
+		t.Fatalf("total = %d, groupBy = %q; want 3 and namespace", cal.Total, cal.GroupBy)
+	}
+	if len(cal.Weeks) != 14 || !cal.Weeks[0].Start.Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("weeks = %d starting %s", len(cal.Weeks), cal.Weeks[0].Start)
+	}
+
+	// web-tls renews 30 days before notAfter, so it is overdue and lands in
+	// the current week alongside the ingress due in four days.
+	first := cal.Weeks[0]
+	if first.Count != 2 || len(first.Groups) != 2 {
+		t.Fatalf("first week = %+v", first)
+	}
+	if g := first.Groups[1]; g.Key != "prod-us/web" || !g.Entries[0].Overdue || !g.Entries[0].Renewal {
+		t.Errorf("web group = %+v", g)
+	}
+	if g := first.Groups[0]; g.Key != "prod-eu/payments" || g.Entries[0].Renewal || g.Entries[0].Name != "ingress" {
+		t.Errorf("payments group = %+v", g)
+	}
+
+	renewal := cal.Weeks[3]
+	if renewal.Count != 1 || !renewal.Groups[0].Entries[0].Due.Equal(now.Add(days(21))) {
+		t.Errorf("week of the api-tls renewal = %+v", renewal)
+	}
+}
+
+func TestBuild_GroupByOwner(t *testing.T) {
+	cal := Build(fleet(), Options{GroupBy: GroupByOwner, Horizon: days(7)}, now)
+	if cal.Total != 2 || len(cal.Weeks) != 2 {
+		t.Fatalf("total = %d over %d weeks, want 2 over 2", cal.Total, len(cal.Weeks))
+	}
+	var keys []string
+	for _, w := range cal.Weeks {
+		for _, g := range w.Groups {
+			keys = append(keys, g.Key)
+		}
+	}
+	if strings.Join(keys, ",") != "(unowned),team-payments" {
+		t.Errorf("owner groups = %v", keys)
+	}
+}
+
+func TestWriteICS(t *testing.T) {
+	cal := Build(fleet(), Options{}, now)
+	var buf bytes.Buffer
+	if err := cal.WriteICS(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+		t.Fatalf("not an iCalendar feed:\n%s", out)
+	}
+	if n := strings.Count(out, "BEGIN:VEVENT"); n != 3 {
+		t.Errorf("events = %d, want 3", n)
+	}
+	for _, want := range []string{
+		"DTSTART;VALUE=DATE:20261104\r\n",
+		"SUMMARY:Certificate renewal: prod-eu:payments/api-tls\r\n",
+		"SUMMARY:Certificate renewal overdue: prod-us:web/web-tls\r\n",
+		`DESCRIPTION:Source: certmanager\nNot after: `,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("feed missing %q:\n%s", want, out)
+		}
+	}
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+
+	// UIDs stay stable when the renewal date moves.
+	moved := fleet()
+	moved[0].RenewalTime = moved[0].RenewalTime.Add(days(2))
+	var again bytes.Buffer
+	if err := Build(moved, Options{}, now).WriteICS(&again); err != nil {
+		t.Fatal(err)
+	}
+	uids := func(s string) (u []string) {
+		for _, line := range strings.Split(s, "\r\n") {
+			if strings.HasPrefix(line, "UID:") {
+				u = append(u, line)
+			}
+		}
+		return u
+	}
+	if strings.Join(uids(out), ",") != strings.Join(uids(again.String()), ",") {
+		t.Errorf("UIDs changed with the renewal date")
+	}
+}
+
+func TestEscapeAndFold(t *testing.T) {
+	if got := escapeText("a,b;c\\d\ne"); got != `a\,b\;c\\d\ne` {
+		t.Errorf("escapeText = %q", got)
+	}
+	folded := foldLine(strings.Repeat("é", 60))
+	for _, part := range strings.Split(folded, "\r\n") {
+		if len(part) > 75 {
+			t.Errorf("folded part of %d octets", len(part))
+		}
+	}
+	if strings.ReplaceAll(folded, "\r\n ", "") != strings.Repeat("é", 60) {
+		t.Error("unfolding does not restore the line")
+	}
+}
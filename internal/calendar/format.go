@@ -0,0 +1,143 @@
+package calendar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	dateLayout  = "2006-01-02"
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405Z"
+)
+
+// WriteTable renders the weeks with certificates due as an aligned plain-text table.
+func (c *Calendar) WriteTable(w io.Writer) error {
+	fmt.Fprintf(w, "%d certificate(s) due by %s\n", c.Total, c.Until.Format(dateLayout)) //nolint:errcheck // best-effort output
+	if c.Total == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, week := range c.Weeks {
+		if week.Count == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "\nWeek of %s (%d)\n", week.Start.Format(dateLayout), week.Count)                 //nolint:errcheck // best-effort output
+		fmt.Fprintf(tw, "  %s\tDUE\tEVENT\tCERTIFICATE\tSOURCE\tNOT AFTER\n", strings.ToUpper(c.GroupBy)) //nolint:errcheck // best-effort output
+		for _, g := range week.Groups {
+			for i := range g.Entries {
+				e := &g.Entries[i]
+				fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", g.Key, e.Due.UTC().Format(dateLayout), //nolint:errcheck // best-effort output
+					e.event(), e.Label(), e.Source, e.NotAfter.UTC().Format(dateLayout))
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// event describes what happens on the due date.
+func (e *Entry) event() string {
+	switch {
+	case e.Overdue:
+		return "renewal overdue"
+	case e.Renewal:
+		return "renewal"
+	default:
+		return "expiry"
+	}
+}
+
+// WriteICS renders the calendar as an iCalendar feed with an all-day event
+// per certificate on its due date. Event UIDs are derived from the
+// certificate identity, so subscribed clients move an event when its
+// renewal date changes instead of adding a new one.
+func (c *Calendar) WriteICS(w io.Writer) error {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(foldLine(s))
+		b.WriteString("\r\n")
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//trustwatch//certificate calendar//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:trustwatch certificate renewals")
+	stamp := c.Generated.UTC().Format(icsDateTime)
+	for _, week := range c.Weeks {
+		for _, g := range week.Groups {
+			for i := range g.Entries {
+				e := &g.Entries[i]
+				sum := sha256.Sum256([]byte(e.Key()))
+				due := e.Due.UTC()
+				if e.Overdue {
+					due = c.Generated
+				}
+				line("BEGIN:VEVENT")
+				line("UID:" + hex.EncodeToString(sum[:16]) + "@trustwatch")
+				line("DTSTAMP:" + stamp)
+				line("DTSTART;VALUE=DATE:" + due.Format(icsDate))
+				line("DTEND;VALUE=DATE:" + due.AddDate(0, 0, 1).Format(icsDate))
+				line("SUMMARY:" + escapeText(fmt.Sprintf("Certificate %s: %s", e.event(), e.Label())))
+				line("DESCRIPTION:" + escapeText(e.description()))
+				line("CATEGORIES:" + escapeText(g.Key))
+				line("TRANSP:TRANSPARENT")
+				line("END:VEVENT")
+			}
+		}
+	}
+	line("END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// description lists the certificate details shown in the event body.
+func (e *Entry) description() string {
+	lines := []string{
+		"Source: " + string(e.Source),
+		"Not after: " + e.NotAfter.UTC().Format(time.RFC3339),
+	}
+	if e.Renewal {
+		lines = append(lines, "Expected renewal: "+e.Due.UTC().Format(time.RFC3339))
+	}
+	if e.Owner != "" {
+		lines = append(lines, "Owner: "+e.Owner)
+	}
+	if e.Issuer != "" {
+		lines = append(lines, "Issuer: "+e.Issuer)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeText escapes an iCalendar TEXT value (RFC 5545 section 3.3.11).
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldLine splits content lines longer than 75 octets (RFC 5545 section 3.1)
+// without breaking a UTF-8 sequence.
+func foldLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		size := utf8.RuneLen(r)
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
@@ -0,0 +1,148 @@
+// Package certreload serves a TLS certificate that is reloaded when its files
+// change, such as a cert-manager Secret mounted into the pod.
+package certreload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultInterval is how often Run checks the files for changes. The kubelet
+// refreshes mounted Secrets about once a minute, so a short poll adds little.
+const DefaultInterval = 10 * time.Second
+
+// Reloader holds the current serving certificate and client CA pool.
+type Reloader struct {
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	certFile  string
+	keyFile   string
+	caFile    string
+	chain     []*x509.Certificate
+	digest    [sha256.Size]byte
+	mu        sync.RWMutex
+}
+
+// New loads the key pair and, when caFile is set, the client CA bundle.
+func New(certFile, keyFile, caFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the files and reports whether they changed. On error the
+// previous certificate stays in use.
+func (r *Reloader) Reload() (bool, error) {
+	var contents [3][]byte
+	for i, name := range []string{r.certFile, r.keyFile, r.caFile} {
+		if name == "" {
+			continue
+		}
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return false, fmt.Errorf("reading %s: %w", name, err)
+		}
+		contents[i] = b
+	}
+	digest := sha256.Sum256(bytes.Join(contents[:], []byte{0}))
+
+	r.mu.RLock()
+	unchanged := r.cert != nil && digest == r.digest
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(contents[0], contents[1])
+	if err != nil {
+		return false, fmt.Errorf("loading key pair %s: %w", r.certFile, err)
+	}
+	chain := make([]*x509.Certificate, 0, len(cert.Certificate))
+	for _, der := range cert.Certificate {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return false, fmt.Errorf("parsing %s: %w", r.certFile, err)
+		}
+		chain = append(chain, c)
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(contents[2]) {
+			return false, fmt.Errorf("%s contains no PEM certificates", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.chain, r.clientCAs, r.digest = &cert, chain, pool, digest
+	r.mu.Unlock()
+	return true, nil
+}
+
+// Run reloads the files every interval until ctx is done.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.Reload()
+			if err != nil {
+				slog.Warn("reloading serving certificate, keeping the current one", "file", r.certFile, "err", err)
+				continue
+			}
+			if changed {
+				leaf := r.Chain()[0]
+				slog.Info("serving certificate reloaded", "file", r.certFile,
+					"subject", leaf.Subject.String(), "notAfter", leaf.NotAfter)
+			}
+		}
+	}
+}
+
+// Chain returns the current certificate chain, leaf first.
+func (r *Reloader) Chain() []*x509.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.chain
+}
+
+// CertFile returns the path of the serving certificate.
+func (r *Reloader) CertFile() string {
+	return r.certFile
+}
+
+// TLSConfig returns a server config that picks up the current certificate
+// and client CAs on every handshake. clientAuth applies only with a caFile.
+func (r *Reloader) TLSConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"h2", "http/1.1"},
+				Certificates: []tls.Certificate{*r.cert},
+			}
+			if r.clientCAs != nil {
+				cfg.ClientCAs = r.clientCAs
+				cfg.ClientAuth = clientAuth
+			}
+			return cfg, nil
+		},
+	}
+}
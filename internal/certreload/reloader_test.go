@@ -0,0 +1,197 @@
+package certreload
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// issue writes a certificate for cn, signed by parent (self-signed when nil),
+// and its key to dir, and returns the certificate, its key, and the file paths.
+func issue(t *testing.T, dir, cn string, serial int64, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (cert *x509.Certificate, key *ecdsa.PrivateKey, certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:         parent == nil,
+
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, cn+".crt")
+	keyFile = filepath.Join(dir, cn+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return cert, key, certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serve accepts TLS connections with cfg until the test ends and returns the
+// listener address.
+func serve(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake() //nolint:errcheck // the client reports failures
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// servedSerial handshakes with addr and returns the serial of the leaf served.
+func servedSerial(t *testing.T, addr string, cfg *tls.Config) (int64, error) {
+	t.Helper()
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", addr, cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(), nil
+}
+
+func TestReloader_ReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, _, certFile, keyFile := issue(t, dir, "serving", 1, nil, nil)
+	r, err := New(certFile, keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := serve(t, r.TLSConfig(tls.NoClientCert))
+	client := &tls.Config{InsecureSkipVerify: true} //nolint:gosec // the test checks which certificate is served
+
+	if serial, err := servedSerial(t, addr, client); err != nil || serial != 1 {
+		t.Fatalf("served serial = %d, %v; want 1", serial, err)
+	}
+	if changed, err := r.Reload(); changed || err != nil {
+		t.Errorf("Reload() of unchanged files = %v, %v; want false, nil", changed, err)
+	}
+
+	// A half-written rotation keeps the current certificate.
+	if err := os.WriteFile(keyFile, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reload(); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+	if serial, err := servedSerial(t, addr, client); err != nil || serial != 1 {
+		t.Errorf("served serial after a failed reload = %d, %v; want 1", serial, err)
+	}
+
+	issue(t, dir, "serving", 2, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx, 10*time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Chain()[0].SerialNumber.Int64() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Run did not pick up the rotated certificate")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if serial, err := servedSerial(t, addr, client); err != nil || serial != 2 {
+		t.Errorf("served serial after rotation = %d, %v; want 2", serial, err)
+	}
+	if r.CertFile() != certFile {
+		t.Errorf("CertFile() = %q, want %q", r.CertFile(), certFile)
+	}
+}
+
+func TestReloader_ClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	_, _, certFile, keyFile := issue(t, dir, "serving", 1, nil, nil)
+	ca, caKey, caFile, _ := issue(t, dir, "client-ca", 10, nil, nil)
+	_, _, clientCert, clientKey := issue(t, dir, "hub", 11, ca, caKey)
+	_, _, otherCert, otherKey := issue(t, dir, "stranger", 12, nil, nil)
+
+	r, err := New(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := serve(t, r.TLSConfig(tls.RequireAndVerifyClientCert))
+	dial := func(certFile, keyFile string) error {
+		cfg := &tls.Config{InsecureSkipVerify: true} //nolint:gosec // the test checks client verification only
+		if certFile != "" {
+			pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.Certificates = []tls.Certificate{pair}
+		}
+		conn, err := tls.Dial("tcp", addr, cfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		// TLS 1.3 reports a rejected client certificate on the first read;
+		// an accepted connection is simply closed by the server.
+		if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	}
+
+	if err := dial(clientCert, clientKey); err != nil {
+		t.Errorf("client certificate from the CA rejected: %v", err)
+	}
+	if err := dial(otherCert, otherKey); err == nil {
+		t.Error("client certificate from another CA accepted")
+	}
+	if err := dial("", ""); err == nil {
+		t.Error("connection without a client certificate accepted")
+	}
+
+	if _, err := New(certFile, keyFile, keyFile); err == nil {
+		t.Error("expected an error for a client CA file without certificates")
+	}
+}
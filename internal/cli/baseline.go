@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 
 	"github.com/spf13/cobra"
 
@@ -92,6 +93,11 @@ func runBaselineCheck(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Drift against a partial scan can be missed changes rather than no changes
+	for _, warning := range coverageWarnings(&baseline, current) {
+		cmd.PrintErrln("warning: " + warning)
+	}
+
 	// Compare
 	driftFindings := drift.Detect(baseline.Findings, current.Findings)
 
@@ -112,6 +118,31 @@ func runBaselineCheck(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// coverageWarnings lists coverage gaps in either snapshot and scope mismatches
+// between them, which make a drift comparison incomplete.
+func coverageWarnings(baseline, current *store.Snapshot) []string {
+	var warnings []string
+	for _, gap := range baseline.CoverageGaps() {
+		warnings = append(warnings, "baseline coverage gap: "+gap)
+	}
+	for _, gap := range current.CoverageGaps() {
+		warnings = append(warnings, "current coverage gap: "+gap)
+	}
+
+	if baseline.Metadata == nil || current.Metadata == nil {
+		return warnings
+	}
+	bs, cs := baseline.Metadata.Scope, current.Metadata.Scope
+	if bs == nil || cs == nil {
+		return warnings
+	}
+	if !slices.Equal(bs.Namespaces, cs.Namespaces) || bs.NamespaceSelector != cs.NamespaceSelector {
+		warnings = append(warnings, fmt.Sprintf("namespace scope differs: baseline covered %d namespace(s), current covered %d",
+			len(bs.Namespaces), len(cs.Namespaces)))
+	}
+	return warnings
+}
+
 // readSnapshotFromStdin reads a store.Snapshot from stdin.
 // Accepts both raw Snapshot JSON and NowOutput envelope ({"snapshot": ...}).
 func readSnapshotFromStdin(r io.Reader) (*store.Snapshot, error) {
@@ -122,7 +153,11 @@ func readSnapshotFromStdin(r io.Reader) (*store.Snapshot, error) {
 	if len(data) == 0 {
 		return nil, fmt.Errorf("no input on stdin, pipe a snapshot via: trustwatch now -o json | trustwatch baseline save")
 	}
+	return parseSnapshot(data)
+}
 
+// parseSnapshot decodes raw Snapshot JSON or a NowOutput envelope ({"snapshot": ...}).
+func parseSnapshot(data []byte) (*store.Snapshot, error) {
 	// Try NowOutput envelope first
 	var envelope monitor.NowOutput
 	if err := json.Unmarshal(data, &envelope); err == nil && !envelope.Snapshot.At.IsZero() {
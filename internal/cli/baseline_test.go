@@ -130,3 +130,26 @@ func TestBaselineSave_AcceptsNowOutputEnvelope(t *testing.T) {
 		t.Errorf("loaded findings = %d, want 2", len(loaded.Findings))
 	}
 }
+
+func TestCoverageWarnings(t *testing.T) {
+	baseline := testSnapshot()
+	baseline.Metadata = &store.Metadata{Scope: &store.Scope{Namespaces: []string{"app", "web"}}}
+	current := testSnapshot()
+	current.Errors = map[string]string{"secrets": "forbidden"}
+	current.Metadata = &store.Metadata{Scope: &store.Scope{Namespaces: []string{"app"}}}
+
+	warnings := coverageWarnings(&baseline, &current)
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	if !strings.Contains(warnings[0], "current coverage gap: secrets failed") {
+		t.Errorf("unexpected gap warning: %q", warnings[0])
+	}
+	if !strings.Contains(warnings[1], "baseline covered 2 namespace(s), current covered 1") {
+		t.Errorf("unexpected scope warning: %q", warnings[1])
+	}
+
+	if warnings := coverageWarnings(&baseline, &baseline); len(warnings) != 0 {
+		t.Errorf("expected no warnings for identical scope, got %v", warnings)
+	}
+}
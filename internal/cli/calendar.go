@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ppiankov/trustwatch/internal/calendar"
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/federation"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+var calendarCmd = &cobra.Command{
+	Use:   "calendar [snapshot.json]",
+	Short: "Forecast certificate renewals and expiries by week",
+	Long: `Bucket the certificates due within the horizon by ISO week and group them
+by owner or namespace. cert-manager certificates are shown on their expected
+renewal date (status.renewalTime, spec.renewBefore, or cert-manager's default
+of a third of the lifetime) rather than notAfter; everything else on notAfter.
+
+The snapshot is read from a file, from stdin, or with --server from a running
+'trustwatch serve'. Point --server at a federation hub to forecast the whole
+fleet; findings are labelled with their cluster. The same forecast is served
+at /api/v1/calendar, and -o ics writes an iCalendar feed.`,
+	Example: `  # What renews or expires in the next 90 days across the fleet?
+  trustwatch calendar --server http://trustwatch-hub:8080 --horizon 90d --group owner
+
+  # From a local scan
+  trustwatch now -o json | trustwatch calendar --horizon 30d
+
+  # iCalendar file to import into a calendar client
+  trustwatch calendar snapshot.json -o ics > renewals.ics`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCalendar,
+}
+
+func init() {
+	rootCmd.AddCommand(calendarCmd)
+	calendarCmd.Flags().String("horizon", "90d", "How far ahead to look (e.g. 90d, 720h)")
+	calendarCmd.Flags().String("group", calendar.GroupByNamespace, "Group entries by owner or namespace")
+	calendarCmd.Flags().String("server", "", "URL of a trustwatch serve instance to read the snapshot from")
+	calendarCmd.Flags().String("token", "", "Bearer token for --server when it has auth enabled (accepts env: and file: references)")
+	calendarCmd.Flags().StringP("output", "o", "", "Output format: table, json, ics (default: table)")
+}
+
+func runCalendar(cmd *cobra.Command, args []string) error {
+	horizonFlag, _ := cmd.Flags().GetString("horizon") //nolint:errcheck // flag registered above
+	groupFlag, _ := cmd.Flags().GetString("group")     //nolint:errcheck // flag registered above
+	serverFlag, _ := cmd.Flags().GetString("server")   //nolint:errcheck // flag registered above
+	tokenFlag, _ := cmd.Flags().GetString("token")     //nolint:errcheck // flag registered above
+	outputFlag, _ := cmd.Flags().GetString("output")   //nolint:errcheck // flag registered above
+
+	if outputFlag != "" && outputFlag != "table" && outputFlag != "json" && outputFlag != "ics" {
+		return fmt.Errorf("invalid --output value %q: must be table, json, or ics", outputFlag)
+	}
+	if groupFlag != calendar.GroupByOwner && groupFlag != calendar.GroupByNamespace {
+		return fmt.Errorf("invalid --group value %q: must be owner or namespace", groupFlag)
+	}
+	horizon, err := config.ParseDays(horizonFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --horizon: %w", err)
+	}
+
+	var snap *store.Snapshot
+	switch {
+	case serverFlag != "" && len(args) > 0:
+		return fmt.Errorf("pass either a snapshot file or --server, not both")
+	case serverFlag != "":
+		token, tokenErr := config.ResolveSecret(tokenFlag)
+		if tokenErr != nil {
+			return fmt.Errorf("invalid --token: %w", tokenErr)
+		}
+		remote := federation.RemoteSource{Name: "server", URL: strings.TrimRight(serverFlag, "/"), Token: token}
+		fetched, fetchErr := remote.Fetch(cmd.Context())
+		if fetchErr != nil {
+			return fetchErr
+		}
+		snap = &fetched
+	case len(args) > 0:
+		snap, err = readSnapshotFile(args[0])
+	default:
+		snap, err = readSnapshotFromStdin(cmd.InOrStdin())
+	}
+	if err != nil {
+		return err
+	}
+
+	cal := calendar.Build(snap.Findings, calendar.Options{GroupBy: groupFlag, Horizon: horizon}, time.Now())
+	out := cmd.OutOrStdout()
+	switch outputFlag {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(cal); err != nil {
+			return fmt.Errorf("writing JSON output: %w", err)
+		}
+		return nil
+	case "ics":
+		return cal.WriteICS(out)
+	default:
+		return cal.WriteTable(out)
+	}
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func TestCalendar_Server(t *testing.T) {
+	now := time.Now().UTC()
+	snap := store.Snapshot{
+		At: now,
+		Findings: []store.CertFinding{
+			{Source: store.SourceCertManager, Namespace: "payments", Name: "api-tls", Cluster: "prod-eu", Owner: "team-payments",
+				ProbeOK: true, NotAfter: now.Add(40 * 24 * time.Hour), RenewalTime: now.Add(10 * 24 * time.Hour)},
+			{Source: store.SourceIngressTLS, Namespace: "web", Name: "site", Cluster: "prod-us",
+				ProbeOK: true, NotAfter: now.Add(120 * 24 * time.Hour)},
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/snapshot" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(snap) //nolint:errcheck // test server
+	}))
+	defer srv.Close()
+
+	stdout := new(bytes.Buffer)
+	cmd := rootCmd
+	cmd.SetOut(stdout)
+	cmd.SetErr(stdout)
+	cmd.SetArgs([]string{"calendar", "--server", srv.URL, "--horizon", "90d", "--group", "owner", "-o", "table"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("calendar: %v", err)
+	}
+
+	out := stdout.String()
+	for _, want := range []string{"1 certificate(s) due", "team-payments", "renewal", "prod-eu:payments/api-tls"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "site") {
+		t.Errorf("certificate beyond the horizon listed:\n%s", out)
+	}
+}
+
+func TestCalendar_InvalidHorizon(t *testing.T) {
+	cmd := rootCmd
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	cmd.SetArgs([]string{"calendar", "--server", "", "--horizon", "soon", "-o", "table"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--horizon") {
+		t.Errorf("expected a --horizon error, got %v", err)
+	}
+}
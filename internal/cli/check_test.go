@@ -4,6 +4,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/spf13/cobra"
+
+	"github.com/ppiankov/trustwatch/internal/config"
 	"github.com/ppiankov/trustwatch/internal/store"
 )
 
@@ -203,6 +206,7 @@ func TestCheckCommand_Flags(t *testing.T) {
 	flags := []string{
 		"policy", "max-severity", "deploy-window",
 		"config", "kubeconfig", "context", "namespace",
+		"exclude-namespace", "namespace-selector",
 		"warn-before", "crit-before",
 		"tunnel", "tunnel-ns", "tunnel-image",
 		"check-revocation", "ct-domains", "ct-allowed-issuers",
@@ -215,3 +219,42 @@ func TestCheckCommand_Flags(t *testing.T) {
 		}
 	}
 }
+
+func TestApplyScopeFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().StringSlice("namespace", nil, "")
+	cmd.Flags().StringSlice("exclude-namespace", nil, "")
+	cmd.Flags().String("namespace-selector", "", "")
+	if err := cmd.Flags().Parse([]string{
+		"--namespace", "team-*", "--exclude-namespace", "team-sandbox", "--namespace-selector", "trustwatch.dev/scan!=false",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Defaults()
+	if err := applyScopeFlags(cmd, cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Namespaces) != 1 || cfg.Namespaces[0] != "team-*" {
+		t.Errorf("expected namespaces [team-*], got %v", cfg.Namespaces)
+	}
+	if len(cfg.ExcludeNamespaces) != 1 || cfg.ExcludeNamespaces[0] != "team-sandbox" {
+		t.Errorf("expected excluded [team-sandbox], got %v", cfg.ExcludeNamespaces)
+	}
+	if cfg.NamespaceSelector != "trustwatch.dev/scan!=false" {
+		t.Errorf("expected namespace selector, got %q", cfg.NamespaceSelector)
+	}
+}
+
+func TestApplyScopeFlags_InvalidSelector(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().StringSlice("namespace", nil, "")
+	cmd.Flags().StringSlice("exclude-namespace", nil, "")
+	cmd.Flags().String("namespace-selector", "", "")
+	if err := cmd.Flags().Parse([]string{"--namespace-selector", "a=b=c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyScopeFlags(cmd, config.Defaults()); err == nil {
+		t.Error("expected error for invalid namespace selector")
+	}
+}
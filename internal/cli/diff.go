@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ppiankov/trustwatch/internal/drift"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <from.json> <to.json>",
+	Short: "Show what changed in the trust surface between two snapshots",
+	Long: `Compare two JSON snapshots and print a structured change set: findings
+added or removed, severity changes, serial rotations, issuer changes, and
+policy violations that appeared or were resolved.
+
+Both files accept raw snapshot JSON or 'trustwatch now -o json' output.
+Unlike 'baseline check', diff always exits 0; it reports, it does not gate.
+A running 'trustwatch serve' with history enabled exposes the same change
+set at /api/v1/diff?from=<id|RFC3339>&to=<id|RFC3339>.`,
+	Example: `  # What changed since last week's scan?
+  trustwatch diff last-week.json today.json
+
+  # Markdown summary for the ops review
+  trustwatch diff last-week.json today.json -o markdown > trust-changes.md`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringP("output", "o", "", "Output format: table, json, markdown (default: table)")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	outputFlag, _ := cmd.Flags().GetString("output") //nolint:errcheck // flag registered above
+	if outputFlag != "" && outputFlag != "table" && outputFlag != "json" && outputFlag != "markdown" {
+		return fmt.Errorf("invalid --output value %q: must be table, json, or markdown", outputFlag)
+	}
+
+	from, err := readSnapshotFile(args[0])
+	if err != nil {
+		return err
+	}
+	to, err := readSnapshotFile(args[1])
+	if err != nil {
+		return err
+	}
+
+	cs := drift.Diff(from, to)
+	out := cmd.OutOrStdout()
+	switch outputFlag {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(cs); err != nil {
+			return fmt.Errorf("writing JSON output: %w", err)
+		}
+		return nil
+	case "markdown":
+		return cs.WriteMarkdown(out)
+	default:
+		return cs.WriteTable(out)
+	}
+}
+
+func readSnapshotFile(path string) (*store.Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	snap, err := parseSnapshot(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return snap, nil
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ppiankov/trustwatch/internal/monitor"
+)
+
+func writeSnapshotFile(t *testing.T, name string, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiff_Markdown(t *testing.T) {
+	from := testSnapshot()
+	to := testSnapshot()
+	to.Findings[0].Serial = "EE:FF"
+	to.Findings = to.Findings[:1]
+
+	fromPath := writeSnapshotFile(t, "from.json", from)
+	// The newer snapshot is wrapped in the `now -o json` envelope
+	toPath := writeSnapshotFile(t, "to.json", monitor.NowOutput{Snapshot: to})
+
+	stdout := new(bytes.Buffer)
+	cmd := rootCmd
+	cmd.SetOut(stdout)
+	cmd.SetErr(stdout)
+	cmd.SetArgs([]string{"diff", fromPath, toPath, "-o", "markdown"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+
+	out := stdout.String()
+	for _, want := range []string{"| Removed | 1 |", "| Serial rotated | 1 |", "AA:BB → EE:FF"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestDiff_MissingFile(t *testing.T) {
+	cmd := rootCmd
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	cmd.SetArgs([]string{"diff", "missing-a.json", "missing-b.json", "-o", "table"})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected error for missing snapshot files")
+	}
+}
@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/discovery"
+	"github.com/ppiankov/trustwatch/internal/doctor"
+	"github.com/ppiankov/trustwatch/internal/tunnel"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose RBAC, CRD, and connectivity problems that hide findings",
+	Long: `Check whether each discoverer can actually see its part of the trust surface.
+
+For every enabled discoverer, doctor runs SelfSubjectAccessReviews for the
+resources it reads, checks that the CRDs it depends on (cert-manager,
+Gateway API, TrustPolicy) are installed, and reports mesh namespaces that
+are missing. It also checks the SPIFFE socket and OTel endpoint, and
+test-probes a sample of targets directly and, with --tunnel, through an
+in-cluster SOCKS5 relay.
+
+Exit codes:
+  0  All discoverers and endpoints ready (warnings allowed)
+  1  At least one discoverer or endpoint failed`,
+	Example: `  # Readiness table for the current cluster
+  trustwatch doctor
+
+  # Compare direct and tunneled probes
+  trustwatch doctor --tunnel
+
+  # JSON output for automation
+  trustwatch doctor -o json`,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().String("config", "", "Path to config file")
+	doctorCmd.Flags().String("kubeconfig", "", "Path to kubeconfig")
+	doctorCmd.Flags().String("context", "", "Kubernetes context to use")
+	doctorCmd.Flags().StringSlice("namespace", nil, "Namespaces or glob patterns to check (empty = all)")
+	doctorCmd.Flags().StringSlice("exclude-namespace", nil, "Namespaces or glob patterns to skip")
+	doctorCmd.Flags().String("namespace-selector", "", "Label selector for namespaces to check (e.g. trustwatch.dev/scan!=false)")
+	doctorCmd.Flags().Bool("tunnel", false, "Also probe targets through a SOCKS5 relay pod")
+	doctorCmd.Flags().String("tunnel-ns", "default", "Namespace for the tunnel relay pod")
+	doctorCmd.Flags().String("tunnel-image", tunnel.DefaultImage, "SOCKS5 proxy image for --tunnel")
+	doctorCmd.Flags().StringSlice("tunnel-command", nil, "Override container command")
+	doctorCmd.Flags().String("tunnel-pull-secret", "", "imagePullSecret name for the tunnel relay pod")
+	doctorCmd.Flags().String("spiffe-socket", "", "Path to SPIFFE workload API socket")
+	doctorCmd.Flags().Int("probe-sample", 3, "Number of webhook and external targets to test-probe")
+	doctorCmd.Flags().StringP("output", "o", "", "Output format: json, table (default: table)")
+}
+
+func runDoctor(cmd *cobra.Command, _ []string) error {
+	outputFlag, _ := cmd.Flags().GetString("output") //nolint:errcheck // flag registered above
+	if outputFlag != "" && outputFlag != "json" && outputFlag != "table" {
+		return fmt.Errorf("invalid --output value %q: must be json or table", outputFlag)
+	}
+
+	cfgPath, _ := cmd.Flags().GetString("config") //nolint:errcheck // flag registered above
+	cfg := config.Defaults()
+	if cfgPath != "" {
+		var err error
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+	}
+	if err := applyScopeFlags(cmd, cfg); err != nil {
+		return err
+	}
+	spiffeSocket, _ := cmd.Flags().GetString("spiffe-socket") //nolint:errcheck // flag registered above
+	if spiffeSocket != "" {
+		cfg.SPIFFESocket = spiffeSocket
+	}
+	otelEndpoint, _ := cmd.Flags().GetString("otel-endpoint") //nolint:errcheck // persistent flag on root
+	if otelEndpoint != "" {
+		cfg.OTelEndpoint = otelEndpoint
+	}
+
+	kubeconfig, _ := cmd.Flags().GetString("kubeconfig") //nolint:errcheck // flag registered above
+	kubeCtx, _ := cmd.Flags().GetString("context")       //nolint:errcheck // flag registered above
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		loadingRules.ExplicitPath = kubeconfig
+	}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		loadingRules,
+		&clientcmd.ConfigOverrides{CurrentContext: kubeCtx},
+	)
+	restCfg, err := clientConfig.ClientConfig()
+	if err != nil {
+		return fmt.Errorf("building kubeconfig: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return fmt.Errorf("creating kubernetes client: %w", err)
+	}
+
+	ctx := context.Background()
+	namespaces, err := discovery.ResolveNamespaceScope(ctx, clientset, cfg.Namespaces, cfg.ExcludeNamespaces, cfg.NamespaceSelector)
+	if err != nil {
+		slog.Warn("resolving namespaces, checking cluster-wide access only", "err", err)
+	}
+
+	sample, _ := cmd.Flags().GetInt("probe-sample") //nolint:errcheck // flag registered above
+	opts := []doctor.Option{
+		doctor.WithNamespaces(namespaces),
+		doctor.WithProbeTargets(doctor.SampleTargets(ctx, clientset, cfg, apiServerFromHost(restCfg.Host), sample)),
+	}
+	for _, d := range discovery.CloudDiscoverers() {
+		detail := "disabled in config"
+		if cfg.Discoverer(d.Name()).IsEnabled() {
+			detail = "cloud provider credentials are not checked"
+		}
+		opts = append(opts, doctor.WithDiscoverers(doctor.DiscovererReport{
+			Name:   d.Name(),
+			Status: doctor.StatusSkipped,
+			Checks: []doctor.Check{{Name: "config", Status: doctor.StatusSkipped, Detail: detail}},
+		}))
+	}
+
+	useTunnel, _ := cmd.Flags().GetBool("tunnel") //nolint:errcheck // flag registered above
+	if useTunnel {
+		tunnelNS, _ := cmd.Flags().GetString("tunnel-ns")              //nolint:errcheck // flag registered above
+		tunnelImg, _ := cmd.Flags().GetString("tunnel-image")          //nolint:errcheck // flag registered above
+		tunnelCmd, _ := cmd.Flags().GetStringSlice("tunnel-command")   //nolint:errcheck // flag registered above
+		tunnelSecret, _ := cmd.Flags().GetString("tunnel-pull-secret") //nolint:errcheck // flag registered above
+
+		relay := tunnel.NewRelay(clientset, restCfg, tunnelNS, tunnelImg, tunnelCmd, tunnelSecret)
+		slog.Info("deploying tunnel relay pod", "namespace", tunnelNS)
+		if startErr := relay.Start(ctx); startErr != nil {
+			return fmt.Errorf("starting tunnel relay: %w", startErr)
+		}
+		defer func() {
+			if closeErr := relay.Close(); closeErr != nil {
+				slog.Warn("cleaning up relay pod", "err", closeErr)
+			}
+		}()
+		opts = append(opts, doctor.WithTunnelProbeFn(relay.ProbeFn()))
+	}
+
+	report := doctor.New(clientset, cfg, opts...).Run(ctx)
+
+	out := cmd.OutOrStdout()
+	if outputFlag == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return fmt.Errorf("writing JSON output: %w", err)
+		}
+	} else if err := printDoctorTable(out, &report); err != nil {
+		return err
+	}
+
+	if failures := report.Failures(); failures > 0 {
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return fmt.Errorf("doctor found %d failing check(s)", failures)
+	}
+	return nil
+}
+
+func printDoctorTable(w io.Writer, r *doctor.Report) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DISCOVERER\tSTATUS\tCHECK\tDETAIL") //nolint:errcheck // best-effort output
+	for i := range r.Discoverers {
+		d := &r.Discoverers[i]
+		if len(d.Checks) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t\tno cluster access required\n", d.Name, d.Status) //nolint:errcheck // best-effort output
+			continue
+		}
+		for j, c := range d.Checks {
+			name, status := d.Name, string(d.Status)
+			if j > 0 {
+				name, status = "", ""
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s: %s\n", name, status, c.Name, c.Status, c.Detail) //nolint:errcheck // best-effort output
+		}
+	}
+
+	fmt.Fprintln(tw)                                            //nolint:errcheck // best-effort output
+	fmt.Fprintln(tw, "API\tINSTALLED\tGROUP/VERSION\tRESOURCE") //nolint:errcheck // best-effort output
+	for _, api := range r.APIs {
+		fmt.Fprintf(tw, "%s\t%t\t%s\t%s\n", api.Name, api.Installed, api.GroupVersion, api.Resource) //nolint:errcheck // best-effort output
+	}
+
+	fmt.Fprintln(tw)                             //nolint:errcheck // best-effort output
+	fmt.Fprintln(tw, "ENDPOINT\tSTATUS\tDETAIL") //nolint:errcheck // best-effort output
+	for _, c := range r.Endpoints {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, c.Status, c.Detail) //nolint:errcheck // best-effort output
+	}
+
+	if len(r.Probes) > 0 {
+		fmt.Fprintln(tw)                           //nolint:errcheck // best-effort output
+		fmt.Fprintln(tw, "TARGET\tDIRECT\tTUNNEL") //nolint:errcheck // best-effort output
+		for i := range r.Probes {
+			p := &r.Probes[i]
+			tunnelCol := "-"
+			if p.Tunnel != nil {
+				tunnelCol = probeColumn(p.Tunnel)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Target, probeColumn(&p.Direct), tunnelCol) //nolint:errcheck // best-effort output
+		}
+	}
+	return tw.Flush()
+}
+
+func probeColumn(c *doctor.Check) string {
+	if c.Status == doctor.StatusOK {
+		return "ok"
+	}
+	return fmt.Sprintf("%s (%s)", c.Status, c.Detail)
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ppiankov/trustwatch/internal/doctor"
+)
+
+func TestDoctorCmd_Flags(t *testing.T) {
+	for _, name := range []string{
+		"config", "kubeconfig", "context", "namespace", "exclude-namespace", "namespace-selector",
+		"tunnel", "tunnel-ns", "tunnel-image", "tunnel-command", "tunnel-pull-secret",
+		"spiffe-socket", "probe-sample", "output",
+	} {
+		if doctorCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected flag --%s", name)
+		}
+	}
+}
+
+func TestPrintDoctorTable(t *testing.T) {
+	tunnelCheck := doctor.Check{Name: "tunnel", Status: doctor.StatusOK}
+	r := &doctor.Report{
+		Discoverers: []doctor.DiscovererReport{
+			{Name: "apiserver", Status: doctor.StatusOK},
+			{Name: "secrets", Status: doctor.StatusWarn, Checks: []doctor.Check{
+				{Name: "list secrets", Status: doctor.StatusWarn, Detail: "denied in 1 of 2 namespace(s): team-b"},
+			}},
+		},
+		APIs:      []doctor.APIReport{{Name: "cert-manager", GroupVersion: "cert-manager.io/v1", Resource: "certificates"}},
+		Endpoints: []doctor.Check{{Name: "kubernetes-api", Status: doctor.StatusOK, Detail: "Kubernetes v1.31.0"}},
+		Probes: []doctor.ProbeReport{{
+			Target: "tcp://hook.system.svc:443",
+			Direct: doctor.Check{Name: "direct", Status: doctor.StatusWarn, Detail: "no such host"},
+			Tunnel: &tunnelCheck,
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := printDoctorTable(&buf, r); err != nil {
+		t.Fatalf("printDoctorTable: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"no cluster access required",
+		"warn: denied in 1 of 2 namespace(s): team-b",
+		"cert-manager  false",
+		"Kubernetes v1.31.0",
+		"warn (no such host)  ok",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,573 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ppiankov/trustwatch/internal/analytics"
+	"github.com/ppiankov/trustwatch/internal/archive"
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/history"
+	"github.com/ppiankov/trustwatch/internal/metrics"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Manage the snapshot history database",
+}
+
+var historyPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Downsample and delete old snapshots from the history database",
+	Long: `Apply the history retention policy once.
+
+Snapshots younger than --full are kept at full resolution. Older ones are
+thinned to the first snapshot per hour until --hourly, then to the first per
+day until --daily, and deleted after that. Defaults come from the
+historyRetention section of --config. serve runs the same compaction in the
+background every historyRetention.compactEvery.`,
+	Example: `  # Preview what the configured policy would delete
+  trustwatch history prune --history-db /data/trustwatch.db --config trustwatch.yaml --dry-run
+
+  # Keep 3 days at full resolution, daily snapshots for 90 days, then reclaim disk space
+  trustwatch history prune --history-db /data/trustwatch.db --full 72h --hourly 0 --daily 2160h --vacuum`,
+	RunE: runHistoryPrune,
+}
+
+var historyLineageCmd = &cobra.Command{
+	Use:   "lineage",
+	Short: "Show certificate generations observed for each finding",
+	Long: `List the certificates observed over time for each finding identity
+(source, namespace, name): serial, issuer, validity, when each was first and
+last seen, and how much lifetime was left when it was replaced.
+
+Generations are told apart by serial, so lineage starts with the first
+snapshot that recorded serials and is only as precise as the retained
+snapshots. A running 'trustwatch serve' with history enabled exposes the same
+data at /api/v1/lineage.`,
+	Example: `  # Rotation history of one certificate
+  trustwatch history lineage --history-db /data/trustwatch.db --source certmanager --namespace payments --name api-tls
+
+  # Everything in a namespace that rotated in the last 30 days, as JSON
+  trustwatch history lineage --history-db /data/trustwatch.db --namespace payments --since 720h --rotated -o json`,
+	RunE: runHistoryLineage,
+}
+
+var historyExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export snapshots and findings as NDJSON or Parquet",
+	Long: `Stream the snapshots taken between --from and --to, oldest first, as
+NDJSON or Parquet for offline analysis.
+
+Every snapshot becomes a row of kind "snapshot" (time, metadata, discoverer
+errors) followed by one row of kind "finding" per finding with every finding
+field as its own column, so both formats load into one data lake table.
+--from and --to take an RFC 3339 time, a date, or an age such as 30d.`,
+	Example: `  # Last week's history for the data lake
+  trustwatch history export --history-db /data/trustwatch.db --from 7d --format parquet --file trustwatch-week.parquet
+
+  # A month as NDJSON on stdout
+  trustwatch history export --history-db /data/trustwatch.db --from 2026-05-01 --to 2026-06-01 | gzip > may.ndjson.gz`,
+	RunE: runHistoryExport,
+}
+
+var historyImportCmd = &cobra.Command{
+	Use:   "import [archive...]",
+	Short: "Import snapshots from NDJSON or Parquet archives",
+	Long: `Rebuild or merge a history database from archives written by
+'history export'. The format is detected from the content; with no arguments
+or "-" the archive is read from stdin.
+
+Snapshots already in the database (same time and cluster) are skipped, so
+overlapping archives can be imported safely. --cluster labels snapshots and
+findings that carry no cluster, which keeps single-cluster archives apart
+when seeding a federation hub.`,
+	Example: `  # Move history to a new cluster
+  trustwatch history import --history-db /data/trustwatch.db trustwatch-*.parquet
+
+  # Seed a federation hub with a remote cluster's history
+  gunzip -c staging.ndjson.gz | trustwatch history import --history-db postgres://tw@hub-db/trustwatch --cluster staging`,
+	RunE: runHistoryImport,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyPruneCmd)
+	historyCmd.AddCommand(historyLineageCmd)
+	historyCmd.AddCommand(historyExportCmd)
+	historyCmd.AddCommand(historyImportCmd)
+	historyPruneCmd.Flags().String("history-db", "", "SQLite path or postgres:// DSN for the history database (default: historyDB from --config)")
+	historyPruneCmd.Flags().String("config", "", "Path to config file (historyRetention settings)")
+	historyPruneCmd.Flags().Duration("full", 0, "Keep every snapshot younger than this (overrides config)")
+	historyPruneCmd.Flags().Duration("hourly", 0, "Keep one snapshot per hour until this age; 0 skips the hourly tier (overrides config)")
+	historyPruneCmd.Flags().Duration("daily", 0, "Keep one snapshot per day until this age; 0 keeps them forever (overrides config)")
+	historyPruneCmd.Flags().Bool("dry-run", false, "Report what would be deleted without changing the database")
+	historyPruneCmd.Flags().Bool("vacuum", false, "Always VACUUM afterwards (default: only when a quarter of the file is free)")
+
+	historyLineageCmd.Flags().String("history-db", "", "SQLite path or postgres:// DSN for the history database (default: historyDB from --config)")
+	historyLineageCmd.Flags().String("config", "", "Path to config file")
+	historyLineageCmd.Flags().String("cluster", "", "Only findings from this federated cluster")
+	historyLineageCmd.Flags().String("source", "", "Only findings from this source, e.g. certmanager")
+	historyLineageCmd.Flags().String("namespace", "", "Only findings in this namespace")
+	historyLineageCmd.Flags().String("name", "", "Only findings with this name")
+	historyLineageCmd.Flags().Duration("since", 0, "Ignore observations older than this (0 uses all history)")
+	historyLineageCmd.Flags().Bool("rotated", false, "Only findings that rotated at least once")
+	historyLineageCmd.Flags().StringP("output", "o", "", "Output format: json, table (default: table)")
+
+	historyExportCmd.Flags().String("history-db", "", "SQLite path or postgres:// DSN for the history database (default: historyDB from --config)")
+	historyExportCmd.Flags().String("config", "", "Path to config file")
+	historyExportCmd.Flags().String("from", "", "Oldest snapshot to export: RFC 3339 time, date, or age such as 30d (default: all)")
+	historyExportCmd.Flags().String("to", "", "Newest snapshot to export: RFC 3339 time, date, or age (default: now)")
+	historyExportCmd.Flags().String("format", archive.FormatNDJSON, "Archive format: ndjson or parquet")
+	historyExportCmd.Flags().StringP("file", "f", "-", "Output file (- for stdout)")
+
+	historyImportCmd.Flags().String("history-db", "", "SQLite path or postgres:// DSN for the history database (default: historyDB from --config)")
+	historyImportCmd.Flags().String("config", "", "Path to config file")
+	historyImportCmd.Flags().String("cluster", "", "Cluster name for imported snapshots and findings that have none")
+	historyImportCmd.Flags().Bool("dry-run", false, "Report what would be imported without writing")
+}
+
+// historyConfig loads --config (or the defaults) and resolves the history
+// database from --history-db, falling back to historyDB.
+func historyConfig(cmd *cobra.Command) (*config.Config, string, error) {
+	cfgPath, _ := cmd.Flags().GetString("config")    //nolint:errcheck // flag registered above
+	dbPath, _ := cmd.Flags().GetString("history-db") //nolint:errcheck // flag registered above
+
+	cfg := config.Defaults()
+	if cfgPath != "" {
+		var err error
+		cfg, err = config.Load(cfgPath)
+		if err != nil {
+			return nil, "", fmt.Errorf("loading config: %w", err)
+		}
+	}
+	if dbPath == "" {
+		dbPath = cfg.HistoryDB
+	}
+	if dbPath == "" {
+		return nil, "", fmt.Errorf("--history-db is required")
+	}
+	return cfg, dbPath, nil
+}
+
+func runHistoryPrune(cmd *cobra.Command, _ []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run") //nolint:errcheck // flag registered above
+	vacuum, _ := cmd.Flags().GetBool("vacuum")  //nolint:errcheck // flag registered above
+
+	cfg, dbPath, err := historyConfig(cmd)
+	if err != nil {
+		return err
+	}
+	r := &cfg.HistoryRetention
+	for name, dst := range map[string]*time.Duration{"full": &r.Full, "hourly": &r.Hourly, "daily": &r.Daily} {
+		if cmd.Flags().Changed(name) {
+			*dst, _ = cmd.Flags().GetDuration(name) //nolint:errcheck // flag registered above
+		}
+	}
+	if vacuum {
+		r.Vacuum = history.VacuumAlways
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if r.Full <= 0 {
+		return fmt.Errorf("retention is disabled (full is 0); set --full or historyRetention.full")
+	}
+
+	hs, err := history.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("opening history database: %w", err)
+	}
+	defer hs.Close() //nolint:errcheck // best-effort cleanup
+
+	out := cmd.OutOrStdout()
+	if dryRun {
+		ids, err := hs.Expired(retention(r), time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Would delete %d snapshot(s)\n", len(ids)) //nolint:errcheck // best-effort output
+		return nil
+	}
+
+	before, err := hs.Stats()
+	if err != nil {
+		return err
+	}
+	res, vacuumed, err := compactHistory(hs, r, time.Now())
+	if err != nil {
+		return err
+	}
+	after, err := hs.Stats()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Deleted %d snapshot(s) and %d finding row(s)\n", res.Snapshots, res.Findings) //nolint:errcheck // best-effort output
+	if vacuumed {
+		fmt.Fprintf(out, "Vacuumed: %s -> %s\n", formatBytes(before.SizeBytes), formatBytes(after.SizeBytes)) //nolint:errcheck // best-effort output
+	} else {
+		fmt.Fprintf(out, "Database size %s (%s free, reused by new scans)\n", //nolint:errcheck // best-effort output
+			formatBytes(after.SizeBytes), formatBytes(after.FreeBytes))
+	}
+	return nil
+}
+
+func runHistoryLineage(cmd *cobra.Command, _ []string) error {
+	outputFlag, _ := cmd.Flags().GetString("output") //nolint:errcheck // flag registered above
+	if outputFlag != "" && outputFlag != "table" && outputFlag != "json" {
+		return fmt.Errorf("invalid --output value %q: must be json or table", outputFlag)
+	}
+	var filter history.LineageFilter
+	filter.Cluster, _ = cmd.Flags().GetString("cluster")     //nolint:errcheck // flag registered above
+	filter.Source, _ = cmd.Flags().GetString("source")       //nolint:errcheck // flag registered above
+	filter.Namespace, _ = cmd.Flags().GetString("namespace") //nolint:errcheck // flag registered above
+	filter.Name, _ = cmd.Flags().GetString("name")           //nolint:errcheck // flag registered above
+	since, _ := cmd.Flags().GetDuration("since")             //nolint:errcheck // flag registered above
+	rotated, _ := cmd.Flags().GetBool("rotated")             //nolint:errcheck // flag registered above
+	if since > 0 {
+		filter.Since = time.Now().Add(-since)
+	}
+
+	_, dbPath, err := historyConfig(cmd)
+	if err != nil {
+		return err
+	}
+	hs, err := history.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("opening history database: %w", err)
+	}
+	defer hs.Close() //nolint:errcheck // best-effort cleanup
+
+	lineages, err := hs.Lineages(filter)
+	if err != nil {
+		return err
+	}
+	if rotated {
+		kept := lineages[:0]
+		for i := range lineages {
+			if lineages[i].Rotations > 0 {
+				kept = append(kept, lineages[i])
+			}
+		}
+		lineages = kept
+	}
+
+	out := cmd.OutOrStdout()
+	if outputFlag == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(lineages); err != nil {
+			return fmt.Errorf("writing JSON output: %w", err)
+		}
+		return nil
+	}
+	return printLineageTable(out, lineages)
+}
+
+func runHistoryExport(cmd *cobra.Command, _ []string) error {
+	fromFlag, _ := cmd.Flags().GetString("from")     //nolint:errcheck // flag registered above
+	toFlag, _ := cmd.Flags().GetString("to")         //nolint:errcheck // flag registered above
+	formatFlag, _ := cmd.Flags().GetString("format") //nolint:errcheck // flag registered above
+	fileFlag, _ := cmd.Flags().GetString("file")     //nolint:errcheck // flag registered above
+
+	now := time.Now()
+	from, err := parseTimeFlag("from", fromFlag, now)
+	if err != nil {
+		return err
+	}
+	to, err := parseTimeFlag("to", toFlag, now)
+	if err != nil {
+		return err
+	}
+	_, dbPath, err := historyConfig(cmd)
+	if err != nil {
+		return err
+	}
+	hs, err := history.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("opening history database: %w", err)
+	}
+	defer hs.Close() //nolint:errcheck // best-effort cleanup
+
+	out := cmd.OutOrStdout()
+	var file *os.File
+	if fileFlag != "-" {
+		file, err = os.Create(fileFlag)
+		if err != nil {
+			return fmt.Errorf("creating export file: %w", err)
+		}
+		defer file.Close() //nolint:errcheck // closed explicitly below on success
+		out = file
+	}
+	w, err := archive.NewWriter(out, formatFlag)
+	if err != nil {
+		return err
+	}
+	st, err := archive.Export(hs, w, from, to)
+	if err != nil {
+		return fmt.Errorf("exporting history: %w", err)
+	}
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("closing export file: %w", err)
+		}
+	}
+	cmd.PrintErrf("Exported %d snapshot(s) with %d finding(s)\n", st.Snapshots, st.Findings)
+	return nil
+}
+
+func runHistoryImport(cmd *cobra.Command, args []string) error {
+	var opts archive.ImportOptions
+	opts.Cluster, _ = cmd.Flags().GetString("cluster") //nolint:errcheck // flag registered above
+	opts.DryRun, _ = cmd.Flags().GetBool("dry-run")    //nolint:errcheck // flag registered above
+
+	_, dbPath, err := historyConfig(cmd)
+	if err != nil {
+		return err
+	}
+	hs, err := history.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("opening history database: %w", err)
+	}
+	defer hs.Close() //nolint:errcheck // best-effort cleanup
+
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+	im := archive.NewImporter(hs, opts)
+	for _, path := range args {
+		if err := importArchive(cmd, im, path); err != nil {
+			return err
+		}
+	}
+	st, err := im.Close()
+	if err != nil {
+		return fmt.Errorf("importing history: %w", err)
+	}
+	verb := "Imported"
+	if opts.DryRun {
+		verb = "Would import"
+	}
+	cmd.Printf("%s %d snapshot(s) with %d finding(s), skipped %d already present\n", verb, st.Snapshots, st.Findings, st.Skipped)
+	return nil
+}
+
+// importArchive feeds one archive file, or stdin for "-", to the importer.
+func importArchive(cmd *cobra.Command, im *archive.Importer, path string) error {
+	r := cmd.InOrStdin()
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("opening archive: %w", err)
+		}
+		defer f.Close() //nolint:errcheck // read-only
+		r = f
+	}
+	if err := archive.Read(r, im.Add); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// parseTimeFlag parses an RFC 3339 time, a date (UTC midnight), or an age
+// such as 30d or 12h before now. An empty value returns the zero time.
+func parseTimeFlag(name, value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	age, err := config.ParseDays(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --%s %q: want an RFC 3339 time, a date, or an age such as 30d", name, value)
+	}
+	return now.Add(-age), nil
+}
+
+// printLineageTable writes one block per finding with a row per generation.
+func printLineageTable(w io.Writer, lineages []history.Lineage) error {
+	if len(lineages) == 0 {
+		_, err := fmt.Fprintln(w, "No certificate lineage recorded")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for i := range lineages {
+		l := &lineages[i]
+		if i > 0 {
+			fmt.Fprintln(tw) //nolint:errcheck // best-effort output
+		}
+		fmt.Fprintf(tw, "%s\t%d rotation(s)", l.Key(), l.Rotations) //nolint:errcheck // best-effort output
+		if l.Rotations > 0 {
+			fmt.Fprintf(tw, ", every %s", formatDays(l.MeanInterval)) //nolint:errcheck // best-effort output
+		}
+		if l.IssuerChanges > 0 {
+			fmt.Fprintf(tw, ", %d issuer change(s)", l.IssuerChanges) //nolint:errcheck // best-effort output
+		}
+		fmt.Fprintln(tw)                                                                         //nolint:errcheck // best-effort output
+		fmt.Fprintln(tw, "  SERIAL\tFIRST SEEN\tLAST SEEN\tNOT AFTER\tLEFT AT ROTATION\tISSUER") //nolint:errcheck // best-effort output
+		for _, g := range l.Generations {
+			left := "current"
+			if !g.RotatedAt.IsZero() {
+				left = formatDays(g.RemainingAtRotation)
+			}
+			issuer := g.Issuer
+			if g.IssuerChanged {
+				issuer += " (changed)"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", g.Serial, //nolint:errcheck // best-effort output
+				g.FirstSeen.UTC().Format(time.DateTime), g.LastSeen.UTC().Format(time.DateTime),
+				g.NotAfter.UTC().Format(time.DateOnly), left, issuer)
+		}
+	}
+	return tw.Flush()
+}
+
+// formatDays renders a duration in days with one decimal, e.g. "29.5d".
+func formatDays(d time.Duration) string {
+	return fmt.Sprintf("%.1fd", d.Hours()/24)
+}
+
+// retention converts config retention settings to a history policy.
+func retention(r *config.RetentionConfig) history.Retention {
+	return history.Retention{Full: r.Full, Hourly: r.Hourly, Daily: r.Daily}
+}
+
+// compactHistory applies the retention policy and vacuums according to its mode.
+func compactHistory(hs *history.Store, r *config.RetentionConfig, now time.Time) (history.CompactResult, bool, error) {
+	res, err := hs.Compact(retention(r), now)
+	if err != nil {
+		return res, false, fmt.Errorf("compacting history: %w", err)
+	}
+	mode := r.Vacuum
+	if mode == "" {
+		mode = history.VacuumAuto
+	}
+	vacuumed, err := hs.Vacuum(mode)
+	return res, vacuumed, err
+}
+
+// analyticsOptions converts config analytics settings to analytics options.
+func analyticsOptions(a *config.AnalyticsConfig) analytics.Options {
+	return analytics.Options{Window: a.Window, NearMiss: a.NearMiss, MinRemaining: a.MinRemaining}
+}
+
+// historyLeader returns a check reporting whether this replica leads the
+// history database, logging when that changes. An error counts as not
+// leading, so a replica that cannot reach the database never writes to it.
+func historyLeader(hs *history.Store) func(context.Context) bool {
+	var mu sync.Mutex
+	leading := false
+	return func(ctx context.Context) bool {
+		ok, err := hs.Lead(ctx)
+		if err != nil {
+			slog.Warn("checking history leadership", "err", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if ok != leading {
+			if ok {
+				slog.Info("leading history: scanning, saving and compacting")
+			} else {
+				slog.Info("following history leader: serving its saved snapshots")
+			}
+			leading = ok
+		}
+		return ok
+	}
+}
+
+// runHistoryMaintenance compacts the history database while this replica
+// leads it, and refreshes its size and rotation analytics metrics and the
+// analytics report served by the API every compactEvery until ctx is canceled.
+func runHistoryMaintenance(ctx context.Context, hs *history.Store, lead func(context.Context) bool,
+	r *config.RetentionConfig, a *config.AnalyticsConfig, collector *metrics.Collector, report *atomic.Pointer[analytics.Report]) {
+	every := r.CompactEvery
+	if every <= 0 {
+		every = time.Hour
+	}
+	run := func() {
+		if r.Full > 0 && lead(ctx) {
+			res, vacuumed, err := compactHistory(hs, r, time.Now())
+			if err != nil {
+				slog.Warn("history maintenance", "err", err)
+			}
+			collector.AddHistoryCompacted("snapshots", res.Snapshots)
+			collector.AddHistoryCompacted("findings", res.Findings)
+			if res.Snapshots > 0 || vacuumed {
+				slog.Info("history compacted", "snapshots", res.Snapshots, "findings", res.Findings, "vacuumed", vacuumed)
+			}
+		}
+		if st, err := hs.Stats(); err != nil {
+			slog.Warn("reading history stats", "err", err)
+		} else {
+			collector.UpdateHistory(st.SizeBytes, st.FreeBytes, st.Rows)
+		}
+		rep, err := analytics.FromHistory(hs, analyticsOptions(a), time.Now())
+		if err != nil {
+			slog.Warn("computing rotation analytics", "err", err)
+			return
+		}
+		collector.UpdateAnalytics(rep)
+		report.Store(rep)
+	}
+
+	run()
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run()
+		}
+	}
+}
+
+// rotationCadence returns a function reporting the rotation intervals observed
+// in hs for cluster, reloaded at most once per refresh. Errors are logged and
+// the last good intervals are kept.
+func rotationCadence(hs *history.Store, cluster string, refresh time.Duration) func() map[string]time.Duration {
+	var intervals map[string]time.Duration
+	var loadedAt time.Time
+	return func() map[string]time.Duration {
+		if !loadedAt.IsZero() && time.Since(loadedAt) < refresh {
+			return intervals
+		}
+		loaded, err := hs.RotationIntervals(time.Time{}, cluster)
+		if err != nil {
+			slog.Warn("loading observed rotation intervals", "err", err)
+			return intervals
+		}
+		intervals, loadedAt = loaded, time.Now()
+		return intervals
+	}
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. 12.5 MiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/history"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func TestHistoryPrune(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "history.db")
+	hs, err := history.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	// Six scans an hour apart, all older than the full-resolution window.
+	for i := range 6 {
+		at := now.Add(-48*time.Hour - time.Duration(i)*time.Hour)
+		snap := store.Snapshot{At: at, Findings: []store.CertFinding{{Name: "a", Namespace: "ns", Source: store.SourceTLSSecret}}}
+		if err := hs.Save(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hs.Close() //nolint:errcheck // reopened by the command
+
+	run := func(args ...string) string {
+		t.Helper()
+		stdout := new(bytes.Buffer)
+		cmd := rootCmd
+		cmd.SetOut(stdout)
+		cmd.SetErr(stdout)
+		cmd.SetArgs(append([]string{"history", "prune", "--history-db", dbPath}, args...))
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("history prune %v: %v", args, err)
+		}
+		return stdout.String()
+	}
+
+	if out := run("--full", "24h", "--hourly", "0", "--daily", "24h1m", "--dry-run"); !strings.Contains(out, "Would delete 6 snapshot(s)") {
+		t.Errorf("dry run output:\n%s", out)
+	}
+	out := run("--full", "24h", "--hourly", "0", "--daily", "24h1m", "--dry-run=false", "--vacuum")
+	if !strings.Contains(out, "Deleted 6 snapshot(s) and 6 finding row(s)") || !strings.Contains(out, "Vacuumed:") {
+		t.Errorf("prune output:\n%s", out)
+	}
+
+	hs, err = history.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hs.Close() //nolint:errcheck // test cleanup
+	st, err := hs.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Rows["snapshots"] != 0 || st.Rows["findings"] != 0 {
+		t.Errorf("rows after prune = %v, want none", st.Rows)
+	}
+}
+
+func TestHistoryLineage(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "history.db")
+	hs, err := history.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().UTC().Add(-72 * time.Hour)
+	for i, serial := range []string{"100", "100", "200"} {
+		snap := store.Snapshot{At: start.Add(time.Duration(i) * 24 * time.Hour), Findings: []store.CertFinding{{
+			Name: "api-tls", Namespace: "payments", Source: store.SourceTLSSecret, Serial: serial, Issuer: "CN=CA",
+			NotAfter: start.Add(30 * 24 * time.Hour), ProbeOK: true,
+		}}}
+		if err := hs.Save(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hs.Close() //nolint:errcheck // reopened by the command
+
+	stdout := new(bytes.Buffer)
+	cmd := rootCmd
+	cmd.SetOut(stdout)
+	cmd.SetErr(stdout)
+	cmd.SetArgs([]string{"history", "lineage", "--history-db", dbPath, "--namespace", "payments", "--rotated"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("history lineage: %v", err)
+	}
+	out := stdout.String()
+	for _, want := range []string{"k8s.tlsSecret/payments/api-tls", "1 rotation(s), every 2.0d", "28.0d", "current"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHistoryExportImport(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src.db")
+	hs, err := history.Open(srcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	for i := range 3 {
+		snap := store.Snapshot{At: now.Add(-time.Duration(10-i)*24*time.Hour + 12*time.Hour), Findings: []store.CertFinding{
+			{Name: "api-tls", Namespace: "payments", Source: store.SourceTLSSecret, Serial: "100", Severity: store.SeverityWarn},
+		}}
+		if err := hs.Save(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hs.Close() //nolint:errcheck // reopened by the command
+
+	run := func(args ...string) string {
+		t.Helper()
+		stdout := new(bytes.Buffer)
+		cmd := rootCmd
+		cmd.SetOut(stdout)
+		cmd.SetErr(stdout)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return stdout.String()
+	}
+
+	for _, format := range []string{"ndjson", "parquet"} {
+		archivePath := filepath.Join(dir, "history."+format)
+		dstPath := filepath.Join(dir, format+".db")
+		out := run("history", "export", "--history-db", srcPath, "--from", "9d", "--to", "", "--format", format, "--file", archivePath)
+		if !strings.Contains(out, "Exported 2 snapshot(s) with 2 finding(s)") {
+			t.Errorf("%s export output:\n%s", format, out)
+		}
+		out = run("history", "import", "--history-db", dstPath, "--cluster", "staging", "--dry-run=false", archivePath)
+		if !strings.Contains(out, "Imported 2 snapshot(s) with 2 finding(s), skipped 0") {
+			t.Errorf("%s import output:\n%s", format, out)
+		}
+		out = run("history", "import", "--history-db", dstPath, "--cluster", "staging", "--dry-run=true", archivePath)
+		if !strings.Contains(out, "Would import 0 snapshot(s) with 0 finding(s), skipped 2") {
+			t.Errorf("%s repeated import output:\n%s", format, out)
+		}
+
+		dst, err := history.Open(dstPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, err := dst.Query(history.Query{Clusters: []string{"staging"}})
+		dst.Close() //nolint:errcheck // test cleanup
+		if err != nil || page.Total != 2 {
+			t.Errorf("%s imported findings = %+v, %v; want 2 labelled staging", format, page, err)
+		}
+	}
+}
+
+func TestParseTimeFlag(t *testing.T) {
+	now := time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Time{
+		"":                     {},
+		"2026-06-01T08:30:00Z": time.Date(2026, 6, 1, 8, 30, 0, 0, time.UTC),
+		"2026-06-01":           time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+		"7d":                   now.Add(-7 * 24 * time.Hour),
+		"12h":                  now.Add(-12 * time.Hour),
+	} {
+		got, err := parseTimeFlag("from", value, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseTimeFlag(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := parseTimeFlag("from", "last week", now); err == nil || !strings.Contains(err.Error(), "--from") {
+		t.Errorf("expected an error naming the flag, got %v", err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{512: "512 B", 1536: "1.5 KiB", 3 << 20: "3.0 MiB", 5 << 30: "5.0 GiB"} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -59,7 +59,9 @@ func init() {
 	impactCmd.Flags().String("config", "", "Path to config file")
 	impactCmd.Flags().String("kubeconfig", "", "Path to kubeconfig")
 	impactCmd.Flags().String("context", "", "Kubernetes context to use")
-	impactCmd.Flags().StringSlice("namespace", nil, "Namespaces to scan (empty = all)")
+	impactCmd.Flags().StringSlice("namespace", nil, "Namespaces or glob patterns to scan (empty = all)")
+	impactCmd.Flags().StringSlice("exclude-namespace", nil, "Namespaces or glob patterns to skip")
+	impactCmd.Flags().String("namespace-selector", "", "Label selector for namespaces to scan (e.g. trustwatch.dev/scan!=false)")
 	impactCmd.Flags().Duration("warn-before", 0, "Warn threshold (default from config)")
 	impactCmd.Flags().Duration("crit-before", 0, "Critical threshold (default from config)")
 	impactCmd.Flags().Bool("tunnel", false, "Deploy a SOCKS5 relay pod to route probes through in-cluster DNS")
@@ -121,9 +123,8 @@ func runImpact(cmd *cobra.Command, _ []string) error { //nolint:dupl // intentio
 	if critDur > 0 {
 		cfg.CritBefore = critDur
 	}
-	ns, _ := cmd.Flags().GetStringSlice("namespace") //nolint:errcheck // flag registered above
-	if len(ns) > 0 {
-		cfg.Namespaces = ns
+	if err := applyScopeFlags(cmd, cfg); err != nil {
+		return err
 	}
 
 	// Build Kubernetes client
@@ -212,59 +213,25 @@ func runImpact(cmd *cobra.Command, _ []string) error { //nolint:dupl // intentio
 	}
 	defer closeRelay()
 
-	// Derive API server host
-	apiServerTarget := apiServerFromHost(restCfg.Host)
-
-	// Resolve namespaces
-	nsCtx := context.Background()
-	allNS, err := discovery.ResolveNamespaces(nsCtx, clientset, cfg.Namespaces)
-	if err != nil {
-		return fmt.Errorf("resolving namespaces: %w", err)
-	}
-	secretNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "", "secrets")
-	ingressNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "networking.k8s.io", "ingresses")
-	svcNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "", "services")
-	gwNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "gateway.networking.k8s.io", "gateways")
-	certNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "cert-manager.io", "certificates")
-
-	// Build discoverers
-	var webhookOpts []func(*discovery.WebhookDiscoverer)
-	var apiSvcOpts []func(*discovery.APIServiceDiscoverer)
-	var annotOpts []func(*discovery.AnnotationDiscoverer)
-	var extOpts []func(*discovery.ExternalDiscoverer)
-	if tunnelProbeFn != nil {
-		webhookOpts = append(webhookOpts, discovery.WithWebhookProbeFn(tunnelProbeFn))
-		apiSvcOpts = append(apiSvcOpts, discovery.WithAPIServiceProbeFn(tunnelProbeFn))
-		annotOpts = append(annotOpts, discovery.WithAnnotationProbeFn(tunnelProbeFn))
-		extOpts = append(extOpts, discovery.WithExternalProbeFn(tunnelProbeFn))
-	}
-	annotOpts = append(annotOpts, discovery.WithAnnotationNamespaces(svcNS))
-
-	discoverers := []discovery.Discoverer{ //nolint:dupl // intentional parallel to now.go/check.go; commands may diverge
-		discovery.NewWebhookDiscoverer(clientset, webhookOpts...),
-		discovery.NewAPIServiceDiscoverer(aggClient, apiSvcOpts...),
-		discovery.NewAPIServerDiscoverer(apiServerTarget, discovery.WithProbeFn(restProbe(restCfg))),
-		discovery.NewSecretDiscoverer(clientset, discovery.WithSecretNamespaces(secretNS)),
-		discovery.NewIngressDiscoverer(clientset, discovery.WithIngressNamespaces(ingressNS)),
-		discovery.NewLinkerdDiscoverer(clientset),
-		discovery.NewIstioDiscoverer(clientset),
-		discovery.NewAnnotationDiscoverer(clientset, annotOpts...),
-		discovery.NewGatewayDiscoverer(gwClient, clientset, discovery.WithGatewayNamespaces(gwNS)),
-		discovery.NewCertManagerDiscoverer(dynClient, clientset, discovery.WithCertManagerNamespaces(certNS)),
-		discovery.NewCertManagerRenewalDiscoverer(dynClient, clientset, discovery.WithRenewalNamespaces(certNS)),
-	}
-	if len(cfg.External) > 0 {
-		discoverers = append(discoverers, discovery.NewExternalDiscoverer(cfg.External, extOpts...))
-	}
-
 	spiffeSocket, _ := cmd.Flags().GetString("spiffe-socket") //nolint:errcheck // flag registered above
-	if spiffeSocket == "" {
-		spiffeSocket = cfg.SPIFFESocket
-	}
 	if spiffeSocket != "" {
-		discoverers = append(discoverers, discovery.NewSPIFFEDiscoverer(spiffeSocket))
+		cfg.SPIFFESocket = spiffeSocket
+	}
+
+	// Build discoverers from config — probing discoverers get the tunnel probe function when --tunnel is set
+	discoverers, scope, err := discovery.Build(context.Background(), discovery.Clients{
+		Core:       clientset,
+		Aggregator: aggClient,
+		Gateway:    gwClient,
+		Dynamic:    dynClient,
+	}, cfg, discovery.BuildOptions{
+		APIServerTarget:  apiServerFromHost(restCfg.Host),
+		APIServerProbeFn: restProbe(restCfg),
+		ProbeFn:          tunnelProbeFn,
+	})
+	if err != nil {
+		return err
 	}
-	discoverers = append(discoverers, discovery.CloudDiscoverers()...)
 
 	// Initialize tracing
 	otelEndpoint, _ := cmd.Flags().GetString("otel-endpoint") //nolint:errcheck // flag registered above
@@ -278,6 +245,8 @@ func runImpact(cmd *cobra.Command, _ []string) error { //nolint:dupl // intentio
 	// Run discovery
 	slog.Info("scanning discovery sources", "count", len(discoverers))
 	var orchOpts []discovery.OrchestratorOption
+	orchOpts = append(orchOpts, discovery.WithMetadata(scanMetadata(clientset, scope, cfg.ClusterName, kubeCtx, useTunnel)))
+	orchOpts = append(orchOpts, discovery.WithNamespaceOwnership(clientset))
 	if len(loadedPolicies) > 0 {
 		orchOpts = append(orchOpts, discovery.WithPolicies(loadedPolicies))
 	}
@@ -19,6 +19,7 @@ func TestImpactCommand_Flags(t *testing.T) {
 	flags := []string{
 		"issuer", "serial", "subject",
 		"config", "kubeconfig", "context", "namespace",
+		"exclude-namespace", "namespace-selector",
 		"warn-before", "crit-before",
 		"tunnel", "tunnel-ns", "tunnel-image",
 		"check-revocation", "ct-domains", "ct-allowed-issuers",
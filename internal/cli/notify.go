@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/notify"
+)
+
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Work with notification webhooks",
+}
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Preview or send a sample notification for a webhook",
+	Long: `Render the payload a webhook would receive for a sample set of findings.
+
+The webhook is selected by its name in the notifications.webhooks config.
+Templated webhooks render their template; generic and Slack webhooks
+without a template show the built-in payload. With --send the payload is
+also delivered once, ignoring severity filters and cooldowns.`,
+	Example: `  # Preview a templated Teams webhook
+  trustwatch notify test --config trustwatch.yaml --webhook teams
+
+  # Deliver the sample notification
+  trustwatch notify test --config trustwatch.yaml --webhook teams --send`,
+	RunE: runNotifyTest,
+}
+
+func init() {
+	rootCmd.AddCommand(notifyCmd)
+	notifyCmd.AddCommand(notifyTestCmd)
+	notifyTestCmd.Flags().String("config", "", "Path to config file")
+	notifyTestCmd.Flags().String("webhook", "", "Name of the webhook to test")
+	notifyTestCmd.Flags().Bool("send", false, "Deliver the sample notification")
+}
+
+func runNotifyTest(cmd *cobra.Command, _ []string) error {
+	cfgPath, _ := cmd.Flags().GetString("config") //nolint:errcheck // flag registered above
+	name, _ := cmd.Flags().GetString("webhook")   //nolint:errcheck // flag registered above
+	send, _ := cmd.Flags().GetBool("send")        //nolint:errcheck // flag registered above
+	if cfgPath == "" || name == "" {
+		return fmt.Errorf("--config and --webhook are required")
+	}
+
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	wh, err := findWebhook(cfg.Notifications.Webhooks, name)
+	if err != nil {
+		return err
+	}
+
+	data := notify.SampleTemplateData(cfg.ClusterName)
+	body, err := notify.Preview(wh, data)
+	if err != nil {
+		return fmt.Errorf("webhook %q: %w", name, err)
+	}
+
+	out := cmd.OutOrStdout()
+	method := "POST"
+	if wh.Method != "" {
+		method = strings.ToUpper(wh.Method)
+	}
+	contentType := wh.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	target := redactURL(wh.URL)
+	if wh.Type == "ticket" {
+		target = wh.APIURL()
+	}
+	fmt.Fprintf(out, "%s %s\n", method, target)         //nolint:errcheck // best-effort output
+	fmt.Fprintf(out, "Content-Type: %s\n", contentType) //nolint:errcheck // best-effort output
+	for _, h := range sortedHeaderNames(wh.Headers) {
+		fmt.Fprintf(out, "%s: <redacted>\n", h) //nolint:errcheck // best-effort output
+	}
+	fmt.Fprintf(out, "\n%s\n", body) //nolint:errcheck // best-effort output
+
+	if !send {
+		return nil
+	}
+	n := notify.New(config.NotificationConfig{
+		Enabled:     true,
+		Webhooks:    []config.WebhookConfig{*wh},
+		MaxAttempts: 1,
+	})
+	if err := n.SendTest(context.Background(), data); err != nil {
+		return err
+	}
+	cmd.PrintErrln("sent")
+	return nil
+}
+
+func findWebhook(webhooks []config.WebhookConfig, name string) (*config.WebhookConfig, error) {
+	var names []string
+	for i := range webhooks {
+		if webhooks[i].Name == name {
+			return &webhooks[i], nil
+		}
+		if webhooks[i].Name != "" {
+			names = append(names, webhooks[i].Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("webhook %q not found: no webhooks have a name", name)
+	}
+	return nil, fmt.Errorf("webhook %q not found (available: %s)", name, strings.Join(names, ", "))
+}
+
+// redactURL keeps the scheme and host of a webhook URL and masks the rest:
+// Slack, Teams and many generic webhooks carry their credential in the path
+// or query.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "<redacted>"
+	}
+	out := u.Scheme + "://" + u.Host
+	if strings.Trim(u.Path, "/") != "" {
+		out += "/<redacted>"
+	}
+	if u.RawQuery != "" {
+		out += "?<redacted>"
+	}
+	return out
+}
+
+func sortedHeaderNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for h := range headers {
+		names = append(names, h)
+	}
+	sort.Strings(names)
+	return names
+}
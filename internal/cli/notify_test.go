@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeNotifyConfig(t *testing.T, url string) string {
+	t.Helper()
+	content := `clusterName: lab
+notifications:
+  enabled: true
+  webhooks:
+    - name: bot
+      url: "` + url + `"
+      method: PUT
+      headers:
+        X-Token: s3cret
+      template: '{"cluster": "{{ .Cluster }}", "count": {{ len .Findings }}}'
+    - name: pd
+      type: pagerduty
+      routingKey: abc
+`
+	path := filepath.Join(t.TempDir(), "trustwatch.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNotifyTest_Preview(t *testing.T) {
+	path := writeNotifyConfig(t, "https://bot.example.com/hooks/T0K3N?sig=abc123")
+
+	stdout := new(bytes.Buffer)
+	cmd := rootCmd
+	cmd.SetOut(stdout)
+	cmd.SetErr(stdout)
+	cmd.SetArgs([]string{"notify", "test", "--config", path, "--webhook", "bot"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("notify test: %v", err)
+	}
+
+	out := stdout.String()
+	for _, want := range []string{"PUT https://bot.example.com/<redacted>?<redacted>", "X-Token: <redacted>", `{"cluster": "lab", "count": 2}`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	for _, secret := range []string{"s3cret", "T0K3N", "abc123"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("output contains the secret %q:\n%s", secret, out)
+		}
+	}
+}
+
+func TestNotifyTest_Send(t *testing.T) {
+	var got []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body) //nolint:errcheck // test helper
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	path := writeNotifyConfig(t, srv.URL)
+
+	cmd := rootCmd
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	cmd.SetArgs([]string{"notify", "test", "--config", path, "--webhook", "bot", "--send"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("notify test --send: %v", err)
+	}
+	if string(got) != `{"cluster": "lab", "count": 2}` {
+		t.Errorf("delivered body = %q", got)
+	}
+	// Flag values persist on rootCmd; reset --send for later tests.
+	cmd.SetArgs([]string{"notify", "test", "--config", path, "--webhook", "bot", "--send=false"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotifyTest_Errors(t *testing.T) {
+	path := writeNotifyConfig(t, "https://bot.example.com/hook")
+	for _, args := range [][]string{
+		{"notify", "test", "--config", path, "--webhook", "missing"},
+		{"notify", "test", "--config", path, "--webhook", "pd"},
+	} {
+		cmd := rootCmd
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetErr(new(bytes.Buffer))
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
@@ -82,7 +82,9 @@ func init() {
 	nowCmd.Flags().String("config", "", "Path to config file")
 	nowCmd.Flags().String("kubeconfig", "", "Path to kubeconfig")
 	nowCmd.Flags().String("context", "", "Kubernetes context to use")
-	nowCmd.Flags().StringSlice("namespace", nil, "Namespaces to scan (empty = all)")
+	nowCmd.Flags().StringSlice("namespace", nil, "Namespaces or glob patterns to scan (empty = all)")
+	nowCmd.Flags().StringSlice("exclude-namespace", nil, "Namespaces or glob patterns to skip")
+	nowCmd.Flags().String("namespace-selector", "", "Label selector for namespaces to scan (e.g. trustwatch.dev/scan!=false)")
 	nowCmd.Flags().Duration("warn-before", 0, "Warn threshold (default from config)")
 	nowCmd.Flags().Duration("crit-before", 0, "Critical threshold (default from config)")
 	nowCmd.Flags().Bool("tunnel", false, "Deploy a SOCKS5 relay pod to route probes through in-cluster DNS")
@@ -90,7 +92,7 @@ func init() {
 	nowCmd.Flags().String("tunnel-image", tunnel.DefaultImage, "SOCKS5 proxy image for --tunnel")
 	nowCmd.Flags().StringSlice("tunnel-command", nil, "Override container command (e.g. 'microsocks,-p,1080')")
 	nowCmd.Flags().String("tunnel-pull-secret", "", "imagePullSecret name for the tunnel relay pod")
-	nowCmd.Flags().String("history-db", "", "Path to SQLite history database (save snapshot)")
+	nowCmd.Flags().String("history-db", "", "SQLite path or postgres:// DSN for the history database (save snapshot)")
 	nowCmd.Flags().Duration("scan-timeout", 120*time.Second, "Maximum time to wait for all discoverers to complete")
 	nowCmd.Flags().String("spiffe-socket", "", "Path to SPIFFE workload API socket")
 	nowCmd.Flags().String("cluster-name", "", "Name for this cluster in federated views")
@@ -126,9 +128,8 @@ func runNow(cmd *cobra.Command, _ []string) error {
 	if critDur > 0 {
 		cfg.CritBefore = critDur
 	}
-	ns, _ := cmd.Flags().GetStringSlice("namespace") //nolint:errcheck // flag registered above
-	if len(ns) > 0 {
-		cfg.Namespaces = ns
+	if err := applyScopeFlags(cmd, cfg); err != nil {
+		return err
 	}
 
 	// Build Kubernetes client
@@ -236,65 +237,26 @@ func runNow(cmd *cobra.Command, _ []string) error {
 		os.Exit(0)
 	}()
 
-	// Derive API server host from kubeconfig for local probing
-	apiServerTarget := apiServerFromHost(restCfg.Host)
-
-	// Resolve namespaces for namespace-scoped discoverers
-	nsCtx := context.Background()
-	allNS, err := discovery.ResolveNamespaces(nsCtx, clientset, cfg.Namespaces)
-	if err != nil {
-		return fmt.Errorf("resolving namespaces: %w", err)
-	}
-	secretNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "", "secrets")
-	ingressNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "networking.k8s.io", "ingresses")
-	svcNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "", "services")
-	gwNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "gateway.networking.k8s.io", "gateways")
-	certNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "cert-manager.io", "certificates")
-	slog.Info("namespace access resolved", "total", len(allNS),
-		"secrets", len(secretNS), "ingresses", len(ingressNS),
-		"services", len(svcNS), "gateways", len(gwNS),
-		"certificates", len(certNS))
-
-	// Build discoverers — probing discoverers get the tunnel probe function when --tunnel is set
-	var webhookOpts []func(*discovery.WebhookDiscoverer)
-	var apiSvcOpts []func(*discovery.APIServiceDiscoverer)
-	var annotOpts []func(*discovery.AnnotationDiscoverer)
-	var extOpts []func(*discovery.ExternalDiscoverer)
-	if tunnelProbeFn != nil {
-		webhookOpts = append(webhookOpts, discovery.WithWebhookProbeFn(tunnelProbeFn))
-		apiSvcOpts = append(apiSvcOpts, discovery.WithAPIServiceProbeFn(tunnelProbeFn))
-		annotOpts = append(annotOpts, discovery.WithAnnotationProbeFn(tunnelProbeFn))
-		extOpts = append(extOpts, discovery.WithExternalProbeFn(tunnelProbeFn))
-	}
-	annotOpts = append(annotOpts, discovery.WithAnnotationNamespaces(svcNS))
-
-	discoverers := []discovery.Discoverer{ //nolint:dupl // intentional parallel to check.go; commands may diverge
-		discovery.NewWebhookDiscoverer(clientset, webhookOpts...),
-		discovery.NewAPIServiceDiscoverer(aggClient, apiSvcOpts...),
-		discovery.NewAPIServerDiscoverer(apiServerTarget, discovery.WithProbeFn(restProbe(restCfg))),
-		discovery.NewSecretDiscoverer(clientset, discovery.WithSecretNamespaces(secretNS)),
-		discovery.NewIngressDiscoverer(clientset, discovery.WithIngressNamespaces(ingressNS)),
-		discovery.NewLinkerdDiscoverer(clientset),
-		discovery.NewIstioDiscoverer(clientset),
-		discovery.NewAnnotationDiscoverer(clientset, annotOpts...),
-		discovery.NewGatewayDiscoverer(gwClient, clientset, discovery.WithGatewayNamespaces(gwNS)),
-		discovery.NewCertManagerDiscoverer(dynClient, clientset, discovery.WithCertManagerNamespaces(certNS)),
-		discovery.NewCertManagerRenewalDiscoverer(dynClient, clientset, discovery.WithRenewalNamespaces(certNS)),
-	}
-	if len(cfg.External) > 0 {
-		discoverers = append(discoverers, discovery.NewExternalDiscoverer(cfg.External, extOpts...))
-	}
-
 	spiffeSocket, _ := cmd.Flags().GetString("spiffe-socket") //nolint:errcheck // flag registered above
-	if spiffeSocket == "" {
-		spiffeSocket = cfg.SPIFFESocket
-	}
 	if spiffeSocket != "" {
-		discoverers = append(discoverers, discovery.NewSPIFFEDiscoverer(spiffeSocket))
+		cfg.SPIFFESocket = spiffeSocket
+	}
+
+	// Build discoverers from config — probing discoverers get the tunnel probe function when --tunnel is set
+	discoverers, scope, err := discovery.Build(context.Background(), discovery.Clients{
+		Core:       clientset,
+		Aggregator: aggClient,
+		Gateway:    gwClient,
+		Dynamic:    dynClient,
+	}, cfg, discovery.BuildOptions{
+		APIServerTarget:  apiServerFromHost(restCfg.Host),
+		APIServerProbeFn: restProbe(restCfg),
+		ProbeFn:          tunnelProbeFn,
+	})
+	if err != nil {
+		return err
 	}
 
-	discoverers = append(discoverers, discovery.CloudDiscoverers()...)
-
 	// Initialize tracing
 	otelEndpoint, _ := cmd.Flags().GetString("otel-endpoint") //nolint:errcheck // flag registered above
 	tracer, tracerShutdown, tracerErr := telemetry.InitTracer(context.Background(), otelEndpoint, "trustwatch", version)
@@ -317,7 +279,13 @@ func runNow(cmd *cobra.Command, _ []string) error {
 
 	// Run discovery
 	slog.Info("scanning discovery sources", "count", len(discoverers))
+	clusterName, _ := cmd.Flags().GetString("cluster-name") //nolint:errcheck // flag registered above
+	if clusterName == "" {
+		clusterName = cfg.ClusterName
+	}
 	var orchOpts []discovery.OrchestratorOption
+	orchOpts = append(orchOpts, discovery.WithMetadata(scanMetadata(clientset, scope, clusterName, kubeCtx, useTunnel)))
+	orchOpts = append(orchOpts, discovery.WithNamespaceOwnership(clientset))
 	if len(loadedPolicies) > 0 {
 		orchOpts = append(orchOpts, discovery.WithPolicies(loadedPolicies))
 	}
@@ -339,15 +307,25 @@ func runNow(cmd *cobra.Command, _ []string) error {
 	if len(ctDomains) > 0 {
 		orchOpts = append(orchOpts, discovery.WithCTCheck(ctDomains, ctIssuers, ct.NewClient()))
 	}
+	remoteFlags, _ := cmd.Flags().GetStringSlice("remote") //nolint:errcheck // flag registered above
+	remoteSources := parseRemoteFlags(remoteFlags, cfg.Remotes)
+	if len(remoteSources) > 0 && clusterName == "" {
+		clusterName = "local"
+	}
 	detectDrift, _ := cmd.Flags().GetBool("detect-drift") //nolint:errcheck // flag registered above
 	if detectDrift && histStore != nil {
-		prevSnap, prevErr := histStore.GetLatest()
+		prevSnap, prevErr := histStore.GetLatest(clusterName)
 		if prevErr != nil {
 			slog.Warn("loading previous snapshot for drift detection", "err", prevErr)
 		} else if prevSnap != nil {
 			orchOpts = append(orchOpts, discovery.WithDriftDetection(prevSnap))
 		}
 	}
+	// Local findings are saved under clusterName, so observed intervals are
+	// read for that cluster only.
+	if histStore != nil {
+		orchOpts = append(orchOpts, discovery.WithRotationCadence(rotationCadence(histStore, clusterName, time.Hour)))
+	}
 	orch := discovery.NewOrchestrator(discoverers, cfg.WarnBefore, cfg.CritBefore, orchOpts...)
 	scanTimeout, _ := cmd.Flags().GetDuration("scan-timeout") //nolint:errcheck // flag registered above
 	scanCtx, scanCancel := context.WithTimeout(context.Background(), scanTimeout)
@@ -361,16 +339,7 @@ func runNow(cmd *cobra.Command, _ []string) error {
 	}
 
 	// Federate with remote clusters if configured
-	clusterName, _ := cmd.Flags().GetString("cluster-name") //nolint:errcheck // flag registered above
-	if clusterName == "" {
-		clusterName = cfg.ClusterName
-	}
-	remoteFlags, _ := cmd.Flags().GetStringSlice("remote") //nolint:errcheck // flag registered above
-	remoteSources := parseRemoteFlags(remoteFlags, cfg.Remotes)
 	if len(remoteSources) > 0 {
-		if clusterName == "" {
-			clusterName = "local"
-		}
 		remoteSnaps := make(map[string]store.Snapshot)
 		for _, rs := range remoteSources {
 			remoteSnap, fetchErr := rs.Fetch(context.Background())
@@ -509,11 +478,59 @@ func filterManagedExpiry(findings []store.CertFinding) []store.CertFinding {
 	return filtered
 }
 
+// scanMetadata returns the base snapshot metadata for a scan of the current cluster.
+// The API server version is best-effort and left empty if it cannot be read.
+func scanMetadata(clientset kubernetes.Interface, scope *store.Scope, clusterName, kubeCtx string, tunneled bool) *store.Metadata {
+	md := &store.Metadata{
+		Scope:   scope,
+		Cluster: clusterName,
+		Context: kubeCtx,
+		Version: version,
+		Tunnel:  tunneled,
+	}
+	info, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		slog.Debug("reading server version", "err", err)
+		return md
+	}
+	md.KubernetesVersion = info.GitVersion
+	return md
+}
+
+// applyScopeFlags overrides the config namespace scope from --namespace,
+// --exclude-namespace, and --namespace-selector.
+func applyScopeFlags(cmd *cobra.Command, cfg *config.Config) error {
+	ns, _ := cmd.Flags().GetStringSlice("namespace") //nolint:errcheck // flag registered by caller
+	if len(ns) > 0 {
+		cfg.Namespaces = ns
+	}
+	excludeNS, _ := cmd.Flags().GetStringSlice("exclude-namespace") //nolint:errcheck // flag registered by caller
+	if len(excludeNS) > 0 {
+		cfg.ExcludeNamespaces = excludeNS
+	}
+	nsSelector, _ := cmd.Flags().GetString("namespace-selector") //nolint:errcheck // flag registered by caller
+	if nsSelector != "" {
+		cfg.NamespaceSelector = nsSelector
+	}
+	return cfg.ValidateNamespaceScope()
+}
+
 // parseRemoteFlags merges --remote flags (name=url format) with config file remotes.
 func parseRemoteFlags(flags []string, cfgRemotes []config.RemoteCluster) []*federation.RemoteSource {
 	var sources []*federation.RemoteSource
 	for _, r := range cfgRemotes {
-		sources = append(sources, &federation.RemoteSource{Name: r.Name, URL: r.URL})
+		rs := &federation.RemoteSource{Name: r.Name, URL: r.URL, Token: r.Token}
+		if r.TLS != nil {
+			tlsCfg, err := r.TLS.TLSConfig()
+			if err != nil {
+				slog.Warn("skipping remote with invalid tls settings", "cluster", r.Name, "err", err)
+				continue
+			}
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = tlsCfg
+			rs.Client = &http.Client{Transport: transport}
+		}
+		sources = append(sources, rs)
 	}
 	for _, f := range flags {
 		parts := strings.SplitN(f, "=", 2)
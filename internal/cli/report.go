@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/dynamic"
@@ -14,9 +15,11 @@ import (
 	aggregatorclient "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset"
 	gatewayclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
 
+	"github.com/ppiankov/trustwatch/internal/analytics"
 	"github.com/ppiankov/trustwatch/internal/config"
 	"github.com/ppiankov/trustwatch/internal/ct"
 	"github.com/ppiankov/trustwatch/internal/discovery"
+	"github.com/ppiankov/trustwatch/internal/history"
 	"github.com/ppiankov/trustwatch/internal/policy"
 	"github.com/ppiankov/trustwatch/internal/probe"
 	"github.com/ppiankov/trustwatch/internal/report"
@@ -32,8 +35,9 @@ var reportCmd = &cobra.Command{
 suitable for compliance audits, email distribution, or archival.
 
 The report includes all findings with certificate details, severity
-counts, and remediation guidance. All CSS is inlined — no external
-dependencies. The output is print-friendly.`,
+counts, and remediation guidance. With --history-db (or historyDB in the
+config) it adds a rotation SLO section computed from snapshot history.
+All CSS is inlined — no external dependencies. The output is print-friendly.`,
 	Example: `  # Generate report to stdout
   trustwatch report > report.html
 
@@ -44,7 +48,10 @@ dependencies. The output is print-friendly.`,
   trustwatch report --context prod --output-file prod-report.html
 
   # Include cluster name in report header
-  trustwatch report --cluster-name production --output-file report.html`,
+  trustwatch report --cluster-name production --output-file report.html
+
+  # Add rotation SLO analytics from the history database
+  trustwatch report --history-db /data/trustwatch.db --output-file report.html`,
 	RunE: runReport,
 }
 
@@ -53,7 +60,9 @@ func init() {
 	reportCmd.Flags().String("config", "", "Path to config file")
 	reportCmd.Flags().String("kubeconfig", "", "Path to kubeconfig")
 	reportCmd.Flags().String("context", "", "Kubernetes context to use")
-	reportCmd.Flags().StringSlice("namespace", nil, "Namespaces to scan (empty = all)")
+	reportCmd.Flags().StringSlice("namespace", nil, "Namespaces or glob patterns to scan (empty = all)")
+	reportCmd.Flags().StringSlice("exclude-namespace", nil, "Namespaces or glob patterns to skip")
+	reportCmd.Flags().String("namespace-selector", "", "Label selector for namespaces to scan (e.g. trustwatch.dev/scan!=false)")
 	reportCmd.Flags().Duration("warn-before", 0, "Warn threshold (default from config)")
 	reportCmd.Flags().Duration("crit-before", 0, "Critical threshold (default from config)")
 	reportCmd.Flags().Bool("tunnel", false, "Deploy a SOCKS5 relay pod to route probes through in-cluster DNS")
@@ -68,6 +77,7 @@ func init() {
 	reportCmd.Flags().String("cluster-name", "", "Name for this cluster in the report header")
 	reportCmd.Flags().Bool("ignore-managed", false, "Hide cert-manager managed expiry findings")
 	reportCmd.Flags().StringP("output-file", "o", "", "Write report to file (default: stdout)")
+	reportCmd.Flags().String("history-db", "", "SQLite path or postgres:// DSN for the history database (adds rotation SLO analytics)")
 }
 
 func runReport(cmd *cobra.Command, _ []string) error { //nolint:dupl,cyclop // intentional parallel to now.go/check.go; commands may diverge
@@ -92,9 +102,8 @@ func runReport(cmd *cobra.Command, _ []string) error { //nolint:dupl,cyclop // i
 	if critDur > 0 {
 		cfg.CritBefore = critDur
 	}
-	ns, _ := cmd.Flags().GetStringSlice("namespace") //nolint:errcheck // flag registered above
-	if len(ns) > 0 {
-		cfg.Namespaces = ns
+	if err := applyScopeFlags(cmd, cfg); err != nil {
+		return err
 	}
 
 	// Build Kubernetes client
@@ -186,59 +195,25 @@ func runReport(cmd *cobra.Command, _ []string) error { //nolint:dupl,cyclop // i
 	}
 	defer closeRelay()
 
-	// Derive API server host
-	apiServerTarget := apiServerFromHost(restCfg.Host)
-
-	// Resolve namespaces
-	nsCtx := context.Background()
-	allNS, err := discovery.ResolveNamespaces(nsCtx, clientset, cfg.Namespaces)
-	if err != nil {
-		return fmt.Errorf("resolving namespaces: %w", err)
-	}
-	secretNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "", "secrets")
-	ingressNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "networking.k8s.io", "ingresses")
-	svcNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "", "services")
-	gwNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "gateway.networking.k8s.io", "gateways")
-	certNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "cert-manager.io", "certificates")
-
-	// Build discoverers
-	var webhookOpts []func(*discovery.WebhookDiscoverer)
-	var apiSvcOpts []func(*discovery.APIServiceDiscoverer)
-	var annotOpts []func(*discovery.AnnotationDiscoverer)
-	var extOpts []func(*discovery.ExternalDiscoverer)
-	if tunnelProbeFn != nil {
-		webhookOpts = append(webhookOpts, discovery.WithWebhookProbeFn(tunnelProbeFn))
-		apiSvcOpts = append(apiSvcOpts, discovery.WithAPIServiceProbeFn(tunnelProbeFn))
-		annotOpts = append(annotOpts, discovery.WithAnnotationProbeFn(tunnelProbeFn))
-		extOpts = append(extOpts, discovery.WithExternalProbeFn(tunnelProbeFn))
-	}
-	annotOpts = append(annotOpts, discovery.WithAnnotationNamespaces(svcNS))
-
-	discoverers := []discovery.Discoverer{ //nolint:dupl // intentional parallel to now.go/check.go; commands may diverge
-		discovery.NewWebhookDiscoverer(clientset, webhookOpts...),
-		discovery.NewAPIServiceDiscoverer(aggClient, apiSvcOpts...),
-		discovery.NewAPIServerDiscoverer(apiServerTarget, discovery.WithProbeFn(restProbe(restCfg))),
-		discovery.NewSecretDiscoverer(clientset, discovery.WithSecretNamespaces(secretNS)),
-		discovery.NewIngressDiscoverer(clientset, discovery.WithIngressNamespaces(ingressNS)),
-		discovery.NewLinkerdDiscoverer(clientset),
-		discovery.NewIstioDiscoverer(clientset),
-		discovery.NewAnnotationDiscoverer(clientset, annotOpts...),
-		discovery.NewGatewayDiscoverer(gwClient, clientset, discovery.WithGatewayNamespaces(gwNS)),
-		discovery.NewCertManagerDiscoverer(dynClient, clientset, discovery.WithCertManagerNamespaces(certNS)),
-		discovery.NewCertManagerRenewalDiscoverer(dynClient, clientset, discovery.WithRenewalNamespaces(certNS)),
-	}
-	if len(cfg.External) > 0 {
-		discoverers = append(discoverers, discovery.NewExternalDiscoverer(cfg.External, extOpts...))
-	}
-
 	spiffeSocket, _ := cmd.Flags().GetString("spiffe-socket") //nolint:errcheck // flag registered above
-	if spiffeSocket == "" {
-		spiffeSocket = cfg.SPIFFESocket
-	}
 	if spiffeSocket != "" {
-		discoverers = append(discoverers, discovery.NewSPIFFEDiscoverer(spiffeSocket))
+		cfg.SPIFFESocket = spiffeSocket
+	}
+
+	// Build discoverers from config — probing discoverers get the tunnel probe function when --tunnel is set
+	discoverers, scope, err := discovery.Build(context.Background(), discovery.Clients{
+		Core:       clientset,
+		Aggregator: aggClient,
+		Gateway:    gwClient,
+		Dynamic:    dynClient,
+	}, cfg, discovery.BuildOptions{
+		APIServerTarget:  apiServerFromHost(restCfg.Host),
+		APIServerProbeFn: restProbe(restCfg),
+		ProbeFn:          tunnelProbeFn,
+	})
+	if err != nil {
+		return err
 	}
-	discoverers = append(discoverers, discovery.CloudDiscoverers()...)
 
 	// Initialize tracing
 	otelEndpoint, _ := cmd.Flags().GetString("otel-endpoint") //nolint:errcheck // flag registered above
@@ -251,7 +226,13 @@ func runReport(cmd *cobra.Command, _ []string) error { //nolint:dupl,cyclop // i
 
 	// Run discovery
 	slog.Info("scanning discovery sources", "count", len(discoverers))
+	clusterName, _ := cmd.Flags().GetString("cluster-name") //nolint:errcheck // flag registered above
+	if clusterName == "" {
+		clusterName = cfg.ClusterName
+	}
 	var orchOpts []discovery.OrchestratorOption
+	orchOpts = append(orchOpts, discovery.WithMetadata(scanMetadata(clientset, scope, clusterName, kubeCtx, useTunnel)))
+	orchOpts = append(orchOpts, discovery.WithNamespaceOwnership(clientset))
 	if len(loadedPolicies) > 0 {
 		orchOpts = append(orchOpts, discovery.WithPolicies(loadedPolicies))
 	}
@@ -283,13 +264,22 @@ func runReport(cmd *cobra.Command, _ []string) error { //nolint:dupl,cyclop // i
 	}
 
 	// Get cluster name for the report header
-	clusterName, _ := cmd.Flags().GetString("cluster-name") //nolint:errcheck // flag registered above
-	if clusterName == "" {
-		clusterName = cfg.ClusterName
+
+	var reportOpts []report.Option
+	historyDB, _ := cmd.Flags().GetString("history-db") //nolint:errcheck // flag registered above
+	if historyDB == "" {
+		historyDB = cfg.HistoryDB
+	}
+	if historyDB != "" {
+		slo, sloErr := loadRotationSLO(historyDB, &cfg.Analytics)
+		if sloErr != nil {
+			return sloErr
+		}
+		reportOpts = append(reportOpts, report.WithRotationSLO(slo))
 	}
 
 	// Generate HTML report
-	html, err := report.Generate(snap, clusterName)
+	html, err := report.Generate(snap, clusterName, reportOpts...)
 	if err != nil {
 		return fmt.Errorf("generating report: %w", err)
 	}
@@ -307,3 +297,43 @@ func runReport(cmd *cobra.Command, _ []string) error { //nolint:dupl,cyclop // i
 
 	return nil
 }
+
+// loadRotationSLO computes rotation analytics from the history database for the report.
+func loadRotationSLO(dsn string, a *config.AnalyticsConfig) (*report.RotationSLO, error) {
+	hs, err := history.Open(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening history database: %w", err)
+	}
+	defer hs.Close() //nolint:errcheck // best-effort cleanup
+
+	rep, err := analytics.FromHistory(hs, analyticsOptions(a), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("computing rotation analytics: %w", err)
+	}
+	slo := &report.RotationSLO{
+		Since:        rep.Since,
+		Total:        rotationStats(&rep.Total),
+		NearMiss:     rep.NearMiss,
+		MinRemaining: rep.MinRemaining,
+	}
+	for i := range rep.Groups {
+		slo.Groups = append(slo.Groups, rotationStats(&rep.Groups[i]))
+	}
+	return slo, nil
+}
+
+func rotationStats(g *analytics.Group) report.RotationStats {
+	return report.RotationStats{
+		SLORatio:      g.SLORatio,
+		SuccessRatio:  g.SuccessRatio,
+		MeanRemaining: g.MeanRemaining,
+		Source:        string(g.Source),
+		Namespace:     g.Namespace,
+		Issuer:        g.Issuer,
+		MeanTimeOpen:  g.MeanTimeOpen,
+		Rotations:     g.Rotations,
+		Expired:       g.Expired,
+		NearMisses:    g.NearMisses,
+		OpenFindings:  g.OpenFindings,
+	}
+}
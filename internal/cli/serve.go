@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -23,10 +24,14 @@ import (
 	aggregatorclient "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset"
 	gatewayclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
 
+	"github.com/ppiankov/trustwatch/internal/analytics"
+	"github.com/ppiankov/trustwatch/internal/auth"
+	"github.com/ppiankov/trustwatch/internal/certreload"
 	"github.com/ppiankov/trustwatch/internal/config"
 	"github.com/ppiankov/trustwatch/internal/ct"
 	"github.com/ppiankov/trustwatch/internal/discovery"
 	"github.com/ppiankov/trustwatch/internal/drift"
+	"github.com/ppiankov/trustwatch/internal/events"
 	"github.com/ppiankov/trustwatch/internal/federation"
 	"github.com/ppiankov/trustwatch/internal/history"
 	"github.com/ppiankov/trustwatch/internal/metrics"
@@ -40,6 +45,7 @@ import (
 
 const (
 	shutdownTimeout   = 5 * time.Second
+	authSetupTimeout  = 30 * time.Second
 	readHeaderTimeout = 10 * time.Second
 	readTimeout       = 30 * time.Second
 	writeTimeout      = 60 * time.Second
@@ -58,7 +64,16 @@ Endpoints:
   /               Problems web UI (only expiring/failed trust surfaces)
   /metrics        Prometheus scrape endpoint
   /healthz        Liveness probe (returns 503 if scan is stale)
-  /api/v1/snapshot  JSON snapshot of all findings`,
+  /api/v1/snapshot  JSON snapshot of all findings
+
+With an auth section in the config, every route except /healthz and /readyz
+requires a static bearer token, a Kubernetes token allowed by RBAC, or an
+OIDC sign-in; auth.routes sets per-path access.
+
+With tlsCertFile and tlsKeyFile the endpoint is served over HTTPS. The files
+are re-read when they change, so certificates mounted from a cert-manager
+Secret rotate without a restart, and the serving certificate is reported as a
+finding. clientCAFile also accepts client certificates as an auth method.`,
 	Example: `  # Run with default config
   trustwatch serve
 
@@ -68,6 +83,10 @@ Endpoints:
   # Override listen address
   trustwatch serve --listen :9090
 
+  # Serve HTTPS with a mounted certificate and accept client certificates
+  trustwatch serve --tls-cert-file /etc/trustwatch-tls/tls.crt --tls-key-file /etc/trustwatch-tls/tls.key \
+    --client-ca-file /etc/trustwatch-tls/ca.crt
+
   # Run with JSON logging for log aggregation
   trustwatch serve --log-format json --log-level debug`,
 	RunE: runServe,
@@ -79,7 +98,7 @@ func init() {
 	serveCmd.Flags().String("listen", "", "Listen address (overrides config)")
 	serveCmd.Flags().String("kubeconfig", "", "Path to kubeconfig")
 	serveCmd.Flags().String("context", "", "Kubernetes context to use")
-	serveCmd.Flags().String("history-db", "", "Path to SQLite history database (enables /api/v1/history and /api/v1/trend)")
+	serveCmd.Flags().String("history-db", "", "SQLite path or postgres:// DSN for the history database (enables /api/v1/history and /api/v1/trend)")
 	serveCmd.Flags().String("spiffe-socket", "", "Path to SPIFFE workload API socket")
 	serveCmd.Flags().String("cluster-name", "", "Name for this cluster in federated views")
 	serveCmd.Flags().StringSlice("remote", nil, "Remote trustwatch URLs (name=url format)")
@@ -88,6 +107,10 @@ func init() {
 	serveCmd.Flags().StringSlice("ct-allowed-issuers", nil, "Expected CA issuers (others flagged as rogue)")
 	serveCmd.Flags().Bool("detect-drift", false, "Detect certificate changes between consecutive scans")
 	serveCmd.Flags().Duration("scan-timeout", 0, "Scan timeout (default: refresh interval minus 10s, min 30s)")
+	serveCmd.Flags().Bool("events", false, "Record Kubernetes Events on objects with expiring or invalid certificates")
+	serveCmd.Flags().String("tls-cert-file", "", "Serve HTTPS with this PEM certificate chain (overrides config; reloaded on change)")
+	serveCmd.Flags().String("tls-key-file", "", "Private key for --tls-cert-file (overrides config)")
+	serveCmd.Flags().String("client-ca-file", "", "Verify client certificates against this PEM bundle (overrides config)")
 }
 
 func runServe(cmd *cobra.Command, _ []string) error {
@@ -122,6 +145,32 @@ func runServe(cmd *cobra.Command, _ []string) error {
 		cfg.HistoryDB = historyDB
 	}
 
+	// Override serving certificate files from flags
+	tlsCertFile, _ := cmd.Flags().GetString("tls-cert-file") //nolint:errcheck // flag registered above
+	if tlsCertFile != "" {
+		cfg.TLSCertFile = tlsCertFile
+	}
+	tlsKeyFile, _ := cmd.Flags().GetString("tls-key-file") //nolint:errcheck // flag registered above
+	if tlsKeyFile != "" {
+		cfg.TLSKeyFile = tlsKeyFile
+	}
+	clientCAFile, _ := cmd.Flags().GetString("client-ca-file") //nolint:errcheck // flag registered above
+	if clientCAFile != "" {
+		cfg.ClientCAFile = clientCAFile
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Load the serving certificate; Run below picks up rotated files
+	var certs *certreload.Reloader
+	if cfg.ServesTLS() {
+		certs, err = certreload.New(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("loading serving certificate: %w", err)
+		}
+	}
+
 	// Open history store if configured
 	var histStore *history.Store
 	if cfg.HistoryDB != "" {
@@ -131,7 +180,7 @@ func runServe(cmd *cobra.Command, _ []string) error {
 			return fmt.Errorf("opening history database: %w", histErr)
 		}
 		defer histStore.Close() //nolint:errcheck // best-effort cleanup on shutdown
-		slog.Info("history storage enabled", "path", cfg.HistoryDB)
+		slog.Info("history storage enabled", "backend", histStore.Backend(), "dsn", history.Redact(cfg.HistoryDB))
 	}
 
 	// Build Kubernetes client
@@ -187,52 +236,28 @@ func runServe(cmd *cobra.Command, _ []string) error {
 		}
 	}
 
-	// Derive API server host from kubeconfig for local probing
-	apiServerTarget := apiServerFromHost(restCfg.Host)
-
-	// Resolve namespaces for namespace-scoped discoverers
-	nsCtx := context.Background()
-	allNS, err := discovery.ResolveNamespaces(nsCtx, clientset, cfg.Namespaces)
-	if err != nil {
-		return fmt.Errorf("resolving namespaces: %w", err)
-	}
-	secretNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "", "secrets")
-	ingressNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "networking.k8s.io", "ingresses")
-	svcNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "", "services")
-	gwNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "gateway.networking.k8s.io", "gateways")
-	certNS := discovery.FilterAccessible(nsCtx, clientset, allNS, "cert-manager.io", "certificates")
-	slog.Info("namespace access resolved", "total", len(allNS),
-		"secrets", len(secretNS), "ingresses", len(ingressNS),
-		"services", len(svcNS), "gateways", len(gwNS),
-		"certificates", len(certNS))
-
-	// Build discoverers
-	discoverers := []discovery.Discoverer{
-		discovery.NewWebhookDiscoverer(clientset),
-		discovery.NewAPIServiceDiscoverer(aggClient),
-		discovery.NewAPIServerDiscoverer(apiServerTarget, discovery.WithProbeFn(restProbe(restCfg))),
-		discovery.NewSecretDiscoverer(clientset, discovery.WithSecretNamespaces(secretNS)),
-		discovery.NewIngressDiscoverer(clientset, discovery.WithIngressNamespaces(ingressNS)),
-		discovery.NewLinkerdDiscoverer(clientset),
-		discovery.NewIstioDiscoverer(clientset),
-		discovery.NewAnnotationDiscoverer(clientset, discovery.WithAnnotationNamespaces(svcNS)),
-		discovery.NewGatewayDiscoverer(gwClient, clientset, discovery.WithGatewayNamespaces(gwNS)),
-		discovery.NewCertManagerDiscoverer(dynClient, clientset, discovery.WithCertManagerNamespaces(certNS)),
-		discovery.NewCertManagerRenewalDiscoverer(dynClient, clientset, discovery.WithRenewalNamespaces(certNS)),
-	}
-	if len(cfg.External) > 0 {
-		discoverers = append(discoverers, discovery.NewExternalDiscoverer(cfg.External))
-	}
-
 	spiffeSocket, _ := cmd.Flags().GetString("spiffe-socket") //nolint:errcheck // flag registered above
-	if spiffeSocket == "" {
-		spiffeSocket = cfg.SPIFFESocket
-	}
 	if spiffeSocket != "" {
-		discoverers = append(discoverers, discovery.NewSPIFFEDiscoverer(spiffeSocket))
+		cfg.SPIFFESocket = spiffeSocket
 	}
 
-	discoverers = append(discoverers, discovery.CloudDiscoverers()...)
+	// Build discoverers from config
+	buildOpts := discovery.BuildOptions{
+		APIServerTarget:  apiServerFromHost(restCfg.Host),
+		APIServerProbeFn: restProbe(restCfg),
+	}
+	if certs != nil {
+		buildOpts.ServingChain = certs.Chain
+	}
+	discoverers, scope, err := discovery.Build(context.Background(), discovery.Clients{
+		Core:       clientset,
+		Aggregator: aggClient,
+		Gateway:    gwClient,
+		Dynamic:    dynClient,
+	}, cfg, buildOpts)
+	if err != nil {
+		return err
+	}
 
 	// Initialize tracing
 	otelEndpoint, _ := cmd.Flags().GetString("otel-endpoint") //nolint:errcheck // flag registered above
@@ -247,7 +272,13 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	registry := prometheus.NewRegistry()
 	collector := metrics.NewCollector(registry)
 
+	clusterName, _ := cmd.Flags().GetString("cluster-name") //nolint:errcheck // flag registered above
+	if clusterName == "" {
+		clusterName = cfg.ClusterName
+	}
 	var orchOpts []discovery.OrchestratorOption
+	orchOpts = append(orchOpts, discovery.WithMetadata(scanMetadata(clientset, scope, clusterName, kubeCtx, false)))
+	orchOpts = append(orchOpts, discovery.WithNamespaceOwnership(clientset))
 	orchOpts = append(orchOpts, discovery.WithDiscoverTimer(collector.ObserveDiscovererDuration))
 	if len(loadedPolicies) > 0 {
 		orchOpts = append(orchOpts, discovery.WithPolicies(loadedPolicies))
@@ -270,13 +301,7 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	if len(ctDomains) > 0 {
 		orchOpts = append(orchOpts, discovery.WithCTCheck(ctDomains, ctIssuers, ct.NewClient()))
 	}
-	orch := discovery.NewOrchestrator(discoverers, cfg.WarnBefore, cfg.CritBefore, orchOpts...)
-
 	// Parse federation remotes
-	clusterName, _ := cmd.Flags().GetString("cluster-name") //nolint:errcheck // flag registered above
-	if clusterName == "" {
-		clusterName = cfg.ClusterName
-	}
 	remoteFlags, _ := cmd.Flags().GetStringSlice("remote") //nolint:errcheck // flag registered above
 	remoteSources := parseRemoteFlags(remoteFlags, cfg.Remotes)
 	if len(remoteSources) > 0 {
@@ -286,16 +311,50 @@ func runServe(cmd *cobra.Command, _ []string) error {
 		slog.Info("federation enabled", "cluster", clusterName, "remotes", len(remoteSources))
 	}
 
+	// Local findings are saved under clusterName, so observed intervals are
+	// read for that cluster only.
+	if histStore != nil {
+		orchOpts = append(orchOpts, discovery.WithRotationCadence(rotationCadence(histStore, clusterName, time.Hour)))
+	}
+	orch := discovery.NewOrchestrator(discoverers, cfg.WarnBefore, cfg.CritBefore, orchOpts...)
+
 	// Drift detection
 	detectDrift, _ := cmd.Flags().GetBool("detect-drift") //nolint:errcheck // flag registered above
 
-	// Notifications (nil if not configured)
-	notifier := notify.New(cfg.Notifications)
+	// Notifications (nil if not configured); queued in the history DB when enabled
+	notifyOpts := []notify.Option{notify.WithScanInterval(cfg.RefreshEvery)}
+	if histStore != nil {
+		notifyOpts = append(notifyOpts, notify.WithOutbox(histStore.Outbox()))
+	}
+	notifier := notify.New(cfg.Notifications, notifyOpts...)
+
+	// Kubernetes Events on affected objects
+	emitEvents, _ := cmd.Flags().GetBool("events") //nolint:errcheck // flag registered above
+	if emitEvents {
+		cfg.Events = true
+	}
+	var eventRecorder *events.Recorder
+	if cfg.Events {
+		eventRecorder = events.NewRecorder(clientset, cfg.WarnBefore)
+		defer eventRecorder.Shutdown()
+		slog.Info("kubernetes events enabled")
+	}
 
 	// Shared state: mutex-protected snapshot
 	var mu sync.RWMutex
 	var currentSnap store.Snapshot
 	var previousSnap store.Snapshot
+	var analyticsReport atomic.Pointer[analytics.Report] // refreshed by history maintenance
+
+	// Resume from the last persisted scan so a restart does not report every
+	// open finding as new, and drift and resolves carry across it.
+	if histStore != nil {
+		if latest, latestErr := histStore.GetLatest(clusterName); latestErr != nil {
+			slog.Warn("loading last snapshot from history", "err", latestErr)
+		} else if latest != nil {
+			currentSnap = *latest
+		}
+	}
 
 	getSnapshot := func() store.Snapshot {
 		mu.RLock()
@@ -313,15 +372,44 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	mux.HandleFunc("/healthz", web.HealthzHandler(getSnapshot, 2*cfg.RefreshEvery))
 	mux.HandleFunc("/readyz", web.ReadyzHandler(getSnapshot, 2*cfg.RefreshEvery))
 	mux.HandleFunc("/api/v1/snapshot", web.SnapshotHandler(getSnapshot))
+	mux.HandleFunc("/api/v1/calendar", web.CalendarHandler(getSnapshot))
 	if histStore != nil {
 		mux.HandleFunc("/api/v1/history", web.HistoryHandler(histStore))
 		mux.HandleFunc("/api/v1/trend", web.TrendHandler(histStore))
+		mux.HandleFunc("/api/v1/diff", web.DiffHandler(histStore, clusterName))
+		mux.HandleFunc("/api/v1/query", web.QueryHandler(histStore))
+		mux.HandleFunc("/api/v1/query/counts", web.QueryCountsHandler(histStore))
+		mux.HandleFunc("/api/v1/lineage", web.LineageHandler(histStore))
+		mux.HandleFunc("/api/v1/analytics", web.AnalyticsHandler(histStore, analyticsOptions(&cfg.Analytics), analyticsReport.Load))
+	}
+	if notifier != nil {
+		mux.HandleFunc("/api/v1/notifications", web.NotificationsHandler(notifier.Outbox()))
 	}
 	mux.Handle(cfg.MetricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
 
+	// Authentication and per-route authorization
+	var handler http.Handler = mux
+	if cfg.Auth.Enabled() || cfg.ClientCAFile != "" {
+		authOpts := []auth.Option{auth.WithKubernetes(clientset)}
+		if cfg.ClientCAFile != "" {
+			authOpts = append(authOpts, auth.WithClientCertificates())
+		}
+		authCtx, authCancel := context.WithTimeout(context.Background(), authSetupTimeout)
+		authMW, authErr := auth.New(authCtx, &cfg.Auth, authOpts...)
+		authCancel()
+		if authErr != nil {
+			return fmt.Errorf("configuring auth: %w", authErr)
+		}
+		handler = authMW.Handler(mux)
+		slog.Info("auth enabled", "tokens", len(cfg.Auth.Tokens), "kubernetes", cfg.Auth.Kubernetes.Enabled,
+			"oidc", cfg.Auth.OIDC != nil, "clientCerts", cfg.ClientCAFile != "", "routes", len(cfg.Auth.Routes))
+	} else {
+		slog.Warn("auth disabled: the API and UI are open to anyone who can reach " + cfg.ListenAddr)
+	}
+
 	srv := &http.Server{
 		Addr:              cfg.ListenAddr,
-		Handler:           mux,
+		Handler:           handler,
 		ReadHeaderTimeout: readHeaderTimeout,
 		ReadTimeout:       readTimeout,
 		WriteTimeout:      writeTimeout,
@@ -332,6 +420,11 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	if certs != nil {
+		srv.TLSConfig = certs.TLSConfig(cfg.ClientAuthType())
+		go certs.Run(ctx, certreload.DefaultInterval)
+	}
+
 	// Compute scan timeout
 	scanTimeout, _ := cmd.Flags().GetDuration("scan-timeout") //nolint:errcheck // flag registered above
 	if scanTimeout <= 0 {
@@ -341,13 +434,44 @@ func runServe(cmd *cobra.Command, _ []string) error {
 		}
 	}
 
+	// Replicas sharing a PostgreSQL history elect one leader to scan, save
+	// and notify; the others serve the snapshots it persists.
+	lead := func(context.Context) bool { return true }
+	if histStore != nil {
+		lead = historyLeader(histStore)
+	}
+
 	// Background scan loop
 	scan := func() {
+		if !lead(ctx) {
+			latest, latestErr := histStore.GetLatest(clusterName)
+			if latestErr != nil {
+				slog.Warn("loading leader snapshot from history", "err", latestErr)
+				return
+			}
+			if latest == nil {
+				return
+			}
+			mu.Lock()
+			if latest.At.After(currentSnap.At) {
+				previousSnap = currentSnap
+				currentSnap = *latest
+			}
+			mu.Unlock()
+			collector.Update(*latest, 0)
+			return
+		}
+
 		start := time.Now()
 		scanCtx, scanCancel := context.WithTimeout(ctx, scanTimeout)
 		defer scanCancel()
 		snap := orch.Run(scanCtx)
 
+		// Record events before federation so remote findings are never attributed to local objects
+		if eventRecorder != nil {
+			eventRecorder.Emit(snap.Findings)
+		}
+
 		// Detect certificate drift vs previous scan
 		if detectDrift {
 			mu.RLock()
@@ -414,6 +538,16 @@ func runServe(cmd *cobra.Command, _ []string) error {
 			"duration", duration.Round(time.Millisecond))
 	}
 
+	// Retry failed notifications, including any left queued by a previous run
+	if notifier != nil {
+		go notifier.Run(ctx)
+	}
+
+	// Apply history retention and export database size metrics
+	if histStore != nil {
+		go runHistoryMaintenance(ctx, histStore, lead, &cfg.HistoryRetention, &cfg.Analytics, collector, &analyticsReport)
+	}
+
 	// Run initial scan
 	scan()
 
@@ -439,10 +573,38 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	}()
 
 	// Start HTTP server
-	srvErr := make(chan error, 1)
+	srvErr := make(chan error, 2)
+	var probeSrv *http.Server
+	if cfg.ProbeAddr != "" {
+		// Plain HTTP health endpoints for kubelet probes, which cannot present
+		// the client certificate clientAuth: require demands on the main port.
+		probeMux := http.NewServeMux()
+		probeMux.HandleFunc("/healthz", web.HealthzHandler(getSnapshot, 2*cfg.RefreshEvery))
+		probeMux.HandleFunc("/readyz", web.ReadyzHandler(getSnapshot, 2*cfg.RefreshEvery))
+		probeSrv = &http.Server{
+			Addr:              cfg.ProbeAddr,
+			Handler:           probeMux,
+			ReadHeaderTimeout: readHeaderTimeout,
+			ReadTimeout:       readTimeout,
+			WriteTimeout:      writeTimeout,
+			IdleTimeout:       idleTimeout,
+		}
+		go func() {
+			slog.Info("trustwatch serve probes listening", "addr", cfg.ProbeAddr)
+			if err := probeSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				srvErr <- fmt.Errorf("probe server error: %w", err)
+			}
+		}()
+	}
 	go func() {
-		slog.Info("trustwatch serve listening", "version", version, "addr", cfg.ListenAddr)
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Info("trustwatch serve listening", "version", version, "addr", cfg.ListenAddr, "tls", certs != nil)
+		var err error
+		if certs != nil {
+			err = srv.ListenAndServeTLS("", "") // certificates come from TLSConfig
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			srvErr <- fmt.Errorf("HTTP server error: %w", err)
 		}
 	}()
@@ -458,6 +620,11 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
+	if probeSrv != nil {
+		if err := probeSrv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("probe server shutdown: %w", err)
+		}
+	}
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		return fmt.Errorf("server shutdown: %w", err)
 	}
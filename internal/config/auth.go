@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Route access levels.
+const (
+	AccessPublic        = "public"
+	AccessAuthenticated = "authenticated"
+)
+
+// AuthConfig protects the serve HTTP API and UI. Authentication is enabled
+// when static tokens, Kubernetes, or OIDC is configured; every route then
+// requires an authenticated caller unless a route says otherwise.
+type AuthConfig struct {
+	OIDC       *OIDCConfig          `yaml:"oidc"`
+	Tokens     []TokenConfig        `yaml:"tokens"`
+	Routes     []AuthRoute          `yaml:"routes"` // most specific path wins
+	Kubernetes KubernetesAuthConfig `yaml:"kubernetes"`
+}
+
+// TokenConfig is a static bearer token, e.g. for a federation hub or Grafana.
+type TokenConfig struct {
+	Name   string   `yaml:"name"`  // identity reported for callers presenting the token
+	Token  string   `yaml:"token"` // accepts env: and file: references
+	Groups []string `yaml:"groups"`
+}
+
+// KubernetesAuthConfig authenticates bearer tokens with a TokenReview and
+// authorizes the callers with a SubjectAccessReview on the request path, so
+// access follows cluster RBAC for non-resource URLs.
+type KubernetesAuthConfig struct {
+	Audiences []string      `yaml:"audiences"` // TokenReview audiences; empty uses the API server's
+	CacheTTL  time.Duration `yaml:"cacheTTL"`  // how long review results are reused (default 1m)
+	Enabled   bool          `yaml:"enabled"`
+}
+
+// OIDCConfig signs UI users in with an OpenID Connect provider. API callers
+// may present an ID token from the same provider as a bearer token.
+type OIDCConfig struct {
+	IssuerURL     string `yaml:"issuerURL"`
+	ClientID      string `yaml:"clientID"`
+	ClientSecret  string `yaml:"clientSecret"` // accepts env: and file: references
+	RedirectURL   string `yaml:"redirectURL"`  // external URL of /auth/callback
+	UsernameClaim string `yaml:"usernameClaim"`
+	GroupsClaim   string `yaml:"groupsClaim"`
+	// CookieSecret signs session cookies. Set it when several replicas serve
+	// the UI; otherwise a random key is generated at startup.
+	CookieSecret string        `yaml:"cookieSecret"`
+	Scopes       []string      `yaml:"scopes"`
+	SessionTTL   time.Duration `yaml:"sessionTTL"`
+}
+
+// AuthRoute sets who may call a path. A path ending in "/" matches every path
+// below it. Authenticated routes may be narrowed to users and groups.
+type AuthRoute struct {
+	Path   string   `yaml:"path"`
+	Access string   `yaml:"access"` // "public" or "authenticated" (default)
+	Users  []string `yaml:"users"`
+	Groups []string `yaml:"groups"`
+}
+
+// OIDC defaults.
+const (
+	DefaultUsernameClaim = "email"
+	DefaultGroupsClaim   = "groups"
+	DefaultSessionTTL    = 12 * time.Hour
+)
+
+// Enabled reports whether any authenticator is configured.
+func (a *AuthConfig) Enabled() bool {
+	return len(a.Tokens) > 0 || a.Kubernetes.Enabled || a.OIDC != nil
+}
+
+// resolveSecrets replaces secret references in tokens and OIDC secrets with their values.
+func (a *AuthConfig) resolveSecrets() error {
+	for i := range a.Tokens {
+		v, err := ResolveSecret(a.Tokens[i].Token)
+		if err != nil {
+			return fmt.Errorf("auth.tokens[%s].token: %w", a.Tokens[i].Name, err)
+		}
+		a.Tokens[i].Token = v
+	}
+	if o := a.OIDC; o != nil {
+		names := []string{"clientSecret", "cookieSecret"}
+		for i, p := range []*string{&o.ClientSecret, &o.CookieSecret} {
+			v, err := ResolveSecret(*p)
+			if err != nil {
+				return fmt.Errorf("auth.oidc.%s: %w", names[i], err)
+			}
+			*p = v
+		}
+	}
+	return nil
+}
+
+// validate checks tokens, the OIDC client, and route policies.
+func (a *AuthConfig) validate() error {
+	names := make(map[string]bool, len(a.Tokens))
+	for i, t := range a.Tokens {
+		if t.Name == "" {
+			return fmt.Errorf("auth.tokens[%d]: name is required", i)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("auth.tokens[%s]: duplicate name", t.Name)
+		}
+		names[t.Name] = true
+		if len(t.Token) < 16 {
+			return fmt.Errorf("auth.tokens[%s]: token must be at least 16 characters", t.Name)
+		}
+	}
+	if a.Kubernetes.CacheTTL < 0 {
+		return fmt.Errorf("auth.kubernetes.cacheTTL must not be negative, got %s", a.Kubernetes.CacheTTL)
+	}
+	if err := a.OIDC.validate(); err != nil {
+		return err
+	}
+
+	paths := make(map[string]bool, len(a.Routes))
+	for _, r := range a.Routes {
+		if !strings.HasPrefix(r.Path, "/") {
+			return fmt.Errorf("auth.routes: path %q must start with /", r.Path)
+		}
+		if paths[r.Path] {
+			return fmt.Errorf("auth.routes[%s]: duplicate path", r.Path)
+		}
+		paths[r.Path] = true
+		switch r.Access {
+		case "", AccessAuthenticated:
+		case AccessPublic:
+			if len(r.Users) > 0 || len(r.Groups) > 0 {
+				return fmt.Errorf("auth.routes[%s]: users and groups require authenticated access", r.Path)
+			}
+		default:
+			return fmt.Errorf("auth.routes[%s]: access must be public or authenticated, got %q", r.Path, r.Access)
+		}
+	}
+	return nil
+}
+
+// validate checks that the OIDC client is complete. A nil config is valid.
+func (o *OIDCConfig) validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.IssuerURL == "" || o.ClientID == "" || o.RedirectURL == "" {
+		return fmt.Errorf("auth.oidc: issuerURL, clientID, and redirectURL are required")
+	}
+	names := []string{"issuerURL", "redirectURL"}
+	for i, raw := range []string{o.IssuerURL, o.RedirectURL} {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("auth.oidc.%s must be an absolute http(s) URL, got %q", names[i], raw)
+		}
+	}
+	if o.CookieSecret != "" && len(o.CookieSecret) < 32 {
+		return fmt.Errorf("auth.oidc.cookieSecret must be at least 32 characters")
+	}
+	if o.SessionTTL < 0 {
+		return fmt.Errorf("auth.oidc.sessionTTL must not be negative, got %s", o.SessionTTL)
+	}
+	return nil
+}
@@ -4,11 +4,43 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
+	"sort"
 	"time"
 
 	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
+// knownDiscoverers lists the discoverer names accepted in the discovery section.
+// Values report whether the discoverer is namespace-scoped.
+var knownDiscoverers = map[string]bool{
+	"webhooks":             false,
+	"apiservices":          false,
+	"apiserver":            false,
+	"secrets":              true,
+	"ingress":              true,
+	"linkerd":              false,
+	"istio":                false,
+	"annotations":          true,
+	"gateway":              true,
+	"certmanager":          true,
+	"certmanager.renewal":  true,
+	"externals":            false,
+	"spiffe":               false,
+	"serving":              false,
+	"cloud.aws.acm":        false,
+	"cloud.azure.keyvault": false,
+	"cloud.gcp.cert":       false,
+}
+
+// labelSelectorDiscoverers lists discoverers that apply a resource label selector to their list calls.
+var labelSelectorDiscoverers = map[string]bool{
+	"secrets":     true,
+	"ingress":     true,
+	"annotations": true,
+}
+
 // ExternalTarget is an explicit TLS endpoint to probe.
 type ExternalTarget struct {
 	URL string `yaml:"url"` // https://host:port or tcp://host:port?sni=name
@@ -16,44 +48,161 @@ type ExternalTarget struct {
 
 // WebhookConfig describes a notification webhook endpoint.
 type WebhookConfig struct {
-	URL          string `yaml:"url"`
-	Type         string `yaml:"type"`         // "slack", "generic", "pagerduty", or "grafana"
-	RoutingKey   string `yaml:"routingKey"`   // PagerDuty Events API v2 routing key
-	APIKey       string `yaml:"apiKey"`       // Grafana API key (Bearer token)
-	DashboardUID string `yaml:"dashboardUID"` // Grafana dashboard UID (optional)
+	Headers      map[string]string `yaml:"headers"` // extra request headers, applied at send time
+	SMTP         *SMTPConfig       `yaml:"smtp"`    // settings for the smtp type
+	Ticket       *TicketConfig     `yaml:"ticket"`  // settings for the ticket type
+	TLS          *ClientTLSConfig  `yaml:"tls"`     // client certificate and CA for HTTP delivery
+	Name         string            `yaml:"name"`    // optional; used by "notify test --webhook"
+	URL          string            `yaml:"url"`
+	Type         string            `yaml:"type"`         // "slack", "generic", "pagerduty", "grafana", "alertmanager", "smtp", or "ticket"
+	RoutingKey   string            `yaml:"routingKey"`   // PagerDuty Events API v2 routing key
+	APIKey       string            `yaml:"apiKey"`       // Grafana API key (Bearer token)
+	DashboardUID string            `yaml:"dashboardUID"` // Grafana dashboard UID (optional)
+	Template     string            `yaml:"template"`     // text/template request body (generic and slack only)
+	Method       string            `yaml:"method"`       // HTTP method for templated webhooks (default POST)
+	ContentType  string            `yaml:"contentType"`  // Content-Type for templated webhooks (default application/json)
+	// SigningSecret enables an HMAC-SHA256 signature header (generic and templated webhooks only).
+	SigningSecret string `yaml:"signingSecret"`
+}
+
+// ClientTLSConfig configures the TLS client used to deliver to a webhook or to
+// fetch from a federation remote.
+type ClientTLSConfig struct {
+	CAFile     string `yaml:"caFile"`   // PEM bundle trusted in addition to the system roots
+	CertFile   string `yaml:"certFile"` // client certificate for mTLS
+	KeyFile    string `yaml:"keyFile"`
+	ServerName string `yaml:"serverName"` // overrides the name verified against the server certificate
+}
+
+// SMTPConfig describes an email notification channel.
+type SMTPConfig struct {
+	Host     string   `yaml:"host"`
+	From     string   `yaml:"from"`
+	Username string   `yaml:"username"` // enables PLAIN auth when set
+	Password string   `yaml:"password"`
+	StartTLS string   `yaml:"startTLS"` // "required" (default), "opportunistic", or "disabled"
+	Digest   string   `yaml:"digest"`   // "daily" or "weekly"; empty sends each new finding immediately
+	To       []string `yaml:"to"`
+	Port     int      `yaml:"port"` // default 587
+}
+
+// TicketConfig describes an issue tracker that receives tickets for long-lived findings.
+type TicketConfig struct {
+	Provider        string        `yaml:"provider"`        // "jira" or "github"
+	Repo            string        `yaml:"repo"`            // GitHub owner/name
+	Project         string        `yaml:"project"`         // Jira project key
+	IssueType       string        `yaml:"issueType"`       // Jira issue type (default Task)
+	CloseTransition string        `yaml:"closeTransition"` // Jira transition used to close; default is the first to a done status
+	Username        string        `yaml:"username"`        // Jira account; enables basic auth with token
+	Token           string        `yaml:"token"`           // GitHub token, Jira API token, or Jira PAT; applied at send time
+	Labels          []string      `yaml:"labels"`
+	OpenAfter       time.Duration `yaml:"openAfter"` // how long a finding stays open before a ticket is filed (default 168h)
 }
 
 // NotificationConfig controls how notifications are sent.
 type NotificationConfig struct {
-	Webhooks   []WebhookConfig `yaml:"webhooks"`
-	Severities []string        `yaml:"severities"`
-	Cooldown   time.Duration   `yaml:"cooldown"`
-	Enabled    bool            `yaml:"enabled"`
+	Webhooks     []WebhookConfig `yaml:"webhooks"`
+	Routes       []RouteConfig   `yaml:"routes"`    // first match decides a finding's webhooks
+	Fallback     []string        `yaml:"fallback"`  // webhooks for unowned findings and findings that match no route
+	Reminders    []string        `yaml:"reminders"` // expiry milestones, e.g. ["30d", "7d", "1d", "expired"]
+	Severities   []string        `yaml:"severities"`
+	Cooldown     time.Duration   `yaml:"cooldown"`
+	RetryBackoff time.Duration   `yaml:"retryBackoff"` // delay before the first retry; doubles per attempt
+	MaxAttempts  int             `yaml:"maxAttempts"`  // delivery attempts before a message is dead-lettered
+	Enabled      bool            `yaml:"enabled"`
+}
+
+// RouteConfig sends findings that match to the named webhooks.
+type RouteConfig struct {
+	Match    RouteMatch `yaml:"match"`
+	Webhooks []string   `yaml:"webhooks"`
+	Continue bool       `yaml:"continue"` // keep evaluating later routes after a match
+}
+
+// RouteMatch selects findings for a route. A finding matches when every
+// non-empty list contains its value; namespaces and owners accept glob patterns.
+type RouteMatch struct {
+	Namespaces   []string `yaml:"namespaces"`
+	Sources      []string `yaml:"sources"`
+	Severities   []string `yaml:"severities"`
+	Clusters     []string `yaml:"clusters"`
+	FindingTypes []string `yaml:"findingTypes"`
+	Owners       []string `yaml:"owners"`
+}
+
+// DiscovererConfig holds per-discoverer settings from the discovery section.
+type DiscovererConfig struct {
+	Enabled           *bool         `yaml:"enabled"`           // nil means enabled
+	LabelSelector     string        `yaml:"labelSelector"`     // resource label selector (secrets, ingress, annotations)
+	Namespaces        []string      `yaml:"namespaces"`        // narrows the global namespace list
+	ExcludeNamespaces []string      `yaml:"excludeNamespaces"` // namespaces to skip
+	StaleDuration     time.Duration `yaml:"staleDuration"`     // certmanager.renewal: pending CertificateRequest threshold
+}
+
+// IsEnabled reports whether the discoverer should run. Discoverers are enabled unless set to false.
+func (d DiscovererConfig) IsEnabled() bool {
+	return d.Enabled == nil || *d.Enabled
 }
 
 // RemoteCluster describes a remote trustwatch instance to federate.
 type RemoteCluster struct {
-	Name string `yaml:"name"` // cluster label
-	URL  string `yaml:"url"`  // base URL of remote trustwatch (e.g. http://trustwatch.staging:8080)
+	TLS   *ClientTLSConfig `yaml:"tls"`   // CA and client certificate for an HTTPS remote
+	Name  string           `yaml:"name"`  // cluster label
+	URL   string           `yaml:"url"`   // base URL of remote trustwatch (e.g. http://trustwatch.staging:8080)
+	Token string           `yaml:"token"` // bearer token for a remote with auth enabled; accepts env: and file: references
+}
+
+// RetentionConfig controls how long history snapshots are kept. Snapshots
+// younger than Full are kept at full resolution, then thinned to one per hour
+// until Hourly and one per day until Daily. A zero Full disables compaction,
+// a zero Hourly skips the hourly tier, and a zero Daily keeps daily snapshots forever.
+type RetentionConfig struct {
+	Vacuum       string        `yaml:"vacuum"` // "auto" (default), "always", or "never"
+	Full         time.Duration `yaml:"full"`
+	Hourly       time.Duration `yaml:"hourly"`
+	Daily        time.Duration `yaml:"daily"`
+	CompactEvery time.Duration `yaml:"compactEvery"` // how often serve compacts the history database
+}
+
+// AnalyticsConfig sets the rotation SLO reported by /api/v1/analytics, the
+// rotation metrics, and the report. A rotation meets the SLO when at least
+// MinRemaining of the certificate's lifetime is left; one with less than
+// NearMiss left is a near miss.
+type AnalyticsConfig struct {
+	Window       time.Duration `yaml:"window"` // history analyzed
+	NearMiss     time.Duration `yaml:"nearMiss"`
+	MinRemaining float64       `yaml:"minRemaining"` // fraction of lifetime, 0-1
 }
 
 // Config holds trustwatch runtime configuration.
 type Config struct {
-	ListenAddr       string             `yaml:"listenAddr"`
-	MetricsPath      string             `yaml:"metricsPath"`
-	HistoryDB        string             `yaml:"historyDB"`
-	SPIFFESocket     string             `yaml:"spiffeSocket"`
-	OTelEndpoint     string             `yaml:"otelEndpoint"`
-	ClusterName      string             `yaml:"clusterName"`
-	Namespaces       []string           `yaml:"namespaces"`
-	External         []ExternalTarget   `yaml:"external"`
-	Remotes          []RemoteCluster    `yaml:"remotes"`
-	CTDomains        []string           `yaml:"ctDomains"`
-	CTAllowedIssuers []string           `yaml:"ctAllowedIssuers"`
-	Notifications    NotificationConfig `yaml:"notifications"`
-	RefreshEvery     time.Duration      `yaml:"refreshEvery"`
-	WarnBefore       time.Duration      `yaml:"warnBefore"`
-	CritBefore       time.Duration      `yaml:"critBefore"`
+	Discovery         map[string]DiscovererConfig `yaml:"discovery"`
+	ListenAddr        string                      `yaml:"listenAddr"`
+	MetricsPath       string                      `yaml:"metricsPath"`
+	HistoryDB         string                      `yaml:"historyDB"`
+	SPIFFESocket      string                      `yaml:"spiffeSocket"`
+	OTelEndpoint      string                      `yaml:"otelEndpoint"`
+	ClusterName       string                      `yaml:"clusterName"`
+	NamespaceSelector string                      `yaml:"namespaceSelector"` // label selector on Namespace objects, e.g. trustwatch.dev/scan!=false
+	Namespaces        []string                    `yaml:"namespaces"`        // names or glob patterns; empty = all
+	ExcludeNamespaces []string                    `yaml:"excludeNamespaces"` // names or glob patterns to skip
+	External          []ExternalTarget            `yaml:"external"`
+	Remotes           []RemoteCluster             `yaml:"remotes"`
+	CTDomains         []string                    `yaml:"ctDomains"`
+	CTAllowedIssuers  []string                    `yaml:"ctAllowedIssuers"`
+	TLSCertFile       string                      `yaml:"tlsCertFile"` // serve HTTPS with this certificate; reloaded when the file changes
+	TLSKeyFile        string                      `yaml:"tlsKeyFile"`
+	ClientCAFile      string                      `yaml:"clientCAFile"` // verify client certificates against this PEM bundle
+	ClientAuth        string                      `yaml:"clientAuth"`   // "optional" (default) or "require"
+	ProbeAddr         string                      `yaml:"probeAddr"`    // plain HTTP listener for /healthz and /readyz only
+	Auth              AuthConfig                  `yaml:"auth"`
+	HistoryRetention  RetentionConfig             `yaml:"historyRetention"`
+	Notifications     NotificationConfig          `yaml:"notifications"`
+	Analytics         AnalyticsConfig             `yaml:"analytics"`
+	RefreshEvery      time.Duration               `yaml:"refreshEvery"`
+	WarnBefore        time.Duration               `yaml:"warnBefore"`
+	CritBefore        time.Duration               `yaml:"critBefore"`
+	Events            bool                        `yaml:"events"` // record Kubernetes Events on affected objects (serve)
 }
 
 // Defaults returns a Config with sane defaults.
@@ -65,6 +214,17 @@ func Defaults() *Config {
 		WarnBefore:   720 * time.Hour, // 30 days
 		CritBefore:   336 * time.Hour, // 14 days
 		Namespaces:   nil,
+		HistoryRetention: RetentionConfig{
+			Full:         7 * 24 * time.Hour,
+			Hourly:       30 * 24 * time.Hour,
+			CompactEvery: time.Hour,
+			Vacuum:       "auto",
+		},
+		Analytics: AnalyticsConfig{
+			Window:       90 * 24 * time.Hour,
+			NearMiss:     72 * time.Hour,
+			MinRemaining: 1.0 / 3,
+		},
 	}
 }
 
@@ -78,6 +238,20 @@ func Load(path string) (*Config, error) {
 	if err := yaml.Unmarshal(b, c); err != nil {
 		return nil, err
 	}
+	if c.HistoryDB, err = resolveEnvSecret(c.HistoryDB); err != nil {
+		return nil, fmt.Errorf("historyDB: %w", err)
+	}
+	if err := c.Notifications.resolveSecrets(); err != nil {
+		return nil, err
+	}
+	if err := c.Auth.resolveSecrets(); err != nil {
+		return nil, err
+	}
+	for i := range c.Remotes {
+		if c.Remotes[i].Token, err = ResolveSecret(c.Remotes[i].Token); err != nil {
+			return nil, fmt.Errorf("remotes[%s].token: %w", c.Remotes[i].Name, err)
+		}
+	}
 	if err := c.Validate(); err != nil {
 		return nil, fmt.Errorf("config validation: %w", err)
 	}
@@ -101,5 +275,132 @@ func (c *Config) Validate() error {
 	if c.ListenAddr == "" {
 		return fmt.Errorf("listenAddr must not be empty")
 	}
+	if err := c.ValidateNamespaceScope(); err != nil {
+		return err
+	}
+	if err := c.Notifications.validate(); err != nil {
+		return err
+	}
+	if err := c.HistoryRetention.validate(); err != nil {
+		return err
+	}
+	if err := c.Analytics.validate(); err != nil {
+		return err
+	}
+	if err := c.Auth.validate(); err != nil {
+		return err
+	}
+	if err := c.validateTLS(); err != nil {
+		return err
+	}
+	return c.validateDiscovery()
+}
+
+// validate checks that retention tiers are ordered and the vacuum mode is known.
+func (r *RetentionConfig) validate() error {
+	if r.Full < 0 || r.Hourly < 0 || r.Daily < 0 {
+		return fmt.Errorf("historyRetention: durations must not be negative")
+	}
+	if r.Hourly > 0 && r.Hourly <= r.Full {
+		return fmt.Errorf("historyRetention.hourly (%s) must be greater than full (%s)", r.Hourly, r.Full)
+	}
+	if r.Daily > 0 && r.Daily <= max(r.Full, r.Hourly) {
+		return fmt.Errorf("historyRetention.daily (%s) must be greater than full and hourly", r.Daily)
+	}
+	if r.CompactEvery != 0 && r.CompactEvery < time.Minute {
+		return fmt.Errorf("historyRetention.compactEvery must be at least 1m, got %s", r.CompactEvery)
+	}
+	switch r.Vacuum {
+	case "", "auto", "always", "never":
+	default:
+		return fmt.Errorf("historyRetention.vacuum must be auto, always, or never, got %q", r.Vacuum)
+	}
+	return nil
+}
+
+// validate checks the analytics window and SLO thresholds.
+func (a *AnalyticsConfig) validate() error {
+	if a.Window < 0 || a.NearMiss < 0 {
+		return fmt.Errorf("analytics: durations must not be negative")
+	}
+	if a.MinRemaining < 0 || a.MinRemaining >= 1 {
+		return fmt.Errorf("analytics.minRemaining must be between 0 and 1, got %g", a.MinRemaining)
+	}
+	return nil
+}
+
+// ValidateNamespaceScope checks the namespace include/exclude patterns and namespace selector.
+func (c *Config) ValidateNamespaceScope() error {
+	if c.NamespaceSelector != "" {
+		if _, err := labels.Parse(c.NamespaceSelector); err != nil {
+			return fmt.Errorf("invalid namespaceSelector: %w", err)
+		}
+	}
+	if err := validatePatterns("namespaces", c.Namespaces); err != nil {
+		return err
+	}
+	return validatePatterns("excludeNamespaces", c.ExcludeNamespaces)
+}
+
+// Discoverer returns the settings for the named discoverer, or the zero value if unset.
+func (c *Config) Discoverer(name string) DiscovererConfig {
+	return c.Discovery[name]
+}
+
+// validateDiscovery checks the per-discoverer settings.
+func (c *Config) validateDiscovery() error {
+	names := make([]string, 0, len(c.Discovery))
+	for name := range c.Discovery {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		d := c.Discovery[name]
+		namespaced, ok := knownDiscoverers[name]
+		if !ok {
+			return fmt.Errorf("discovery: unknown discoverer %q", name)
+		}
+		if !namespaced && (len(d.Namespaces) > 0 || len(d.ExcludeNamespaces) > 0) {
+			return fmt.Errorf("discovery.%s: namespaces and excludeNamespaces require a namespace-scoped discoverer", name)
+		}
+		if err := validatePatterns("discovery."+name+".namespaces", d.Namespaces); err != nil {
+			return err
+		}
+		if err := validatePatterns("discovery."+name+".excludeNamespaces", d.ExcludeNamespaces); err != nil {
+			return err
+		}
+		if d.LabelSelector != "" {
+			if !labelSelectorDiscoverers[name] {
+				return fmt.Errorf("discovery.%s: labelSelector is not supported", name)
+			}
+			if _, err := labels.Parse(d.LabelSelector); err != nil {
+				return fmt.Errorf("discovery.%s: invalid labelSelector: %w", name, err)
+			}
+		}
+		if d.StaleDuration != 0 {
+			if name != "certmanager.renewal" {
+				return fmt.Errorf("discovery.%s: staleDuration is only supported for certmanager.renewal", name)
+			}
+			if d.StaleDuration < 0 {
+				return fmt.Errorf("discovery.%s: staleDuration must be positive, got %s", name, d.StaleDuration)
+			}
+		}
+	}
+	return nil
+}
+
+// validatePatterns checks that namespace names and glob patterns are well-formed.
+// Patterns are checked with path.Match, the matcher discovery uses, since
+// namespace names are not file paths.
+func validatePatterns(field string, patterns []string) error {
+	for _, p := range patterns {
+		if p == "" {
+			return fmt.Errorf("%s: empty namespace pattern", field)
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("%s: invalid pattern %q: %w", field, p, err)
+		}
+	}
 	return nil
 }
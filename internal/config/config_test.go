@@ -1,7 +1,10 @@
 package config
 
 import (
+	"crypto/tls"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -123,6 +126,8 @@ notifications:
       type: generic
   severities: ["critical", "warn"]
   cooldown: "30m"
+  maxAttempts: 8
+  retryBackoff: "1m"
 `
 	f, err := os.CreateTemp("", "trustwatch-notify-*.yaml")
 	if err != nil {
@@ -157,6 +162,196 @@ notifications:
 	if c.Notifications.Cooldown != 30*time.Minute {
 		t.Errorf("expected cooldown 30m, got %v", c.Notifications.Cooldown)
 	}
+	if c.Notifications.MaxAttempts != 8 || c.Notifications.RetryBackoff != time.Minute {
+		t.Errorf("expected maxAttempts 8 and retryBackoff 1m, got %d and %v",
+			c.Notifications.MaxAttempts, c.Notifications.RetryBackoff)
+	}
+}
+
+func TestLoadSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("file-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TW_TEST_SIGNING", "env-secret")
+	t.Setenv("TW_TEST_HISTORY_DSN", "postgres://tw:pw@db/trustwatch")
+	path := filepath.Join(dir, "config.yaml")
+	content := `
+historyDB: "env:TW_TEST_HISTORY_DSN"
+notifications:
+  enabled: true
+  webhooks:
+    - url: "https://alerts.example.com/trustwatch"
+      signingSecret: "env:TW_TEST_SIGNING"
+      headers:
+        Authorization: "file:` + tokenFile + `"
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.HistoryDB != "postgres://tw:pw@db/trustwatch" {
+		t.Errorf("historyDB = %q, want the DSN from the environment", c.HistoryDB)
+	}
+	wh := c.Notifications.Webhooks[0]
+	if wh.SigningSecret != "env-secret" {
+		t.Errorf("signingSecret = %q, want env-secret", wh.SigningSecret)
+	}
+	if got := wh.Headers["Authorization"]; got != "file-secret" {
+		t.Errorf("Authorization header = %q, want file-secret", got)
+	}
+
+	t.Setenv("TW_TEST_SIGNING", "")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "signingSecret") {
+		t.Errorf("expected error naming signingSecret for an unset variable, got %v", err)
+	}
+}
+
+func TestLoadHistoryDBSQLiteURI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(`historyDB: "file:/data/trustwatch.db?_pragma=busy_timeout(5000)"`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.HistoryDB != "file:/data/trustwatch.db?_pragma=busy_timeout(5000)" {
+		t.Errorf("historyDB = %q, want the SQLite URI unchanged", c.HistoryDB)
+	}
+}
+
+func TestLoadAuthConfig(t *testing.T) {
+	t.Setenv("TW_TEST_HUB_TOKEN", "hub-token-from-env-0123")
+	t.Setenv("TW_TEST_REMOTE_TOKEN", "remote-token")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+remotes:
+  - name: staging
+    url: https://trustwatch.staging.example.com
+    token: "env:TW_TEST_REMOTE_TOKEN"
+auth:
+  tokens:
+    - name: hub
+      token: "env:TW_TEST_HUB_TOKEN"
+  kubernetes:
+    enabled: true
+  routes:
+    - path: /metrics
+      access: public
+    - path: /api/v1/
+      groups: [sre]
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Auth.Enabled() || c.Auth.Tokens[0].Token != "hub-token-from-env-0123" || len(c.Auth.Routes) != 2 {
+		t.Errorf("auth = %+v", c.Auth)
+	}
+	if c.Remotes[0].Token != "remote-token" {
+		t.Errorf("remote token = %q, want the value from the environment", c.Remotes[0].Token)
+	}
+	if Defaults().Auth.Enabled() {
+		t.Error("auth must be disabled by default")
+	}
+}
+
+func TestValidate_Auth(t *testing.T) {
+	token := TokenConfig{Name: "hub", Token: "0123456789abcdef"}
+	oidc := func(mod func(*OIDCConfig)) *OIDCConfig {
+		o := &OIDCConfig{IssuerURL: "https://accounts.example.com", ClientID: "trustwatch", RedirectURL: "https://tw.example.com/auth/callback"}
+		mod(o)
+		return o
+	}
+	for _, tt := range []struct {
+		name    string
+		errPart string
+		a       AuthConfig
+	}{
+		{name: "disabled", a: AuthConfig{}},
+		{name: "token", a: AuthConfig{Tokens: []TokenConfig{token}}},
+		{name: "short token", a: AuthConfig{Tokens: []TokenConfig{{Name: "hub", Token: "short"}}}, errPart: "at least 16"},
+		{name: "duplicate token", a: AuthConfig{Tokens: []TokenConfig{token, token}}, errPart: "duplicate name"},
+		{name: "unnamed token", a: AuthConfig{Tokens: []TokenConfig{{Token: token.Token}}}, errPart: "name is required"},
+		{name: "oidc", a: AuthConfig{OIDC: oidc(func(*OIDCConfig) {})}},
+		{name: "oidc without client", a: AuthConfig{OIDC: oidc(func(o *OIDCConfig) { o.ClientID = "" })}, errPart: "required"},
+		{name: "oidc relative redirect", a: AuthConfig{OIDC: oidc(func(o *OIDCConfig) { o.RedirectURL = "/auth/callback" })}, errPart: "redirectURL"},
+		{name: "oidc short cookie secret", a: AuthConfig{OIDC: oidc(func(o *OIDCConfig) { o.CookieSecret = "short" })}, errPart: "cookieSecret"},
+		{name: "relative route", a: AuthConfig{Routes: []AuthRoute{{Path: "metrics"}}}, errPart: "must start with /"},
+		{name: "unknown access", a: AuthConfig{Routes: []AuthRoute{{Path: "/metrics", Access: "open"}}}, errPart: "public or authenticated"},
+		{name: "public with groups", a: AuthConfig{Routes: []AuthRoute{{Path: "/metrics", Access: AccessPublic, Groups: []string{"sre"}}}}, errPart: "require authenticated"},
+		{name: "duplicate route", a: AuthConfig{Routes: []AuthRoute{{Path: "/metrics"}, {Path: "/metrics"}}}, errPart: "duplicate path"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Defaults()
+			c.Auth = tt.a
+			err := c.Validate()
+			if tt.errPart == "" && err != nil {
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+			if tt.errPart != "" && (err == nil || !strings.Contains(err.Error(), tt.errPart)) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+func TestValidate_TLS(t *testing.T) {
+	for _, tt := range []struct {
+		mod      func(*Config)
+		name     string
+		errPart  string
+		wantAuth tls.ClientAuthType
+	}{
+		{name: "plain HTTP", mod: func(*Config) {}, wantAuth: tls.NoClientCert},
+		{name: "serving cert", mod: func(c *Config) { c.TLSCertFile, c.TLSKeyFile = "tls.crt", "tls.key" }, wantAuth: tls.NoClientCert},
+		{name: "cert without key", mod: func(c *Config) { c.TLSCertFile = "tls.crt" }, errPart: "set together"},
+		{name: "client CA", mod: func(c *Config) {
+			c.TLSCertFile, c.TLSKeyFile, c.ClientCAFile = "tls.crt", "tls.key", "ca.crt"
+		}, wantAuth: tls.VerifyClientCertIfGiven},
+		{name: "client certs required", mod: func(c *Config) {
+			c.TLSCertFile, c.TLSKeyFile, c.ClientCAFile, c.ClientAuth = "tls.crt", "tls.key", "ca.crt", ClientAuthRequire
+			c.ProbeAddr = ":8081"
+		}, wantAuth: tls.RequireAndVerifyClientCert},
+		{name: "client certs required without probe listener", mod: func(c *Config) {
+			c.TLSCertFile, c.TLSKeyFile, c.ClientCAFile, c.ClientAuth = "tls.crt", "tls.key", "ca.crt", ClientAuthRequire
+		}, errPart: "needs probeAddr"},
+		{name: "probe listener on the serve address", mod: func(c *Config) { c.ProbeAddr = c.ListenAddr }, errPart: "must differ"},
+		{name: "client CA without serving cert", mod: func(c *Config) { c.ClientCAFile = "ca.crt" }, errPart: "requires tlsCertFile"},
+		{name: "clientAuth without client CA", mod: func(c *Config) {
+			c.TLSCertFile, c.TLSKeyFile, c.ClientAuth = "tls.crt", "tls.key", ClientAuthRequire
+		}, errPart: "needs clientCAFile"},
+		{name: "unknown clientAuth", mod: func(c *Config) {
+			c.TLSCertFile, c.TLSKeyFile, c.ClientCAFile, c.ClientAuth = "tls.crt", "tls.key", "ca.crt", "always"
+		}, errPart: "optional or require"},
+		{name: "remote client cert without key", mod: func(c *Config) {
+			c.Remotes = []RemoteCluster{{Name: "prod", URL: "https://tw.prod", TLS: &ClientTLSConfig{CertFile: "c.pem"}}}
+		}, errPart: "remotes[prod]"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Defaults()
+			tt.mod(c)
+			err := c.Validate()
+			if tt.errPart == "" && err != nil {
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+			if tt.errPart != "" && (err == nil || !strings.Contains(err.Error(), tt.errPart)) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.errPart)
+			}
+			if tt.errPart == "" && c.ClientAuthType() != tt.wantAuth {
+				t.Errorf("ClientAuthType() = %v, want %v", c.ClientAuthType(), tt.wantAuth)
+			}
+		})
+	}
 }
 
 func TestLoadInvalidConfig(t *testing.T) {
@@ -181,3 +376,312 @@ warnBefore: "720h"
 		t.Error("expected validation error when critBefore > warnBefore")
 	}
 }
+
+func TestLoadDiscoveryConfig(t *testing.T) {
+	content := `
+discovery:
+  linkerd:
+    enabled: false
+  secrets:
+    namespaces: ["app"]
+    excludeNamespaces: ["kube-system"]
+    labelSelector: "team=payments"
+  certmanager.renewal:
+    staleDuration: "2h"
+`
+	f, err := os.CreateTemp("", "trustwatch-discovery-*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, writeErr := f.WriteString(content); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	f.Close()
+
+	c, err := Load(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Discoverer("linkerd").IsEnabled() {
+		t.Error("expected linkerd to be disabled")
+	}
+	if !c.Discoverer("istio").IsEnabled() {
+		t.Error("expected unset discoverer to be enabled")
+	}
+	secrets := c.Discoverer("secrets")
+	if len(secrets.Namespaces) != 1 || secrets.Namespaces[0] != "app" {
+		t.Errorf("expected secrets namespaces [app], got %v", secrets.Namespaces)
+	}
+	if len(secrets.ExcludeNamespaces) != 1 || secrets.ExcludeNamespaces[0] != "kube-system" {
+		t.Errorf("expected secrets excludeNamespaces [kube-system], got %v", secrets.ExcludeNamespaces)
+	}
+	if secrets.LabelSelector != "team=payments" {
+		t.Errorf("expected label selector team=payments, got %q", secrets.LabelSelector)
+	}
+	if got := c.Discoverer("certmanager.renewal").StaleDuration; got != 2*time.Hour {
+		t.Errorf("expected staleDuration 2h, got %v", got)
+	}
+}
+
+func TestValidate_Discovery(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name    string
+		source  string
+		dc      DiscovererConfig
+		wantErr bool
+	}{
+		{name: "disabled known", source: "istio", dc: DiscovererConfig{Enabled: &disabled}},
+		{name: "unknown discoverer", source: "vault", wantErr: true},
+		{name: "namespaces on cluster-scoped", source: "webhooks", dc: DiscovererConfig{Namespaces: []string{"a"}}, wantErr: true},
+		{name: "exclude on namespaced", source: "ingress", dc: DiscovererConfig{ExcludeNamespaces: []string{"a"}}},
+		{name: "valid label selector", source: "annotations", dc: DiscovererConfig{LabelSelector: "tier in (web,api)"}},
+		{name: "invalid label selector", source: "secrets", dc: DiscovererConfig{LabelSelector: "a=b=c"}, wantErr: true},
+		{name: "unsupported label selector", source: "gateway", dc: DiscovererConfig{LabelSelector: "a=b"}, wantErr: true},
+		{name: "stale duration", source: "certmanager.renewal", dc: DiscovererConfig{StaleDuration: time.Hour}},
+		{name: "negative stale duration", source: "certmanager.renewal", dc: DiscovererConfig{StaleDuration: -time.Hour}, wantErr: true},
+		{name: "stale duration wrong source", source: "secrets", dc: DiscovererConfig{StaleDuration: time.Hour}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Defaults()
+			c.Discovery = map[string]DiscovererConfig{tt.source: tt.dc}
+			err := c.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected validation error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidate_NamespaceScope(t *testing.T) {
+	c := Defaults()
+	c.Namespaces = []string{"team-*"}
+	c.ExcludeNamespaces = []string{"kube-system", "ci-*"}
+	c.NamespaceSelector = "trustwatch.dev/scan!=false"
+	if err := c.Validate(); err != nil {
+		t.Errorf("expected valid scope, got %v", err)
+	}
+
+	c.NamespaceSelector = "a=b=c"
+	if err := c.Validate(); err == nil {
+		t.Error("expected error for invalid namespaceSelector")
+	}
+
+	c.NamespaceSelector = ""
+	c.ExcludeNamespaces = []string{"team-["}
+	if err := c.Validate(); err == nil {
+		t.Error("expected error for malformed exclude pattern")
+	}
+}
+
+func TestValidate_Analytics(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		a       AnalyticsConfig
+		wantErr bool
+	}{
+		{name: "defaults", a: Defaults().Analytics},
+		{name: "zero uses defaults", a: AnalyticsConfig{}},
+		{name: "half lifetime", a: AnalyticsConfig{MinRemaining: 0.5, NearMiss: 24 * time.Hour}},
+		{name: "whole lifetime", a: AnalyticsConfig{MinRemaining: 1}, wantErr: true},
+		{name: "negative window", a: AnalyticsConfig{Window: -time.Hour}, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Defaults()
+			c.Analytics = tt.a
+			err := c.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_HistoryRetention(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name    string
+		r       RetentionConfig
+		wantErr bool
+	}{
+		{name: "defaults", r: Defaults().HistoryRetention},
+		{name: "disabled", r: RetentionConfig{}},
+		{name: "daily only", r: RetentionConfig{Full: 3 * day, Daily: 90 * day}},
+		{name: "negative full", r: RetentionConfig{Full: -day}, wantErr: true},
+		{name: "hourly not after full", r: RetentionConfig{Full: 7 * day, Hourly: 7 * day}, wantErr: true},
+		{name: "daily not after hourly", r: RetentionConfig{Full: day, Hourly: 30 * day, Daily: 10 * day}, wantErr: true},
+		{name: "compactEvery too short", r: RetentionConfig{Full: day, CompactEvery: time.Second}, wantErr: true},
+		{name: "unknown vacuum", r: RetentionConfig{Full: day, Vacuum: "full"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Defaults()
+			c.HistoryRetention = tt.r
+			err := c.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected validation error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidate_Notifications(t *testing.T) {
+	tests := []struct {
+		name    string
+		wh      []WebhookConfig
+		wantErr bool
+	}{
+		{name: "plain generic", wh: []WebhookConfig{{URL: "https://x", Type: "generic"}}},
+		{name: "templated generic", wh: []WebhookConfig{{
+			Name: "teams", URL: "https://x", Template: `{"text": {{ json .Summary }}}`,
+			Method: "put", ContentType: "application/json", Headers: map[string]string{"X-Token": "t"},
+		}}},
+		{name: "headers on alertmanager", wh: []WebhookConfig{{URL: "https://x", Type: "alertmanager", Headers: map[string]string{"Authorization": "Basic x"}}}},
+		{name: "unknown type", wh: []WebhookConfig{{URL: "https://x", Type: "teams"}}, wantErr: true},
+		{name: "duplicate name", wh: []WebhookConfig{{Name: "a", URL: "https://x"}, {Name: "a", URL: "https://y"}}, wantErr: true},
+		{name: "template syntax error", wh: []WebhookConfig{{URL: "https://x", Template: "{{ .Summary "}}, wantErr: true},
+		{name: "unknown template function", wh: []WebhookConfig{{URL: "https://x", Template: "{{ yaml .Summary }}"}}, wantErr: true},
+		{name: "template on pagerduty", wh: []WebhookConfig{{Type: "pagerduty", Template: "{{ .Summary }}"}}, wantErr: true},
+		{name: "bad method", wh: []WebhookConfig{{URL: "https://x", Template: "x", Method: "GET"}}, wantErr: true},
+		{name: "empty header name", wh: []WebhookConfig{{URL: "https://x", Headers: map[string]string{" ": "v"}}}, wantErr: true},
+		{name: "smtp digest", wh: []WebhookConfig{{Type: "smtp", SMTP: &SMTPConfig{
+			Host: "mail.example.com", From: "trustwatch <tw@example.com>", To: []string{"ops@example.com"}, Digest: "weekly",
+		}}}},
+		{name: "smtp without section", wh: []WebhookConfig{{Type: "smtp"}}, wantErr: true},
+		{name: "smtp missing recipients", wh: []WebhookConfig{{Type: "smtp", SMTP: &SMTPConfig{Host: "m", From: "tw@example.com"}}}, wantErr: true},
+		{name: "smtp bad address", wh: []WebhookConfig{{Type: "smtp", SMTP: &SMTPConfig{Host: "m", From: "tw@example.com", To: []string{"not an address"}}}}, wantErr: true},
+		{name: "smtp bad startTLS", wh: []WebhookConfig{{Type: "smtp", SMTP: &SMTPConfig{Host: "m", From: "tw@example.com", To: []string{"o@example.com"}, StartTLS: "always"}}}, wantErr: true},
+		{name: "smtp bad digest", wh: []WebhookConfig{{Type: "smtp", SMTP: &SMTPConfig{Host: "m", From: "tw@example.com", To: []string{"o@example.com"}, Digest: "hourly"}}}, wantErr: true},
+		{name: "smtp section on slack", wh: []WebhookConfig{{URL: "https://x", Type: "slack", SMTP: &SMTPConfig{Host: "m"}}}, wantErr: true},
+		{name: "github ticket", wh: []WebhookConfig{{Type: "ticket", Ticket: &TicketConfig{Provider: "github", Repo: "acme/infra", Token: "t"}}}},
+		{name: "jira ticket", wh: []WebhookConfig{{URL: "https://acme.atlassian.net", Type: "ticket", Ticket: &TicketConfig{Provider: "jira", Project: "OPS", Username: "bot@acme.io", Token: "t"}}}},
+		{name: "ticket without section", wh: []WebhookConfig{{Type: "ticket"}}, wantErr: true},
+		{name: "ticket bad provider", wh: []WebhookConfig{{Type: "ticket", Ticket: &TicketConfig{Provider: "linear", Token: "t"}}}, wantErr: true},
+		{name: "github ticket bad repo", wh: []WebhookConfig{{Type: "ticket", Ticket: &TicketConfig{Provider: "github", Repo: "infra", Token: "t"}}}, wantErr: true},
+		{name: "jira ticket without url", wh: []WebhookConfig{{Type: "ticket", Ticket: &TicketConfig{Provider: "jira", Project: "OPS", Token: "t"}}}, wantErr: true},
+		{name: "signed generic", wh: []WebhookConfig{{URL: "https://x", SigningSecret: "s"}}},
+		{name: "signed templated slack", wh: []WebhookConfig{{URL: "https://x", Type: "slack", Template: "{{ .Summary }}", SigningSecret: "s"}}},
+		{name: "signed plain slack", wh: []WebhookConfig{{URL: "https://x", Type: "slack", SigningSecret: "s"}}, wantErr: true},
+		{name: "signed pagerduty", wh: []WebhookConfig{{Type: "pagerduty", RoutingKey: "k", SigningSecret: "s"}}, wantErr: true},
+		{name: "mtls generic", wh: []WebhookConfig{{URL: "https://x", TLS: &ClientTLSConfig{CAFile: "ca.pem", CertFile: "c.pem", KeyFile: "k.pem"}}}},
+		{name: "tls cert without key", wh: []WebhookConfig{{URL: "https://x", TLS: &ClientTLSConfig{CertFile: "c.pem"}}}, wantErr: true},
+		{name: "tls on smtp", wh: []WebhookConfig{{Type: "smtp", TLS: &ClientTLSConfig{CAFile: "ca.pem"}, SMTP: &SMTPConfig{Host: "m", From: "tw@example.com", To: []string{"o@example.com"}}}}, wantErr: true},
+		{name: "ticket without token", wh: []WebhookConfig{{Type: "ticket", Ticket: &TicketConfig{Provider: "github", Repo: "acme/infra"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Defaults()
+			c.Notifications.Webhooks = tt.wh
+			err := c.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected validation error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidate_NotificationRoutes(t *testing.T) {
+	webhooks := []WebhookConfig{{Name: "payments", URL: "https://x"}, {Name: "platform", URL: "https://y"}}
+	tests := []struct {
+		name     string
+		fallback []string
+		routes   []RouteConfig
+		webhooks []WebhookConfig
+		wantErr  bool
+	}{
+		{name: "valid", fallback: []string{"platform"}, routes: []RouteConfig{{
+			Match:    RouteMatch{Namespaces: []string{"payments-*"}, Owners: []string{"payments"}, Severities: []string{"critical"}},
+			Webhooks: []string{"payments"},
+		}}},
+		{name: "unknown webhook", routes: []RouteConfig{{Webhooks: []string{"teams"}}}, wantErr: true},
+		{name: "no webhooks", routes: []RouteConfig{{Match: RouteMatch{Owners: []string{"a"}}}}, wantErr: true},
+		{name: "bad severity", routes: []RouteConfig{{Match: RouteMatch{Severities: []string{"high"}}, Webhooks: []string{"payments"}}}, wantErr: true},
+		{name: "bad pattern", routes: []RouteConfig{{Match: RouteMatch{Namespaces: []string{"team-["}}, Webhooks: []string{"payments"}}}, wantErr: true},
+		{name: "unknown fallback", fallback: []string{"nobody"}, wantErr: true},
+		{name: "unnamed webhook", fallback: []string{"platform"}, webhooks: []WebhookConfig{{URL: "https://z"}}, wantErr: true},
+		{name: "unnamed alertmanager", fallback: []string{"platform"}, webhooks: []WebhookConfig{{URL: "https://am", Type: "alertmanager"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Defaults()
+			c.Notifications.Webhooks = append(append([]WebhookConfig(nil), webhooks...), tt.webhooks...)
+			c.Notifications.Routes = tt.routes
+			c.Notifications.Fallback = tt.fallback
+			err := c.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("expected validation error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestWebhookConfig_ID(t *testing.T) {
+	named := WebhookConfig{Name: "payments", URL: "https://hooks.slack.com/services/T/B/secret"}
+	if got := named.ID(); got != "payments" {
+		t.Errorf("named ID = %q, want payments", got)
+	}
+	unnamed := WebhookConfig{Type: "slack", URL: "https://hooks.slack.com/services/T/B/secret"}
+	id := unnamed.ID()
+	if strings.Contains(id, "secret") || !strings.HasPrefix(id, "slack-") {
+		t.Errorf("unnamed ID = %q, want a slack- prefixed hash without the URL", id)
+	}
+	other := WebhookConfig{Type: "slack", URL: "https://hooks.slack.com/services/T/B/other"}
+	if other.ID() == id || unnamed.ID() != id {
+		t.Errorf("IDs must be stable per URL and differ between URLs: %q, %q", id, other.ID())
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	for in, want := range map[string]time.Duration{"90d": 90 * 24 * time.Hour, "36h": 36 * time.Hour} {
+		if got, err := ParseDays(in); err != nil || got != want {
+			t.Errorf("ParseDays(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"0d", "-7d", "expired", "1w", ""} {
+		if _, err := ParseDays(in); err == nil {
+			t.Errorf("ParseDays(%q): expected error", in)
+		}
+	}
+}
+
+func TestParseMilestone(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "30d", want: 30 * 24 * time.Hour},
+		{in: "12h", want: 12 * time.Hour},
+		{in: "expired"},
+		{in: "0d", wantErr: true},
+		{in: "-1d", wantErr: true},
+		{in: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMilestone(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMilestone(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	c := Defaults()
+	c.Notifications.Reminders = []string{"7d", "168h"}
+	if err := c.Validate(); err == nil {
+		t.Error("expected duplicate milestone error")
+	}
+}
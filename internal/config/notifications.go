@@ -0,0 +1,357 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/mail"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// webhookTypes lists the supported notification webhook types. An empty type is generic.
+var webhookTypes = map[string]bool{
+	"": true, "generic": true, "slack": true, "pagerduty": true, "grafana": true, "alertmanager": true, "smtp": true, "ticket": true,
+}
+
+// SMTP STARTTLS modes and digest periods.
+const (
+	StartTLSRequired      = "required"
+	StartTLSOpportunistic = "opportunistic"
+	StartTLSDisabled      = "disabled"
+
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+
+	TicketJira   = "jira"
+	TicketGitHub = "github"
+
+	// DefaultGitHubAPI is the API base URL for ticket webhooks without a url.
+	DefaultGitHubAPI = "https://api.github.com"
+	// DefaultTicketOpenAfter is how long a finding stays open before a ticket is filed.
+	DefaultTicketOpenAfter = 7 * 24 * time.Hour
+)
+
+// routeSeverities lists the severities a route can match.
+var routeSeverities = map[string]bool{"critical": true, "warn": true, "info": true}
+
+// templateMethods lists the HTTP methods allowed for templated webhooks.
+var templateMethods = map[string]bool{
+	http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true,
+}
+
+// TemplateFuncs are the functions available to webhook body templates.
+var TemplateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+	"until": func(t time.Time) time.Duration {
+		return time.Until(t).Truncate(time.Minute)
+	},
+}
+
+// ID returns the webhook's name. Unnamed webhooks get the SMTP server for
+// email, the repository for GitHub tickets, or otherwise the type and a hash
+// of the URL and routing key, so the ID can be logged and stored in the
+// outbox without revealing a token embedded in the URL.
+func (w *WebhookConfig) ID() string {
+	switch {
+	case w.Name != "":
+		return w.Name
+	case w.URL == "" && w.SMTP != nil:
+		return "smtp://" + w.SMTP.Address()
+	case w.URL == "" && w.Ticket != nil && w.Ticket.Provider == TicketGitHub:
+		return "github://" + w.Ticket.Repo
+	}
+	typ := w.Type
+	if typ == "" {
+		typ = "generic"
+	}
+	sum := sha256.Sum256([]byte(w.URL + "\x00" + w.RoutingKey))
+	return typ + "-" + hex.EncodeToString(sum[:6])
+}
+
+// Address returns the SMTP server's host:port.
+func (s *SMTPConfig) Address() string {
+	port := s.Port
+	if port == 0 {
+		port = 587
+	}
+	return net.JoinHostPort(s.Host, strconv.Itoa(port))
+}
+
+// DigestPeriod returns how often digests are sent, or 0 for immediate mode.
+func (s *SMTPConfig) DigestPeriod() time.Duration {
+	switch s.Digest {
+	case DigestDaily:
+		return 24 * time.Hour
+	case DigestWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// APIURL returns the issue tracker's API base URL.
+func (w *WebhookConfig) APIURL() string {
+	if w.URL == "" && w.Ticket != nil && w.Ticket.Provider == TicketGitHub {
+		return DefaultGitHubAPI
+	}
+	return strings.TrimRight(w.URL, "/")
+}
+
+// OpenDelay returns how long a finding stays open before a ticket is filed.
+func (t *TicketConfig) OpenDelay() time.Duration {
+	if t.OpenAfter > 0 {
+		return t.OpenAfter
+	}
+	return DefaultTicketOpenAfter
+}
+
+// ParseTemplate parses the webhook's body template. It returns nil when no template is set.
+func (w *WebhookConfig) ParseTemplate() (*template.Template, error) {
+	if w.Template == "" {
+		return nil, nil
+	}
+	return template.New(w.ID()).Funcs(TemplateFuncs).Option("missingkey=error").Parse(w.Template)
+}
+
+// MilestoneExpired is the reminder milestone for certificates past their expiry.
+const MilestoneExpired = "expired"
+
+// ParseMilestone parses a reminder milestone: a duration before expiry such as
+// "30d" or "12h", or "expired".
+func ParseMilestone(s string) (time.Duration, error) {
+	if s == MilestoneExpired {
+		return 0, nil
+	}
+	d, err := ParseDays(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid milestone %q: want a positive duration such as 30d or 12h, or %q", s, MilestoneExpired)
+	}
+	return d, nil
+}
+
+// ParseDays parses a positive duration such as "90d" or "36h". A "d" suffix
+// counts whole days; anything else uses time.ParseDuration.
+func ParseDays(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q: want a positive duration such as 90d or 36h", s)
+	}
+	return d, nil
+}
+
+// validate checks webhook types, names, and templates.
+func (n *NotificationConfig) validate() error {
+	if n.MaxAttempts < 0 {
+		return fmt.Errorf("notifications.maxAttempts must not be negative, got %d", n.MaxAttempts)
+	}
+	if n.RetryBackoff < 0 {
+		return fmt.Errorf("notifications.retryBackoff must not be negative, got %s", n.RetryBackoff)
+	}
+	seen := make(map[time.Duration]bool)
+	for _, m := range n.Reminders {
+		d, err := ParseMilestone(m)
+		if err != nil {
+			return fmt.Errorf("notifications.reminders: %w", err)
+		}
+		if seen[d] {
+			return fmt.Errorf("notifications.reminders: duplicate milestone %q", m)
+		}
+		seen[d] = true
+	}
+	names := make(map[string]bool)
+	for i := range n.Webhooks {
+		wh := &n.Webhooks[i]
+		field := fmt.Sprintf("notifications.webhooks[%d]", i)
+		if wh.Name != "" {
+			if names[wh.Name] {
+				return fmt.Errorf("%s: duplicate webhook name %q", field, wh.Name)
+			}
+			names[wh.Name] = true
+			field = fmt.Sprintf("notifications.webhooks[%s]", wh.Name)
+		}
+		if !webhookTypes[wh.Type] {
+			return fmt.Errorf("%s: unknown type %q", field, wh.Type)
+		}
+		for h := range wh.Headers {
+			if strings.TrimSpace(h) == "" {
+				return fmt.Errorf("%s: empty header name", field)
+			}
+		}
+		templated := wh.Template != "" || wh.Method != "" || wh.ContentType != ""
+		if templated && wh.Type != "" && wh.Type != "generic" && wh.Type != "slack" {
+			return fmt.Errorf("%s: template, method, and contentType are only supported for generic and slack webhooks", field)
+		}
+		if wh.Method != "" && !templateMethods[strings.ToUpper(wh.Method)] {
+			return fmt.Errorf("%s: method must be POST, PUT, or PATCH, got %q", field, wh.Method)
+		}
+		if _, err := wh.ParseTemplate(); err != nil {
+			return fmt.Errorf("%s: invalid template: %w", field, err)
+		}
+		if err := wh.validateSMTP(); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		if err := wh.validateTicket(); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		if err := wh.validateSecurity(); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	return n.validateRoutes(names)
+}
+
+// validateRoutes checks that routes and the fallback reference named webhooks
+// and that, with routing enabled, every webhook that is routed has a name.
+func (n *NotificationConfig) validateRoutes(names map[string]bool) error {
+	if len(n.Routes) > 0 || len(n.Fallback) > 0 {
+		for i := range n.Webhooks {
+			if wh := &n.Webhooks[i]; wh.Name == "" && wh.Type != "alertmanager" {
+				return fmt.Errorf("notifications.webhooks[%d]: name is required when routes or fallback are set", i)
+			}
+		}
+	}
+	checkNames := func(field string, webhooks []string) error {
+		for _, name := range webhooks {
+			if !names[name] {
+				return fmt.Errorf("%s: unknown webhook %q (routes reference webhooks by name)", field, name)
+			}
+		}
+		return nil
+	}
+	for i := range n.Routes {
+		r := &n.Routes[i]
+		field := fmt.Sprintf("notifications.routes[%d]", i)
+		if len(r.Webhooks) == 0 {
+			return fmt.Errorf("%s: at least one webhook is required", field)
+		}
+		if err := checkNames(field, r.Webhooks); err != nil {
+			return err
+		}
+		for _, s := range r.Match.Severities {
+			if !routeSeverities[s] {
+				return fmt.Errorf("%s: match.severities must be critical, warn, or info, got %q", field, s)
+			}
+		}
+		for _, p := range append(append([]string(nil), r.Match.Namespaces...), r.Match.Owners...) {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("%s: invalid pattern %q: %w", field, p, err)
+			}
+		}
+	}
+	return checkNames("notifications.fallback", n.Fallback)
+}
+
+func (w *WebhookConfig) validateSMTP() error {
+	if w.Type != "smtp" {
+		if w.SMTP != nil {
+			return fmt.Errorf("smtp settings require type smtp")
+		}
+		return nil
+	}
+	s := w.SMTP
+	if s == nil {
+		return fmt.Errorf("type smtp requires an smtp section")
+	}
+	if s.Host == "" || s.From == "" || len(s.To) == 0 {
+		return fmt.Errorf("smtp requires host, from, and to")
+	}
+	if _, err := mail.ParseAddress(s.From); err != nil {
+		return fmt.Errorf("smtp.from: %w", err)
+	}
+	for _, to := range s.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("smtp.to %q: %w", to, err)
+		}
+	}
+	if s.Port < 0 || s.Port > 65535 {
+		return fmt.Errorf("smtp.port out of range: %d", s.Port)
+	}
+	switch s.StartTLS {
+	case "", StartTLSRequired, StartTLSOpportunistic, StartTLSDisabled:
+	default:
+		return fmt.Errorf("smtp.startTLS must be required, opportunistic, or disabled, got %q", s.StartTLS)
+	}
+	switch s.Digest {
+	case "", DigestDaily, DigestWeekly:
+	default:
+		return fmt.Errorf("smtp.digest must be daily or weekly, got %q", s.Digest)
+	}
+	return nil
+}
+
+func (w *WebhookConfig) validateTicket() error {
+	if w.Type != "ticket" {
+		if w.Ticket != nil {
+			return fmt.Errorf("ticket settings require type ticket")
+		}
+		return nil
+	}
+	t := w.Ticket
+	if t == nil {
+		return fmt.Errorf("type ticket requires a ticket section")
+	}
+	if t.Token == "" {
+		return fmt.Errorf("ticket.token is required")
+	}
+	if t.OpenAfter < 0 {
+		return fmt.Errorf("ticket.openAfter must not be negative, got %s", t.OpenAfter)
+	}
+	switch t.Provider {
+	case TicketGitHub:
+		if owner, name, ok := strings.Cut(t.Repo, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("ticket.repo must be owner/name, got %q", t.Repo)
+		}
+	case TicketJira:
+		if w.URL == "" || t.Project == "" {
+			return fmt.Errorf("jira tickets require url and ticket.project")
+		}
+	default:
+		return fmt.Errorf("ticket.provider must be jira or github, got %q", t.Provider)
+	}
+	return nil
+}
+
+// Signed reports whether requests to the webhook carry an HMAC signature.
+func (w *WebhookConfig) Signed() bool {
+	return w.SigningSecret != ""
+}
+
+func (w *WebhookConfig) validateSecurity() error {
+	if w.Signed() {
+		generic := w.Type == "" || w.Type == "generic"
+		if !generic && (w.Type != "slack" || w.Template == "") {
+			return fmt.Errorf("signingSecret is only supported for generic and templated webhooks")
+		}
+	}
+	t := w.TLS
+	if t == nil {
+		return nil
+	}
+	if w.Type == "smtp" {
+		return fmt.Errorf("tls settings are not supported for smtp webhooks")
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("tls.certFile and tls.keyFile must be set together")
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Secret reference prefixes. A secret field set to "env:NAME" is read from the
+// environment and one set to "file:PATH" is read from a file, so credentials
+// can come from a Kubernetes Secret instead of living in config.yaml.
+const (
+	secretEnvPrefix  = "env:"
+	secretFilePrefix = "file:"
+)
+
+// ResolveSecret returns the value a secret reference points to. Values without
+// an env: or file: prefix are returned unchanged. File contents are trimmed of
+// surrounding whitespace, so a trailing newline in a mounted secret is ignored.
+func ResolveSecret(ref string) (string, error) {
+	if strings.HasPrefix(ref, secretEnvPrefix) {
+		return resolveEnvSecret(ref)
+	}
+	if path, ok := strings.CutPrefix(ref, secretFilePrefix); ok {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading secret file: %w", err)
+		}
+		v := strings.TrimSpace(string(b))
+		if v == "" {
+			return "", fmt.Errorf("secret file %s is empty", path)
+		}
+		return v, nil
+	}
+	return ref, nil
+}
+
+// resolveEnvSecret resolves an env: reference and returns anything else
+// unchanged. It is used for historyDB, where file: starts a SQLite URI.
+func resolveEnvSecret(ref string) (string, error) {
+	name, ok := strings.CutPrefix(ref, secretEnvPrefix)
+	if !ok {
+		return ref, nil
+	}
+	v, set := os.LookupEnv(name)
+	if !set || v == "" {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return v, nil
+}
+
+// resolveSecrets replaces secret references in webhook credentials with their values.
+func (n *NotificationConfig) resolveSecrets() error {
+	for i := range n.Webhooks {
+		wh := &n.Webhooks[i]
+		field := fmt.Sprintf("notifications.webhooks[%d]", i)
+		if wh.Name != "" {
+			field = fmt.Sprintf("notifications.webhooks[%s]", wh.Name)
+		}
+		names := []string{"url", "routingKey", "apiKey", "signingSecret"}
+		values := []*string{&wh.URL, &wh.RoutingKey, &wh.APIKey, &wh.SigningSecret}
+		if wh.SMTP != nil {
+			names = append(names, "smtp.password")
+			values = append(values, &wh.SMTP.Password)
+		}
+		if wh.Ticket != nil {
+			names = append(names, "ticket.token")
+			values = append(values, &wh.Ticket.Token)
+		}
+		for j, p := range values {
+			v, err := ResolveSecret(*p)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", field, names[j], err)
+			}
+			*p = v
+		}
+		for h, ref := range wh.Headers {
+			v, err := ResolveSecret(ref)
+			if err != nil {
+				return fmt.Errorf("%s.headers[%s]: %w", field, h, err)
+			}
+			wh.Headers[h] = v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// Client certificate modes for the serve endpoint.
+const (
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+// ServesTLS reports whether serve listens with HTTPS.
+func (c *Config) ServesTLS() bool {
+	return c.TLSCertFile != ""
+}
+
+// ClientAuthType returns the TLS client authentication mode for clientCAFile
+// and clientAuth.
+func (c *Config) ClientAuthType() tls.ClientAuthType {
+	switch {
+	case c.ClientCAFile == "":
+		return tls.NoClientCert
+	case c.ClientAuth == ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert
+	default:
+		return tls.VerifyClientCertIfGiven
+	}
+}
+
+// validateTLS checks the serving certificate settings and the TLS settings of
+// federation remotes.
+func (c *Config) validateTLS() error {
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("tlsCertFile and tlsKeyFile must be set together")
+	}
+	if c.ClientCAFile != "" && !c.ServesTLS() {
+		return fmt.Errorf("clientCAFile requires tlsCertFile and tlsKeyFile")
+	}
+	switch c.ClientAuth {
+	case "", ClientAuthOptional:
+	case ClientAuthRequire:
+		if c.ClientCAFile == "" {
+			return fmt.Errorf("clientAuth: require needs clientCAFile")
+		}
+		// Kubelet probes cannot present a client certificate.
+		if c.ProbeAddr == "" {
+			return fmt.Errorf("clientAuth: require needs probeAddr for health probes; " +
+				"or keep clientAuth: optional and require callers with auth routes")
+		}
+	default:
+		return fmt.Errorf("clientAuth must be optional or require, got %q", c.ClientAuth)
+	}
+	if c.ProbeAddr != "" && c.ProbeAddr == c.ListenAddr {
+		return fmt.Errorf("probeAddr must differ from listenAddr")
+	}
+	for i := range c.Remotes {
+		if t := c.Remotes[i].TLS; t != nil && (t.CertFile == "") != (t.KeyFile == "") {
+			return fmt.Errorf("remotes[%s]: tls.certFile and tls.keyFile must be set together", c.Remotes[i].Name)
+		}
+	}
+	return nil
+}
+
+// TLSConfig builds a client TLS config from t. The client certificate is
+// re-read on every handshake so a rotated certificate is picked up without a
+// restart.
+func (t *ClientTLSConfig) TLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: t.ServerName,
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading caFile: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("caFile %s contains no PEM certificates", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if t.CertFile != "" {
+		// Load once up front so a bad key pair fails fast.
+		if _, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile); err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("loading client certificate: %w", err)
+			}
+			return &cert, nil
+		}
+	}
+	return cfg, nil
+}
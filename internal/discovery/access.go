@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"path"
+	"strings"
 
 	authv1 "k8s.io/api/authorization/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -13,26 +15,82 @@ import (
 // ResolveNamespaces returns the explicit list if non-empty, otherwise lists all
 // namespaces in the cluster.
 func ResolveNamespaces(ctx context.Context, client kubernetes.Interface, explicit []string) ([]string, error) {
-	if len(explicit) > 0 {
-		return explicit, nil
+	return ResolveNamespaceScope(ctx, client, explicit, nil, "")
+}
+
+// ResolveNamespaceScope resolves the namespaces discovery should cover.
+// Include and exclude entries are namespace names or glob patterns (path.Match
+// syntax, e.g. "team-*"); selector is a label selector applied to Namespace objects.
+// A plain include list without a selector is returned as-is (minus exclusions)
+// without listing namespaces, so it works without cluster-wide namespace access.
+func ResolveNamespaceScope(ctx context.Context, client kubernetes.Interface, include, exclude []string, selector string) ([]string, error) {
+	var candidates []string
+	if len(include) > 0 && !hasGlob(include) && selector == "" {
+		candidates = include
+	} else {
+		nsList, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, fmt.Errorf("listing namespaces: %w", err)
+		}
+		candidates = make([]string, 0, len(nsList.Items))
+		for i := range nsList.Items {
+			name := nsList.Items[i].Name
+			if len(include) == 0 || matchesAny(name, include) {
+				candidates = append(candidates, name)
+			}
+		}
 	}
-	nsList, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("listing namespaces: %w", err)
+
+	if len(exclude) == 0 {
+		return candidates, nil
 	}
-	names := make([]string, len(nsList.Items))
-	for i := range nsList.Items {
-		names[i] = nsList.Items[i].Name
+	names := make([]string, 0, len(candidates))
+	for _, ns := range candidates {
+		if matchesAny(ns, exclude) {
+			slog.Debug("namespace excluded from scope", "namespace", ns)
+			continue
+		}
+		names = append(names, ns)
 	}
 	return names, nil
 }
 
+// matchesAny reports whether name equals or glob-matches any of the patterns.
+// Malformed patterns only match literally.
+func matchesAny(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if p == name {
+			return true
+		}
+		if ok, err := path.Match(p, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGlob reports whether any pattern contains glob metacharacters.
+func hasGlob(patterns []string) bool {
+	for _, p := range patterns {
+		if strings.ContainsAny(p, "*?[") {
+			return true
+		}
+	}
+	return false
+}
+
 // FilterAccessible returns the subset of namespaces where the current identity
 // can list the given resource type. Uses SelfSubjectAccessReview.
 // If the access check itself fails (e.g. RBAC for SSAR is missing), the
 // namespace is included to avoid silently dropping accessible namespaces.
 func FilterAccessible(ctx context.Context, client kubernetes.Interface, namespaces []string, group, resource string) []string {
-	var accessible []string
+	accessible, _ := PartitionAccessible(ctx, client, namespaces, group, resource)
+	return accessible
+}
+
+// PartitionAccessible is FilterAccessible that also returns the namespaces
+// skipped because access was denied, so callers can report coverage gaps.
+func PartitionAccessible(ctx context.Context, client kubernetes.Interface, namespaces []string, group, resource string) (accessible, denied []string) {
 	for _, ns := range namespaces {
 		review := &authv1.SelfSubjectAccessReview{
 			Spec: authv1.SelfSubjectAccessReviewSpec{
@@ -56,9 +114,10 @@ func FilterAccessible(ctx context.Context, client kubernetes.Interface, namespac
 		} else {
 			slog.Debug("access denied, skipping namespace", "namespace", ns,
 				"group", group, "resource", resource)
+			denied = append(denied, ns)
 		}
 	}
-	return accessible
+	return accessible, denied
 }
 
 // namespacesOrAll returns the given namespaces, or a single empty-string entry
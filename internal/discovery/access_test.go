@@ -130,3 +130,48 @@ func TestNamespacesOrAll_WithNamespaces(t *testing.T) {
 		t.Errorf("expected [ns1 ns2], got %v", result)
 	}
 }
+
+func TestResolveNamespaceScope(t *testing.T) {
+	objs := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "team-sandbox",
+			Labels: map[string]string{"trustwatch.dev/scan": "false"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ci-123"}},
+	}
+
+	tests := []struct {
+		name     string
+		selector string
+		include  []string
+		exclude  []string
+		want     []string
+	}{
+		{name: "glob include", include: []string{"team-*"}, want: []string{"team-a", "team-b", "team-sandbox"}},
+		{name: "glob exclude", exclude: []string{"kube-*", "ci-*"}, want: []string{"team-a", "team-b", "team-sandbox"}},
+		{name: "include and exclude", include: []string{"team-*"}, exclude: []string{"team-b"}, want: []string{"team-a", "team-sandbox"}},
+		{name: "selector", include: []string{"team-*"}, selector: "trustwatch.dev/scan!=false", want: []string{"team-a", "team-b"}},
+		{name: "explicit with exclude", include: []string{"app", "ci-1"}, exclude: []string{"ci-*"}, want: []string{"app"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := fake.NewClientset(objs...)
+			got, err := ResolveNamespaceScope(context.Background(), cs, tt.include, tt.exclude, tt.selector)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected %v, got %v", tt.want, got)
+					break
+				}
+			}
+		})
+	}
+}
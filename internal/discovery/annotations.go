@@ -26,9 +26,10 @@ const (
 
 // AnnotationDiscoverer finds TLS targets from trustwatch.dev/* annotations on Services and Deployments.
 type AnnotationDiscoverer struct {
-	client     kubernetes.Interface
-	probeFn    func(string) probe.Result
-	namespaces []string
+	client        kubernetes.Interface
+	probeFn       func(string) probe.Result
+	labelSelector string
+	namespaces    []string
 }
 
 // NewAnnotationDiscoverer creates a discoverer that scans annotations for TLS targets.
@@ -57,6 +58,13 @@ func WithAnnotationProbeFn(fn func(string) probe.Result) func(*AnnotationDiscove
 	}
 }
 
+// WithAnnotationLabelSelector restricts discovery to Services and Deployments matching the label selector.
+func WithAnnotationLabelSelector(selector string) func(*AnnotationDiscoverer) {
+	return func(d *AnnotationDiscoverer) {
+		d.labelSelector = selector
+	}
+}
+
 // Name returns the discoverer label.
 func (d *AnnotationDiscoverer) Name() string {
 	return "annotations"
@@ -67,7 +75,7 @@ func (d *AnnotationDiscoverer) Discover(ctx context.Context) ([]store.CertFindin
 	var findings []store.CertFinding
 
 	for _, ns := range namespacesOrAll(d.namespaces) {
-		svcs, err := d.client.CoreV1().Services(ns).List(ctx, metav1.ListOptions{})
+		svcs, err := d.client.CoreV1().Services(ns).List(ctx, metav1.ListOptions{LabelSelector: d.labelSelector})
 		if err != nil {
 			return nil, fmt.Errorf("listing services: %w", err)
 		}
@@ -76,12 +84,13 @@ func (d *AnnotationDiscoverer) Discover(ctx context.Context) ([]store.CertFindin
 			if svc.Annotations[annoEnabled] != "true" {
 				continue
 			}
-			findings = append(findings, d.processAnnotated(ctx, svc.Namespace, svc.Name, "Service", svc.Annotations)...)
+			found := withOwnership(d.processAnnotated(ctx, svc.Namespace, svc.Name, "Service", svc.Annotations), svc)
+			findings = append(findings, withObject(found, objectRef("v1", "Service", svc))...)
 		}
 	}
 
 	for _, ns := range namespacesOrAll(d.namespaces) {
-		deps, err := d.client.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{})
+		deps, err := d.client.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{LabelSelector: d.labelSelector})
 		if err != nil {
 			return nil, fmt.Errorf("listing deployments: %w", err)
 		}
@@ -90,7 +99,8 @@ func (d *AnnotationDiscoverer) Discover(ctx context.Context) ([]store.CertFindin
 			if dep.Annotations[annoEnabled] != "true" {
 				continue
 			}
-			findings = append(findings, d.processAnnotated(ctx, dep.Namespace, dep.Name, "Deployment", dep.Annotations)...)
+			found := withOwnership(d.processAnnotated(ctx, dep.Namespace, dep.Name, "Deployment", dep.Annotations), dep)
+			findings = append(findings, withObject(found, objectRef("apps/v1", "Deployment", dep))...)
 		}
 	}
 
@@ -147,6 +157,7 @@ func (d *AnnotationDiscoverer) probeTarget(target, sni, namespace, name string,
 
 	if result.ProbeOK && result.Cert != nil {
 		finding.NotAfter = result.Cert.NotAfter
+		finding.NotBefore = result.Cert.NotBefore
 		finding.DNSNames = result.Cert.DNSNames
 		finding.Issuer = result.Cert.Issuer.String()
 		finding.Subject = result.Cert.Subject.String()
@@ -193,6 +204,7 @@ func (d *AnnotationDiscoverer) findingFromSecret(ctx context.Context, namespace,
 
 	finding.ProbeOK = true
 	finding.NotAfter = cert.NotAfter
+	finding.NotBefore = cert.NotBefore
 	finding.DNSNames = cert.DNSNames
 	finding.Issuer = cert.Issuer.String()
 	finding.Subject = cert.Subject.String()
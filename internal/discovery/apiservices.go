@@ -55,6 +55,7 @@ func (d *APIServiceDiscoverer) Discover(ctx context.Context) ([]store.CertFindin
 
 	for i := range apiServices.Items {
 		spec := &apiServices.Items[i].Spec
+		ref := objectRef("apiregistration.k8s.io/v1", "APIService", &apiServices.Items[i])
 		svc := spec.Service
 		if svc == nil {
 			continue
@@ -75,6 +76,7 @@ func (d *APIServiceDiscoverer) Discover(ctx context.Context) ([]store.CertFindin
 				Target:    fmt.Sprintf("%s.%s.svc:%d", svc.Name, svc.Namespace, port),
 				Notes:     "insecureSkipTLSVerify=true",
 				ProbeOK:   true,
+				Object:    ref,
 			})
 			continue
 		}
@@ -95,15 +97,18 @@ func (d *APIServiceDiscoverer) Discover(ctx context.Context) ([]store.CertFindin
 			Target:    target,
 			ProbeOK:   result.ProbeOK,
 			ProbeErr:  result.ProbeErr,
+			Object:    ref,
 		}
 
 		if result.ProbeOK && result.Cert != nil {
 			finding.NotAfter = result.Cert.NotAfter
+			finding.NotBefore = result.Cert.NotBefore
 			finding.DNSNames = result.Cert.DNSNames
 			finding.Issuer = result.Cert.Issuer.String()
 			finding.Subject = result.Cert.Subject.String()
 			finding.Serial = result.Cert.SerialNumber.String()
 			applyProbeChainValidation(&finding, &result, extractHostFromTarget(target))
+			applyCABundleCheck(&finding, spec.CABundle, result.Chain)
 		}
 
 		findings = append(findings, finding)
@@ -0,0 +1,193 @@
+package discovery
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	aggregatorclient "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset"
+	gatewayclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/probe"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// Clients bundles the Kubernetes API clients used by the built-in discoverers.
+type Clients struct {
+	Core       kubernetes.Interface
+	Aggregator aggregatorclient.Interface
+	Gateway    gatewayclient.Interface
+	Dynamic    dynamic.Interface
+}
+
+// BuildOptions carries per-invocation settings that do not come from config.Config.
+type BuildOptions struct {
+	APIServerProbeFn func(string) probe.Result  // probe used for the API server (e.g. via the REST transport)
+	ProbeFn          func(string) probe.Result  // overrides probing for network discoverers (e.g. --tunnel)
+	ServingChain     func() []*x509.Certificate // current serve TLS chain; enables the serving discoverer
+	APIServerTarget  string                     // host:port of the API server
+}
+
+// Build resolves namespaces and constructs the discoverer set described by cfg.
+// Disabled discoverers are skipped, and namespace-scoped discoverers receive the
+// namespaces the current identity can list after per-discoverer filtering.
+// The returned scope records the resolved namespaces, and the namespaces dropped
+// for RBAC, for snapshot metadata.
+func Build(ctx context.Context, clients Clients, cfg *config.Config, opts BuildOptions) ([]Discoverer, *store.Scope, error) {
+	allNS, err := ResolveNamespaceScope(ctx, clients.Core, cfg.Namespaces, cfg.ExcludeNamespaces, cfg.NamespaceSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolving namespaces: %w", err)
+	}
+	if len(allNS) == 0 && (len(cfg.Namespaces) > 0 || len(cfg.ExcludeNamespaces) > 0 || cfg.NamespaceSelector != "") {
+		return nil, nil, fmt.Errorf("namespace scope matches no namespaces")
+	}
+
+	// A namespace-scoped discoverer whose include/exclude lists leave nothing to
+	// scan is skipped rather than falling back to listing across all namespaces.
+	outOfScope := make(map[string]bool)
+	denied := make(map[string][]string)
+	enabled := func(name string) bool {
+		return cfg.Discoverer(name).IsEnabled() && !outOfScope[name]
+	}
+	accessibleNS := func(name, group, resource string) []string {
+		if !enabled(name) {
+			return nil
+		}
+		ns := ScopeNamespaces(allNS, cfg.Discoverer(name))
+		if len(ns) == 0 && len(allNS) > 0 {
+			slog.Info("no namespaces in scope, skipping discoverer", "source", name)
+			outOfScope[name] = true
+			return nil
+		}
+		accessible, skipped := PartitionAccessible(ctx, clients.Core, ns, group, resource)
+		if len(skipped) > 0 {
+			denied[name] = skipped
+		}
+		return accessible
+	}
+
+	secretNS := accessibleNS("secrets", "", "secrets")
+	ingressNS := accessibleNS("ingress", "networking.k8s.io", "ingresses")
+	svcNS := accessibleNS("annotations", "", "services")
+	gwNS := accessibleNS("gateway", "gateway.networking.k8s.io", "gateways")
+	certNS := accessibleNS("certmanager", "cert-manager.io", "certificates")
+	renewalNS := accessibleNS("certmanager.renewal", "cert-manager.io", "certificates")
+	slog.Info("namespace access resolved", "total", len(allNS),
+		"secrets", len(secretNS), "ingresses", len(ingressNS),
+		"services", len(svcNS), "gateways", len(gwNS),
+		"certificates", len(certNS))
+
+	var webhookOpts []func(*WebhookDiscoverer)
+	var apiSvcOpts []func(*APIServiceDiscoverer)
+	var annotOpts []func(*AnnotationDiscoverer)
+	var extOpts []func(*ExternalDiscoverer)
+	if opts.ProbeFn != nil {
+		webhookOpts = append(webhookOpts, WithWebhookProbeFn(opts.ProbeFn))
+		apiSvcOpts = append(apiSvcOpts, WithAPIServiceProbeFn(opts.ProbeFn))
+		annotOpts = append(annotOpts, WithAnnotationProbeFn(opts.ProbeFn))
+		extOpts = append(extOpts, WithExternalProbeFn(opts.ProbeFn))
+	}
+	annotOpts = append(annotOpts,
+		WithAnnotationNamespaces(svcNS),
+		WithAnnotationLabelSelector(cfg.Discoverer("annotations").LabelSelector))
+
+	var apiServerOpts []func(*APIServerDiscoverer)
+	if opts.APIServerProbeFn != nil {
+		apiServerOpts = append(apiServerOpts, WithProbeFn(opts.APIServerProbeFn))
+	}
+
+	renewalOpts := []func(*CertManagerRenewalDiscoverer){WithRenewalNamespaces(renewalNS)}
+	if stale := cfg.Discoverer("certmanager.renewal").StaleDuration; stale > 0 {
+		renewalOpts = append(renewalOpts, WithStaleDuration(stale))
+	}
+
+	var discoverers []Discoverer
+	add := func(name string, build func() Discoverer) {
+		if enabled(name) {
+			discoverers = append(discoverers, build())
+		}
+	}
+
+	add("webhooks", func() Discoverer { return NewWebhookDiscoverer(clients.Core, webhookOpts...) })
+	add("apiservices", func() Discoverer { return NewAPIServiceDiscoverer(clients.Aggregator, apiSvcOpts...) })
+	add("apiserver", func() Discoverer { return NewAPIServerDiscoverer(opts.APIServerTarget, apiServerOpts...) })
+	add("secrets", func() Discoverer {
+		return NewSecretDiscoverer(clients.Core,
+			WithSecretNamespaces(secretNS),
+			WithSecretLabelSelector(cfg.Discoverer("secrets").LabelSelector))
+	})
+	add("ingress", func() Discoverer {
+		return NewIngressDiscoverer(clients.Core,
+			WithIngressNamespaces(ingressNS),
+			WithIngressLabelSelector(cfg.Discoverer("ingress").LabelSelector))
+	})
+	add("linkerd", func() Discoverer { return NewLinkerdDiscoverer(clients.Core) })
+	add("istio", func() Discoverer { return NewIstioDiscoverer(clients.Core) })
+	add("annotations", func() Discoverer { return NewAnnotationDiscoverer(clients.Core, annotOpts...) })
+	add("gateway", func() Discoverer {
+		return NewGatewayDiscoverer(clients.Gateway, clients.Core, WithGatewayNamespaces(gwNS))
+	})
+	add("certmanager", func() Discoverer {
+		return NewCertManagerDiscoverer(clients.Dynamic, clients.Core, WithCertManagerNamespaces(certNS))
+	})
+	add("certmanager.renewal", func() Discoverer {
+		return NewCertManagerRenewalDiscoverer(clients.Dynamic, clients.Core, renewalOpts...)
+	})
+	if len(cfg.External) > 0 {
+		add("externals", func() Discoverer { return NewExternalDiscoverer(cfg.External, extOpts...) })
+	}
+	if cfg.SPIFFESocket != "" {
+		add("spiffe", func() Discoverer { return NewSPIFFEDiscoverer(cfg.SPIFFESocket) })
+	}
+	if opts.ServingChain != nil {
+		add("serving", func() Discoverer {
+			return NewServingDiscoverer(opts.ServingChain, cfg.ListenAddr, cfg.TLSCertFile)
+		})
+	}
+	for _, d := range CloudDiscoverers() {
+		if enabled(d.Name()) {
+			discoverers = append(discoverers, d)
+		}
+	}
+
+	scope := &store.Scope{
+		NamespaceSelector: cfg.NamespaceSelector,
+		Include:           cfg.Namespaces,
+		Exclude:           cfg.ExcludeNamespaces,
+		Namespaces:        allNS,
+	}
+	if len(denied) > 0 {
+		scope.Denied = denied
+	}
+	for _, name := range []string{"secrets", "ingress", "annotations"} {
+		if sel := cfg.Discoverer(name).LabelSelector; sel != "" && enabled(name) {
+			if scope.LabelSelectors == nil {
+				scope.LabelSelectors = make(map[string]string)
+			}
+			scope.LabelSelectors[name] = sel
+		}
+	}
+
+	return discoverers, scope, nil
+}
+
+// ScopeNamespaces narrows the resolved namespaces to a discoverer's include list
+// and drops its excluded namespaces. Both lists accept glob patterns.
+func ScopeNamespaces(namespaces []string, dc config.DiscovererConfig) []string {
+	scoped := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		if len(dc.Namespaces) > 0 && !matchesAny(ns, dc.Namespaces) {
+			continue
+		}
+		if matchesAny(ns, dc.ExcludeNamespaces) {
+			continue
+		}
+		scoped = append(scoped, ns)
+	}
+	return scoped
+}
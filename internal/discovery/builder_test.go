@@ -0,0 +1,212 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	aggregatorfake "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset/fake"
+	gatewayfake "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/fake"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+)
+
+func builderClients(namespaces ...string) Clients {
+	objs := make([]runtime.Object, 0, len(namespaces))
+	for _, ns := range namespaces {
+		objs = append(objs, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}})
+	}
+	cs := fake.NewClientset(objs...)
+	cs.PrependReactor("create", "selfsubjectaccessreviews",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			review := action.(k8stesting.CreateAction).GetObject().(*authv1.SelfSubjectAccessReview)
+			review.Status.Allowed = true
+			return true, review, nil
+		})
+	return Clients{
+		Core:       cs,
+		Aggregator: aggregatorfake.NewSimpleClientset(),
+		Gateway:    gatewayfake.NewSimpleClientset(),
+		Dynamic:    renewalDynClient(),
+	}
+}
+
+func discovererNames(discoverers []Discoverer) map[string]Discoverer {
+	byName := make(map[string]Discoverer, len(discoverers))
+	for _, d := range discoverers {
+		byName[d.Name()] = d
+	}
+	return byName
+}
+
+func TestBuild_Defaults(t *testing.T) {
+	cfg := config.Defaults()
+	discoverers, _, err := Build(context.Background(), builderClients(testNS1, testNS2), cfg, BuildOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	byName := discovererNames(discoverers)
+	for _, name := range []string{
+		"webhooks", "apiservices", "apiserver", "secrets", "ingress", "linkerd",
+		"istio", "annotations", "gateway", "certmanager", "certmanager.renewal",
+	} {
+		if _, ok := byName[name]; !ok {
+			t.Errorf("expected discoverer %q", name)
+		}
+	}
+	if _, ok := byName["externals"]; ok {
+		t.Error("externals should not be built without external targets")
+	}
+	if _, ok := byName["spiffe"]; ok {
+		t.Error("spiffe should not be built without a socket")
+	}
+	if _, ok := byName["serving"]; ok {
+		t.Error("serving should not be built without a serving certificate")
+	}
+}
+
+func TestBuild_DisabledDiscoverers(t *testing.T) {
+	disabled := false
+	cfg := config.Defaults()
+	cfg.External = []config.ExternalTarget{{URL: "https://vault.internal:8200"}}
+	cfg.Discovery = map[string]config.DiscovererConfig{
+		"linkerd":   {Enabled: &disabled},
+		"externals": {Enabled: &disabled},
+	}
+
+	discoverers, _, err := Build(context.Background(), builderClients(testNS1), cfg, BuildOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	byName := discovererNames(discoverers)
+	if _, ok := byName["linkerd"]; ok {
+		t.Error("linkerd should be disabled")
+	}
+	if _, ok := byName["externals"]; ok {
+		t.Error("externals should be disabled")
+	}
+	if _, ok := byName["istio"]; !ok {
+		t.Error("istio should still be enabled")
+	}
+}
+
+func TestBuild_PerDiscovererSettings(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Discovery = map[string]config.DiscovererConfig{
+		"secrets": {
+			Namespaces:    []string{testNS1, testNS2},
+			LabelSelector: "team=payments",
+		},
+		"ingress":             {ExcludeNamespaces: []string{testNS2}},
+		"certmanager.renewal": {StaleDuration: 2 * time.Hour},
+	}
+
+	discoverers, _, err := Build(context.Background(), builderClients(testNS1, testNS2, testNS3), cfg, BuildOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	byName := discovererNames(discoverers)
+
+	secrets := byName["secrets"].(*SecretDiscoverer)
+	if len(secrets.namespaces) != 2 {
+		t.Errorf("expected 2 secret namespaces, got %v", secrets.namespaces)
+	}
+	if secrets.labelSelector != "team=payments" {
+		t.Errorf("expected secret label selector, got %q", secrets.labelSelector)
+	}
+
+	ingress := byName["ingress"].(*IngressDiscoverer)
+	for _, ns := range ingress.namespaces {
+		if ns == testNS2 {
+			t.Errorf("expected %s to be excluded from ingress namespaces, got %v", testNS2, ingress.namespaces)
+		}
+	}
+	if len(ingress.namespaces) != 2 {
+		t.Errorf("expected 2 ingress namespaces, got %v", ingress.namespaces)
+	}
+
+	renewal := byName["certmanager.renewal"].(*CertManagerRenewalDiscoverer)
+	if renewal.staleDuration != 2*time.Hour {
+		t.Errorf("expected staleDuration 2h, got %v", renewal.staleDuration)
+	}
+}
+
+func TestBuild_NoNamespacesInScope(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Discovery = map[string]config.DiscovererConfig{
+		"secrets": {Namespaces: []string{"missing"}},
+	}
+
+	discoverers, _, err := Build(context.Background(), builderClients(testNS1), cfg, BuildOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := discovererNames(discoverers)["secrets"]; ok {
+		t.Error("secrets should be skipped when no namespaces are in scope")
+	}
+}
+
+func TestBuild_ScopeMetadata(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.ExcludeNamespaces = []string{testNS3}
+	cfg.Discovery = map[string]config.DiscovererConfig{
+		"ingress": {LabelSelector: "expose=public"},
+	}
+
+	_, scope, err := Build(context.Background(), builderClients(testNS1, testNS2, testNS3), cfg, BuildOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(scope.Namespaces) != 2 {
+		t.Errorf("expected 2 resolved namespaces, got %v", scope.Namespaces)
+	}
+	if len(scope.Exclude) != 1 || scope.Exclude[0] != testNS3 {
+		t.Errorf("expected exclude [%s], got %v", testNS3, scope.Exclude)
+	}
+	if scope.LabelSelectors["ingress"] != "expose=public" {
+		t.Errorf("expected ingress label selector in scope, got %v", scope.LabelSelectors)
+	}
+}
+
+func TestBuild_ScopeMatchesNothing(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Namespaces = []string{"prod-*"}
+
+	if _, _, err := Build(context.Background(), builderClients(testNS1), cfg, BuildOptions{}); err == nil {
+		t.Error("expected error when namespace scope matches no namespaces")
+	}
+}
+
+func TestBuild_ScopeRecordsDeniedNamespaces(t *testing.T) {
+	clients := builderClients(testNS1, testNS2)
+	clients.Core.(*fake.Clientset).PrependReactor("create", "selfsubjectaccessreviews",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			review := action.(k8stesting.CreateAction).GetObject().(*authv1.SelfSubjectAccessReview)
+			attrs := review.Spec.ResourceAttributes
+			review.Status.Allowed = attrs.Resource != "secrets" || attrs.Namespace != testNS2
+			return true, review, nil
+		})
+
+	discoverers, scope, err := Build(context.Background(), clients, config.Defaults(), BuildOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if denied := scope.Denied["secrets"]; len(denied) != 1 || denied[0] != testNS2 {
+		t.Errorf("expected secrets denied [%s], got %v", testNS2, scope.Denied)
+	}
+	if _, ok := scope.Denied["ingress"]; ok {
+		t.Errorf("expected no denied namespaces for ingress, got %v", scope.Denied)
+	}
+	secrets := discovererNames(discoverers)["secrets"].(*SecretDiscoverer)
+	if len(secrets.namespaces) != 1 || secrets.namespaces[0] != testNS1 {
+		t.Errorf("expected secrets to scan only %s, got %v", testNS1, secrets.namespaces)
+	}
+}
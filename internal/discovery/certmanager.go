@@ -83,20 +83,50 @@ func (d *CertManagerDiscoverer) findingFromCert(ctx context.Context, obj *unstru
 		Severity:  store.SeverityInfo,
 		Name:      obj.GetName(),
 		Namespace: obj.GetNamespace(),
+		Object:    objectRef("cert-manager.io/v1", "Certificate", obj),
 	}
+	setOwnership(&finding, obj)
 
 	spec := extractMap(obj.Object, "spec")
 	d.populateSpecFields(&finding, spec)
 
-	// Try status.notAfter first
+	// Try status.notAfter first, falling back to reading the Secret
 	if notAfter := d.extractNotAfter(obj.Object); !notAfter.IsZero() {
 		finding.NotAfter = notAfter
 		finding.ProbeOK = true
-		return finding
+	} else {
+		finding = d.populateFromSecret(ctx, &finding, spec)
 	}
+	finding.RenewalTime = renewalTime(obj.Object, spec, &finding)
+	return finding
+}
 
-	// Fall back to reading the Secret
-	return d.populateFromSecret(ctx, &finding, spec)
+// renewalTime returns when cert-manager will renew the certificate:
+// status.renewalTime when set, otherwise notAfter less spec.renewBefore or
+// spec.renewBeforePercentage of the lifetime. It is zero when cert-manager's
+// default applies or notAfter is unknown.
+func renewalTime(obj, spec map[string]interface{}, f *store.CertFinding) time.Time {
+	if status := extractMap(obj, "status"); status != nil {
+		if t := parseTime(status, "renewalTime"); !t.IsZero() {
+			return t
+		}
+	}
+	if f.NotAfter.IsZero() || spec == nil {
+		return time.Time{}
+	}
+	if s, ok := spec["renewBefore"].(string); ok {
+		if before, err := time.ParseDuration(s); err == nil && before > 0 {
+			return f.NotAfter.Add(-before)
+		}
+	}
+	lifetime := f.CertDuration
+	if !f.NotBefore.IsZero() {
+		lifetime = f.NotAfter.Sub(f.NotBefore)
+	}
+	if pct, ok := spec["renewBeforePercentage"].(int64); ok && pct > 0 && pct < 100 && lifetime > 0 {
+		return f.NotAfter.Add(-lifetime * time.Duration(pct) / 100)
+	}
+	return time.Time{}
 }
 
 // populateSpecFields extracts commonName, dnsNames, and issuerRef from the spec.
@@ -162,6 +192,7 @@ func (d *CertManagerDiscoverer) populateFromSecret(ctx context.Context, f *store
 
 	f.ProbeOK = true
 	f.NotAfter = cert.NotAfter
+	f.NotBefore = cert.NotBefore
 	if f.Subject == "" {
 		f.Subject = cert.Subject.String()
 	}
@@ -184,11 +215,16 @@ func (d *CertManagerDiscoverer) extractNotAfter(obj map[string]interface{}) time
 	if !ok {
 		return time.Time{}
 	}
-	notAfterStr, ok := status["notAfter"].(string)
-	if !ok || notAfterStr == "" {
+	return parseTime(status, "notAfter")
+}
+
+// parseTime reads an RFC 3339 timestamp field, returning zero if it is missing or malformed.
+func parseTime(m map[string]interface{}, key string) time.Time {
+	s, ok := m[key].(string)
+	if !ok || s == "" {
 		return time.Time{}
 	}
-	t, err := time.Parse(time.RFC3339, notAfterStr)
+	t, err := time.Parse(time.RFC3339, s)
 	if err != nil {
 		return time.Time{}
 	}
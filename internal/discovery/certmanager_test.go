@@ -315,3 +315,36 @@ func TestCertManagerDiscoverer_NoSecretName(t *testing.T) {
 		t.Errorf("expected specific probeErr, got %q", f.ProbeErr)
 	}
 }
+
+func TestCertManagerDiscoverer_RenewalTime(t *testing.T) {
+	notAfter := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+	renewal := notAfter.Add(-10 * 24 * time.Hour)
+	withStatusRenewal := func(obj map[string]interface{}) {
+		obj["status"].(map[string]interface{})["renewalTime"] = renewal.Format(time.RFC3339)
+	}
+	withRenewBefore := func(obj map[string]interface{}) {
+		obj["spec"].(map[string]interface{})["renewBefore"] = "360h"
+	}
+
+	tests := []struct {
+		want time.Time
+		name string
+		opts []func(map[string]interface{})
+	}{
+		{name: "status", opts: []func(map[string]interface{}){withNotAfter(notAfter), withStatusRenewal, withRenewBefore}, want: renewal},
+		{name: "renewBefore", opts: []func(map[string]interface{}){withNotAfter(notAfter), withRenewBefore}, want: notAfter.Add(-15 * 24 * time.Hour)},
+		{name: "default", opts: []func(map[string]interface{}){withNotAfter(notAfter)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewCertManagerDiscoverer(newDynamicClient(t, makeCertificateCR("my-cert", testNS1, tt.opts...)), fakeWithCertManager())
+			findings, err := d.Discover(context.Background())
+			if err != nil || len(findings) != 1 {
+				t.Fatalf("Discover = %d findings, %v", len(findings), err)
+			}
+			if got := findings[0].RenewalTime; !got.Equal(tt.want) {
+				t.Errorf("renewal time = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -54,6 +54,36 @@ func applyProbeChainValidation(finding *store.CertFinding, pr *probe.Result, hos
 	}
 }
 
+// FindingCABundleMismatch indicates that a webhook or APIService caBundle does not
+// verify the certificate its backing service serves.
+const FindingCABundleMismatch = "CA_BUNDLE_MISMATCH"
+
+// applyCABundleCheck verifies the served chain against a configured caBundle, as the
+// API server does when calling the service. A mismatch is recorded as a chain error.
+func applyCABundleCheck(finding *store.CertFinding, caBundle []byte, served []*x509.Certificate) {
+	if len(caBundle) == 0 || len(served) == 0 {
+		return
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caBundle) {
+		finding.FindingType = FindingCABundleMismatch
+		finding.ChainErrors = append(finding.ChainErrors, "caBundle contains no valid PEM certificates")
+		return
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range served[1:] {
+		intermediates.AddCert(c)
+	}
+	if _, err := served[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   served[0].NotBefore,
+	}); err != nil {
+		finding.FindingType = FindingCABundleMismatch
+		finding.ChainErrors = append(finding.ChainErrors, "caBundle does not verify the serving certificate: "+err.Error())
+	}
+}
+
 // applyPEMChainValidation parses a PEM bundle, runs chain validation, and populates finding fields.
 // Returns the leaf certificate for metadata extraction, or nil on error.
 func applyPEMChainValidation(finding *store.CertFinding, pemData []byte, hostname string) *x509.Certificate {
@@ -59,6 +59,7 @@ func (d *ExternalDiscoverer) Discover(_ context.Context) ([]store.CertFinding, e
 
 		if result.ProbeOK && result.Cert != nil {
 			finding.NotAfter = result.Cert.NotAfter
+			finding.NotBefore = result.Cert.NotBefore
 			finding.DNSNames = result.Cert.DNSNames
 			finding.Issuer = result.Cert.Issuer.String()
 			finding.Subject = result.Cert.Subject.String()
@@ -104,6 +104,7 @@ func (d *GatewayDiscoverer) findingFromCertRef(
 		Source:   store.SourceGateway,
 		Severity: store.SeverityInfo,
 		Name:     fmt.Sprintf("%s/%s/%s", gw.Name, listener.Name, secretName),
+		Object:   objectRef("gateway.networking.k8s.io/v1", "Gateway", gw),
 	}
 	if gw.Namespace != "" {
 		finding.Namespace = gw.Namespace
@@ -149,6 +150,7 @@ func (d *GatewayDiscoverer) findingFromCertRef(
 
 	finding.ProbeOK = true
 	finding.NotAfter = cert.NotAfter
+	finding.NotBefore = cert.NotBefore
 	finding.DNSNames = cert.DNSNames
 	finding.Issuer = cert.Issuer.String()
 	finding.Subject = cert.Subject.String()
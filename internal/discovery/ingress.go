@@ -14,8 +14,9 @@ import (
 
 // IngressDiscoverer finds TLS certificates referenced by Ingress objects.
 type IngressDiscoverer struct {
-	client     kubernetes.Interface
-	namespaces []string
+	client        kubernetes.Interface
+	labelSelector string
+	namespaces    []string
 }
 
 // NewIngressDiscoverer creates a discoverer that extracts TLS secrets from Ingress specs.
@@ -34,6 +35,13 @@ func WithIngressNamespaces(ns []string) func(*IngressDiscoverer) {
 	}
 }
 
+// WithIngressLabelSelector restricts discovery to Ingresses matching the label selector.
+func WithIngressLabelSelector(selector string) func(*IngressDiscoverer) {
+	return func(d *IngressDiscoverer) {
+		d.labelSelector = selector
+	}
+}
+
 // Name returns the discoverer label.
 func (d *IngressDiscoverer) Name() string {
 	return "ingress"
@@ -45,7 +53,7 @@ func (d *IngressDiscoverer) Discover(ctx context.Context) ([]store.CertFinding,
 	var findings []store.CertFinding
 
 	for _, ns := range namespacesOrAll(d.namespaces) {
-		ingresses, err := d.client.NetworkingV1().Ingresses(ns).List(ctx, metav1.ListOptions{})
+		ingresses, err := d.client.NetworkingV1().Ingresses(ns).List(ctx, metav1.ListOptions{LabelSelector: d.labelSelector})
 		if err != nil {
 			return nil, fmt.Errorf("listing ingresses: %w", err)
 		}
@@ -73,7 +81,9 @@ func (d *IngressDiscoverer) findingFromIngressTLS(ctx context.Context, ing *netw
 		Severity:  store.SeverityInfo,
 		Namespace: ing.Namespace,
 		Name:      fmt.Sprintf("%s/%s", ing.Name, tls.SecretName),
+		Object:    objectRef("networking.k8s.io/v1", "Ingress", ing),
 	}
+	setOwnership(&finding, ing)
 
 	secret, err := d.client.CoreV1().Secrets(ing.Namespace).Get(ctx, tls.SecretName, metav1.GetOptions{})
 	if err != nil {
@@ -89,6 +99,8 @@ func (d *IngressDiscoverer) findingFromIngressTLS(ctx context.Context, ing *netw
 		return finding
 	}
 
+	setOwnership(&finding, secret)
+
 	// Accept kubernetes.io/tls and Opaque secrets that contain tls.crt
 	pemData, ok := secret.Data["tls.crt"]
 	if !ok {
@@ -109,6 +121,7 @@ func (d *IngressDiscoverer) findingFromIngressTLS(ctx context.Context, ing *netw
 
 	finding.ProbeOK = true
 	finding.NotAfter = cert.NotAfter
+	finding.NotBefore = cert.NotBefore
 	finding.DNSNames = cert.DNSNames
 	finding.Issuer = cert.Issuer.String()
 	finding.Subject = cert.Subject.String()
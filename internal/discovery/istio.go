@@ -147,6 +147,7 @@ func (d *IstioDiscoverer) discoverRootCertCM(ctx context.Context) (*store.CertFi
 
 	finding.ProbeOK = true
 	finding.NotAfter = cert.NotAfter
+	finding.NotBefore = cert.NotBefore
 	finding.DNSNames = cert.DNSNames
 	finding.Issuer = cert.Issuer.String()
 	finding.Subject = cert.Subject.String()
@@ -177,6 +178,7 @@ func (d *IstioDiscoverer) parseCertFromSecret(data map[string][]byte, key, secre
 
 	finding.ProbeOK = true
 	finding.NotAfter = cert.NotAfter
+	finding.NotBefore = cert.NotBefore
 	finding.DNSNames = cert.DNSNames
 	finding.Issuer = cert.Issuer.String()
 	finding.Subject = cert.Subject.String()
@@ -90,6 +90,7 @@ func (d *LinkerdDiscoverer) discoverTrustRoots(ctx context.Context) (*store.Cert
 
 	finding.ProbeOK = true
 	finding.NotAfter = cert.NotAfter
+	finding.NotBefore = cert.NotBefore
 	finding.DNSNames = cert.DNSNames
 	finding.Issuer = cert.Issuer.String()
 	finding.Subject = cert.Subject.String()
@@ -130,6 +131,7 @@ func (d *LinkerdDiscoverer) discoverIssuer(ctx context.Context) (*store.CertFind
 
 	finding.ProbeOK = true
 	finding.NotAfter = cert.NotAfter
+	finding.NotBefore = cert.NotBefore
 	finding.DNSNames = cert.DNSNames
 	finding.Issuer = cert.Issuer.String()
 	finding.Subject = cert.Subject.String()
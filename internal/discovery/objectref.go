@@ -0,0 +1,30 @@
+package discovery
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// objectRef builds a reference to the object a finding was discovered on.
+// Typed objects returned by List carry no TypeMeta, so callers pass the
+// apiVersion and kind explicitly.
+func objectRef(apiVersion, kind string, obj metav1.Object) *store.ObjectRef {
+	return &store.ObjectRef{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		UID:        string(obj.GetUID()),
+	}
+}
+
+// withObject sets ref on every finding that has no object yet and returns them.
+func withObject(findings []store.CertFinding, ref *store.ObjectRef) []store.CertFinding {
+	for i := range findings {
+		if findings[i].Object == nil {
+			findings[i].Object = ref
+		}
+	}
+	return findings
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/kubernetes"
 
 	"fmt"
 
@@ -26,11 +27,14 @@ const FindingManagedExpiry = "MANAGED_EXPIRY"
 // Orchestrator runs all discoverers concurrently and classifies findings.
 type Orchestrator struct {
 	tracer           trace.Tracer
+	nsClient         kubernetes.Interface
 	nowFn            func() time.Time
+	rotationCadence  func() map[string]time.Duration
 	discoverTimer    func(string, time.Duration)
 	crlCache         *revocation.CRLCache
 	ctClient         *ct.Client
 	prevSnap         *store.Snapshot
+	metadata         *store.Metadata
 	policies         []policy.TrustPolicy
 	discoverers      []Discoverer
 	ctDomains        []string
@@ -79,6 +83,30 @@ func WithDiscoverTimer(fn func(string, time.Duration)) OrchestratorOption {
 	}
 }
 
+// WithMetadata sets the base snapshot metadata (cluster, versions, scope).
+// Each run copies it and adds thresholds, discoverer timings and scan duration.
+func WithMetadata(md *store.Metadata) OrchestratorOption {
+	return func(o *Orchestrator) {
+		o.metadata = md
+	}
+}
+
+// WithNamespaceOwnership fills owner metadata from namespace annotations and
+// labels for findings whose own object has none.
+func WithNamespaceOwnership(client kubernetes.Interface) OrchestratorOption {
+	return func(o *Orchestrator) {
+		o.nsClient = client
+	}
+}
+
+// WithRotationCadence supplies observed rotation intervals, keyed by
+// rotation.Key, for the excessive rotation check. fn is called once per scan.
+func WithRotationCadence(fn func() map[string]time.Duration) OrchestratorOption {
+	return func(o *Orchestrator) {
+		o.rotationCadence = fn
+	}
+}
+
 // WithPolicies adds TrustPolicy CRs for policy engine evaluation.
 func WithPolicies(policies []policy.TrustPolicy) OrchestratorOption {
 	return func(o *Orchestrator) {
@@ -104,6 +132,7 @@ func NewOrchestrator(discoverers []Discoverer, warnBefore, critBefore time.Durat
 // Individual discoverer failures are logged but do not abort the run.
 // If ctx is canceled, in-flight discoverers that haven't returned are recorded as errors.
 func (o *Orchestrator) Run(ctx context.Context) store.Snapshot {
+	runStart := time.Now()
 	if o.tracer != nil {
 		var span trace.Span
 		ctx, span = o.tracer.Start(ctx, "discovery.run")
@@ -114,6 +143,7 @@ func (o *Orchestrator) Run(ctx context.Context) store.Snapshot {
 		err      error
 		name     string
 		findings []store.CertFinding
+		duration time.Duration
 	}
 
 	ch := make(chan result, len(o.discoverers))
@@ -129,15 +159,16 @@ func (o *Orchestrator) Run(ctx context.Context) store.Snapshot {
 			}
 			start := time.Now()
 			findings, err := d.Discover(ctx)
+			elapsed := time.Since(start)
 			if o.discoverTimer != nil {
-				o.discoverTimer(d.Name(), time.Since(start))
+				o.discoverTimer(d.Name(), elapsed)
 			}
 			// Check context before sending — if canceled, report timeout
 			if ctx.Err() != nil {
-				ch <- result{name: d.Name(), err: fmt.Errorf("scan timeout: %w", ctx.Err())}
+				ch <- result{name: d.Name(), err: fmt.Errorf("scan timeout: %w", ctx.Err()), duration: elapsed}
 				return
 			}
-			ch <- result{name: d.Name(), findings: findings, err: err}
+			ch <- result{name: d.Name(), findings: findings, err: err, duration: elapsed}
 		}(d)
 	}
 
@@ -147,13 +178,16 @@ func (o *Orchestrator) Run(ctx context.Context) store.Snapshot {
 	now := o.nowFn()
 	var allFindings []store.CertFinding
 	discoveryErrors := make(map[string]string)
+	runs := make(map[string]store.DiscovererRun, len(o.discoverers))
 
 	for r := range ch {
 		if r.err != nil {
 			slog.Warn("discoverer failed", "source", r.name, "err", r.err)
 			discoveryErrors[r.name] = r.err.Error()
+			runs[r.name] = store.DiscovererRun{Duration: r.duration, Error: r.err.Error()}
 			continue
 		}
+		runs[r.name] = store.DiscovererRun{Duration: r.duration, Findings: len(r.findings)}
 		slog.Debug("discoverer complete", "source", r.name, "findings", len(r.findings))
 		allFindings = append(allFindings, r.findings...)
 	}
@@ -162,7 +196,11 @@ func (o *Orchestrator) Run(ctx context.Context) store.Snapshot {
 	applyManagedExpiry(allFindings)
 
 	// Check for excessive rotation frequency
-	rotationFindings := rotation.Check(allFindings)
+	var observed map[string]time.Duration
+	if o.rotationCadence != nil {
+		observed = o.rotationCadence()
+	}
+	rotationFindings := rotation.CheckObserved(allFindings, observed)
 	allFindings = append(allFindings, rotationFindings...)
 
 	// Run revocation checks if enabled
@@ -231,6 +269,10 @@ func (o *Orchestrator) Run(ctx context.Context) store.Snapshot {
 		allFindings = append(allFindings, driftFindings...)
 	}
 
+	if o.nsClient != nil {
+		applyNamespaceOwnership(ctx, o.nsClient, allFindings)
+	}
+
 	snap := store.Snapshot{
 		At:       now,
 		Findings: allFindings,
@@ -238,9 +280,25 @@ func (o *Orchestrator) Run(ctx context.Context) store.Snapshot {
 	if len(discoveryErrors) > 0 {
 		snap.Errors = discoveryErrors
 	}
+	snap.Metadata = o.runMetadata(runs, time.Since(runStart))
 	return snap
 }
 
+// runMetadata copies the base metadata and fills in per-run fields.
+func (o *Orchestrator) runMetadata(runs map[string]store.DiscovererRun, elapsed time.Duration) *store.Metadata {
+	md := store.Metadata{}
+	if o.metadata != nil {
+		md = *o.metadata
+	}
+	md.WarnBefore = o.warnBefore
+	md.CritBefore = o.critBefore
+	md.ScanDuration = elapsed
+	if len(runs) > 0 {
+		md.Discoverers = runs
+	}
+	return &md
+}
+
 // classifyFindings applies severity based on time thresholds.
 func (o *Orchestrator) classifyFindings(findings []store.CertFinding, now time.Time) {
 	warnCutoff := now.Add(o.warnBefore)
@@ -367,6 +367,36 @@ func TestOrchestrator_SnapshotTimestamp(t *testing.T) {
 	}
 }
 
+func TestOrchestrator_Metadata(t *testing.T) {
+	scope := &store.Scope{Namespaces: []string{"app"}, Exclude: []string{"kube-*"}}
+	base := &store.Metadata{Cluster: "prod", Version: "1.2.3", Scope: scope}
+	o := NewOrchestrator([]Discoverer{
+		&stubDiscoverer{name: "secrets", findings: []store.CertFinding{{Source: store.SourceTLSSecret}}},
+		&stubDiscoverer{name: "istio", err: fmt.Errorf("forbidden")},
+	}, testWarnBefore, testCritBefore, WithMetadata(base))
+	snap := o.Run(context.Background())
+
+	md := snap.Metadata
+	if md == nil {
+		t.Fatal("expected snapshot metadata")
+	}
+	if md.Scope != scope || md.Cluster != "prod" || md.Version != "1.2.3" {
+		t.Errorf("expected base metadata to be carried over, got %+v", md)
+	}
+	if md.WarnBefore != testWarnBefore || md.CritBefore != testCritBefore {
+		t.Errorf("expected thresholds %v/%v, got %v/%v", testWarnBefore, testCritBefore, md.WarnBefore, md.CritBefore)
+	}
+	if run := md.Discoverers["secrets"]; run.Findings != 1 || run.Error != "" {
+		t.Errorf("unexpected secrets run: %+v", run)
+	}
+	if run := md.Discoverers["istio"]; run.Error != "forbidden" {
+		t.Errorf("expected istio run error, got %+v", run)
+	}
+	if base.Discoverers != nil {
+		t.Error("base metadata should not be mutated by a run")
+	}
+}
+
 func TestOrchestrator_ConcurrentExecution(t *testing.T) {
 	// Verify all discoverers run (order doesn't matter)
 	var discoverers []Discoverer
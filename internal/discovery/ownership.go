@@ -0,0 +1,94 @@
+package discovery
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+const (
+	annoOwner  = "trustwatch.dev/owner"
+	annoNotify = "trustwatch.dev/notify"
+)
+
+// ownerLabels are team labels used when no owner annotation is set, in order of preference.
+var ownerLabels = []string{"team", "owner", "app.kubernetes.io/team"}
+
+// ownership reads the owner and notification targets from an object's metadata.
+// The trustwatch.dev/owner annotation wins over team labels; trustwatch.dev/notify
+// is a comma-separated list of webhook names.
+func ownership(obj metav1.Object) (owner string, notify []string) {
+	annotations := obj.GetAnnotations()
+	owner = strings.TrimSpace(annotations[annoOwner])
+	if owner == "" {
+		labels := obj.GetLabels()
+		for _, l := range ownerLabels {
+			if v := labels[l]; v != "" {
+				owner = v
+				break
+			}
+		}
+	}
+	for _, name := range strings.Split(annotations[annoNotify], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			notify = append(notify, name)
+		}
+	}
+	return owner, notify
+}
+
+// setOwnership copies owner metadata from obj onto f, keeping values already set
+// from a more specific object.
+func setOwnership(f *store.CertFinding, obj metav1.Object) {
+	owner, notify := ownership(obj)
+	if f.Owner == "" {
+		f.Owner = owner
+	}
+	if len(f.Notify) == 0 {
+		f.Notify = notify
+	}
+}
+
+// withOwnership sets owner metadata from obj on every finding and returns them.
+func withOwnership(findings []store.CertFinding, obj metav1.Object) []store.CertFinding {
+	for i := range findings {
+		setOwnership(&findings[i], obj)
+	}
+	return findings
+}
+
+// applyNamespaceOwnership fills owner metadata from each finding's namespace
+// for findings whose own object carried none. Listing namespaces is
+// best-effort: without access, findings keep whatever owner they have.
+func applyNamespaceOwnership(ctx context.Context, client kubernetes.Interface, findings []store.CertFinding) {
+	needed := false
+	for i := range findings {
+		if findings[i].Namespace != "" && (findings[i].Owner == "" || len(findings[i].Notify) == 0) {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return
+	}
+
+	nsList, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		slog.Debug("listing namespaces for ownership", "err", err)
+		return
+	}
+	byName := make(map[string]metav1.Object, len(nsList.Items))
+	for i := range nsList.Items {
+		byName[nsList.Items[i].Name] = &nsList.Items[i]
+	}
+	for i := range findings {
+		if ns, ok := byName[findings[i].Namespace]; ok {
+			setOwnership(&findings[i], ns)
+		}
+	}
+}
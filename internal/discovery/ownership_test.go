@@ -0,0 +1,100 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func TestOwnership(t *testing.T) {
+	tests := []struct {
+		meta       metav1.ObjectMeta
+		name       string
+		wantOwner  string
+		wantNotify []string
+	}{
+		{name: "none", meta: metav1.ObjectMeta{}},
+		{name: "team label", meta: metav1.ObjectMeta{Labels: map[string]string{"team": "payments"}}, wantOwner: "payments"},
+		{name: "label order", meta: metav1.ObjectMeta{Labels: map[string]string{"owner": "b", "team": "a"}}, wantOwner: "a"},
+		{
+			name: "annotation wins",
+			meta: metav1.ObjectMeta{
+				Labels:      map[string]string{"team": "payments"},
+				Annotations: map[string]string{annoOwner: " checkout ", annoNotify: "checkout-slack, checkout-email,"},
+			},
+			wantOwner:  "checkout",
+			wantNotify: []string{"checkout-slack", "checkout-email"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner, notify := ownership(&tt.meta)
+			if owner != tt.wantOwner {
+				t.Errorf("owner = %q, want %q", owner, tt.wantOwner)
+			}
+			if len(notify) != len(tt.wantNotify) {
+				t.Fatalf("notify = %v, want %v", notify, tt.wantNotify)
+			}
+			for i := range notify {
+				if notify[i] != tt.wantNotify[i] {
+					t.Errorf("notify = %v, want %v", notify, tt.wantNotify)
+				}
+			}
+		})
+	}
+}
+
+func TestSecretDiscoverer_Ownership(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "api-tls", Namespace: "payments", UID: "secret-uid",
+			Annotations: map[string]string{annoOwner: "payments-team", annoNotify: "payments-slack"},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{"tls.crt": testCert(t, time.Now().Add(24*time.Hour), nil)},
+	}
+	findings, err := NewSecretDiscoverer(fake.NewClientset(secret)).Discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].Owner != "payments-team" || len(findings[0].Notify) != 1 {
+		t.Errorf("findings = %+v", findings)
+	}
+	want := store.ObjectRef{APIVersion: "v1", Kind: "Secret", Namespace: "payments", Name: "api-tls", UID: "secret-uid"}
+	if obj := findings[0].Object; obj == nil || *obj != want {
+		t.Errorf("object = %+v, want %+v", obj, want)
+	}
+}
+
+func TestApplyNamespaceOwnership(t *testing.T) {
+	client := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"team": "payments"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "search", Annotations: map[string]string{annoNotify: "search-slack"}}},
+	)
+	findings := []store.CertFinding{
+		{Name: "a", Namespace: "payments"},
+		{Name: "b", Namespace: "payments", Owner: "checkout"},
+		{Name: "c", Namespace: "search"},
+		{Name: "d"},
+	}
+	applyNamespaceOwnership(context.Background(), client, findings)
+
+	if findings[0].Owner != "payments" {
+		t.Errorf("namespace owner not applied: %+v", findings[0])
+	}
+	if findings[1].Owner != "checkout" {
+		t.Errorf("object owner overwritten by namespace: %+v", findings[1])
+	}
+	if findings[2].Owner != "" || len(findings[2].Notify) != 1 || findings[2].Notify[0] != "search-slack" {
+		t.Errorf("namespace notify not applied: %+v", findings[2])
+	}
+	if findings[3].Owner != "" {
+		t.Errorf("cluster-scoped finding got an owner: %+v", findings[3])
+	}
+}
@@ -0,0 +1,87 @@
+package discovery
+
+// Permission is a Kubernetes API access a discoverer needs.
+type Permission struct {
+	Verb       string
+	Group      string
+	Resource   string
+	Namespace  string // fixed namespace; empty means the discoverer's namespace scope
+	Namespaced bool   // false for cluster-scoped resources
+}
+
+// APIRequirement is an optional API (usually a CRD) a discoverer reads.
+// When the API is not served the discoverer returns no findings.
+type APIRequirement struct {
+	Name         string // human-readable name, e.g. "cert-manager"
+	GroupVersion string
+	Resource     string
+}
+
+// Requirement describes what a built-in discoverer needs from the cluster.
+type Requirement struct {
+	Name        string
+	Namespace   string // namespace that must exist for the discoverer to report (mesh installs)
+	Permissions []Permission
+	APIs        []APIRequirement
+}
+
+// Optional APIs read by built-in discoverers and the policy engine.
+var (
+	APICertManager     = APIRequirement{Name: "cert-manager", GroupVersion: "cert-manager.io/v1", Resource: "certificates"}
+	APICertManagerACME = APIRequirement{Name: "cert-manager ACME", GroupVersion: "acme.cert-manager.io/v1", Resource: "challenges"}
+	APIGateway         = APIRequirement{Name: "Gateway API", GroupVersion: "gateway.networking.k8s.io/v1", Resource: "gateways"}
+	APITrustPolicy     = APIRequirement{Name: "TrustPolicy", GroupVersion: "trustwatch.dev/v1alpha1", Resource: "trustpolicies"}
+)
+
+func listPerm(group, resource string) Permission {
+	return Permission{Verb: "list", Group: group, Resource: resource, Namespaced: true}
+}
+
+func getPerm(group, resource string) Permission {
+	return Permission{Verb: "get", Group: group, Resource: resource, Namespaced: true}
+}
+
+func getPermIn(namespace, group, resource string) Permission {
+	return Permission{Verb: "get", Group: group, Resource: resource, Namespace: namespace, Namespaced: true}
+}
+
+// Requirements lists the cluster access each built-in discoverer needs, in build order.
+// Cloud discoverers are not included; they authenticate against their provider.
+func Requirements() []Requirement {
+	return []Requirement{
+		{Name: "webhooks", Permissions: []Permission{
+			{Verb: "list", Group: "admissionregistration.k8s.io", Resource: "validatingwebhookconfigurations"},
+			{Verb: "list", Group: "admissionregistration.k8s.io", Resource: "mutatingwebhookconfigurations"},
+		}},
+		{Name: "apiservices", Permissions: []Permission{
+			{Verb: "list", Group: "apiregistration.k8s.io", Resource: "apiservices"},
+		}},
+		{Name: "apiserver"},
+		{Name: "secrets", Permissions: []Permission{listPerm("", "secrets")}},
+		{Name: "ingress", Permissions: []Permission{listPerm("networking.k8s.io", "ingresses"), getPerm("", "secrets")}},
+		{Name: "linkerd", Namespace: linkerdNamespace, Permissions: []Permission{
+			getPermIn(linkerdNamespace, "", "configmaps"),
+			getPermIn(linkerdNamespace, "", "secrets"),
+		}},
+		{Name: "istio", Namespace: istioNamespace, Permissions: []Permission{
+			getPermIn(istioNamespace, "", "configmaps"),
+			getPermIn(istioNamespace, "", "secrets"),
+		}},
+		{Name: "annotations", Permissions: []Permission{
+			listPerm("", "services"), listPerm("apps", "deployments"), getPerm("", "secrets"),
+		}},
+		{Name: "gateway", APIs: []APIRequirement{APIGateway}, Permissions: []Permission{
+			listPerm("gateway.networking.k8s.io", "gateways"), getPerm("", "secrets"),
+		}},
+		{Name: "certmanager", APIs: []APIRequirement{APICertManager}, Permissions: []Permission{
+			listPerm("cert-manager.io", "certificates"), getPerm("", "secrets"),
+		}},
+		{Name: "certmanager.renewal", APIs: []APIRequirement{APICertManager, APICertManagerACME}, Permissions: []Permission{
+			listPerm("cert-manager.io", "certificates"),
+			listPerm("cert-manager.io", "certificaterequests"),
+			listPerm("acme.cert-manager.io", "challenges"),
+		}},
+		{Name: "externals"},
+		{Name: "spiffe"},
+	}
+}
@@ -15,8 +15,9 @@ const errMissingTLSCrt = "missing tls.crt key"
 
 // SecretDiscoverer inventories TLS certificates stored in kubernetes.io/tls Secrets.
 type SecretDiscoverer struct {
-	client     kubernetes.Interface
-	namespaces []string
+	client        kubernetes.Interface
+	labelSelector string
+	namespaces    []string
 }
 
 // NewSecretDiscoverer creates a discoverer that parses TLS Secrets for certificate metadata.
@@ -35,6 +36,13 @@ func WithSecretNamespaces(ns []string) func(*SecretDiscoverer) {
 	}
 }
 
+// WithSecretLabelSelector restricts discovery to Secrets matching the label selector.
+func WithSecretLabelSelector(selector string) func(*SecretDiscoverer) {
+	return func(d *SecretDiscoverer) {
+		d.labelSelector = selector
+	}
+}
+
 // Name returns the discoverer label.
 func (d *SecretDiscoverer) Name() string {
 	return "secrets"
@@ -45,7 +53,7 @@ func (d *SecretDiscoverer) Discover(ctx context.Context) ([]store.CertFinding, e
 	var findings []store.CertFinding
 
 	for _, ns := range namespacesOrAll(d.namespaces) {
-		secrets, err := d.client.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{})
+		secrets, err := d.client.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{LabelSelector: d.labelSelector})
 		if err != nil {
 			return nil, fmt.Errorf("listing secrets: %w", err)
 		}
@@ -61,7 +69,9 @@ func (d *SecretDiscoverer) Discover(ctx context.Context) ([]store.CertFinding, e
 				Severity:  store.SeverityInfo,
 				Namespace: s.Namespace,
 				Name:      s.Name,
+				Object:    objectRef("v1", "Secret", s),
 			}
+			setOwnership(&finding, s)
 
 			pemData, ok := s.Data["tls.crt"]
 			if !ok {
@@ -79,6 +89,7 @@ func (d *SecretDiscoverer) Discover(ctx context.Context) ([]store.CertFinding, e
 
 			finding.ProbeOK = true
 			finding.NotAfter = cert.NotAfter
+			finding.NotBefore = cert.NotBefore
 			finding.DNSNames = cert.DNSNames
 			finding.Issuer = cert.Issuer.String()
 			finding.Subject = cert.Subject.String()
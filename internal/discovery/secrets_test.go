@@ -303,3 +303,29 @@ func TestSecretDiscoverer_NamespaceFiltered(t *testing.T) {
 		t.Errorf("expected finding[1] namespace ns3, got %q", findings[1].Namespace)
 	}
 }
+
+func TestSecretDiscoverer_LabelSelector(t *testing.T) {
+	pemData := testCert(t, time.Now().Add(30*24*time.Hour), []string{"example.com"})
+	matching := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments-tls", Namespace: "default", Labels: map[string]string{"team": "payments"}},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{"tls.crt": pemData},
+	}
+	other := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "search-tls", Namespace: "default", Labels: map[string]string{"team": "search"}},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{"tls.crt": pemData},
+	}
+
+	d := NewSecretDiscoverer(fake.NewClientset(matching, other), WithSecretLabelSelector("team=payments"))
+	findings, err := d.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	if findings[0].Name != "payments-tls" {
+		t.Errorf("expected payments-tls, got %q", findings[0].Name)
+	}
+}
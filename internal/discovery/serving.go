@@ -0,0 +1,69 @@
+package discovery
+
+import (
+	"context"
+	"crypto/x509"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/chain"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// ServingDiscoverer reports the certificate trustwatch serve itself listens
+// with, so its own expiry is tracked like any other trust surface.
+type ServingDiscoverer struct {
+	chainFn  func() []*x509.Certificate
+	target   string
+	certFile string
+}
+
+// NewServingDiscoverer creates a discoverer for the serving certificate.
+// chainFn returns the current chain, leaf first; target is the listen address.
+func NewServingDiscoverer(chainFn func() []*x509.Certificate, target, certFile string) *ServingDiscoverer {
+	return &ServingDiscoverer{chainFn: chainFn, target: target, certFile: certFile}
+}
+
+// Name returns the discoverer label.
+func (d *ServingDiscoverer) Name() string {
+	return "serving"
+}
+
+// Discover returns a single finding for the serving certificate currently loaded.
+func (d *ServingDiscoverer) Discover(_ context.Context) ([]store.CertFinding, error) {
+	certs := d.chainFn()
+	finding := store.CertFinding{
+		Source:   store.SourceServing,
+		Severity: store.SeverityInfo,
+		Name:     "trustwatch",
+		Target:   d.target,
+		Notes:    "serving certificate " + d.certFile,
+		ProbeOK:  len(certs) > 0,
+	}
+	if len(certs) == 0 {
+		finding.ProbeErr = "no serving certificate loaded"
+		return []store.CertFinding{finding}, nil
+	}
+
+	leaf := certs[0]
+	finding.NotAfter = leaf.NotAfter
+	finding.NotBefore = leaf.NotBefore
+	finding.DNSNames = leaf.DNSNames
+	finding.Issuer = leaf.Issuer.String()
+	finding.Subject = leaf.Subject.String()
+	finding.Serial = leaf.SerialNumber.String()
+	finding.ChainLen = len(certs)
+	finding.RawCert = leaf
+	applyCertMetadata(&finding, leaf, certs)
+	if result := chain.ValidateChain(certs, "", time.Now()); len(result.Errors) > 0 {
+		finding.ChainErrors = result.Errors
+	}
+	if len(certs) > 1 {
+		finding.RawIssuer = certs[1]
+		issuerChain := make([]string, 0, len(certs)-1)
+		for _, c := range certs[1:] {
+			issuerChain = append(issuerChain, c.Subject.String())
+		}
+		finding.IssuerChain = issuerChain
+	}
+	return []store.CertFinding{finding}, nil
+}
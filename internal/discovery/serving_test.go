@@ -0,0 +1,56 @@
+package discovery
+
+import (
+	"context"
+	"crypto/x509"
+	"testing"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func TestServingDiscoverer_Discover(t *testing.T) {
+	_, leaf := generateTestCert(t)
+	d := NewServingDiscoverer(func() []*x509.Certificate { return []*x509.Certificate{leaf} }, ":8443", "/etc/trustwatch/tls/tls.crt")
+	if d.Name() != "serving" {
+		t.Errorf("expected name %q, got %q", "serving", d.Name())
+	}
+
+	findings, err := d.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	f := findings[0]
+	if f.Source != store.SourceServing || f.Name != "trustwatch" || f.Target != ":8443" {
+		t.Errorf("unexpected finding identity: source=%q name=%q target=%q", f.Source, f.Name, f.Target)
+	}
+	if !f.ProbeOK || !f.NotAfter.Equal(leaf.NotAfter) || f.Serial != "42" {
+		t.Errorf("unexpected certificate fields: probeOK=%v notAfter=%v serial=%q", f.ProbeOK, f.NotAfter, f.Serial)
+	}
+	if !f.SelfSigned || f.KeyAlgorithm != "ECDSA" {
+		t.Errorf("expected a self-signed ECDSA certificate, got selfSigned=%v key=%q", f.SelfSigned, f.KeyAlgorithm)
+	}
+
+	empty := NewServingDiscoverer(func() []*x509.Certificate { return nil }, ":8443", "")
+	findings, err = empty.Discover(context.Background())
+	if err != nil || len(findings) != 1 || findings[0].ProbeOK {
+		t.Errorf("expected a failed finding without a certificate, got %+v, %v", findings, err)
+	}
+}
+
+func TestBuild_ServingDiscoverer(t *testing.T) {
+	_, leaf := generateTestCert(t)
+	cfg := config.Defaults()
+	discoverers, _, err := Build(context.Background(), builderClients(testNS1), cfg, BuildOptions{
+		ServingChain: func() []*x509.Certificate { return []*x509.Certificate{leaf} },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := discovererNames(discoverers)["serving"]; !ok {
+		t.Error("expected the serving discoverer with a serving certificate")
+	}
+}
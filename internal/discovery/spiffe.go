@@ -68,6 +68,7 @@ func findingsFromBundles(bundles map[string][]*x509.Certificate) []store.CertFin
 		for _, cert := range certs {
 			f := store.CertFinding{
 				NotAfter:  cert.NotAfter,
+				NotBefore: cert.NotBefore,
 				Name:      cert.Subject.CommonName,
 				Namespace: td,
 				Source:    store.SourceSPIFFE,
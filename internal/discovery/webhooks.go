@@ -66,6 +66,7 @@ func (d *WebhookDiscoverer) Discover(ctx context.Context) ([]store.CertFinding,
 		for j := range vwcs.Items[i].Webhooks {
 			wh := &vwcs.Items[i].Webhooks[j]
 			if f, ok := d.processWebhook(vwcs.Items[i].Name, wh.Name, wh.FailurePolicy, &wh.ClientConfig); ok {
+				f.Object = objectRef("admissionregistration.k8s.io/v1", "ValidatingWebhookConfiguration", &vwcs.Items[i])
 				findings = append(findings, f)
 			}
 		}
@@ -75,6 +76,7 @@ func (d *WebhookDiscoverer) Discover(ctx context.Context) ([]store.CertFinding,
 		for j := range mwcs.Items[i].Webhooks {
 			wh := &mwcs.Items[i].Webhooks[j]
 			if f, ok := d.processWebhook(mwcs.Items[i].Name, wh.Name, wh.FailurePolicy, &wh.ClientConfig); ok {
+				f.Object = objectRef("admissionregistration.k8s.io/v1", "MutatingWebhookConfiguration", &mwcs.Items[i])
 				findings = append(findings, f)
 			}
 		}
@@ -117,11 +119,13 @@ func (d *WebhookDiscoverer) processWebhook(configName, webhookName string, failu
 
 	if result.ProbeOK && result.Cert != nil {
 		finding.NotAfter = result.Cert.NotAfter
+		finding.NotBefore = result.Cert.NotBefore
 		finding.DNSNames = result.Cert.DNSNames
 		finding.Issuer = result.Cert.Issuer.String()
 		finding.Subject = result.Cert.Subject.String()
 		finding.Serial = result.Cert.SerialNumber.String()
 		applyProbeChainValidation(&finding, &result, extractHostFromTarget(target))
+		applyCABundleCheck(&finding, clientConfig.CABundle, result.Chain)
 	}
 
 	return finding, true
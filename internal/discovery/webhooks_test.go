@@ -3,6 +3,7 @@ package discovery
 import (
 	"context"
 	"crypto/x509"
+	"encoding/pem"
 	"math/big"
 	"testing"
 	"time"
@@ -88,6 +89,9 @@ func TestWebhookDiscoverer_ValidatingFailPolicy(t *testing.T) {
 	if !f.NotAfter.Equal(notAfter) {
 		t.Errorf("expected NotAfter %v, got %v", notAfter, f.NotAfter)
 	}
+	if f.Object == nil || f.Object.Kind != "ValidatingWebhookConfiguration" || f.Object.Name != "my-vwc" || f.Object.Namespace != "" {
+		t.Errorf("expected reference to the webhook configuration, got %+v", f.Object)
+	}
 }
 
 func TestWebhookDiscoverer_MutatingIgnorePolicy(t *testing.T) {
@@ -409,3 +413,71 @@ func mockProbeFn(notAfter time.Time) func(string) probe.Result {
 		}
 	}
 }
+
+func TestApplyCABundleCheck(t *testing.T) {
+	servedPEM := testCert(t, time.Now().Add(24*time.Hour), nil)
+	served, err := x509.ParseCertificate(mustDecodePEM(t, servedPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var match store.CertFinding
+	applyCABundleCheck(&match, servedPEM, []*x509.Certificate{served})
+	if match.FindingType != "" || len(match.ChainErrors) != 0 {
+		t.Errorf("matching caBundle flagged: %+v", match)
+	}
+
+	var mismatch store.CertFinding
+	applyCABundleCheck(&mismatch, testCert(t, time.Now().Add(24*time.Hour), nil), []*x509.Certificate{served})
+	if mismatch.FindingType != FindingCABundleMismatch || len(mismatch.ChainErrors) != 1 {
+		t.Errorf("expected CA bundle mismatch, got %+v", mismatch)
+	}
+
+	var unset store.CertFinding
+	applyCABundleCheck(&unset, nil, []*x509.Certificate{served})
+	if unset.FindingType != "" {
+		t.Errorf("empty caBundle flagged: %+v", unset)
+	}
+}
+
+func TestWebhookDiscoverer_CABundleMismatch(t *testing.T) {
+	servedPEM := testCert(t, time.Now().Add(24*time.Hour), nil)
+	served, err := x509.ParseCertificate(mustDecodePEM(t, servedPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := int32(443)
+	vwc := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "stale-vwc"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{
+				Name: "validate.example.com",
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service:  &admissionregistrationv1.ServiceReference{Name: "webhook-svc", Namespace: "webhook-ns", Port: &port},
+					CABundle: testCert(t, time.Now().Add(24*time.Hour), nil),
+				},
+			},
+		},
+	}
+
+	d := NewWebhookDiscoverer(fake.NewClientset(vwc))
+	d.probeFn = func(string) probe.Result {
+		return probe.Result{ProbeOK: true, Cert: served, Chain: []*x509.Certificate{served}}
+	}
+	findings, err := d.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(findings) != 1 || findings[0].FindingType != FindingCABundleMismatch {
+		t.Fatalf("expected a CA bundle mismatch finding, got %+v", findings)
+	}
+}
+
+func mustDecodePEM(t *testing.T, data []byte) []byte {
+	t.Helper()
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatal("no PEM block")
+	}
+	return block.Bytes
+}
@@ -0,0 +1,458 @@
+// Package doctor diagnoses why a trustwatch scan may be missing findings:
+// RBAC gaps, missing CRDs, unreachable endpoints, and probe connectivity.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	authv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/discovery"
+	"github.com/ppiankov/trustwatch/internal/probe"
+)
+
+// Status is the outcome of a single check.
+type Status string
+
+// Check outcomes, ordered from best to worst.
+const (
+	StatusSkipped Status = "skipped"
+	StatusOK      Status = "ok"
+	StatusWarn    Status = "warn"
+	StatusFail    Status = "fail"
+)
+
+const dialTimeout = 3 * time.Second
+
+// maxListedNamespaces caps how many denied namespaces are named in a check detail.
+const maxListedNamespaces = 5
+
+// Check is a single diagnostic result.
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// DiscovererReport is the readiness of one discoverer.
+type DiscovererReport struct {
+	Name   string  `json:"name"`
+	Status Status  `json:"status"`
+	Checks []Check `json:"checks,omitempty"`
+}
+
+// APIReport records whether an optional API (CRD) is served by the cluster.
+type APIReport struct {
+	Name         string `json:"name"`
+	GroupVersion string `json:"groupVersion"`
+	Resource     string `json:"resource"`
+	Installed    bool   `json:"installed"`
+}
+
+// ProbeReport is the result of test-probing one target directly and, if a
+// tunnel is available, through it.
+type ProbeReport struct {
+	Tunnel *Check `json:"tunnel,omitempty"`
+	Target string `json:"target"`
+	Direct Check  `json:"direct"`
+}
+
+// Report is the full doctor output.
+type Report struct {
+	Discoverers []DiscovererReport `json:"discoverers"`
+	APIs        []APIReport        `json:"apis"`
+	Endpoints   []Check            `json:"endpoints"`
+	Probes      []ProbeReport      `json:"probes,omitempty"`
+}
+
+// Failures returns the number of discoverers and endpoints that failed.
+func (r *Report) Failures() int {
+	n := 0
+	for i := range r.Discoverers {
+		if r.Discoverers[i].Status == StatusFail {
+			n++
+		}
+	}
+	for i := range r.Endpoints {
+		if r.Endpoints[i].Status == StatusFail {
+			n++
+		}
+	}
+	return n
+}
+
+// Doctor runs readiness checks against a cluster and configuration.
+type Doctor struct {
+	client        kubernetes.Interface
+	cfg           *config.Config
+	probeFn       func(string) probe.Result
+	tunnelProbeFn func(string) probe.Result
+	dialFn        func(ctx context.Context, network, addr string) (net.Conn, error)
+	statFn        func(string) (os.FileInfo, error)
+	extraChecks   []DiscovererReport
+	namespaces    []string
+	targets       []string
+}
+
+// Option configures a Doctor.
+type Option func(*Doctor)
+
+// WithNamespaces sets the resolved namespace scope used for namespaced RBAC checks.
+func WithNamespaces(namespaces []string) Option {
+	return func(d *Doctor) {
+		d.namespaces = namespaces
+	}
+}
+
+// WithProbeTargets sets the targets to test-probe.
+func WithProbeTargets(targets []string) Option {
+	return func(d *Doctor) {
+		d.targets = targets
+	}
+}
+
+// WithProbeFn overrides the direct probe function (default: probe.Probe).
+func WithProbeFn(fn func(string) probe.Result) Option {
+	return func(d *Doctor) {
+		d.probeFn = fn
+	}
+}
+
+// WithTunnelProbeFn enables probing each target through the SOCKS5 tunnel as well.
+func WithTunnelProbeFn(fn func(string) probe.Result) Option {
+	return func(d *Doctor) {
+		d.tunnelProbeFn = fn
+	}
+}
+
+// WithDialFn overrides the dialer used for endpoint reachability checks.
+func WithDialFn(fn func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(d *Doctor) {
+		d.dialFn = fn
+	}
+}
+
+// WithDiscoverers adds readiness entries for discoverers outside the built-in
+// requirement table (e.g. build-tagged cloud discoverers).
+func WithDiscoverers(reports ...DiscovererReport) Option {
+	return func(d *Doctor) {
+		d.extraChecks = append(d.extraChecks, reports...)
+	}
+}
+
+// New creates a Doctor for the given cluster client and configuration.
+func New(client kubernetes.Interface, cfg *config.Config, opts ...Option) *Doctor {
+	d := &Doctor{
+		client:  client,
+		cfg:     cfg,
+		probeFn: probe.Probe,
+		dialFn:  (&net.Dialer{Timeout: dialTimeout}).DialContext,
+		statFn:  os.Stat,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run executes all checks and returns the report.
+func (d *Doctor) Run(ctx context.Context) Report {
+	var r Report
+
+	installed := make(map[string]bool)
+	for _, api := range []discovery.APIRequirement{
+		discovery.APICertManager, discovery.APICertManagerACME, discovery.APIGateway, discovery.APITrustPolicy,
+	} {
+		ok := d.apiServed(api)
+		installed[api.GroupVersion+"/"+api.Resource] = ok
+		r.APIs = append(r.APIs, APIReport{
+			Name:         api.Name,
+			GroupVersion: api.GroupVersion,
+			Resource:     api.Resource,
+			Installed:    ok,
+		})
+	}
+
+	spiffe := d.checkSPIFFE()
+	r.Endpoints = []Check{d.checkAPIServer(), spiffe, d.checkOTel(ctx)}
+
+	for _, req := range discovery.Requirements() {
+		r.Discoverers = append(r.Discoverers, d.checkDiscoverer(ctx, req, installed, spiffe))
+	}
+	r.Discoverers = append(r.Discoverers, d.extraChecks...)
+
+	for _, target := range d.targets {
+		pr := ProbeReport{Target: target, Direct: probeCheck("direct", d.probeFn(target))}
+		if d.tunnelProbeFn != nil {
+			tc := probeCheck("tunnel", d.tunnelProbeFn(target))
+			pr.Tunnel = &tc
+		}
+		r.Probes = append(r.Probes, pr)
+	}
+	return r
+}
+
+// checkDiscoverer evaluates one discoverer's configuration, APIs and RBAC.
+func (d *Doctor) checkDiscoverer(ctx context.Context, req discovery.Requirement, installed map[string]bool, spiffe Check) DiscovererReport {
+	rep := DiscovererReport{Name: req.Name}
+	dc := d.cfg.Discoverer(req.Name)
+	if !dc.IsEnabled() {
+		rep.Status = StatusSkipped
+		rep.Checks = []Check{{Name: "config", Status: StatusSkipped, Detail: "disabled in config"}}
+		return rep
+	}
+
+	switch req.Name {
+	case "externals":
+		if len(d.cfg.External) == 0 {
+			rep.Checks = append(rep.Checks, Check{Name: "config", Status: StatusSkipped, Detail: "no external targets configured"})
+		} else {
+			rep.Checks = append(rep.Checks, Check{Name: "config", Status: StatusOK, Detail: fmt.Sprintf("%d target(s) configured", len(d.cfg.External))})
+		}
+	case "spiffe":
+		rep.Checks = append(rep.Checks, spiffe)
+	}
+
+	for _, api := range req.APIs {
+		if !installed[api.GroupVersion+"/"+api.Resource] {
+			rep.Checks = append(rep.Checks, Check{
+				Name:   "api " + api.GroupVersion,
+				Status: StatusWarn,
+				Detail: fmt.Sprintf("%s not installed; discoverer will find nothing", api.Name),
+			})
+		}
+	}
+
+	if req.Namespace != "" {
+		rep.Checks = append(rep.Checks, d.checkNamespaceExists(ctx, req.Namespace))
+	}
+
+	namespaces := discovery.ScopeNamespaces(d.namespaces, dc)
+	for _, perm := range req.Permissions {
+		rep.Checks = append(rep.Checks, d.checkPermission(ctx, perm, namespaces))
+	}
+
+	rep.Status = worst(rep.Checks)
+	return rep
+}
+
+// checkPermission runs SelfSubjectAccessReviews for a permission. Namespaced
+// permissions are checked cluster-wide first and per namespace only when the
+// cluster-wide check is denied.
+func (d *Doctor) checkPermission(ctx context.Context, perm discovery.Permission, namespaces []string) Check {
+	c := Check{Name: permissionName(perm)}
+
+	ns := perm.Namespace
+	allowed, err := d.review(ctx, perm, ns)
+	switch {
+	case err != nil:
+		c.Status = StatusWarn
+		c.Detail = fmt.Sprintf("access check failed: %v", err)
+		return c
+	case allowed:
+		c.Status = StatusOK
+		c.Detail = "allowed"
+		if perm.Namespaced && ns == "" {
+			c.Detail = "allowed in all namespaces"
+		}
+		return c
+	case !perm.Namespaced || ns != "" || len(namespaces) == 0:
+		c.Status = StatusFail
+		c.Detail = "denied"
+		return c
+	}
+
+	var denied, unchecked []string
+	var firstErr error
+	for _, n := range namespaces {
+		ok, reviewErr := d.review(ctx, perm, n)
+		switch {
+		case reviewErr != nil:
+			unchecked = append(unchecked, n)
+			if firstErr == nil {
+				firstErr = reviewErr
+			}
+		case !ok:
+			denied = append(denied, n)
+		}
+	}
+	switch {
+	case len(unchecked) > 0:
+		c.Status = StatusWarn
+		c.Detail = fmt.Sprintf("access check failed in %d of %d namespace(s): %s: %v",
+			len(unchecked), len(namespaces), summarize(unchecked), firstErr)
+		if len(denied) > 0 {
+			c.Detail += fmt.Sprintf("; denied in %s", summarize(denied))
+		}
+	case len(denied) == 0:
+		c.Status = StatusOK
+		c.Detail = fmt.Sprintf("allowed in %d namespace(s)", len(namespaces))
+	case len(denied) == len(namespaces):
+		c.Status = StatusFail
+		c.Detail = fmt.Sprintf("denied in all %d namespace(s)", len(namespaces))
+	default:
+		c.Status = StatusWarn
+		c.Detail = fmt.Sprintf("denied in %d of %d namespace(s): %s", len(denied), len(namespaces), summarize(denied))
+	}
+	return c
+}
+
+func (d *Doctor) review(ctx context.Context, perm discovery.Permission, namespace string) (bool, error) {
+	review := &authv1.SelfSubjectAccessReview{
+		Spec: authv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      perm.Verb,
+				Group:     perm.Group,
+				Resource:  perm.Resource,
+			},
+		},
+	}
+	result, err := d.client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return result.Status.Allowed, nil
+}
+
+func (d *Doctor) checkNamespaceExists(ctx context.Context, namespace string) Check {
+	c := Check{Name: "namespace " + namespace}
+	_, err := d.client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		c.Status = StatusOK
+		c.Detail = "present"
+	case apierrors.IsNotFound(err):
+		c.Status = StatusWarn
+		c.Detail = "not found; mesh not installed"
+	default:
+		c.Status = StatusWarn
+		c.Detail = fmt.Sprintf("lookup failed: %v", err)
+	}
+	return c
+}
+
+func (d *Doctor) apiServed(api discovery.APIRequirement) bool {
+	list, err := d.client.Discovery().ServerResourcesForGroupVersion(api.GroupVersion)
+	if err != nil || list == nil {
+		return false
+	}
+	for i := range list.APIResources {
+		if list.APIResources[i].Name == api.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Doctor) checkAPIServer() Check {
+	c := Check{Name: "kubernetes-api"}
+	info, err := d.client.Discovery().ServerVersion()
+	if err != nil {
+		c.Status = StatusFail
+		c.Detail = fmt.Sprintf("unreachable: %v", err)
+		return c
+	}
+	c.Status = StatusOK
+	c.Detail = "Kubernetes " + info.GitVersion
+	return c
+}
+
+func (d *Doctor) checkSPIFFE() Check {
+	c := Check{Name: "spiffe-socket"}
+	socket := d.cfg.SPIFFESocket
+	if socket == "" {
+		c.Status = StatusSkipped
+		c.Detail = "no socket configured"
+		return c
+	}
+	info, err := d.statFn(socket)
+	switch {
+	case err != nil:
+		c.Status = StatusFail
+		c.Detail = fmt.Sprintf("%s: %v", socket, err)
+	case info.Mode()&os.ModeSocket == 0:
+		c.Status = StatusFail
+		c.Detail = socket + " is not a socket"
+	default:
+		c.Status = StatusOK
+		c.Detail = socket
+	}
+	return c
+}
+
+func (d *Doctor) checkOTel(ctx context.Context) Check {
+	c := Check{Name: "otel-endpoint"}
+	endpoint := d.cfg.OTelEndpoint
+	if endpoint == "" {
+		c.Status = StatusSkipped
+		c.Detail = "no endpoint configured"
+		return c
+	}
+	conn, err := d.dialFn(ctx, "tcp", endpoint)
+	if err != nil {
+		c.Status = StatusFail
+		c.Detail = fmt.Sprintf("%s: %v", endpoint, err)
+		return c
+	}
+	conn.Close() //nolint:errcheck // reachability check only
+	c.Status = StatusOK
+	c.Detail = endpoint
+	return c
+}
+
+// probeCheck converts a probe result into a check. Probe failures are warnings:
+// cluster-internal targets are expected to fail when probed directly.
+func probeCheck(name string, res probe.Result) Check {
+	if !res.ProbeOK {
+		return Check{Name: name, Status: StatusWarn, Detail: res.ProbeErr}
+	}
+	detail := "TLS handshake ok"
+	if res.Cert != nil {
+		detail = fmt.Sprintf("TLS handshake ok, certificate expires %s", res.Cert.NotAfter.UTC().Format("2006-01-02"))
+	}
+	return Check{Name: name, Status: StatusOK, Detail: detail}
+}
+
+func permissionName(perm discovery.Permission) string {
+	resource := perm.Resource
+	if perm.Group != "" {
+		resource += "." + perm.Group
+	}
+	name := perm.Verb + " " + resource
+	if perm.Namespace != "" {
+		name += " in " + perm.Namespace
+	}
+	return name
+}
+
+// worst returns the most severe status among checks, or ok when there are none.
+func worst(checks []Check) Status {
+	rank := map[Status]int{StatusSkipped: 0, StatusOK: 1, StatusWarn: 2, StatusFail: 3}
+	if len(checks) == 0 {
+		return StatusOK
+	}
+	status := StatusSkipped
+	for i := range checks {
+		if rank[checks[i].Status] > rank[status] {
+			status = checks[i].Status
+		}
+	}
+	return status
+}
+
+func summarize(namespaces []string) string {
+	if len(namespaces) <= maxListedNamespaces {
+		return strings.Join(namespaces, ", ")
+	}
+	return strings.Join(namespaces[:maxListedNamespaces], ", ") + fmt.Sprintf(" and %d more", len(namespaces)-maxListedNamespaces)
+}
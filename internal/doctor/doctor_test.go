@@ -0,0 +1,216 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	authv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/probe"
+)
+
+// doctorClient returns a fake clientset whose SSARs are decided by allow.
+func doctorClient(allow func(attrs *authv1.ResourceAttributes) bool, objs ...runtime.Object) *fake.Clientset {
+	cs := fake.NewClientset(objs...)
+	cs.PrependReactor("create", "selfsubjectaccessreviews",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			review := action.(k8stesting.CreateAction).GetObject().(*authv1.SelfSubjectAccessReview)
+			review.Status.Allowed = allow(review.Spec.ResourceAttributes)
+			return true, review, nil
+		})
+	return cs
+}
+
+func allowAll(*authv1.ResourceAttributes) bool { return true }
+
+func reportByName(r *Report) map[string]DiscovererReport {
+	byName := make(map[string]DiscovererReport, len(r.Discoverers))
+	for _, d := range r.Discoverers {
+		byName[d.Name] = d
+	}
+	return byName
+}
+
+func TestRun_AllAllowed(t *testing.T) {
+	cs := doctorClient(allowAll,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "linkerd"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "istio-system"}})
+	cs.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{GroupVersion: "cert-manager.io/v1", APIResources: []metav1.APIResource{{Name: "certificates"}}},
+		{GroupVersion: "acme.cert-manager.io/v1", APIResources: []metav1.APIResource{{Name: "challenges"}}},
+		{GroupVersion: "gateway.networking.k8s.io/v1", APIResources: []metav1.APIResource{{Name: "gateways"}}},
+	}
+
+	r := New(cs, config.Defaults()).Run(context.Background())
+
+	byName := reportByName(&r)
+	for _, name := range []string{"webhooks", "secrets", "linkerd", "istio", "gateway", "certmanager", "certmanager.renewal"} {
+		if byName[name].Status != StatusOK {
+			t.Errorf("expected %s ok, got %+v", name, byName[name])
+		}
+	}
+	if byName["externals"].Status != StatusSkipped {
+		t.Errorf("expected externals skipped without targets, got %s", byName["externals"].Status)
+	}
+	for _, api := range r.APIs {
+		want := api.Name != "TrustPolicy"
+		if api.Installed != want {
+			t.Errorf("API %s installed = %v, want %v", api.Name, api.Installed, want)
+		}
+	}
+	if r.Failures() != 0 {
+		t.Errorf("expected no failures, got %d", r.Failures())
+	}
+}
+
+func TestRun_RBACAndMissingCRDs(t *testing.T) {
+	cs := doctorClient(func(attrs *authv1.ResourceAttributes) bool {
+		switch attrs.Resource {
+		case "apiservices":
+			return false
+		case "secrets":
+			// denied cluster-wide and in team-b only
+			return attrs.Namespace != "" && attrs.Namespace != "team-b"
+		}
+		return true
+	})
+
+	r := New(cs, config.Defaults(), WithNamespaces([]string{"team-a", "team-b"})).Run(context.Background())
+	byName := reportByName(&r)
+
+	if byName["apiservices"].Status != StatusFail {
+		t.Errorf("expected apiservices fail, got %+v", byName["apiservices"])
+	}
+	secrets := byName["secrets"]
+	if secrets.Status != StatusWarn || secrets.Checks[0].Detail != "denied in 1 of 2 namespace(s): team-b" {
+		t.Errorf("expected partial secrets access, got %+v", secrets)
+	}
+	if byName["certmanager"].Status != StatusWarn {
+		t.Errorf("expected certmanager warn without CRD, got %+v", byName["certmanager"])
+	}
+	if byName["linkerd"].Status != StatusWarn {
+		t.Errorf("expected linkerd warn without namespace, got %+v", byName["linkerd"])
+	}
+	if r.Failures() != 1 {
+		t.Errorf("expected 1 failure, got %d", r.Failures())
+	}
+}
+
+func TestRun_PerNamespaceReviewErrors(t *testing.T) {
+	cs := doctorClient(func(attrs *authv1.ResourceAttributes) bool {
+		// denied cluster-wide, so each namespace is reviewed
+		return attrs.Resource != "secrets" || attrs.Namespace != ""
+	})
+	cs.PrependReactor("create", "selfsubjectaccessreviews",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			review := action.(k8stesting.CreateAction).GetObject().(*authv1.SelfSubjectAccessReview)
+			if review.Spec.ResourceAttributes.Namespace == "team-b" {
+				return true, nil, errors.New("request timed out")
+			}
+			return false, nil, nil
+		})
+
+	r := New(cs, config.Defaults(), WithNamespaces([]string{"team-a", "team-b"})).Run(context.Background())
+	secrets := reportByName(&r)["secrets"]
+	if secrets.Status != StatusWarn || secrets.Checks[0].Detail != "access check failed in 1 of 2 namespace(s): team-b: request timed out" {
+		t.Errorf("expected a warning naming the failed review, got %+v", secrets)
+	}
+}
+
+func TestRun_DisabledDiscoverer(t *testing.T) {
+	disabled := false
+	cfg := config.Defaults()
+	cfg.Discovery = map[string]config.DiscovererConfig{"secrets": {Enabled: &disabled}}
+
+	r := New(doctorClient(func(*authv1.ResourceAttributes) bool { return false }), cfg).Run(context.Background())
+	if got := reportByName(&r)["secrets"].Status; got != StatusSkipped {
+		t.Errorf("expected disabled secrets to be skipped, got %s", got)
+	}
+}
+
+func TestRun_Endpoints(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.SPIFFESocket = "/nonexistent/spire-agent.sock"
+	cfg.OTelEndpoint = "otel-collector:4317"
+
+	d := New(doctorClient(allowAll), cfg, WithDialFn(func(context.Context, string, string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}))
+	r := d.Run(context.Background())
+
+	endpoints := make(map[string]Check)
+	for _, c := range r.Endpoints {
+		endpoints[c.Name] = c
+	}
+	if endpoints["kubernetes-api"].Status != StatusOK {
+		t.Errorf("expected kubernetes-api ok, got %+v", endpoints["kubernetes-api"])
+	}
+	if endpoints["spiffe-socket"].Status != StatusFail {
+		t.Errorf("expected spiffe-socket fail, got %+v", endpoints["spiffe-socket"])
+	}
+	if endpoints["otel-endpoint"].Status != StatusFail {
+		t.Errorf("expected otel-endpoint fail, got %+v", endpoints["otel-endpoint"])
+	}
+	if got := reportByName(&r)["spiffe"].Status; got != StatusFail {
+		t.Errorf("expected spiffe discoverer fail, got %s", got)
+	}
+}
+
+func TestRun_Probes(t *testing.T) {
+	direct := func(string) probe.Result { return probe.Result{ProbeErr: "no such host"} }
+	tunneled := func(string) probe.Result { return probe.Result{ProbeOK: true} }
+
+	r := New(doctorClient(allowAll), config.Defaults(),
+		WithProbeTargets([]string{"tcp://hook.default.svc:443"}),
+		WithProbeFn(direct),
+		WithTunnelProbeFn(tunneled)).Run(context.Background())
+
+	if len(r.Probes) != 1 {
+		t.Fatalf("expected 1 probe, got %d", len(r.Probes))
+	}
+	p := r.Probes[0]
+	if p.Direct.Status != StatusWarn || p.Direct.Detail != "no such host" {
+		t.Errorf("unexpected direct probe: %+v", p.Direct)
+	}
+	if p.Tunnel == nil || p.Tunnel.Status != StatusOK {
+		t.Errorf("unexpected tunnel probe: %+v", p.Tunnel)
+	}
+}
+
+func TestSampleTargets(t *testing.T) {
+	port := int32(8443)
+	vwc := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "a", ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{Name: "hook", Namespace: "system", Port: &port},
+			}},
+			{Name: "b", ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{Name: "other", Namespace: "system"},
+			}},
+		},
+	}
+	cfg := config.Defaults()
+	cfg.External = []config.ExternalTarget{{URL: "https://vault:8200"}, {URL: "https://db:5432"}}
+
+	targets := SampleTargets(context.Background(), fake.NewClientset(vwc), cfg, "10.0.0.1:6443", 1)
+	want := []string{"tcp://10.0.0.1:6443", "tcp://hook.system.svc:8443", "https://vault:8200"}
+	if len(targets) != len(want) {
+		t.Fatalf("expected %v, got %v", want, targets)
+	}
+	for i := range want {
+		if targets[i] != want[i] {
+			t.Errorf("target %d = %q, want %q", i, targets[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/probe"
+)
+
+const defaultWebhookPort = 443
+
+// SampleTargets picks up to n admission webhook services and n external targets
+// to test-probe, after the API server. Webhook services use cluster DNS, so they
+// show whether probes need --tunnel.
+func SampleTargets(ctx context.Context, client kubernetes.Interface, cfg *config.Config, apiServer string, n int) []string {
+	var targets []string
+	if apiServer != "" {
+		targets = append(targets, probe.FormatTarget(apiServer, ""))
+	}
+
+	vwcs, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		slog.Debug("listing webhooks for probe sample", "err", err)
+	} else {
+		seen := make(map[string]bool)
+		for i := range vwcs.Items {
+			for j := range vwcs.Items[i].Webhooks {
+				svc := vwcs.Items[i].Webhooks[j].ClientConfig.Service
+				if svc == nil || len(seen) >= n {
+					continue
+				}
+				port := int32(defaultWebhookPort)
+				if svc.Port != nil {
+					port = *svc.Port
+				}
+				target := probe.FormatTarget(fmt.Sprintf("%s.%s.svc:%d", svc.Name, svc.Namespace, port), "")
+				if !seen[target] {
+					seen[target] = true
+					targets = append(targets, target)
+				}
+			}
+		}
+	}
+
+	for i := range cfg.External {
+		if i >= n {
+			break
+		}
+		targets = append(targets, cfg.External[i].URL)
+	}
+	return targets
+}
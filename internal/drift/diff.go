@@ -0,0 +1,187 @@
+package drift
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// ChangeKind classifies a difference between two snapshots.
+type ChangeKind string
+
+// Change kinds, in the order they are reported.
+const (
+	ChangeAdded             ChangeKind = "added"
+	ChangeRemoved           ChangeKind = "removed"
+	ChangeSeverityChanged   ChangeKind = "severity_changed"
+	ChangeSerialRotated     ChangeKind = "serial_rotated"
+	ChangeIssuerChanged     ChangeKind = "issuer_changed"
+	ChangeViolationAdded    ChangeKind = "policy_violation_added"
+	ChangeViolationResolved ChangeKind = "policy_violation_resolved"
+)
+
+var changeOrder = []ChangeKind{
+	ChangeAdded, ChangeRemoved, ChangeSeverityChanged, ChangeSerialRotated,
+	ChangeIssuerChanged, ChangeViolationAdded, ChangeViolationResolved,
+}
+
+// Change is a single difference between two snapshots.
+type Change struct {
+	Kind        ChangeKind       `json:"kind"`
+	Cluster     string           `json:"cluster,omitempty"`
+	Source      store.SourceKind `json:"source"`
+	Namespace   string           `json:"namespace,omitempty"`
+	Name        string           `json:"name"`
+	FindingType string           `json:"findingType,omitempty"`
+	PolicyName  string           `json:"policyName,omitempty"`
+	Severity    store.Severity   `json:"severity,omitempty"` // severity in the newer snapshot, or the older one for removals
+	From        string           `json:"from,omitempty"`     // previous value (severity, serial, or issuer)
+	To          string           `json:"to,omitempty"`       // new value
+	Detail      string           `json:"detail,omitempty"`   // policy violation message
+}
+
+// Where returns the namespace/name location of the change.
+func (c *Change) Where() string {
+	where := c.Name
+	if c.Namespace != "" {
+		where = c.Namespace + "/" + c.Name
+	}
+	if c.Cluster != "" {
+		where = c.Cluster + ":" + where
+	}
+	return where
+}
+
+// ChangeSet is the structured difference between two snapshots.
+type ChangeSet struct {
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	Summary map[ChangeKind]int `json:"summary"`
+	Changes []Change           `json:"changes"`
+}
+
+// Diff compares two snapshots and returns every added, removed, re-classified,
+// rotated, or re-issued finding, plus policy violations that appeared or were
+// resolved. Findings are matched by cluster, source, namespace, name, and
+// finding type; drift findings produced by a previous comparison are ignored.
+func Diff(from, to *store.Snapshot) ChangeSet {
+	cs := ChangeSet{
+		From:    from.At,
+		To:      to.At,
+		Summary: make(map[ChangeKind]int),
+		Changes: []Change{},
+	}
+
+	prev := indexForDiff(from.Findings)
+	curr := indexForDiff(to.Findings)
+
+	for key, c := range curr {
+		p, existed := prev[key]
+		if !existed {
+			kind := ChangeAdded
+			if c.Source == store.SourcePolicy {
+				kind = ChangeViolationAdded
+			}
+			cs.add(kind, c, "", "")
+			continue
+		}
+		if c.Source == store.SourcePolicy {
+			continue
+		}
+		if p.Severity != "" && c.Severity != "" && p.Severity != c.Severity {
+			cs.add(ChangeSeverityChanged, c, string(p.Severity), string(c.Severity))
+		}
+		if p.Serial != "" && c.Serial != "" && p.Serial != c.Serial {
+			cs.add(ChangeSerialRotated, c, p.Serial, c.Serial)
+		}
+		if p.Issuer != "" && c.Issuer != "" && p.Issuer != c.Issuer {
+			cs.add(ChangeIssuerChanged, c, p.Issuer, c.Issuer)
+		}
+	}
+
+	for key, p := range prev {
+		if _, exists := curr[key]; exists {
+			continue
+		}
+		kind := ChangeRemoved
+		if p.Source == store.SourcePolicy {
+			kind = ChangeViolationResolved
+		}
+		cs.add(kind, p, "", "")
+	}
+
+	rank := make(map[ChangeKind]int, len(changeOrder))
+	for i, k := range changeOrder {
+		rank[k] = i
+	}
+	sort.Slice(cs.Changes, func(i, j int) bool {
+		a, b := &cs.Changes[i], &cs.Changes[j]
+		if rank[a.Kind] != rank[b.Kind] {
+			return rank[a.Kind] < rank[b.Kind]
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Where() < b.Where()
+	})
+	return cs
+}
+
+func (cs *ChangeSet) add(kind ChangeKind, f *store.CertFinding, from, to string) {
+	c := Change{
+		Kind:        kind,
+		Cluster:     f.Cluster,
+		Source:      f.Source,
+		Namespace:   f.Namespace,
+		Name:        f.Name,
+		FindingType: f.FindingType,
+		PolicyName:  f.PolicyName,
+		Severity:    f.Severity,
+		From:        from,
+		To:          to,
+	}
+	if f.Source == store.SourcePolicy {
+		c.Detail = f.Notes
+	}
+	cs.Changes = append(cs.Changes, c)
+	cs.Summary[kind]++
+}
+
+// Empty reports whether the two snapshots had no differences.
+func (cs *ChangeSet) Empty() bool {
+	return len(cs.Changes) == 0
+}
+
+// isDriftType reports whether a finding type was produced by drift detection.
+func isDriftType(findingType string) bool {
+	switch findingType {
+	case FindingCertNew, FindingCertGone, FindingSerialChanged, FindingIssuerChanged:
+		return true
+	}
+	return false
+}
+
+func indexForDiff(findings []store.CertFinding) map[string]*store.CertFinding {
+	m := make(map[string]*store.CertFinding, len(findings))
+	for i := range findings {
+		f := &findings[i]
+		if isDriftType(f.FindingType) {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s/%s/%s/%s", f.Cluster, f.Source, f.Namespace, f.Name, f.FindingType)
+		if f.Source == store.SourcePolicy {
+			key += "/" + f.PolicyName + "/" + ruleName(f.Notes)
+		}
+		m[key] = f
+	}
+	return m
+}
+
+// ruleName extracts the rule name from a policy violation's "rule: reason" notes.
+func ruleName(notes string) string {
+	name, _, _ := strings.Cut(notes, ": ")
+	return name
+}
@@ -0,0 +1,138 @@
+package drift
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func diffSnapshots() (from, to store.Snapshot) {
+	from = store.Snapshot{
+		At: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+		Findings: []store.CertFinding{
+			finding("kept", "default", "A1", "CN=CA"),
+			finding("rotated", "default", "B1", "CN=CA"),
+			finding("reissued", "default", "C1", "CN=Old CA"),
+			finding("gone", "default", "D1", "CN=CA"),
+			{Name: "kept", Namespace: "default", Source: store.SourcePolicy, FindingType: "POLICY_VIOLATION",
+				PolicyName: "baseline", Notes: "min-key: key too small", Severity: store.SeverityWarn, ProbeOK: true},
+		},
+	}
+	escalated := finding("kept", "default", "A1", "CN=CA")
+	escalated.Severity = store.SeverityCritical
+	to = store.Snapshot{
+		At: time.Date(2026, 5, 8, 0, 0, 0, 0, time.UTC),
+		Findings: []store.CertFinding{
+			escalated,
+			finding("rotated", "default", "B2", "CN=CA"),
+			finding("reissued", "default", "C1", "CN=New CA"),
+			finding("new", "default", "E1", "CN=CA"),
+			{Name: "new", Namespace: "default", Source: store.SourcePolicy, FindingType: "POLICY_VIOLATION",
+				PolicyName: "baseline", Notes: "no-sha1: weak signature", Severity: store.SeverityCritical, ProbeOK: true},
+			{Name: "new", Namespace: "default", Source: store.SourceTLSSecret, FindingType: FindingCertNew, ProbeOK: true},
+		},
+	}
+	return from, to
+}
+
+func TestDiff_ChangeKinds(t *testing.T) {
+	from, to := diffSnapshots()
+	cs := Diff(&from, &to)
+
+	want := map[ChangeKind]string{
+		ChangeAdded:             "default/new",
+		ChangeRemoved:           "default/gone",
+		ChangeSeverityChanged:   "default/kept",
+		ChangeSerialRotated:     "default/rotated",
+		ChangeIssuerChanged:     "default/reissued",
+		ChangeViolationAdded:    "default/new",
+		ChangeViolationResolved: "default/kept",
+	}
+	if len(cs.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %d: %+v", len(want), len(cs.Changes), cs.Changes)
+	}
+	for i, kind := range changeOrder {
+		c := cs.Changes[i]
+		if c.Kind != kind || c.Where() != want[kind] {
+			t.Errorf("change %d = %s %s, want %s %s", i, c.Kind, c.Where(), kind, want[kind])
+		}
+		if cs.Summary[kind] != 1 {
+			t.Errorf("summary[%s] = %d, want 1", kind, cs.Summary[kind])
+		}
+	}
+
+	if c := cs.Changes[3]; c.From != "B1" || c.To != "B2" {
+		t.Errorf("expected serial B1 → B2, got %q → %q", c.From, c.To)
+	}
+	if c := cs.Changes[5]; c.Detail != "no-sha1: weak signature" || c.PolicyName != "baseline" {
+		t.Errorf("unexpected violation change: %+v", c)
+	}
+}
+
+func TestDiff_NoChanges(t *testing.T) {
+	from, _ := diffSnapshots()
+	cs := Diff(&from, &from)
+	if !cs.Empty() {
+		t.Errorf("expected no changes, got %+v", cs.Changes)
+	}
+}
+
+func TestDiff_ClusterIsPartOfIdentity(t *testing.T) {
+	a := finding("cert", "default", "A1", "CN=CA")
+	a.Cluster = "prod"
+	b := finding("cert", "default", "A1", "CN=CA")
+	b.Cluster = "staging"
+
+	cs := Diff(&store.Snapshot{Findings: []store.CertFinding{a}}, &store.Snapshot{Findings: []store.CertFinding{b}})
+	if cs.Summary[ChangeAdded] != 1 || cs.Summary[ChangeRemoved] != 1 {
+		t.Errorf("expected one add and one removal across clusters, got %v", cs.Summary)
+	}
+}
+
+func TestChangeSet_WriteMarkdown(t *testing.T) {
+	from, to := diffSnapshots()
+	cs := Diff(&from, &to)
+
+	var buf bytes.Buffer
+	if err := cs.WriteMarkdown(&buf); err != nil {
+		t.Fatalf("WriteMarkdown: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"## Trust surface changes",
+		"2026-05-01 00:00 UTC → 2026-05-08 00:00 UTC",
+		"| Serial rotated | 1 |",
+		"### Issuer changed",
+		"| k8s.tlsSecret | `default/reissued` | info | CN=Old CA → CN=New CA |",
+		"### New policy violations",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected markdown to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestChangeSet_WriteTable(t *testing.T) {
+	from, to := diffSnapshots()
+	cs := Diff(&from, &to)
+
+	var buf bytes.Buffer
+	if err := cs.WriteTable(&buf); err != nil {
+		t.Fatalf("WriteTable: %v", err)
+	}
+	if !strings.Contains(buf.String(), "severity_changed") || !strings.Contains(buf.String(), "info → critical") {
+		t.Errorf("unexpected table output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	empty := Diff(&from, &from)
+	if err := empty.WriteTable(&buf); err != nil {
+		t.Fatalf("WriteTable: %v", err)
+	}
+	if !strings.Contains(buf.String(), "No changes.") {
+		t.Errorf("expected no-changes message, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,88 @@
+package drift
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+var changeTitles = map[ChangeKind]string{
+	ChangeAdded:             "Added",
+	ChangeRemoved:           "Removed",
+	ChangeSeverityChanged:   "Severity changed",
+	ChangeSerialRotated:     "Serial rotated",
+	ChangeIssuerChanged:     "Issuer changed",
+	ChangeViolationAdded:    "New policy violations",
+	ChangeViolationResolved: "Resolved policy violations",
+}
+
+const timeLayout = "2006-01-02 15:04 UTC"
+
+// WriteTable renders the change set as an aligned plain-text table.
+func (cs *ChangeSet) WriteTable(w io.Writer) error {
+	fmt.Fprintf(w, "Changes from %s to %s\n\n", cs.From.UTC().Format(timeLayout), cs.To.UTC().Format(timeLayout)) //nolint:errcheck // best-effort output
+	if cs.Empty() {
+		_, err := fmt.Fprintln(w, "No changes.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHANGE\tSOURCE\tWHERE\tSEVERITY\tDETAIL") //nolint:errcheck // best-effort output
+	for i := range cs.Changes {
+		c := &cs.Changes[i]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Kind, c.Source, c.Where(), c.Severity, c.detail()) //nolint:errcheck // best-effort output
+	}
+	return tw.Flush()
+}
+
+// WriteMarkdown renders the change set as a Markdown summary with one section
+// per change kind, suitable for pasting into a review document.
+func (cs *ChangeSet) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Trust surface changes\n\n%s → %s\n\n", cs.From.UTC().Format(timeLayout), cs.To.UTC().Format(timeLayout))
+	if cs.Empty() {
+		b.WriteString("No changes.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	b.WriteString("| Change | Count |\n|---|---|\n")
+	for _, kind := range changeOrder {
+		if n := cs.Summary[kind]; n > 0 {
+			fmt.Fprintf(&b, "| %s | %d |\n", changeTitles[kind], n)
+		}
+	}
+
+	for _, kind := range changeOrder {
+		if cs.Summary[kind] == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n| Source | Where | Severity | Detail |\n|---|---|---|---|\n", changeTitles[kind])
+		for i := range cs.Changes {
+			c := &cs.Changes[i]
+			if c.Kind != kind {
+				continue
+			}
+			fmt.Fprintf(&b, "| %s | `%s` | %s | %s |\n", c.Source, c.Where(), c.Severity, markdownEscape(c.detail()))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// detail describes what changed in a single line.
+func (c *Change) detail() string {
+	switch {
+	case c.Detail != "":
+		return c.Detail
+	case c.From != "" || c.To != "":
+		return c.From + " → " + c.To
+	default:
+		return c.FindingType
+	}
+}
+
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,114 @@
+// Package events records Kubernetes Events on the objects behind findings.
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/ppiankov/trustwatch/internal/discovery"
+	"github.com/ppiankov/trustwatch/internal/policy"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// Event reasons recorded on affected objects.
+const (
+	ReasonCertificateExpiring = "CertificateExpiring"
+	ReasonChainInvalid        = "ChainInvalid"
+	ReasonPolicyViolation     = "PolicyViolation"
+	ReasonCABundleMismatch    = "CABundleMismatch"
+)
+
+const component = "trustwatch"
+
+// Recorder emits Warning Events for warn and critical findings. Repeated
+// events are aggregated and rate-limited by the client-go event correlator,
+// so calling Emit after every scan only bumps the count on existing events.
+type Recorder struct {
+	recorder    record.EventRecorder
+	broadcaster record.EventBroadcaster
+	warnBefore  time.Duration
+}
+
+// NewRecorder creates a Recorder that writes events through client. Certificates
+// expiring within warnBefore raise CertificateExpiring.
+func NewRecorder(client kubernetes.Interface, warnBefore time.Duration) *Recorder {
+	b := record.NewBroadcaster()
+	b.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return &Recorder{
+		recorder:    b.NewRecorder(scheme.Scheme, corev1.EventSource{Component: component}),
+		broadcaster: b,
+		warnBefore:  warnBefore,
+	}
+}
+
+// Emit records an event for each problem on findings that carry an object reference.
+func (r *Recorder) Emit(findings []store.CertFinding) {
+	expiryCutoff := time.Now().Add(r.warnBefore)
+	for i := range findings {
+		f := &findings[i]
+		if f.Object == nil || (f.Severity != store.SeverityWarn && f.Severity != store.SeverityCritical) {
+			continue
+		}
+		ref := objectReference(f.Object)
+		for _, e := range eventsFor(f, expiryCutoff) {
+			r.recorder.Event(ref, corev1.EventTypeWarning, e.reason, e.message)
+		}
+	}
+}
+
+// Shutdown stops the broadcaster, flushing queued events on a best-effort basis.
+func (r *Recorder) Shutdown() {
+	if r.broadcaster != nil {
+		r.broadcaster.Shutdown()
+	}
+}
+
+type event struct {
+	reason  string
+	message string
+}
+
+// eventsFor returns the events a finding should raise. Messages avoid
+// relative times so that repeated scans aggregate into one event.
+func eventsFor(f *store.CertFinding, expiryCutoff time.Time) []event {
+	label := f.Name
+	if f.Namespace != "" {
+		label = f.Namespace + "/" + f.Name
+	}
+
+	switch f.FindingType {
+	case policy.FindingPolicyViolation:
+		return []event{{ReasonPolicyViolation, fmt.Sprintf("Policy %s: %s", f.PolicyName, f.Notes)}}
+	case discovery.FindingCABundleMismatch:
+		return []event{{ReasonCABundleMismatch, fmt.Sprintf("CA bundle mismatch for %s: %s", label, strings.Join(f.ChainErrors, "; "))}}
+	}
+
+	var out []event
+	if f.ProbeOK && !f.NotAfter.IsZero() && f.NotAfter.Before(expiryCutoff) {
+		out = append(out, event{ReasonCertificateExpiring, fmt.Sprintf("Certificate %s (%s) expires at %s",
+			label, f.Source, f.NotAfter.UTC().Format(time.RFC3339))})
+	}
+	if len(f.ChainErrors) > 0 {
+		out = append(out, event{ReasonChainInvalid, fmt.Sprintf("Certificate chain for %s is invalid: %s",
+			label, strings.Join(f.ChainErrors, "; "))})
+	}
+	return out
+}
+
+func objectReference(o *store.ObjectRef) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: o.APIVersion,
+		Kind:       o.Kind,
+		Namespace:  o.Namespace,
+		Name:       o.Name,
+		UID:        types.UID(o.UID),
+	}
+}
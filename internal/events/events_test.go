@@ -0,0 +1,86 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+
+	"github.com/ppiankov/trustwatch/internal/discovery"
+	"github.com/ppiankov/trustwatch/internal/policy"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func secretRef(name string) *store.ObjectRef {
+	return &store.ObjectRef{APIVersion: "v1", Kind: "Secret", Namespace: "payments", Name: name, UID: "uid-" + name}
+}
+
+func drain(fake *record.FakeRecorder) []string {
+	var out []string
+	for {
+		select {
+		case e := <-fake.Events:
+			out = append(out, e)
+		default:
+			return out
+		}
+	}
+}
+
+func TestRecorder_Emit(t *testing.T) {
+	fake := record.NewFakeRecorder(10)
+	r := &Recorder{recorder: fake, warnBefore: 30 * 24 * time.Hour}
+
+	soon := time.Now().Add(5 * 24 * time.Hour)
+	findings := []store.CertFinding{
+		{Name: "api-tls", Namespace: "payments", Source: store.SourceTLSSecret, Severity: store.SeverityCritical, NotAfter: soon, ProbeOK: true, Object: secretRef("api-tls")},
+		{Name: "web-tls", Namespace: "payments", Source: store.SourceTLSSecret, Severity: store.SeverityWarn, NotAfter: time.Now().Add(365 * 24 * time.Hour), ProbeOK: true, ChainErrors: []string{"missing intermediate"}, Object: secretRef("web-tls")},
+		{Name: "api-tls", Namespace: "payments", Source: store.SourcePolicy, Severity: store.SeverityWarn, FindingType: policy.FindingPolicyViolation, PolicyName: "strict", Notes: "noSHA1: uses SHA-1", ProbeOK: true, Object: secretRef("api-tls")},
+		{Name: "healthy", Namespace: "payments", Severity: store.SeverityInfo, NotAfter: soon, ProbeOK: true, Object: secretRef("healthy")},
+		{Name: "external", Severity: store.SeverityCritical, NotAfter: soon, ProbeOK: true},
+	}
+	r.Emit(findings)
+
+	got := drain(fake)
+	want := []string{
+		"Warning CertificateExpiring Certificate payments/api-tls (k8s.tlsSecret) expires at " + soon.UTC().Format(time.RFC3339),
+		"Warning ChainInvalid Certificate chain for payments/web-tls is invalid: missing intermediate",
+		"Warning PolicyViolation Policy strict: noSHA1: uses SHA-1",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %q", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEventsFor_StableMessage(t *testing.T) {
+	f := store.CertFinding{Name: "api", Namespace: "payments", Source: store.SourceIngressTLS, Severity: store.SeverityWarn, NotAfter: time.Now().Add(time.Hour), ProbeOK: true}
+	cutoff := time.Now().Add(24 * time.Hour)
+	// A later scan must produce the same message so the recorder aggregates it.
+	first := eventsFor(&f, cutoff)
+	second := eventsFor(&f, cutoff.Add(time.Minute))
+	if len(first) != 1 || first[0] != second[0] {
+		t.Fatalf("messages differ between scans: %v vs %v", first, second)
+	}
+}
+
+func TestEventsFor_CABundleMismatch(t *testing.T) {
+	f := store.CertFinding{
+		Name: "my-vwc/hook", Source: store.SourceWebhook, Severity: store.SeverityCritical, ProbeOK: true,
+		NotAfter:    time.Now().Add(365 * 24 * time.Hour),
+		FindingType: discovery.FindingCABundleMismatch,
+		ChainErrors: []string{"caBundle does not verify the serving certificate: x509: certificate signed by unknown authority"},
+	}
+	got := eventsFor(&f, time.Now().Add(24*time.Hour))
+	if len(got) != 1 || got[0].reason != ReasonCABundleMismatch {
+		t.Fatalf("CA bundle mismatch events = %v", got)
+	}
+	want := "CA bundle mismatch for my-vwc/hook: " + f.ChainErrors[0]
+	if got[0].message != want {
+		t.Errorf("message = %q, want %q", got[0].message, want)
+	}
+}
@@ -7,7 +7,8 @@ import (
 )
 
 // Merge combines a local snapshot with remote snapshots, adding the cluster
-// label to all findings.
+// label to all findings. Remote metadata, including remote discoverer errors,
+// is kept under the local metadata so coverage gaps survive federation.
 func Merge(localName string, local store.Snapshot, remotes map[string]store.Snapshot) store.Snapshot {
 	// Label local findings
 	for i := range local.Findings {
@@ -20,6 +21,18 @@ func Merge(localName string, local store.Snapshot, remotes map[string]store.Snap
 			remote.Findings[i].Cluster = clusterName
 		}
 		local.Findings = append(local.Findings, remote.Findings...)
+
+		if local.Metadata == nil {
+			local.Metadata = &store.Metadata{}
+		}
+		if local.Metadata.Remotes == nil {
+			local.Metadata.Remotes = make(map[string]*store.Metadata, len(remotes))
+		}
+		local.Metadata.Remotes[clusterName] = remoteMetadata(clusterName, remote)
+	}
+
+	if local.Metadata != nil && local.Metadata.Cluster == "" {
+		local.Metadata.Cluster = localName
 	}
 
 	// Use latest timestamp
@@ -27,3 +40,28 @@ func Merge(localName string, local store.Snapshot, remotes map[string]store.Snap
 
 	return local
 }
+
+// remoteMetadata returns a copy of a remote snapshot's metadata with its
+// discovery errors folded in, for snapshots produced before metadata existed.
+func remoteMetadata(clusterName string, remote store.Snapshot) *store.Metadata {
+	md := store.Metadata{}
+	if remote.Metadata != nil {
+		md = *remote.Metadata
+	}
+	md.Cluster = clusterName
+	if len(remote.Errors) == 0 {
+		return &md
+	}
+
+	runs := make(map[string]store.DiscovererRun, len(md.Discoverers)+len(remote.Errors))
+	for name, run := range md.Discoverers {
+		runs[name] = run
+	}
+	for name, errMsg := range remote.Errors {
+		if _, ok := runs[name]; !ok {
+			runs[name] = store.DiscovererRun{Error: errMsg}
+		}
+	}
+	md.Discoverers = runs
+	return &md
+}
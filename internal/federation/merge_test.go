@@ -69,3 +69,39 @@ func TestMerge_NoRemotes(t *testing.T) {
 		t.Errorf("cluster = %q, want %q", merged.Findings[0].Cluster, "local")
 	}
 }
+
+func TestMerge_RemoteMetadata(t *testing.T) {
+	local := store.Snapshot{Metadata: &store.Metadata{Version: "1.0.0"}}
+	remotes := map[string]store.Snapshot{
+		"staging": {
+			Metadata: &store.Metadata{
+				KubernetesVersion: "v1.31.0",
+				Discoverers:       map[string]store.DiscovererRun{"secrets": {Findings: 2}},
+			},
+			Errors: map[string]string{"istio": "forbidden"},
+		},
+		"legacy": {Errors: map[string]string{"webhooks": "timeout"}},
+	}
+
+	merged := Merge("prod", local, remotes)
+
+	if merged.Metadata.Version != "1.0.0" {
+		t.Errorf("expected local metadata to be preserved, got %+v", merged.Metadata)
+	}
+	staging := merged.Metadata.Remotes["staging"]
+	if staging == nil || staging.KubernetesVersion != "v1.31.0" || staging.Cluster != "staging" {
+		t.Fatalf("expected staging metadata, got %+v", staging)
+	}
+	if staging.Discoverers["istio"].Error != "forbidden" {
+		t.Errorf("expected remote error folded into discoverers, got %+v", staging.Discoverers)
+	}
+	if merged.Metadata.Remotes["legacy"].Discoverers["webhooks"].Error != "timeout" {
+		t.Errorf("expected legacy remote error, got %+v", merged.Metadata.Remotes["legacy"])
+	}
+	if remotes["staging"].Metadata.Discoverers["istio"].Error != "" {
+		t.Error("remote metadata should not be mutated")
+	}
+	if gaps := merged.CoverageGaps(); len(gaps) != 2 {
+		t.Errorf("expected 2 coverage gaps, got %v", gaps)
+	}
+}
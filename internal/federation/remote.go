@@ -15,8 +15,10 @@ const defaultTimeout = 15 * time.Second
 
 // RemoteSource fetches a snapshot from a remote trustwatch instance.
 type RemoteSource struct {
-	Name string
-	URL  string
+	Client *http.Client // e.g. with a CA and client certificate for HTTPS remotes; nil uses http.DefaultClient
+	Name   string
+	URL    string
+	Token  string // bearer token, for remotes with auth enabled
 }
 
 // Fetch retrieves the snapshot from the remote /api/v1/snapshot endpoint.
@@ -28,8 +30,15 @@ func (r *RemoteSource) Fetch(ctx context.Context) (store.Snapshot, error) {
 	if err != nil {
 		return store.Snapshot{}, fmt.Errorf("building request: %w", err)
 	}
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return store.Snapshot{}, fmt.Errorf("fetching remote snapshot: %w", err)
 	}
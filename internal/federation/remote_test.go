@@ -50,3 +50,35 @@ func TestRemoteSource_FetchError(t *testing.T) {
 		t.Error("expected error for 500 response")
 	}
 }
+
+func TestRemoteSource_FetchToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer hub-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(store.Snapshot{}) //nolint:errcheck // test handler
+	}))
+	defer srv.Close()
+
+	if _, err := (&RemoteSource{Name: "secured", URL: srv.URL, Token: "hub-token"}).Fetch(context.Background()); err != nil {
+		t.Errorf("fetch with token: %v", err)
+	}
+	if _, err := (&RemoteSource{Name: "secured", URL: srv.URL}).Fetch(context.Background()); err == nil {
+		t.Error("expected error without token")
+	}
+}
+
+func TestRemoteSource_FetchClient(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(store.Snapshot{}) //nolint:errcheck // test handler
+	}))
+	defer srv.Close()
+
+	if _, err := (&RemoteSource{Name: "https", URL: srv.URL}).Fetch(context.Background()); err == nil {
+		t.Error("expected error for an untrusted remote certificate")
+	}
+	if _, err := (&RemoteSource{Name: "https", URL: srv.URL, Client: srv.Client()}).Fetch(context.Background()); err != nil {
+		t.Errorf("fetch with a trusting client: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// backend captures what differs between the supported databases. Queries
+// are written once with "?" placeholders and rebound for the backend.
+type backend interface {
+	// migrate creates the schema or upgrades it to the current version.
+	migrate(db *sql.DB) error
+	// rebind rewrites "?" placeholders into the backend's bind syntax.
+	rebind(query string) string
+	// returningID reports whether inserts read the new id with RETURNING
+	// rather than sql.Result.LastInsertId.
+	returningID() bool
+	// skipLocked is appended to the SELECT that picks outbox rows to claim.
+	skipLocked() string
+	// size returns the bytes used by trustwatch tables and the bytes VACUUM could reclaim.
+	size(db *sql.DB) (used, free int64, err error)
+	// vacuum reclaims space after compaction according to mode.
+	vacuum(db *sql.DB, mode string, st Stats) (bool, error)
+}
+
+// database is a *sql.DB whose queries are rebound for its backend.
+type database struct {
+	*sql.DB
+	backend backend
+}
+
+// Exec runs a statement with "?" placeholders.
+func (d *database) Exec(query string, args ...any) (sql.Result, error) {
+	return d.DB.Exec(d.backend.rebind(query), utc(args)...)
+}
+
+// Query runs a query with "?" placeholders.
+func (d *database) Query(query string, args ...any) (*sql.Rows, error) {
+	return d.DB.Query(d.backend.rebind(query), utc(args)...)
+}
+
+// QueryRow runs a single-row query with "?" placeholders.
+func (d *database) QueryRow(query string, args ...any) *sql.Row {
+	return d.DB.QueryRow(d.backend.rebind(query), utc(args)...)
+}
+
+// Begin starts a transaction whose statements are rebound for the backend.
+func (d *database) Begin() (*transaction, error) {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{Tx: tx, backend: d.backend}, nil
+}
+
+// insert runs an INSERT and returns the id of the new row.
+func (d *database) insert(query string, args ...any) (int64, error) {
+	if d.backend.returningID() {
+		var id int64
+		err := d.QueryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	res, err := d.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// transaction is a *sql.Tx whose statements are rebound for its backend.
+type transaction struct {
+	*sql.Tx
+	backend backend
+}
+
+// Exec runs a statement with "?" placeholders.
+func (t *transaction) Exec(query string, args ...any) (sql.Result, error) {
+	return t.Tx.Exec(t.backend.rebind(query), utc(args)...)
+}
+
+// Prepare prepares a statement with "?" placeholders.
+func (t *transaction) Prepare(query string) (*sql.Stmt, error) {
+	return t.Tx.Prepare(t.backend.rebind(query))
+}
+
+// insert runs an INSERT and returns the id of the new row.
+func (t *transaction) insert(query string, args ...any) (int64, error) {
+	if t.backend.returningID() {
+		var id int64
+		err := t.Tx.QueryRow(t.backend.rebind(query+" RETURNING id"), utc(args)...).Scan(&id)
+		return id, err
+	}
+	res, err := t.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// utc returns args with every time.Time converted to UTC. SQLite stores times
+// as text that keeps the zone offset, so range filters only compare
+// correctly when every stored and bound time is in the same zone.
+func utc(args []any) []any {
+	out := make([]any, len(args))
+	for i, a := range args {
+		if t, ok := a.(time.Time); ok {
+			a = t.UTC()
+		}
+		out[i] = a
+	}
+	return out
+}
+
+// isPostgres reports whether a history DSN selects the PostgreSQL backend.
+func isPostgres(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// Redact returns a history DSN with any password masked, for logging.
+func Redact(dsn string) string {
+	if !isPostgres(dsn) {
+		return dsn
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "postgres://<unparseable>"
+	}
+	return u.Redacted()
+}
+
+// Backend returns the name of the database backend: "sqlite" or "postgres".
+func (s *Store) Backend() string {
+	if _, ok := s.db.backend.(postgresBackend); ok {
+		return "postgres"
+	}
+	return "sqlite"
+}
+
+// openDatabase opens db for backend b and runs its migrations.
+func openDatabase(driver, dsn string, b backend) (*database, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	if err := b.migrate(db); err != nil {
+		db.Close() //nolint:errcheck // best-effort cleanup
+		return nil, fmt.Errorf("running migrations: %w", err)
+	}
+	return &database{DB: db, backend: b}, nil
+}
@@ -0,0 +1,108 @@
+package history
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// Episode is a span of consecutive snapshots in which a finding was open at
+// warn or critical severity.
+type Episode struct {
+	Opened time.Time `json:"opened"`
+	// Closed is the first snapshot without the finding at warn or critical;
+	// zero while it is still open.
+	Closed      time.Time        `json:"closed,omitzero"`
+	Source      store.SourceKind `json:"source"`
+	Cluster     string           `json:"cluster,omitempty"`
+	Namespace   string           `json:"namespace,omitempty"`
+	Name        string           `json:"name"`
+	FindingType string           `json:"findingType,omitempty"`
+	Issuer      string           `json:"issuer,omitempty"`
+	Severity    store.Severity   `json:"severity"` // worst severity while open
+}
+
+// Episodes returns the warn and critical episodes seen in snapshots taken
+// since the given time, in the order they opened. A finding open in the
+// first snapshot is counted from there, so episodes are only as precise as
+// the retained snapshots. Each cluster's snapshot stream opens and closes
+// only its own episodes.
+func (s *Store) Episodes(since time.Time) ([]Episode, error) {
+	snaps, err := s.db.Query("SELECT id, at, cluster FROM snapshots WHERE at >= ? ORDER BY at, id", since)
+	if err != nil {
+		return nil, fmt.Errorf("querying snapshots: %w", err)
+	}
+	type snapshotRef struct {
+		at      time.Time
+		cluster string
+		id      int64
+	}
+	var order []snapshotRef
+	for snaps.Next() {
+		var ref snapshotRef
+		if err := snaps.Scan(&ref.id, &ref.at, &ref.cluster); err != nil {
+			snaps.Close() //nolint:errcheck // returning the scan error
+			return nil, fmt.Errorf("scanning snapshot: %w", err)
+		}
+		order = append(order, ref)
+	}
+	snaps.Close() //nolint:errcheck // read-only query
+	if err := snaps.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT f.snapshot_id, f.source, COALESCE(f.cluster, ''), f.namespace, f.name, f.finding_type, f.issuer, f.severity
+		FROM findings f
+		JOIN snapshots s ON s.id = f.snapshot_id
+		WHERE s.at >= ? AND f.severity IN (?, ?)`,
+		since, store.SeverityWarn, store.SeverityCritical,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying findings: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // read-only query
+
+	bySnapshot := make(map[int64][]Episode)
+	for rows.Next() {
+		var id int64
+		var e Episode
+		if err := rows.Scan(&id, &e.Source, &e.Cluster, &e.Namespace, &e.Name, &e.FindingType, &e.Issuer, &e.Severity); err != nil {
+			return nil, fmt.Errorf("scanning finding: %w", err)
+		}
+		bySnapshot[id] = append(bySnapshot[id], e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var episodes []Episode
+	open := make(map[string]int)          // stream and finding key → index in episodes
+	openStream := make(map[string]string) // stream and finding key → stream
+	for _, ref := range order {
+		seen := make(map[string]bool)
+		for _, e := range bySnapshot[ref.id] {
+			key := fmt.Sprintf("%s\x00%s/%s/%s/%s/%s", ref.cluster, e.Cluster, e.Source, e.Namespace, e.Name, e.FindingType)
+			seen[key] = true
+			if i, ok := open[key]; ok {
+				if e.Severity == store.SeverityCritical {
+					episodes[i].Severity = store.SeverityCritical
+				}
+				continue
+			}
+			e.Opened = ref.at
+			open[key] = len(episodes)
+			openStream[key] = ref.cluster
+			episodes = append(episodes, e)
+		}
+		for key, i := range open {
+			if openStream[key] == ref.cluster && !seen[key] {
+				episodes[i].Closed = ref.at
+				delete(open, key)
+				delete(openStream, key)
+			}
+		}
+	}
+	return episodes, nil
+}
@@ -0,0 +1,80 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func TestEpisodes(t *testing.T) {
+	s := openMemory(t)
+	base := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	// Severity of "api" per scan; "db" is critical in the first two scans and then disappears.
+	for i, sev := range []store.Severity{store.SeverityInfo, store.SeverityWarn, store.SeverityCritical, store.SeverityInfo, store.SeverityWarn} {
+		findings := []store.CertFinding{{Name: "api", Namespace: "ns", Source: store.SourceTLSSecret, Severity: sev, Issuer: "CN=CA", ProbeOK: true}}
+		if i < 2 {
+			findings = append(findings, store.CertFinding{Name: "db", Namespace: "ns", Source: store.SourceTLSSecret, Severity: store.SeverityCritical})
+		}
+		if err := s.Save(store.Snapshot{At: base.Add(time.Duration(i) * time.Hour), Findings: findings}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	episodes, err := s.Episodes(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(episodes) != 3 {
+		t.Fatalf("episodes = %+v, want 3", episodes)
+	}
+	db, api, reopened := episodes[0], episodes[1], episodes[2]
+	if db.Name != "db" || !db.Opened.Equal(base) || !db.Closed.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("db episode = %+v", db)
+	}
+	if api.Name != "api" || api.Severity != store.SeverityCritical || api.Issuer != "CN=CA" ||
+		!api.Opened.Equal(base.Add(time.Hour)) || !api.Closed.Equal(base.Add(3*time.Hour)) {
+		t.Errorf("api episode = %+v", api)
+	}
+	if !reopened.Opened.Equal(base.Add(4*time.Hour)) || !reopened.Closed.IsZero() {
+		t.Errorf("reopened episode = %+v", reopened)
+	}
+
+	recent, err := s.Episodes(base.Add(3 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 1 || recent[0].Name != "api" {
+		t.Errorf("episodes since the fourth scan = %+v", recent)
+	}
+}
+
+func TestEpisodes_FederatedClusters(t *testing.T) {
+	s := openMemory(t)
+	base := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	// The same finding is critical in both clusters, then clears in west only.
+	for i := range 2 {
+		findings := []store.CertFinding{{Cluster: "east", Name: "api", Namespace: "ns", Source: store.SourceTLSSecret, Severity: store.SeverityCritical}}
+		if i == 0 {
+			findings = append(findings, store.CertFinding{Cluster: "west", Name: "api", Namespace: "ns", Source: store.SourceTLSSecret, Severity: store.SeverityCritical})
+		}
+		if err := s.Save(store.Snapshot{At: base.Add(time.Duration(i) * time.Hour), Findings: findings}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	episodes, err := s.Episodes(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(episodes) != 2 {
+		t.Fatalf("episodes = %+v, want one per cluster", episodes)
+	}
+	east, west := episodes[0], episodes[1]
+	if east.Cluster != "east" || !east.Closed.IsZero() {
+		t.Errorf("east episode = %+v, want still open", east)
+	}
+	if west.Cluster != "west" || !west.Closed.Equal(base.Add(time.Hour)) {
+		t.Errorf("west episode = %+v, want closed by the second scan", west)
+	}
+}
@@ -0,0 +1,62 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// leaderPingTimeout bounds the check that the leader's session is still
+// alive. It is independent of the caller's context, so a cancelled scan does
+// not look like a lost session.
+const leaderPingTimeout = 5 * time.Second
+
+// Lead reports whether this process is the history leader: the one replica
+// that scans, saves snapshots and compacts. A SQLite history has a single
+// writer, so it always leads. On PostgreSQL the leader holds a session
+// advisory lock on a dedicated connection; if that connection drops, the
+// database releases the lock and another replica takes over on its next call.
+func (s *Store) Lead(ctx context.Context) (bool, error) {
+	if _, ok := s.db.backend.(postgresBackend); !ok {
+		return true, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.leader != nil {
+		pingCtx, cancel := context.WithTimeout(context.Background(), leaderPingTimeout)
+		err := s.leader.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			return true, nil
+		}
+		// The session may still be open and holding the lock; closing the
+		// physical connection ends it so no session keeps the lock.
+		discardConn(s.leader)
+		s.leader = nil
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("acquiring leader connection: %w", err)
+	}
+	var ok bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", postgresLeaderLock).Scan(&ok); err != nil {
+		discardConn(conn) // the lock may have been taken before the error
+		return false, fmt.Errorf("taking leader lock: %w", err)
+	}
+	if !ok {
+		conn.Close() //nolint:errcheck // lock not taken, connection returns to the pool
+		return false, nil
+	}
+	s.leader = conn
+	return true, nil
+}
+
+// discardConn closes conn's driver connection instead of returning it to the
+// pool, ending its database session and any session locks it holds.
+func discardConn(conn *sql.Conn) {
+	conn.Raw(func(any) error { return driver.ErrBadConn }) //nolint:errcheck // ErrBadConn is how the connection is dropped
+	conn.Close()                                           //nolint:errcheck // already released by Raw
+}
@@ -0,0 +1,211 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// Generation is one certificate observed for a finding identity.
+type Generation struct {
+	NotBefore time.Time `json:"notBefore,omitzero"`
+	NotAfter  time.Time `json:"notAfter"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	// RotatedAt is when the next generation was first observed; zero for the current one.
+	RotatedAt time.Time `json:"rotatedAt,omitzero"`
+	Serial    string    `json:"serial"`
+	Issuer    string    `json:"issuer,omitempty"`
+	// RemainingAtRotation is the lifetime left at RotatedAt; negative when the
+	// certificate had already expired.
+	RemainingAtRotation time.Duration `json:"remainingAtRotation,omitempty"`
+	Observations        int           `json:"observations"`
+	IssuerChanged       bool          `json:"issuerChanged,omitempty"` // issuer differs from the previous generation
+}
+
+// Lineage is the ordered list of certificate generations for one finding identity.
+type Lineage struct {
+	Source      store.SourceKind `json:"source"`
+	Cluster     string           `json:"cluster,omitempty"`
+	Namespace   string           `json:"namespace,omitempty"`
+	Name        string           `json:"name"`
+	Generations []Generation     `json:"generations"`
+	// MeanInterval is the mean lifetime of replaced generations; zero without a rotation.
+	MeanInterval  time.Duration `json:"meanInterval,omitempty"`
+	Rotations     int           `json:"rotations"`
+	IssuerChanges int           `json:"issuerChanges,omitempty"`
+}
+
+// Key returns the finding identity as source/namespace/name, prefixed with
+// the cluster when the finding was recorded with one.
+func (l *Lineage) Key() string {
+	if l.Cluster != "" {
+		return fmt.Sprintf("%s/%s/%s/%s", l.Cluster, l.Source, l.Namespace, l.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", l.Source, l.Namespace, l.Name)
+}
+
+// LineageFilter selects lineages. Empty fields match everything; Since drops
+// observations from earlier snapshots.
+type LineageFilter struct {
+	Since     time.Time
+	Cluster   string
+	Source    string
+	Namespace string
+	Name      string
+}
+
+// Lineages returns the certificate generations of every finding identity
+// matching f, ordered by identity. Generations are told apart by serial and
+// ordered by first observation, so their timestamps are only as precise as
+// the retained snapshots.
+func (s *Store) Lineages(f LineageFilter) ([]Lineage, error) {
+	query := `SELECT COALESCE(f.cluster, ''), f.source, f.namespace, f.name, f.serial, f.issuer, f.not_before, f.not_after, s.at
+		FROM findings f
+		JOIN snapshots s ON s.id = f.snapshot_id
+		WHERE f.serial != '' AND f.probe_ok`
+	var args []any
+	for _, c := range []struct {
+		column string
+		value  string
+	}{{"f.cluster", f.Cluster}, {"f.source", f.Source}, {"f.namespace", f.Namespace}, {"f.name", f.Name}} {
+		if c.value != "" {
+			query += " AND " + c.column + " = ?"
+			args = append(args, c.value)
+		}
+	}
+	if !f.Since.IsZero() {
+		query += " AND s.at >= ?"
+		args = append(args, f.Since)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying lineage: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // read-only query
+
+	lineages := make(map[string]*Lineage)
+	generations := make(map[string]*Generation)
+	for rows.Next() {
+		var source store.SourceKind
+		var cluster, ns, name, serial, issuer string
+		var notBefore, notAfter sql.NullTime
+		var at time.Time
+		if err := rows.Scan(&cluster, &source, &ns, &name, &serial, &issuer, &notBefore, &notAfter, &at); err != nil {
+			return nil, fmt.Errorf("scanning lineage row: %w", err)
+		}
+		l := &Lineage{Source: source, Cluster: cluster, Namespace: ns, Name: name}
+		key := l.Key()
+		if existing, ok := lineages[key]; ok {
+			l = existing
+		} else {
+			lineages[key] = l
+		}
+		gkey := key + "\x00" + serial
+		g, ok := generations[gkey]
+		if !ok {
+			g = &Generation{Serial: serial, Issuer: issuer, FirstSeen: at, LastSeen: at}
+			generations[gkey] = g
+		}
+		g.Observations++
+		if at.Before(g.FirstSeen) {
+			g.FirstSeen = at
+		}
+		if at.After(g.LastSeen) {
+			g.LastSeen = at
+		}
+		if notBefore.Valid && !notBefore.Time.IsZero() {
+			g.NotBefore = notBefore.Time
+		}
+		if notAfter.Valid && !notAfter.Time.IsZero() {
+			g.NotAfter = notAfter.Time
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for gkey, g := range generations {
+		key, _, _ := strings.Cut(gkey, "\x00")
+		lineages[key].Generations = append(lineages[key].Generations, *g)
+	}
+	result := make([]Lineage, 0, len(lineages))
+	for _, l := range lineages {
+		l.summarize()
+		result = append(result, *l)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key() < result[j].Key()
+	})
+	return result, nil
+}
+
+// Lineage returns the certificate generations of one finding, or nil if it
+// was never observed with a serial.
+func (s *Store) Lineage(name, ns, source string) (*Lineage, error) {
+	lineages, err := s.Lineages(LineageFilter{Source: source, Namespace: ns, Name: name})
+	if err != nil || len(lineages) == 0 {
+		return nil, err
+	}
+	return &lineages[0], nil
+}
+
+// summarize orders generations and fills in rotation details.
+func (l *Lineage) summarize() {
+	gens := l.Generations
+	sort.Slice(gens, func(i, j int) bool {
+		if !gens[i].FirstSeen.Equal(gens[j].FirstSeen) {
+			return gens[i].FirstSeen.Before(gens[j].FirstSeen)
+		}
+		return gens[i].Serial < gens[j].Serial
+	})
+	for i := 1; i < len(gens); i++ {
+		prev, cur := &gens[i-1], &gens[i]
+		prev.RotatedAt = cur.FirstSeen
+		if !prev.NotAfter.IsZero() {
+			prev.RemainingAtRotation = prev.NotAfter.Sub(cur.FirstSeen)
+		}
+		if cur.Issuer != prev.Issuer {
+			cur.IssuerChanged = true
+			l.IssuerChanges++
+		}
+	}
+	l.Rotations = max(len(gens)-1, 0)
+	if l.Rotations == 0 {
+		return
+	}
+	// A certificate may predate the first snapshot that saw it, so intervals
+	// start at notBefore when it is known.
+	var total time.Duration
+	for i := 1; i < len(gens); i++ {
+		start := gens[i-1].FirstSeen
+		if nb := gens[i-1].NotBefore; !nb.IsZero() && nb.Before(start) {
+			start = nb
+		}
+		total += gens[i].FirstSeen.Sub(start)
+	}
+	l.MeanInterval = total / time.Duration(l.Rotations)
+}
+
+// RotationIntervals returns the mean observed rotation interval of every
+// finding in cluster ("" for findings recorded without one) that rotated at
+// least once since the given time, keyed by source/namespace/name.
+func (s *Store) RotationIntervals(since time.Time, cluster string) (map[string]time.Duration, error) {
+	lineages, err := s.Lineages(LineageFilter{Since: since, Cluster: cluster})
+	if err != nil {
+		return nil, err
+	}
+	intervals := make(map[string]time.Duration)
+	for i := range lineages {
+		l := &lineages[i]
+		if l.Cluster == cluster && l.Rotations > 0 {
+			intervals[fmt.Sprintf("%s/%s/%s", l.Source, l.Namespace, l.Name)] = l.MeanInterval
+		}
+	}
+	return intervals, nil
+}
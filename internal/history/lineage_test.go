@@ -0,0 +1,139 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// saveRotations records a daily scan of one cert-manager certificate for
+// 60 days. It is reissued every 20 days with 30 days of validity, and the
+// third certificate comes from a new issuer.
+func saveRotations(t *testing.T, s *Store, start time.Time) {
+	t.Helper()
+	for day := range 60 {
+		gen := day / 20
+		issued := start.Add(time.Duration(gen*20*24) * time.Hour)
+		issuer := "CN=Old CA"
+		if gen == 2 {
+			issuer = "CN=New CA"
+		}
+		snap := store.Snapshot{
+			At: start.Add(time.Duration(day*24) * time.Hour),
+			Findings: []store.CertFinding{
+				{
+					Name: "api-tls", Namespace: "payments", Source: store.SourceCertManager, ProbeOK: true,
+					Serial: []string{"100", "200", "300"}[gen], Issuer: issuer,
+					NotBefore: issued, NotAfter: issued.Add(30 * 24 * time.Hour),
+				},
+				// Probe failures carry no certificate and are not part of the lineage.
+				{Name: "api-tls", Namespace: "payments", Source: store.SourceIngressTLS, ProbeOK: false},
+			},
+		}
+		if err := s.Save(snap); err != nil {
+			t.Fatalf("save failed: %v", err)
+		}
+	}
+}
+
+func TestLineage(t *testing.T) {
+	s := openMemory(t)
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	saveRotations(t, s, start)
+
+	l, err := s.Lineage("api-tls", "payments", string(store.SourceCertManager))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l == nil {
+		t.Fatal("expected a lineage")
+	}
+	if l.Rotations != 2 || len(l.Generations) != 3 {
+		t.Fatalf("rotations = %d, generations = %d, want 2 and 3", l.Rotations, len(l.Generations))
+	}
+	if l.MeanInterval != 20*24*time.Hour {
+		t.Errorf("mean interval = %s, want 480h", l.MeanInterval)
+	}
+	if l.IssuerChanges != 1 || !l.Generations[2].IssuerChanged || l.Generations[1].IssuerChanged {
+		t.Errorf("issuer changes = %d, generations = %+v", l.IssuerChanges, l.Generations)
+	}
+
+	first := l.Generations[0]
+	if first.Serial != "100" || first.Observations != 20 {
+		t.Errorf("first generation = %+v", first)
+	}
+	if !first.NotBefore.Equal(start) || !first.LastSeen.Equal(start.Add(19*24*time.Hour)) {
+		t.Errorf("first generation times = %+v", first)
+	}
+	if !first.RotatedAt.Equal(start.Add(20*24*time.Hour)) || first.RemainingAtRotation != 10*24*time.Hour {
+		t.Errorf("first rotation at %s with %s left, want day 20 with 240h", first.RotatedAt, first.RemainingAtRotation)
+	}
+	if current := l.Generations[2]; !current.RotatedAt.IsZero() || current.RemainingAtRotation != 0 {
+		t.Errorf("current generation should not be rotated: %+v", current)
+	}
+
+	missing, err := s.Lineage("api-tls", "payments", string(store.SourceIngressTLS))
+	if err != nil || missing != nil {
+		t.Errorf("lineage of probe failures = %+v, %v, want nil", missing, err)
+	}
+}
+
+func TestLineages_Since(t *testing.T) {
+	s := openMemory(t)
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	saveRotations(t, s, start)
+
+	lineages, err := s.Lineages(LineageFilter{Since: start.Add(45 * 24 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lineages) != 1 || lineages[0].Rotations != 0 || lineages[0].Generations[0].Serial != "300" {
+		t.Errorf("lineages since day 45 = %+v", lineages)
+	}
+
+	intervals, err := s.RotationIntervals(time.Time{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := intervals["certmanager/payments/api-tls"]; got != 20*24*time.Hour || len(intervals) != 1 {
+		t.Errorf("rotation intervals = %v", intervals)
+	}
+}
+
+func TestLineages_FederatedClusters(t *testing.T) {
+	s := openMemory(t)
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for day := range 3 {
+		snap := store.Snapshot{At: start.Add(time.Duration(day*24) * time.Hour)}
+		// The same identity in two clusters, each with its own certificate.
+		for cluster, serial := range map[string]string{"east": "100", "west": "200"} {
+			snap.Findings = append(snap.Findings, store.CertFinding{
+				Cluster: cluster, Name: "api-tls", Namespace: "payments", Source: store.SourceCertManager,
+				ProbeOK: true, Serial: serial, NotAfter: start.Add(90 * 24 * time.Hour),
+			})
+		}
+		if err := s.Save(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lineages, err := s.Lineages(LineageFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lineages) != 2 || lineages[0].Rotations != 0 || lineages[1].Rotations != 0 {
+		t.Fatalf("lineages = %+v, want one unrotated lineage per cluster", lineages)
+	}
+	if lineages[0].Key() != "east/certmanager/payments/api-tls" {
+		t.Errorf("key = %q, want the cluster prefixed", lineages[0].Key())
+	}
+	west, err := s.Lineages(LineageFilter{Cluster: "west"})
+	if err != nil || len(west) != 1 || west[0].Generations[0].Serial != "200" {
+		t.Errorf("west lineages = %+v, %v", west, err)
+	}
+	intervals, err := s.RotationIntervals(time.Time{}, "east")
+	if err != nil || len(intervals) != 0 {
+		t.Errorf("east rotation intervals = %v, %v; want none", intervals, err)
+	}
+}
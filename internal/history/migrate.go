@@ -2,9 +2,80 @@ package history
 
 import (
 	"database/sql"
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // CGO-free SQLite driver
 )
 
+// sqliteBackend stores history in a local SQLite file.
+type sqliteBackend struct{}
+
+// busyTimeoutMillis is how long a statement waits for a database lock.
+const busyTimeoutMillis = 30000
+
+// openSQLite opens or creates the SQLite database at path.
+func openSQLite(path string) (*database, error) {
+	// Wait for locks instead of failing, so scans can save while background
+	// compaction or VACUUM holds the database.
+	dsn := path
+	if !strings.Contains(dsn, "?") {
+		dsn += "?_pragma=busy_timeout(" + strconv.Itoa(busyTimeoutMillis) + ")"
+	}
+	return openDatabase("sqlite", dsn, sqliteBackend{})
+}
+
+func (sqliteBackend) migrate(db *sql.DB) error {
+	// Enable WAL mode for better concurrent read performance
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		return fmt.Errorf("setting WAL mode: %w", err)
+	}
+	return migrate(db)
+}
+
+func (sqliteBackend) rebind(query string) string { return query }
+
+func (sqliteBackend) returningID() bool { return false }
+
+// skipLocked is empty because SQLite serializes writers, so a claiming
+// UPDATE never sees rows another writer is claiming.
+func (sqliteBackend) skipLocked() string { return "" }
+
+func (sqliteBackend) size(db *sql.DB) (used, free int64, err error) {
+	var pageSize, pageCount, freePages int64
+	for _, p := range []struct {
+		dst    *int64
+		pragma string
+	}{
+		{&pageSize, "page_size"},
+		{&pageCount, "page_count"},
+		{&freePages, "freelist_count"},
+	} {
+		if err := db.QueryRow("PRAGMA " + p.pragma).Scan(p.dst); err != nil {
+			return 0, 0, fmt.Errorf("reading %s: %w", p.pragma, err)
+		}
+	}
+	return pageSize * pageCount, pageSize * freePages, nil
+}
+
+// vacuum rebuilds the database file to return free pages to the filesystem
+// and truncates the write-ahead log. In auto mode it only runs once at least a
+// quarter of the file is free, since VACUUM rewrites the whole database.
+func (sqliteBackend) vacuum(db *sql.DB, mode string, st Stats) (bool, error) {
+	if mode != VacuumAlways && (st.SizeBytes == 0 || float64(st.FreeBytes) < vacuumFreeRatio*float64(st.SizeBytes)) {
+		return false, nil
+	}
+	if _, err := db.Exec("VACUUM"); err != nil {
+		return false, fmt.Errorf("vacuuming database: %w", err)
+	}
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return true, fmt.Errorf("truncating write-ahead log: %w", err)
+	}
+	return true, nil
+}
+
 const schema = `
 CREATE TABLE IF NOT EXISTS snapshots (
     id             INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -29,8 +100,33 @@ CREATE TABLE IF NOT EXISTS findings (
 
 CREATE INDEX IF NOT EXISTS idx_findings_snapshot ON findings(snapshot_id);
 CREATE INDEX IF NOT EXISTS idx_findings_trend ON findings(source, namespace, name);
+
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at   INTEGER NOT NULL,
+    next_attempt INTEGER NOT NULL DEFAULT 0,
+    sent_at      INTEGER NOT NULL DEFAULT 0,
+    kind         TEXT NOT NULL DEFAULT '',
+    webhook      TEXT NOT NULL DEFAULT '',
+    url          TEXT NOT NULL DEFAULT '',
+    target       TEXT NOT NULL DEFAULT '',
+    content_type TEXT NOT NULL DEFAULT '',
+    summary      TEXT NOT NULL DEFAULT '',
+    body         BLOB,
+    status       TEXT NOT NULL DEFAULT 'pending',
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    last_error   TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON notification_outbox(status, next_attempt);
+
+CREATE TABLE IF NOT EXISTS notification_cooldowns (
+    key     TEXT PRIMARY KEY,
+    sent_at INTEGER NOT NULL
+);
 `
 
+// migrate creates the SQLite schema and applies column additions idempotently.
 func migrate(db *sql.DB) error {
 	if _, err := db.Exec(schema); err != nil {
 		return err
@@ -39,11 +135,72 @@ func migrate(db *sql.DB) error {
 	for _, stmt := range []string{
 		"ALTER TABLE findings ADD COLUMN serial TEXT DEFAULT ''",
 		"ALTER TABLE findings ADD COLUMN issuer TEXT DEFAULT ''",
+		// v3: snapshot metadata and coverage gap count
+		"ALTER TABLE snapshots ADD COLUMN metadata TEXT DEFAULT ''",
+		"ALTER TABLE snapshots ADD COLUMN coverage_gaps INTEGER DEFAULT 0",
+		// v4: full finding JSON and discoverer errors, so stored snapshots round-trip
+		"ALTER TABLE findings ADD COLUMN detail TEXT DEFAULT ''",
+		"ALTER TABLE snapshots ADD COLUMN errors TEXT DEFAULT ''",
+		// v5: certificate notBefore for lineage
+		"ALTER TABLE findings ADD COLUMN not_before DATETIME",
+		// v6: finding cluster and a snapshot time index for range queries
+		"ALTER TABLE findings ADD COLUMN cluster TEXT DEFAULT ''",
+		"CREATE INDEX IF NOT EXISTS idx_snapshots_at ON snapshots(at)",
 	} {
 		if _, err := db.Exec(stmt); err != nil && !isDuplicateColumn(err) {
 			return err
 		}
 	}
+	// v7: snapshot times in UTC
+	if err := normalizeSnapshotTimes(db); err != nil {
+		return err
+	}
+	// v8: snapshot cluster, so imported clusters keep separate streams
+	return addSnapshotCluster(db)
+}
+
+// addSnapshotCluster adds the snapshot cluster column and fills it from the
+// metadata of snapshots saved before it existed.
+func addSnapshotCluster(db *sql.DB) error {
+	if _, err := db.Exec("ALTER TABLE snapshots ADD COLUMN cluster TEXT NOT NULL DEFAULT ''"); err != nil {
+		if !isDuplicateColumn(err) {
+			return err
+		}
+	} else if _, err := db.Exec(`UPDATE snapshots SET cluster = json_extract(metadata, '$.cluster')
+		WHERE metadata != '' AND json_extract(metadata, '$.cluster') IS NOT NULL`); err != nil {
+		return fmt.Errorf("filling snapshot clusters: %w", err)
+	}
+	_, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_snapshots_cluster_at ON snapshots(cluster, at)")
+	return err
+}
+
+// normalizeSnapshotTimes rewrites snapshot times saved with a zone offset in
+// UTC. Times are stored as text, so range filters compare them as strings
+// and only order correctly when every row uses the same zone.
+func normalizeSnapshotTimes(db *sql.DB) error {
+	rows, err := db.Query("SELECT id, at FROM snapshots WHERE CAST(at AS TEXT) NOT LIKE '% +0000 UTC'")
+	if err != nil {
+		return fmt.Errorf("querying snapshot times: %w", err)
+	}
+	stale := make(map[int64]time.Time)
+	for rows.Next() {
+		var id int64
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			rows.Close() //nolint:errcheck // returning the scan error
+			return fmt.Errorf("scanning snapshot time: %w", err)
+		}
+		stale[id] = at
+	}
+	rows.Close() //nolint:errcheck // read-only query
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, at := range stale {
+		if _, err := db.Exec("UPDATE snapshots SET at = ? WHERE id = ?", at.UTC(), id); err != nil {
+			return fmt.Errorf("normalizing snapshot %d time: %w", id, err)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,214 @@
+package history
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/notify"
+)
+
+// Outbox times are stored as Unix nanoseconds so due-message queries compare
+// integers rather than formatted strings.
+const outboxColumns = `id, created_at, next_attempt, sent_at, kind, webhook, url, target,
+	content_type, summary, body, status, attempts, last_error`
+
+var _ notify.Outbox = (*Outbox)(nil)
+
+// Outbox is a notify.Outbox backed by the history database, so queued
+// notifications and cooldowns survive restarts.
+type Outbox struct {
+	db *database
+}
+
+// Outbox returns the notification outbox stored alongside the snapshot history.
+func (s *Store) Outbox() *Outbox {
+	return &Outbox{db: s.db}
+}
+
+// Enqueue stores a pending notification and assigns its ID.
+func (o *Outbox) Enqueue(msg *notify.Message) error {
+	id, err := o.db.insert(
+		`INSERT INTO notification_outbox (created_at, next_attempt, sent_at, kind, webhook, url, target,
+			content_type, summary, body, status, attempts, last_error)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		msg.CreatedAt.UnixNano(), msg.NextAttempt.UnixNano(), sentAtNanos(msg.SentAt),
+		msg.Kind, msg.Webhook, msg.Endpoint, msg.Target, msg.ContentType, msg.Summary, msg.Body,
+		string(msg.Status), msg.Attempts, msg.LastError,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting notification: %w", err)
+	}
+	msg.ID = id
+	return nil
+}
+
+// Due claims pending, failed, and lease-expired sending notifications whose
+// next attempt is at or before now. The claim is a single UPDATE, and on
+// PostgreSQL rows another replica is claiming are skipped rather than waited on.
+func (o *Outbox) Due(now time.Time, limit int) ([]notify.Message, error) {
+	msgs, err := o.queryMessages(
+		`UPDATE notification_outbox SET status = ?, next_attempt = ?
+		 WHERE id IN (SELECT id FROM notification_outbox
+			WHERE status IN (?, ?, ?) AND next_attempt <= ?
+			ORDER BY id ASC LIMIT ?`+o.db.backend.skipLocked()+`)
+		 RETURNING `+outboxColumns,
+		string(notify.StatusSending), now.Add(notify.ClaimLease).UnixNano(),
+		string(notify.StatusPending), string(notify.StatusFailed), string(notify.StatusSending),
+		now.UnixNano(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	// RETURNING does not preserve the subquery's order.
+	slices.SortFunc(msgs, func(a, b notify.Message) int { return cmp.Compare(a.ID, b.ID) })
+	return msgs, nil
+}
+
+// Update records the outcome of a delivery attempt.
+func (o *Outbox) Update(msg *notify.Message) error {
+	_, err := o.db.Exec(
+		`UPDATE notification_outbox
+		 SET status = ?, attempts = ?, next_attempt = ?, sent_at = ?, last_error = ?
+		 WHERE id = ?`,
+		string(msg.Status), msg.Attempts, msg.NextAttempt.UnixNano(), sentAtNanos(msg.SentAt), msg.LastError, msg.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("updating notification %d: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// List returns notifications newest first, filtered by status when status is non-empty.
+func (o *Outbox) List(status notify.Status, limit int) ([]notify.Message, error) {
+	if status == "" {
+		return o.queryMessages(
+			`SELECT `+outboxColumns+` FROM notification_outbox ORDER BY id DESC LIMIT ?`, limit)
+	}
+	return o.queryMessages(
+		`SELECT `+outboxColumns+` FROM notification_outbox WHERE status = ? ORDER BY id DESC LIMIT ?`,
+		string(status), limit)
+}
+
+// Counts returns the number of notifications in each status.
+func (o *Outbox) Counts() (map[notify.Status]int, error) {
+	rows, err := o.db.Query(`SELECT status, COUNT(*) FROM notification_outbox GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("counting notifications: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // read-only close
+
+	counts := make(map[notify.Status]int)
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("scanning notification count: %w", err)
+		}
+		counts[notify.Status(status)] = n
+	}
+	return counts, rows.Err()
+}
+
+// Cooldowns returns when each finding key was last notified.
+func (o *Outbox) Cooldowns() (map[string]time.Time, error) {
+	rows, err := o.db.Query(`SELECT key, sent_at FROM notification_cooldowns`)
+	if err != nil {
+		return nil, fmt.Errorf("querying cooldowns: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // read-only close
+
+	cooldowns := make(map[string]time.Time)
+	for rows.Next() {
+		var key string
+		var at int64
+		if err := rows.Scan(&key, &at); err != nil {
+			return nil, fmt.Errorf("scanning cooldown: %w", err)
+		}
+		cooldowns[key] = time.Unix(0, at).UTC()
+	}
+	return cooldowns, rows.Err()
+}
+
+// SaveCooldown records when a finding key was last notified.
+func (o *Outbox) SaveCooldown(key string, at time.Time) error {
+	_, err := o.db.Exec(
+		`INSERT INTO notification_cooldowns (key, sent_at) VALUES (?, ?)
+		 ON CONFLICT(key) DO UPDATE SET sent_at = excluded.sent_at`,
+		key, at.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("saving cooldown for %s: %w", key, err)
+	}
+	return nil
+}
+
+// DeleteCooldowns removes the given keys from the cooldown state.
+func (o *Outbox) DeleteCooldowns(keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	tx, err := o.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // commit below; rollback is no-op after commit
+	for _, key := range keys {
+		if _, err := tx.Exec(`DELETE FROM notification_cooldowns WHERE key = ?`, key); err != nil {
+			return fmt.Errorf("deleting cooldown for %s: %w", key, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Prune deletes sent and dead notifications created before the given time.
+func (o *Outbox) Prune(before time.Time) (int, error) {
+	res, err := o.db.Exec(
+		`DELETE FROM notification_outbox WHERE status IN (?, ?) AND created_at < ?`,
+		string(notify.StatusSent), string(notify.StatusDead), before.UnixNano(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("pruning notifications: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("counting pruned notifications: %w", err)
+	}
+	return int(n), nil
+}
+
+func (o *Outbox) queryMessages(query string, args ...any) ([]notify.Message, error) {
+	rows, err := o.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying notifications: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // read-only close
+
+	msgs := []notify.Message{}
+	for rows.Next() {
+		var m notify.Message
+		var created, next, sent int64
+		var status string
+		if err := rows.Scan(&m.ID, &created, &next, &sent, &m.Kind, &m.Webhook, &m.Endpoint, &m.Target,
+			&m.ContentType, &m.Summary, &m.Body, &status, &m.Attempts, &m.LastError); err != nil {
+			return nil, fmt.Errorf("scanning notification: %w", err)
+		}
+		m.CreatedAt = time.Unix(0, created).UTC()
+		m.NextAttempt = time.Unix(0, next).UTC()
+		if sent != 0 {
+			t := time.Unix(0, sent).UTC()
+			m.SentAt = &t
+		}
+		m.Status = notify.Status(status)
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+func sentAtNanos(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.UnixNano()
+}
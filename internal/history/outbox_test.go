@@ -0,0 +1,237 @@
+package history
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/notify"
+)
+
+func TestOutbox_Lifecycle(t *testing.T) {
+	o := openMemory(t).Outbox()
+	now := time.Now().UTC()
+
+	msg := &notify.Message{
+		CreatedAt:   now,
+		NextAttempt: now,
+		Body:        []byte(`{"ok":true}`),
+		Kind:        "generic",
+		Webhook:     "alerts",
+		Target:      "alerts.example.com",
+		ContentType: "application/json",
+		Summary:     "1 critical finding(s)",
+		Status:      notify.StatusPending,
+	}
+	if err := o.Enqueue(msg); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if msg.ID == 0 {
+		t.Fatal("expected Enqueue to assign an ID")
+	}
+
+	due, err := o.Due(now, 10)
+	if err != nil {
+		t.Fatalf("Due: %v", err)
+	}
+	if len(due) != 1 || string(due[0].Body) != `{"ok":true}` || due[0].Webhook != msg.Webhook {
+		t.Fatalf("Due = %+v, want the queued message with its body", due)
+	}
+
+	// Failed attempt scheduled in the future is not due yet.
+	due[0].Status = notify.StatusFailed
+	due[0].Attempts = 1
+	due[0].LastError = "webhook returned status 503"
+	due[0].NextAttempt = now.Add(time.Minute)
+	if err := o.Update(&due[0]); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if due, _ = o.Due(now, 10); len(due) != 0 { //nolint:errcheck // checked above
+		t.Fatalf("expected nothing due before next attempt, got %d", len(due))
+	}
+	later, err := o.Due(now.Add(2*time.Minute), 10)
+	if err != nil || len(later) != 1 {
+		t.Fatalf("Due after backoff = %d messages, err %v; want 1", len(later), err)
+	}
+
+	sentAt := now.Add(2 * time.Minute)
+	later[0].Status = notify.StatusSent
+	later[0].Attempts = 2
+	later[0].LastError = ""
+	later[0].SentAt = &sentAt
+	if err := o.Update(&later[0]); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	sent, err := o.List(notify.StatusSent, 10)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(sent) != 1 || sent[0].Attempts != 2 || sent[0].SentAt == nil || !sent[0].SentAt.Equal(sentAt) {
+		t.Errorf("List(sent) = %+v, want one message sent at %s after 2 attempts", sent, sentAt)
+	}
+
+	counts, err := o.Counts()
+	if err != nil {
+		t.Fatalf("Counts: %v", err)
+	}
+	if counts[notify.StatusSent] != 1 || len(counts) != 1 {
+		t.Errorf("Counts = %v, want only 1 sent", counts)
+	}
+}
+
+func TestOutbox_Cooldowns(t *testing.T) {
+	o := openMemory(t).Outbox()
+	first := time.Now().UTC().Add(-time.Hour)
+	second := time.Now().UTC()
+
+	if err := o.SaveCooldown("tls-secret/default/web", first); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.SaveCooldown("tls-secret/default/web", second); err != nil {
+		t.Fatal(err)
+	}
+
+	cooldowns, err := o.Cooldowns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cooldowns) != 1 || !cooldowns["tls-secret/default/web"].Equal(second) {
+		t.Errorf("Cooldowns = %v, want the latest time for one key", cooldowns)
+	}
+
+	if err := o.SaveCooldown("open:tls-secret/default/api", second); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.DeleteCooldowns([]string{"tls-secret/default/web", "missing"}); err != nil {
+		t.Fatal(err)
+	}
+	cooldowns, err = o.Cooldowns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cooldowns["open:tls-secret/default/api"]; len(cooldowns) != 1 || !ok {
+		t.Errorf("Cooldowns after delete = %v, want only the open key", cooldowns)
+	}
+}
+
+func TestOutbox_Prune(t *testing.T) {
+	o := openMemory(t).Outbox()
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	for _, m := range []notify.Message{
+		{CreatedAt: old, Status: notify.StatusSent},
+		{CreatedAt: old, Status: notify.StatusPending},
+		{CreatedAt: time.Now().UTC(), Status: notify.StatusDead},
+	} {
+		if err := o.Enqueue(&m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruned, err := o.Prune(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 1 {
+		t.Errorf("pruned = %d, want 1", pruned)
+	}
+	counts, err := o.Counts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[notify.StatusPending] != 1 || counts[notify.StatusDead] != 1 || counts[notify.StatusSent] != 0 {
+		t.Errorf("counts after prune = %v", counts)
+	}
+}
+
+func TestOutbox_DueClaimsMessages(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *Store) {
+		o := s.Outbox()
+		now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+		for range 3 {
+			msg := &notify.Message{CreatedAt: now, NextAttempt: now, Kind: "generic", Status: notify.StatusPending}
+			if err := o.Enqueue(msg); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		first, err := o.Due(now, 2)
+		if err != nil || len(first) != 2 || first[0].ID >= first[1].ID {
+			t.Fatalf("first Due = %+v, %v; want the two oldest messages", first, err)
+		}
+		second, err := o.Due(now, 2)
+		if err != nil || len(second) != 1 || second[0].ID == first[0].ID || second[0].ID == first[1].ID {
+			t.Fatalf("second Due = %+v, %v; want only the unclaimed message", second, err)
+		}
+		if none, _ := o.Due(now, 2); len(none) != 0 { //nolint:errcheck // checked above
+			t.Fatalf("expected every message to be claimed, got %+v", none)
+		}
+		counts, err := o.Counts()
+		if err != nil || counts[notify.StatusSending] != 3 {
+			t.Fatalf("Counts = %v, %v; want 3 sending", counts, err)
+		}
+
+		// A claimer that died mid-delivery loses its messages when the lease expires.
+		reclaimed, err := o.Due(now.Add(notify.ClaimLease), 10)
+		if err != nil || len(reclaimed) != 3 {
+			t.Fatalf("Due after lease = %d messages, %v; want 3", len(reclaimed), err)
+		}
+	})
+}
+
+func TestOutbox_DueConcurrentReplicas(t *testing.T) {
+	a := openTestPostgres(t)
+	b, err := Open(postgresDSN(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close() //nolint:errcheck // test cleanup
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	const total = 60
+	for range total {
+		msg := &notify.Message{CreatedAt: now, NextAttempt: now, Kind: "generic", Status: notify.StatusPending}
+		if err := a.Outbox().Enqueue(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each replica drains the queue with two workers; SKIP LOCKED must hand
+	// every message to exactly one of them.
+	var (
+		mu      sync.Mutex
+		claimed = map[int64]int{}
+		wg      sync.WaitGroup
+	)
+	for _, s := range []*Store{a, b, a, b} {
+		wg.Add(1)
+		go func(o *Outbox) {
+			defer wg.Done()
+			for {
+				due, err := o.Due(now, 3)
+				if err != nil {
+					t.Errorf("Due: %v", err)
+					return
+				}
+				if len(due) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, m := range due {
+					claimed[m.ID]++
+				}
+				mu.Unlock()
+			}
+		}(s.Outbox())
+	}
+	wg.Wait()
+
+	if len(claimed) != total {
+		t.Errorf("claimed %d distinct messages, want %d", len(claimed), total)
+	}
+	for id, n := range claimed {
+		if n != 1 {
+			t.Errorf("message %d claimed %d times", id, n)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" database/sql driver
+)
+
+// postgresBackend stores history in a shared PostgreSQL database.
+type postgresBackend struct{}
+
+// postgresMigrationLock is the advisory lock key held while migrating, so
+// replicas starting together apply each migration once.
+const postgresMigrationLock = 7_372_757_374
+
+// postgresLeaderLock is the session advisory lock held by the replica that
+// scans, saves and compacts when several share one database.
+const postgresLeaderLock = 7_372_757_375
+
+// postgresMigrations are applied in order; the index plus one is the schema
+// version recorded in schema_migrations. Append new migrations, never edit
+// released ones.
+var postgresMigrations = [][]string{
+	// v1: snapshots, findings with full detail, notification outbox and cooldowns
+	{
+		`CREATE TABLE snapshots (
+			id             BIGSERIAL PRIMARY KEY,
+			at             TIMESTAMPTZ NOT NULL,
+			findings_count INTEGER NOT NULL DEFAULT 0,
+			crit_count     INTEGER NOT NULL DEFAULT 0,
+			warn_count     INTEGER NOT NULL DEFAULT 0,
+			error_count    INTEGER NOT NULL DEFAULT 0,
+			coverage_gaps  INTEGER NOT NULL DEFAULT 0,
+			metadata       TEXT NOT NULL DEFAULT '',
+			errors         TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX idx_snapshots_at ON snapshots(at)`,
+		`CREATE TABLE findings (
+			id           BIGSERIAL PRIMARY KEY,
+			snapshot_id  BIGINT NOT NULL REFERENCES snapshots(id),
+			source       TEXT NOT NULL DEFAULT '',
+			namespace    TEXT NOT NULL DEFAULT '',
+			name         TEXT NOT NULL DEFAULT '',
+			severity     TEXT NOT NULL DEFAULT '',
+			not_after    TIMESTAMPTZ,
+			probe_ok     BOOLEAN NOT NULL DEFAULT FALSE,
+			finding_type TEXT NOT NULL DEFAULT '',
+			serial       TEXT NOT NULL DEFAULT '',
+			issuer       TEXT NOT NULL DEFAULT '',
+			detail       TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX idx_findings_snapshot ON findings(snapshot_id)`,
+		`CREATE INDEX idx_findings_trend ON findings(source, namespace, name)`,
+		`CREATE TABLE notification_outbox (
+			id           BIGSERIAL PRIMARY KEY,
+			created_at   BIGINT NOT NULL,
+			next_attempt BIGINT NOT NULL DEFAULT 0,
+			sent_at      BIGINT NOT NULL DEFAULT 0,
+			kind         TEXT NOT NULL DEFAULT '',
+			webhook      TEXT NOT NULL DEFAULT '',
+			url          TEXT NOT NULL DEFAULT '',
+			target       TEXT NOT NULL DEFAULT '',
+			content_type TEXT NOT NULL DEFAULT '',
+			summary      TEXT NOT NULL DEFAULT '',
+			body         BYTEA,
+			status       TEXT NOT NULL DEFAULT 'pending',
+			attempts     INTEGER NOT NULL DEFAULT 0,
+			last_error   TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX idx_outbox_due ON notification_outbox(status, next_attempt)`,
+		`CREATE TABLE notification_cooldowns (
+			key     TEXT PRIMARY KEY,
+			sent_at BIGINT NOT NULL
+		)`,
+	},
+	// v2: certificate notBefore for lineage
+	{
+		`ALTER TABLE findings ADD COLUMN not_before TIMESTAMPTZ`,
+	},
+	// v3: finding cluster for range queries
+	{
+		`ALTER TABLE findings ADD COLUMN cluster TEXT NOT NULL DEFAULT ''`,
+	},
+	// v4: snapshot cluster, so imported clusters keep separate streams
+	{
+		`ALTER TABLE snapshots ADD COLUMN cluster TEXT NOT NULL DEFAULT ''`,
+		`UPDATE snapshots SET cluster = metadata::jsonb->>'cluster'
+			WHERE metadata <> '' AND metadata::jsonb->>'cluster' IS NOT NULL`,
+		`CREATE INDEX idx_snapshots_cluster_at ON snapshots(cluster, at)`,
+	},
+}
+
+// openPostgres connects to the PostgreSQL database named by dsn.
+func openPostgres(dsn string) (*database, error) {
+	return openDatabase("pgx", dsn, postgresBackend{})
+}
+
+// migrate applies pending migrations in one transaction under an advisory lock.
+func (postgresBackend) migrate(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning migration: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // commit below; rollback is no-op after commit
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", postgresMigrationLock); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	var current int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if current > len(postgresMigrations) {
+		return fmt.Errorf("database schema version %d is newer than this trustwatch (%d)", current, len(postgresMigrations))
+	}
+	for v := current + 1; v <= len(postgresMigrations); v++ {
+		for _, stmt := range postgresMigrations[v-1] {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("migration v%d: %w", v, err)
+			}
+		}
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", v); err != nil {
+			return fmt.Errorf("recording migration v%d: %w", v, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// rebind numbers "?" placeholders as $1, $2, ... outside quoted literals.
+func (postgresBackend) rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	quoted := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case c == '?' && !quoted:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func (postgresBackend) returningID() bool { return true }
+
+// skipLocked lets replicas claiming outbox rows at the same time take
+// disjoint batches instead of queueing behind each other's row locks.
+func (postgresBackend) skipLocked() string { return " FOR UPDATE SKIP LOCKED" }
+
+// size sums the trustwatch tables including indexes and TOAST. Dead rows are
+// reused after autovacuum, so nothing is reported as reclaimable.
+func (postgresBackend) size(db *sql.DB) (used, free int64, err error) {
+	err = db.QueryRow(`SELECT COALESCE(SUM(pg_total_relation_size(c.oid)), 0)::BIGINT
+		FROM pg_class c
+		WHERE c.relkind = 'r' AND pg_table_is_visible(c.oid)
+		  AND c.relname IN ('snapshots', 'findings', 'notification_outbox', 'notification_cooldowns')`).Scan(&used)
+	if err != nil {
+		return 0, 0, fmt.Errorf("reading table sizes: %w", err)
+	}
+	return used, 0, nil
+}
+
+// vacuum runs a plain VACUUM in always mode. It marks deleted rows reusable
+// without the exclusive lock VACUUM FULL would take on a shared database.
+func (postgresBackend) vacuum(db *sql.DB, mode string, _ Stats) (bool, error) {
+	if mode != VacuumAlways {
+		return false, nil
+	}
+	if _, err := db.Exec("VACUUM (ANALYZE) findings, snapshots"); err != nil {
+		return false, fmt.Errorf("vacuuming database: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,236 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// Query limits.
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+)
+
+// Query selects findings recorded in snapshots taken between From and To.
+// A zero From or To leaves that end open. Each filter matches any of its
+// values and empty filters match everything; Issuers match case-insensitive
+// substrings.
+type Query struct {
+	From         time.Time
+	To           time.Time
+	Sources      []string
+	Namespaces   []string
+	Clusters     []string
+	Severities   []string
+	FindingTypes []string
+	Issuers      []string
+	Limit        int // page size; DefaultQueryLimit when zero, at most MaxQueryLimit
+	Offset       int
+}
+
+// Record is one finding as recorded in a snapshot.
+type Record struct {
+	At         time.Time         `json:"at"`
+	Finding    store.CertFinding `json:"finding"`
+	SnapshotID int64             `json:"snapshotId"`
+}
+
+// Page is one page of query results, oldest snapshot first.
+type Page struct {
+	Records []Record `json:"records"`
+	Total   int      `json:"total"` // matching records across all pages
+	Limit   int      `json:"limit"`
+	Offset  int      `json:"offset"`
+	// NextOffset is the offset of the next page; zero on the last page.
+	NextOffset int `json:"nextOffset,omitempty"`
+}
+
+// Bucket counts the matching findings of one interval by severity.
+type Bucket struct {
+	Start  time.Time              `json:"start"`
+	At     time.Time              `json:"at"` // the snapshot counted
+	Counts map[store.Severity]int `json:"counts"`
+	Total  int                    `json:"total"`
+}
+
+// likeEscaper escapes LIKE wildcards so issuer filters match literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// where returns the SQL conditions and arguments for q over findings f
+// joined with snapshots s.
+func (q *Query) where() (string, []any) {
+	conds := []string{"1 = 1"}
+	var args []any
+	if !q.From.IsZero() {
+		conds = append(conds, "s.at >= ?")
+		args = append(args, q.From)
+	}
+	if !q.To.IsZero() {
+		conds = append(conds, "s.at <= ?")
+		args = append(args, q.To)
+	}
+	for _, c := range []struct {
+		column string
+		values []string
+	}{
+		{"f.source", q.Sources}, {"f.namespace", q.Namespaces}, {"f.cluster", q.Clusters},
+		{"f.severity", q.Severities}, {"f.finding_type", q.FindingTypes},
+	} {
+		if len(c.values) == 0 {
+			continue
+		}
+		conds = append(conds, c.column+" IN (?"+strings.Repeat(", ?", len(c.values)-1)+")")
+		for _, v := range c.values {
+			args = append(args, v)
+		}
+	}
+	if len(q.Issuers) > 0 {
+		var like []string
+		for _, v := range q.Issuers {
+			like = append(like, `LOWER(f.issuer) LIKE ? ESCAPE '\'`)
+			args = append(args, "%"+likeEscaper.Replace(strings.ToLower(v))+"%")
+		}
+		conds = append(conds, "("+strings.Join(like, " OR ")+")")
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// Query returns one page of the findings matching q, ordered by snapshot
+// time and then by the order they were saved.
+func (s *Store) Query(q Query) (*Page, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultQueryLimit
+	}
+	q.Limit = min(q.Limit, MaxQueryLimit)
+	q.Offset = max(q.Offset, 0)
+	where, args := q.where()
+
+	page := &Page{Records: []Record{}, Limit: q.Limit, Offset: q.Offset}
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM findings f JOIN snapshots s ON s.id = f.snapshot_id WHERE "+where, args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("counting findings: %w", err)
+	}
+
+	rows, err := s.db.Query(
+		"SELECT s.at, f.snapshot_id, "+findingColumns+" FROM findings f JOIN snapshots s ON s.id = f.snapshot_id WHERE "+where+
+			" ORDER BY s.at, f.id LIMIT ? OFFSET ?",
+		append(args, q.Limit, q.Offset)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying findings: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // read-only query
+
+	for rows.Next() {
+		var r Record
+		r.Finding, err = scanFinding(rows, &r.At, &r.SnapshotID)
+		if err != nil {
+			return nil, err
+		}
+		page.Records = append(page.Records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if next := q.Offset + len(page.Records); next < page.Total {
+		page.NextOffset = next
+	}
+	return page, nil
+}
+
+// CountBySeverity counts the findings matching q by severity per interval.
+// Buckets are aligned as by time.Time.Truncate in UTC, so daily buckets start
+// at midnight and weekly ones on Monday. Each bucket counts the last snapshot
+// taken in it for each cluster's stream, so it reads as the state at the end
+// of the interval however often scans ran. Intervals without a snapshot are omitted; Limit and Offset
+// are ignored.
+func (s *Store) CountBySeverity(q Query, interval time.Duration) ([]Bucket, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %s", interval)
+	}
+	var snapConds []string
+	var snapArgs []any
+	if !q.From.IsZero() {
+		snapConds = append(snapConds, "at >= ?")
+		snapArgs = append(snapArgs, q.From)
+	}
+	if !q.To.IsZero() {
+		snapConds = append(snapConds, "at <= ?")
+		snapArgs = append(snapArgs, q.To)
+	}
+	snapQuery := "SELECT id, at, cluster FROM snapshots"
+	if len(snapConds) > 0 {
+		snapQuery += " WHERE " + strings.Join(snapConds, " AND ")
+	}
+	snaps, err := s.db.Query(snapQuery+" ORDER BY at, id", snapArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("querying snapshots: %w", err)
+	}
+	type slot struct {
+		bucket Bucket
+		last   map[string]int64 // cluster → ID of its last snapshot in the bucket
+	}
+	var slots []slot
+	for snaps.Next() {
+		var id int64
+		var at time.Time
+		var cluster string
+		if err := snaps.Scan(&id, &at, &cluster); err != nil {
+			snaps.Close() //nolint:errcheck // returning the scan error
+			return nil, fmt.Errorf("scanning snapshot: %w", err)
+		}
+		start := at.UTC().Truncate(interval)
+		if n := len(slots); n > 0 && slots[n-1].bucket.Start.Equal(start) {
+			slots[n-1].bucket.At, slots[n-1].last[cluster] = at, id
+			continue
+		}
+		slots = append(slots, slot{
+			bucket: Bucket{Start: start, At: at, Counts: make(map[store.Severity]int)},
+			last:   map[string]int64{cluster: id},
+		})
+	}
+	snaps.Close() //nolint:errcheck // read-only query
+	if err := snaps.Err(); err != nil {
+		return nil, err
+	}
+	buckets := make(map[int64]*Bucket, len(slots))
+	for i := range slots {
+		for _, id := range slots[i].last {
+			buckets[id] = &slots[i].bucket
+		}
+	}
+
+	where, args := q.where()
+	rows, err := s.db.Query(
+		"SELECT f.snapshot_id, f.severity, COUNT(*) FROM findings f JOIN snapshots s ON s.id = f.snapshot_id WHERE "+where+
+			" GROUP BY f.snapshot_id, f.severity",
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("counting findings: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // read-only query
+	for rows.Next() {
+		var id int64
+		var severity store.Severity
+		var n int
+		if err := rows.Scan(&id, &severity, &n); err != nil {
+			return nil, fmt.Errorf("scanning count: %w", err)
+		}
+		if b, ok := buckets[id]; ok {
+			b.Counts[severity] += n
+			b.Total += n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]Bucket, 0, len(slots))
+	for i := range slots {
+		result = append(result, slots[i].bucket)
+	}
+	return result, nil
+}
@@ -0,0 +1,127 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// saveFleet records three scans an hour apart of a federated snapshot with
+// one finding per cluster; the prod finding turns critical in the last scan.
+func saveFleet(t *testing.T, s *Store, base time.Time) {
+	t.Helper()
+	for i := range 3 {
+		prod := store.SeverityWarn
+		if i == 2 {
+			prod = store.SeverityCritical
+		}
+		snap := store.Snapshot{
+			At: base.Add(time.Duration(i) * time.Hour),
+			Findings: []store.CertFinding{
+				{Name: "api", Namespace: "payments", Cluster: "prod", Source: store.SourceCertManager, Severity: prod,
+					Issuer: "CN=Let's Encrypt R11", ProbeOK: true},
+				{Name: "web", Namespace: "web", Cluster: "staging", Source: store.SourceIngressTLS, Severity: store.SeverityInfo,
+					Issuer: "CN=Internal CA", ProbeOK: true, FindingType: "POLICY_VIOLATION"},
+			},
+		}
+		if err := s.Save(snap); err != nil {
+			t.Fatalf("save failed: %v", err)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	s := openMemory(t)
+	base := time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)
+	saveFleet(t, s, base)
+
+	page, err := s.Query(Query{From: base.Add(30 * time.Minute), Clusters: []string{"prod"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || len(page.Records) != 2 || page.NextOffset != 0 {
+		t.Fatalf("page = %+v", page)
+	}
+	if r := page.Records[1]; !r.At.Equal(base.Add(2*time.Hour)) || r.Finding.Severity != store.SeverityCritical || r.Finding.Cluster != "prod" {
+		t.Errorf("last record = %+v", r)
+	}
+
+	page, err = s.Query(Query{Issuers: []string{"internal"}, FindingTypes: []string{"POLICY_VIOLATION"}, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || len(page.Records) != 2 || page.NextOffset != 2 {
+		t.Fatalf("first page = %+v", page)
+	}
+	page, err = s.Query(Query{Issuers: []string{"Internal"}, Limit: 2, Offset: page.NextOffset})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Records) != 1 || page.NextOffset != 0 || page.Records[0].Finding.Name != "web" {
+		t.Errorf("second page = %+v", page)
+	}
+
+	page, err = s.Query(Query{Severities: []string{"critical", "warn"}, To: base.Add(time.Hour)})
+	if err != nil || page.Total != 2 {
+		t.Errorf("warn and critical up to the second scan = %+v, %v", page, err)
+	}
+}
+
+func TestQuery_IssuerWildcards(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *Store) {
+		snap := store.Snapshot{
+			At: time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC),
+			Findings: []store.CertFinding{
+				{Name: "pct", Namespace: "a", Source: store.SourceTLSSecret, Severity: store.SeverityInfo, Issuer: "CN=100% Trusted CA"},
+				{Name: "plain", Namespace: "a", Source: store.SourceTLSSecret, Severity: store.SeverityInfo, Issuer: "CN=100 Trusted CA"},
+				{Name: "under", Namespace: "a", Source: store.SourceTLSSecret, Severity: store.SeverityInfo, Issuer: `CN=ops_ca\int`},
+				{Name: "other", Namespace: "a", Source: store.SourceTLSSecret, Severity: store.SeverityInfo, Issuer: "CN=opsXca/int"},
+			},
+		}
+		if err := s.Save(snap); err != nil {
+			t.Fatal(err)
+		}
+		for filter, want := range map[string]string{"100%": "pct", "ops_ca": "under", `_ca\int`: "under"} {
+			page, err := s.Query(Query{Issuers: []string{filter}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total != 1 || page.Records[0].Finding.Name != want {
+				t.Errorf("issuer %q matched %+v, want only %s", filter, page.Records, want)
+			}
+		}
+	})
+}
+
+func TestCountBySeverity(t *testing.T) {
+	s := openMemory(t)
+	base := time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)
+	saveFleet(t, s, base)
+	saveFleet(t, s, base.Add(24*time.Hour))
+
+	buckets, err := s.CountBySeverity(Query{Namespaces: []string{"payments", "web"}}, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("buckets = %+v, want one per day", buckets)
+	}
+	// Each day counts its last scan only.
+	b := buckets[0]
+	if !b.Start.Equal(time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)) || !b.At.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("first bucket at %s for snapshot %s", b.Start, b.At)
+	}
+	if b.Total != 2 || b.Counts[store.SeverityCritical] != 1 || b.Counts[store.SeverityInfo] != 1 || b.Counts[store.SeverityWarn] != 0 {
+		t.Errorf("first bucket counts = %+v", b)
+	}
+
+	hourly, err := s.CountBySeverity(Query{Clusters: []string{"prod"}, From: base.Add(24 * time.Hour)}, time.Hour)
+	if err != nil || len(hourly) != 3 || hourly[0].Counts[store.SeverityWarn] != 1 || hourly[0].Total != 1 {
+		t.Errorf("hourly prod buckets = %+v, %v", hourly, err)
+	}
+
+	if _, err := s.CountBySeverity(Query{}, 0); err == nil {
+		t.Error("expected an error for a zero interval")
+	}
+}
@@ -0,0 +1,174 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Vacuum modes for Store.Vacuum.
+const (
+	VacuumAuto   = "auto"   // SQLite: vacuum once free pages reach vacuumFreeRatio; PostgreSQL: autovacuum
+	VacuumAlways = "always" // vacuum after every compaction
+	VacuumNever  = "never"  // rely on the database reusing free space
+
+	vacuumFreeRatio = 0.25
+	deleteBatch     = 500
+)
+
+// Retention describes how long snapshots are kept at each resolution.
+// Snapshots younger than Full are all kept. Older snapshots are thinned to the
+// first one per hour until Hourly, then to the first one per day until Daily,
+// and deleted after that. A zero Full disables compaction, a zero Hourly skips
+// the hourly tier, and a zero Daily keeps daily snapshots forever.
+type Retention struct {
+	Full   time.Duration
+	Hourly time.Duration
+	Daily  time.Duration
+}
+
+// CompactResult reports the rows removed by a compaction.
+type CompactResult struct {
+	Snapshots int64 `json:"snapshots"`
+	Findings  int64 `json:"findings"`
+}
+
+// Stats reports the database size and per-table row counts.
+type Stats struct {
+	Rows      map[string]int64 `json:"rows"`
+	SizeBytes int64            `json:"sizeBytes"`
+	FreeBytes int64            `json:"freeBytes"` // reclaimable by VACUUM
+}
+
+// statTables are the tables counted by Stats.
+var statTables = []string{"snapshots", "findings", "notification_outbox"}
+
+// Expired returns the IDs of snapshots the retention policy no longer keeps,
+// oldest first. Each cluster's stream is thinned separately.
+func (s *Store) Expired(r Retention, now time.Time) ([]int64, error) {
+	if r.Full <= 0 {
+		return nil, nil
+	}
+	rows, err := s.db.Query("SELECT id, at, cluster FROM snapshots WHERE at < ? ORDER BY at ASC, id ASC", now.Add(-r.Full))
+	if err != nil {
+		return nil, fmt.Errorf("querying snapshots: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // read-only query
+
+	var expired []int64
+	kept := make(map[string]bool)
+	for rows.Next() {
+		var id int64
+		var at time.Time
+		var cluster string
+		if err := rows.Scan(&id, &at, &cluster); err != nil {
+			return nil, fmt.Errorf("scanning snapshot: %w", err)
+		}
+		age := now.Sub(at)
+		if r.Daily > 0 && age >= r.Daily {
+			expired = append(expired, id)
+			continue
+		}
+		bucket := "d" + at.UTC().Truncate(24*time.Hour).Format(time.RFC3339)
+		if r.Hourly > 0 && age < r.Hourly {
+			bucket = "h" + at.UTC().Truncate(time.Hour).Format(time.RFC3339)
+		}
+		bucket = cluster + "\x00" + bucket
+		if kept[bucket] {
+			expired = append(expired, id)
+			continue
+		}
+		kept[bucket] = true
+	}
+	return expired, rows.Err()
+}
+
+// Delete removes the given snapshots and their findings in batches.
+func (s *Store) Delete(ids []int64) (CompactResult, error) {
+	var res CompactResult
+	for start := 0; start < len(ids); start += deleteBatch {
+		batch := ids[start:min(start+deleteBatch, len(ids))]
+		n, err := s.deleteBatch(batch)
+		res.Findings += n
+		if err != nil {
+			return res, err
+		}
+		res.Snapshots += int64(len(batch))
+	}
+	return res, nil
+}
+
+func (s *Store) deleteBatch(ids []int64) (int64, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // commit below; rollback is no-op after commit
+
+	res, err := tx.Exec("DELETE FROM findings WHERE snapshot_id IN ("+placeholders+")", args...)
+	if err != nil {
+		return 0, fmt.Errorf("deleting findings: %w", err)
+	}
+	findings, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("counting deleted findings: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM snapshots WHERE id IN ("+placeholders+")", args...); err != nil {
+		return 0, fmt.Errorf("deleting snapshots: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing deletes: %w", err)
+	}
+	return findings, nil
+}
+
+// Compact deletes the snapshots the retention policy no longer keeps.
+func (s *Store) Compact(r Retention, now time.Time) (CompactResult, error) {
+	ids, err := s.Expired(r, now)
+	if err != nil {
+		return CompactResult{}, err
+	}
+	return s.Delete(ids)
+}
+
+// Vacuum reclaims space freed by compaction. SQLite rebuilds the file (in
+// auto mode only once a quarter of it is free); PostgreSQL runs a plain
+// VACUUM in always mode and otherwise leaves it to autovacuum.
+// It reports whether a vacuum ran.
+func (s *Store) Vacuum(mode string) (bool, error) {
+	if mode == VacuumNever {
+		return false, nil
+	}
+	st, err := s.Stats()
+	if err != nil {
+		return false, err
+	}
+	return s.db.backend.vacuum(s.db.DB, mode, st)
+}
+
+// Stats returns the database size and row counts.
+func (s *Store) Stats() (Stats, error) {
+	used, free, err := s.db.backend.size(s.db.DB)
+	if err != nil {
+		return Stats{}, err
+	}
+	st := Stats{
+		SizeBytes: used,
+		FreeBytes: free,
+		Rows:      make(map[string]int64, len(statTables)),
+	}
+	for _, table := range statTables {
+		var n int64
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			return Stats{}, fmt.Errorf("counting %s: %w", table, err)
+		}
+		st.Rows[table] = n
+	}
+	return st, nil
+}
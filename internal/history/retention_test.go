@@ -0,0 +1,141 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func saveAt(t *testing.T, s *Store, at time.Time) {
+	t.Helper()
+	snap := store.Snapshot{
+		At: at,
+		Findings: []store.CertFinding{
+			{Name: "a", Namespace: "ns", Source: store.SourceTLSSecret, Severity: store.SeverityWarn, ProbeOK: true},
+			{Name: "b", Namespace: "ns", Source: store.SourceTLSSecret, Severity: store.SeverityInfo, ProbeOK: true},
+		},
+	}
+	if err := s.Save(snap); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+}
+
+func TestCompact_Downsamples(t *testing.T) {
+	s := openMemory(t)
+	now := time.Date(2026, 6, 30, 12, 0, 0, 0, time.UTC)
+
+	// Scans every 30 minutes for 4 days.
+	for at := now.Add(-96 * time.Hour); !at.After(now); at = at.Add(30 * time.Minute) {
+		saveAt(t, s, at)
+	}
+	// Two scans on one day 10 days ago, and one 40 days ago.
+	saveAt(t, s, now.Add(-240*time.Hour))
+	saveAt(t, s, now.Add(-239*time.Hour))
+	saveAt(t, s, now.Add(-960*time.Hour))
+
+	r := Retention{Full: 24 * time.Hour, Hourly: 72 * time.Hour, Daily: 30 * 24 * time.Hour}
+	res, err := s.Compact(r, now)
+	if err != nil {
+		t.Fatalf("compact failed: %v", err)
+	}
+
+	summaries, err := s.List(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var full, hourly, daily int
+	for _, sm := range summaries {
+		switch age := now.Sub(sm.At); {
+		case age < r.Full:
+			full++
+		case age < r.Hourly:
+			hourly++
+		default:
+			daily++
+		}
+	}
+	// Every scan from the last day is kept.
+	if full != 48 {
+		t.Errorf("full-resolution snapshots = %d, want 48", full)
+	}
+	// Days 1-3 keep one scan per clock hour; the oldest half-hour scan opens its own hour.
+	if hourly != 49 {
+		t.Errorf("hourly snapshots = %d, want 49", hourly)
+	}
+	// Older scans keep one per UTC day; the 40-day-old scan is deleted.
+	if daily != 3 {
+		t.Errorf("daily snapshots = %d, want 3", daily)
+	}
+	if res.Snapshots != int64(193+3-len(summaries)) {
+		t.Errorf("deleted %d snapshots, but %d remain of %d", res.Snapshots, len(summaries), 193+3)
+	}
+	if res.Findings != 2*res.Snapshots {
+		t.Errorf("deleted %d findings, want %d", res.Findings, 2*res.Snapshots)
+	}
+
+	// A second pass finds nothing to delete.
+	again, err := s.Compact(r, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Snapshots != 0 {
+		t.Errorf("second compaction deleted %d snapshots, want 0", again.Snapshots)
+	}
+}
+
+func TestCompact_Disabled(t *testing.T) {
+	s := openMemory(t)
+	now := time.Now().UTC()
+	saveAt(t, s, now.Add(-1000*time.Hour))
+	saveAt(t, s, now.Add(-1000*time.Hour+time.Minute))
+
+	ids, err := s.Expired(Retention{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 0 {
+		t.Errorf("expected no expired snapshots with compaction disabled, got %d", len(ids))
+	}
+}
+
+func TestStatsAndVacuum(t *testing.T) {
+	s := openMemory(t)
+	now := time.Now().UTC()
+	for i := range 50 {
+		saveAt(t, s, now.Add(-time.Duration(i)*time.Hour))
+	}
+
+	st, err := s.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Rows["snapshots"] != 50 || st.Rows["findings"] != 100 || st.Rows["notification_outbox"] != 0 {
+		t.Errorf("rows = %v", st.Rows)
+	}
+	if st.SizeBytes <= 0 {
+		t.Errorf("sizeBytes = %d, want positive", st.SizeBytes)
+	}
+
+	if _, err := s.Compact(Retention{Full: time.Hour, Daily: 2 * time.Hour}, now); err != nil {
+		t.Fatal(err)
+	}
+	ran, err := s.Vacuum(VacuumNever)
+	if err != nil || ran {
+		t.Errorf("never mode: ran=%v err=%v", ran, err)
+	}
+	ran, err = s.Vacuum(VacuumAlways)
+	if err != nil || !ran {
+		t.Fatalf("always mode: ran=%v err=%v", ran, err)
+	}
+	after, err := s.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.FreeBytes != 0 {
+		t.Errorf("freeBytes after vacuum = %d, want 0", after.FreeBytes)
+	}
+	if after.Rows["snapshots"] != 2 {
+		t.Errorf("snapshots after compaction = %d, want 2", after.Rows["snapshots"])
+	}
+}
@@ -1,13 +1,13 @@
-// Package history provides persistent snapshot storage using SQLite.
+// Package history provides persistent snapshot storage using SQLite or PostgreSQL.
 package history
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
-	_ "modernc.org/sqlite" // CGO-free SQLite driver
-
 	"github.com/ppiankov/trustwatch/internal/store"
 )
 
@@ -15,10 +15,12 @@ import (
 type SnapshotSummary struct {
 	At            time.Time `json:"at"`
 	ID            int64     `json:"id"`
+	Cluster       string    `json:"cluster,omitempty"`
 	FindingsCount int       `json:"findingsCount"`
 	CritCount     int       `json:"critCount"`
 	WarnCount     int       `json:"warnCount"`
 	ErrorCount    int       `json:"errorCount"`
+	CoverageGaps  int       `json:"coverageGaps"` // non-zero marks a partial-coverage scan
 }
 
 // TrendPoint represents a single data point for trend analysis.
@@ -28,36 +30,47 @@ type TrendPoint struct {
 	ProbeOK  bool      `json:"probeOk"`
 }
 
-// Store persists snapshots and findings to SQLite.
+// Store persists snapshots and findings to SQLite or PostgreSQL.
+// Snapshots form one stream per cluster named in their metadata, e.g. a
+// server's own scans and each cluster imported from an archive; retention,
+// episodes and latest lookups work within a stream.
 type Store struct {
-	db *sql.DB
+	db     *database
+	leader *sql.Conn // holds postgresLeaderLock while this process leads
+	mu     sync.Mutex
 }
 
-// Open creates or opens a SQLite database at the given path and runs migrations.
-// Use ":memory:" for an in-memory database (useful for tests).
-func Open(path string) (*Store, error) {
-	db, err := sql.Open("sqlite", path)
-	if err != nil {
-		return nil, fmt.Errorf("opening database: %w", err)
+// Open opens the history database and runs migrations. A postgres:// or
+// postgresql:// DSN selects PostgreSQL, so several serve replicas can share
+// one database; anything else is a SQLite file path.
+// Use ":memory:" for an in-memory SQLite database (useful for tests).
+func Open(dsn string) (*Store, error) {
+	var db *database
+	var err error
+	if isPostgres(dsn) {
+		db, err = openPostgres(dsn)
+	} else {
+		db, err = openSQLite(dsn)
 	}
-	// Enable WAL mode for better concurrent read performance
-	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
-		db.Close() //nolint:errcheck // best-effort cleanup
-		return nil, fmt.Errorf("setting WAL mode: %w", err)
-	}
-	if err := migrate(db); err != nil {
-		db.Close() //nolint:errcheck // best-effort cleanup
-		return nil, fmt.Errorf("running migrations: %w", err)
+	if err != nil {
+		return nil, err
 	}
 	return &Store{db: db}, nil
 }
 
 // Close closes the database connection.
 func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.leader != nil {
+		s.leader.Close() //nolint:errcheck // closing the database below ends the session
+		s.leader = nil
+	}
+	s.mu.Unlock()
 	return s.db.Close()
 }
 
-// Save persists a snapshot and its findings to the database.
+// Save persists a snapshot and its findings to the database. Times are
+// stored in UTC.
 func (s *Store) Save(snap store.Snapshot) error {
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -78,21 +91,36 @@ func (s *Store) Save(snap store.Snapshot) error {
 		}
 	}
 
-	result, err := tx.Exec(
-		"INSERT INTO snapshots (at, findings_count, crit_count, warn_count, error_count) VALUES (?, ?, ?, ?, ?)",
-		snap.At, len(snap.Findings), critCount, warnCount, errCount,
-	)
-	if err != nil {
-		return fmt.Errorf("inserting snapshot: %w", err)
+	var metadata, cluster string
+	if snap.Metadata != nil {
+		cluster = snap.Metadata.Cluster
+		data, marshalErr := json.Marshal(snap.Metadata)
+		if marshalErr != nil {
+			return fmt.Errorf("marshaling snapshot metadata: %w", marshalErr)
+		}
+		metadata = string(data)
+	}
+	var discoveryErrors string
+	if len(snap.Errors) > 0 {
+		data, marshalErr := json.Marshal(snap.Errors)
+		if marshalErr != nil {
+			return fmt.Errorf("marshaling snapshot errors: %w", marshalErr)
+		}
+		discoveryErrors = string(data)
 	}
 
-	snapID, err := result.LastInsertId()
+	snapID, err := tx.insert(
+		"INSERT INTO snapshots (at, cluster, findings_count, crit_count, warn_count, error_count, metadata, coverage_gaps, errors) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		snap.At.UTC(), cluster, len(snap.Findings), critCount, warnCount, errCount, metadata, len(snap.CoverageGaps()), discoveryErrors,
+	)
 	if err != nil {
-		return fmt.Errorf("getting snapshot id: %w", err)
+		return fmt.Errorf("inserting snapshot: %w", err)
 	}
 
+	// The indexed columns serve trend queries; detail holds the complete
+	// finding so snapshots can be reconstructed exactly.
 	stmt, err := tx.Prepare(
-		"INSERT INTO findings (snapshot_id, source, namespace, name, severity, not_after, probe_ok, finding_type, serial, issuer) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		"INSERT INTO findings (snapshot_id, source, namespace, name, cluster, severity, not_after, not_before, probe_ok, finding_type, serial, issuer, detail) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
 	)
 	if err != nil {
 		return fmt.Errorf("preparing finding insert: %w", err)
@@ -101,7 +129,11 @@ func (s *Store) Save(snap store.Snapshot) error {
 
 	for i := range snap.Findings {
 		f := &snap.Findings[i]
-		_, err := stmt.Exec(snapID, f.Source, f.Namespace, f.Name, f.Severity, f.NotAfter, f.ProbeOK, f.FindingType, f.Serial, f.Issuer)
+		detail, err := json.Marshal(f)
+		if err != nil {
+			return fmt.Errorf("marshaling finding: %w", err)
+		}
+		_, err = stmt.Exec(snapID, f.Source, f.Namespace, f.Name, f.Cluster, f.Severity, f.NotAfter.UTC(), f.NotBefore.UTC(), f.ProbeOK, f.FindingType, f.Serial, f.Issuer, string(detail))
 		if err != nil {
 			return fmt.Errorf("inserting finding: %w", err)
 		}
@@ -117,7 +149,7 @@ func (s *Store) List(limit int) ([]SnapshotSummary, error) {
 	}
 
 	rows, err := s.db.Query(
-		"SELECT id, at, findings_count, crit_count, warn_count, error_count FROM snapshots ORDER BY at DESC LIMIT ?",
+		"SELECT id, at, cluster, findings_count, crit_count, warn_count, error_count, coverage_gaps FROM snapshots ORDER BY at DESC LIMIT ?",
 		limit,
 	)
 	if err != nil {
@@ -128,7 +160,7 @@ func (s *Store) List(limit int) ([]SnapshotSummary, error) {
 	var summaries []SnapshotSummary
 	for rows.Next() {
 		var s SnapshotSummary
-		if err := rows.Scan(&s.ID, &s.At, &s.FindingsCount, &s.CritCount, &s.WarnCount, &s.ErrorCount); err != nil {
+		if err := rows.Scan(&s.ID, &s.At, &s.Cluster, &s.FindingsCount, &s.CritCount, &s.WarnCount, &s.ErrorCount, &s.CoverageGaps); err != nil {
 			return nil, fmt.Errorf("scanning snapshot: %w", err)
 		}
 		summaries = append(summaries, s)
@@ -167,34 +199,140 @@ func (s *Store) Trend(name, ns, source string, limit int) ([]TrendPoint, error)
 	return points, rows.Err()
 }
 
-// GetLatest returns the most recent snapshot with its findings, or nil if no snapshots exist.
-func (s *Store) GetLatest() (*store.Snapshot, error) {
+// snapshotColumns are the snapshot columns read by getOne.
+const snapshotColumns = "id, at, metadata, errors"
+
+// GetLatest returns the most recent snapshot saved for cluster (from its
+// metadata) with its findings, or nil if there is none. Snapshots imported
+// for other clusters are separate streams and never returned.
+func (s *Store) GetLatest(cluster string) (*store.Snapshot, error) {
+	return s.getOne("SELECT "+snapshotColumns+" FROM snapshots WHERE cluster = ? ORDER BY at DESC LIMIT 1", cluster)
+}
+
+// GetSnapshot returns the snapshot with the given ID, or nil if it does not exist.
+func (s *Store) GetSnapshot(id int64) (*store.Snapshot, error) {
+	return s.getOne("SELECT "+snapshotColumns+" FROM snapshots WHERE id = ?", id)
+}
+
+// GetAt returns the most recent snapshot saved for cluster taken at or
+// before t, or nil if none exists.
+func (s *Store) GetAt(t time.Time, cluster string) (*store.Snapshot, error) {
+	return s.getOne("SELECT "+snapshotColumns+" FROM snapshots WHERE cluster = ? AND at <= ? ORDER BY at DESC LIMIT 1", cluster, t)
+}
+
+// SnapshotIDs returns the IDs of the snapshots taken between from and to,
+// oldest first. A zero from or to leaves that end open.
+func (s *Store) SnapshotIDs(from, to time.Time) ([]int64, error) {
+	query := "SELECT id FROM snapshots WHERE 1 = 1"
+	var args []any
+	if !from.IsZero() {
+		query += " AND at >= ?"
+		args = append(args, from)
+	}
+	if !to.IsZero() {
+		query += " AND at <= ?"
+		args = append(args, to)
+	}
+	rows, err := s.db.Query(query+" ORDER BY at, id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying snapshots: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // read-only query
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning snapshot: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// HasSnapshot reports whether a snapshot taken at the given time for the
+// given cluster (from its metadata) is already stored. Times are compared to
+// the microsecond, the precision PostgreSQL keeps.
+func (s *Store) HasSnapshot(at time.Time, cluster string) (bool, error) {
+	rows, err := s.db.Query("SELECT at FROM snapshots WHERE cluster = ? AND at >= ? AND at <= ?", cluster, at.Add(-time.Second), at.Add(time.Second))
+	if err != nil {
+		return false, fmt.Errorf("querying snapshots: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck // read-only query
+
+	for rows.Next() {
+		var stored time.Time
+		if err := rows.Scan(&stored); err != nil {
+			return false, fmt.Errorf("scanning snapshot: %w", err)
+		}
+		if stored.Truncate(time.Microsecond).Equal(at.Truncate(time.Microsecond)) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// getOne loads the single snapshot selected by query with its findings, in
+// the order they were saved.
+func (s *Store) getOne(query string, args ...any) (*store.Snapshot, error) {
 	var snapID int64
 	var at time.Time
-	err := s.db.QueryRow("SELECT id, at FROM snapshots ORDER BY at DESC LIMIT 1").Scan(&snapID, &at)
+	var metadata, discoveryErrors string
+	err := s.db.QueryRow(query, args...).Scan(&snapID, &at, &metadata, &discoveryErrors)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("querying latest snapshot: %w", err)
+		return nil, fmt.Errorf("querying snapshot: %w", err)
 	}
 
-	rows, err := s.db.Query(
-		"SELECT source, namespace, name, severity, not_after, probe_ok, finding_type, serial, issuer FROM findings WHERE snapshot_id = ?",
-		snapID,
-	)
+	rows, err := s.db.Query("SELECT "+findingColumns+" FROM findings f WHERE f.snapshot_id = ? ORDER BY f.id", snapID)
 	if err != nil {
 		return nil, fmt.Errorf("querying findings: %w", err)
 	}
 	defer rows.Close() //nolint:errcheck // read-only query
 
 	snap := &store.Snapshot{At: at}
+	if metadata != "" {
+		var md store.Metadata
+		if err := json.Unmarshal([]byte(metadata), &md); err != nil {
+			return nil, fmt.Errorf("parsing snapshot metadata: %w", err)
+		}
+		snap.Metadata = &md
+	}
+	if discoveryErrors != "" {
+		if err := json.Unmarshal([]byte(discoveryErrors), &snap.Errors); err != nil {
+			return nil, fmt.Errorf("parsing snapshot errors: %w", err)
+		}
+	}
 	for rows.Next() {
-		var f store.CertFinding
-		if err := rows.Scan(&f.Source, &f.Namespace, &f.Name, &f.Severity, &f.NotAfter, &f.ProbeOK, &f.FindingType, &f.Serial, &f.Issuer); err != nil {
-			return nil, fmt.Errorf("scanning finding: %w", err)
+		f, err := scanFinding(rows)
+		if err != nil {
+			return nil, err
 		}
 		snap.Findings = append(snap.Findings, f)
 	}
 	return snap, rows.Err()
 }
+
+// findingColumns are the columns scanFinding reads, qualified for joins with snapshots.
+const findingColumns = "f.source, f.namespace, f.name, f.severity, f.not_after, f.probe_ok, f.finding_type, f.serial, f.issuer, f.detail"
+
+// scanFinding reads the leading dest columns followed by findingColumns.
+// Findings saved before full detail was stored are rebuilt from the indexed
+// columns only.
+func scanFinding(rows *sql.Rows, dest ...any) (store.CertFinding, error) {
+	var f store.CertFinding
+	var detail string
+	dest = append(dest, &f.Source, &f.Namespace, &f.Name, &f.Severity, &f.NotAfter, &f.ProbeOK, &f.FindingType, &f.Serial, &f.Issuer, &detail)
+	if err := rows.Scan(dest...); err != nil {
+		return f, fmt.Errorf("scanning finding: %w", err)
+	}
+	if detail != "" {
+		f = store.CertFinding{}
+		if err := json.Unmarshal([]byte(detail), &f); err != nil {
+			return f, fmt.Errorf("parsing finding detail: %w", err)
+		}
+	}
+	return f, nil
+}
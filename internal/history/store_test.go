@@ -1,6 +1,9 @@
 package history
 
 import (
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -24,10 +27,26 @@ func TestOpen_InMemory(t *testing.T) {
 	}
 }
 
+func TestOpen_BusyTimeout(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close() //nolint:errcheck // test cleanup
+
+	var ms int
+	if err := s.db.QueryRow("PRAGMA busy_timeout").Scan(&ms); err != nil {
+		t.Fatal(err)
+	}
+	if ms != busyTimeoutMillis {
+		t.Errorf("busy_timeout = %d, want %d", ms, busyTimeoutMillis)
+	}
+}
+
 func TestMigrate_Idempotent(t *testing.T) {
 	s := openMemory(t)
 	// Running migrate again should not error
-	if err := migrate(s.db); err != nil {
+	if err := migrate(s.db.DB); err != nil {
 		t.Fatalf("second migrate failed: %v", err)
 	}
 }
@@ -177,3 +196,249 @@ func TestList_EmptyDB(t *testing.T) {
 		t.Errorf("expected 0 snapshots, got %d", len(summaries))
 	}
 }
+
+func TestSave_MetadataRoundTrip(t *testing.T) {
+	s := openMemory(t)
+	snap := store.Snapshot{
+		At:     time.Now().UTC().Truncate(time.Second),
+		Errors: map[string]string{"istio": "forbidden"},
+		Metadata: &store.Metadata{
+			Cluster: "prod",
+			Version: "1.2.3",
+			Scope:   &store.Scope{Namespaces: []string{"app"}, Denied: map[string][]string{"secrets": {"app"}}},
+		},
+	}
+	if err := s.Save(snap); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	summaries, err := s.List(10)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if summaries[0].CoverageGaps != 2 {
+		t.Errorf("coverageGaps = %d, want 2", summaries[0].CoverageGaps)
+	}
+
+	latest, err := s.GetLatest("prod")
+	if err != nil {
+		t.Fatalf("get latest failed: %v", err)
+	}
+	if latest.Metadata == nil || latest.Metadata.Cluster != "prod" || latest.Metadata.Version != "1.2.3" {
+		t.Fatalf("expected metadata to round-trip, got %+v", latest.Metadata)
+	}
+	if got := latest.Metadata.Scope.Denied["secrets"]; len(got) != 1 || got[0] != "app" {
+		t.Errorf("expected denied namespaces to round-trip, got %v", latest.Metadata.Scope.Denied)
+	}
+}
+
+func TestGetLatest_WithoutMetadata(t *testing.T) {
+	s := openMemory(t)
+	if err := s.Save(store.Snapshot{At: time.Now().UTC()}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	latest, err := s.GetLatest("")
+	if err != nil {
+		t.Fatalf("get latest failed: %v", err)
+	}
+	if latest.Metadata != nil {
+		t.Errorf("expected nil metadata, got %+v", latest.Metadata)
+	}
+}
+
+func TestGetSnapshotAndGetAt(t *testing.T) {
+	s := openMemory(t)
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		snap := store.Snapshot{
+			At:       base.Add(time.Duration(i) * 24 * time.Hour),
+			Findings: []store.CertFinding{{Name: "cert", Serial: string(rune('A' + i)), ProbeOK: true}},
+		}
+		if err := s.Save(snap); err != nil {
+			t.Fatalf("save failed: %v", err)
+		}
+	}
+
+	summaries, err := s.List(10)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	oldest := summaries[len(summaries)-1]
+
+	snap, err := s.GetSnapshot(oldest.ID)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if snap == nil || !snap.At.Equal(base) || snap.Findings[0].Serial != "A" {
+		t.Errorf("expected oldest snapshot, got %+v", snap)
+	}
+
+	snap, err = s.GetAt(base.Add(36*time.Hour), "")
+	if err != nil {
+		t.Fatalf("get at failed: %v", err)
+	}
+	if snap == nil || snap.Findings[0].Serial != "B" {
+		t.Errorf("expected second snapshot at or before +36h, got %+v", snap)
+	}
+
+	if snap, err = s.GetAt(base.Add(-time.Hour), ""); err != nil || snap != nil {
+		t.Errorf("expected no snapshot before the first, got %+v (err %v)", snap, err)
+	}
+	if snap, err = s.GetSnapshot(999); err != nil || snap != nil {
+		t.Errorf("expected nil for unknown id, got %+v (err %v)", snap, err)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	s := openMemory(t)
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	want := store.Snapshot{
+		At:       at,
+		Metadata: &store.Metadata{Cluster: "prod-east", Version: "v1.2.3"},
+		Errors:   map[string]string{"istio": "forbidden"},
+		Findings: []store.CertFinding{
+			{
+				Name: "api", Namespace: "payments", Source: store.SourceIngressTLS, Severity: store.SeverityCritical,
+				NotAfter: at.Add(72 * time.Hour), Cluster: "prod-east", Target: "api.example.com:443", SNI: "api.example.com",
+				DNSNames: []string{"api.example.com", "www.example.com"}, ChainErrors: []string{"unknown authority"},
+				PostureIssues: []string{"TLS 1.0 offered"}, PolicyName: "strict", Remediation: "renew the certificate",
+				Owner: "payments", Notify: []string{"payments-slack"}, KeySize: 2048, CertDuration: 90 * 24 * time.Hour,
+				Object:  &store.ObjectRef{APIVersion: "networking.k8s.io/v1", Kind: "Ingress", Namespace: "payments", Name: "api"},
+				ProbeOK: true,
+			},
+			{Name: "zz-first-saved-last", Source: store.SourceExternal, Severity: store.SeverityInfo, ProbeErr: "timeout"},
+		},
+	}
+	if err := s.Save(want); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	got, err := s.GetLatest("prod-east")
+	if err != nil {
+		t.Fatalf("get latest failed: %v", err)
+	}
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("snapshot did not round-trip:\n got %s\nwant %s", gotJSON, wantJSON)
+	}
+}
+
+func TestGetSnapshot_LegacyRows(t *testing.T) {
+	s := openMemory(t)
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	res, err := s.db.Exec("INSERT INTO snapshots (at, findings_count) VALUES (?, 1)", at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rows written before the detail column existed carry only the indexed columns.
+	if _, err := s.db.Exec(
+		"INSERT INTO findings (snapshot_id, source, namespace, name, severity, not_after, probe_ok, serial) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		id, store.SourceTLSSecret, "ns", "legacy", store.SeverityWarn, at.Add(time.Hour), true, "01",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := s.GetSnapshot(id)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if snap == nil || len(snap.Findings) != 1 {
+		t.Fatalf("expected 1 finding, got %+v", snap)
+	}
+	f := snap.Findings[0]
+	if f.Name != "legacy" || f.Severity != store.SeverityWarn || f.Serial != "01" || !f.ProbeOK {
+		t.Errorf("legacy finding = %+v", f)
+	}
+	if snap.Errors != nil {
+		t.Errorf("expected no errors, got %v", snap.Errors)
+	}
+}
+
+func TestStore_NonUTCTimes(t *testing.T) {
+	s := openMemory(t)
+	cest := time.FixedZone("CEST", 2*60*60)
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, cest) // 10:00Z
+	snap := store.Snapshot{At: at, Findings: []store.CertFinding{{Name: "api", Source: store.SourceTLSSecret, Severity: store.SeverityWarn}}}
+	if err := s.Save(snap); err != nil {
+		t.Fatal(err)
+	}
+	eleven := time.Date(2026, 5, 1, 11, 0, 0, 0, time.UTC)
+
+	page, err := s.Query(Query{To: eleven})
+	if err != nil || page.Total != 1 {
+		t.Errorf("Query(to=11:00Z) = %+v, %v; want the 10:00Z finding", page, err)
+	}
+	got, err := s.GetAt(eleven, "")
+	if err != nil || got == nil || !got.At.Equal(at) {
+		t.Errorf("GetAt(11:00Z) = %+v, %v; want the snapshot taken at %s", got, err, at)
+	}
+	if ids, err := s.SnapshotIDs(time.Time{}, eleven); err != nil || len(ids) != 1 {
+		t.Errorf("SnapshotIDs(to=11:00Z) = %v, %v", ids, err)
+	}
+	if ok, err := s.HasSnapshot(at.UTC(), ""); err != nil || !ok {
+		t.Errorf("HasSnapshot(10:00Z) = %v, %v; want true", ok, err)
+	}
+}
+
+func TestMigrate_NormalizesSnapshotTimes(t *testing.T) {
+	s := openMemory(t)
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	// Bypass the wrapper to store the offset the way earlier releases did.
+	if _, err := s.db.DB.Exec("INSERT INTO snapshots (at) VALUES (?)", at); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.GetAt(time.Date(2026, 5, 1, 11, 0, 0, 0, time.UTC), ""); got != nil { //nolint:errcheck // checked below
+		t.Fatalf("expected the offset row to compare as a string before migrating, got %+v", got)
+	}
+
+	if err := migrate(s.db.DB); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetAt(time.Date(2026, 5, 1, 11, 0, 0, 0, time.UTC), "")
+	if err != nil || got == nil || !got.At.Equal(at) || got.At.Location() != time.UTC {
+		t.Errorf("GetAt after migrating = %+v, %v; want %s in UTC", got, err, at)
+	}
+}
+
+func TestMigrate_FillsSnapshotCluster(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A database from before snapshots had a cluster column.
+	for _, stmt := range []string{
+		schema,
+		"ALTER TABLE snapshots ADD COLUMN metadata TEXT DEFAULT ''",
+		`INSERT INTO snapshots (at, metadata) VALUES ('2026-05-01 10:00:00 +0000 UTC', '{"cluster":"prod"}')`,
+		`INSERT INTO snapshots (at, metadata) VALUES ('2026-05-01 11:00:00 +0000 UTC', '')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close() //nolint:errcheck // reopened through Open below
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close() //nolint:errcheck // test cleanup
+	for cluster, hour := range map[string]int{"prod": 10, "": 11} {
+		got, err := s.GetLatest(cluster)
+		if err != nil || got == nil || got.At.Hour() != hour {
+			t.Errorf("GetLatest(%q) after migrating = %+v, %v; want the %d:00 snapshot", cluster, got, err, hour)
+		}
+	}
+}
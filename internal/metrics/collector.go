@@ -7,6 +7,7 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/ppiankov/trustwatch/internal/analytics"
 	"github.com/ppiankov/trustwatch/internal/store"
 )
 
@@ -18,12 +19,24 @@ type Collector struct {
 	findingsTotal      *prometheus.GaugeVec
 	discoveryErrors    *prometheus.GaugeVec
 	chainErrors        *prometheus.GaugeVec
+	historyRows        *prometheus.GaugeVec
+	rotationSLO        *prometheus.GaugeVec
+	rotationSuccess    *prometheus.GaugeVec
+	rotationRemaining  *prometheus.GaugeVec
+	rotationNearMisses *prometheus.GaugeVec
+	findingTimeOpen    *prometheus.GaugeVec
+	historyDeleted     *prometheus.CounterVec
 	discovererDuration *prometheus.HistogramVec
 	scanDuration       prometheus.Gauge
 	lastScanTimestamp  prometheus.Gauge
+	historySize        prometheus.Gauge
+	historyFree        prometheus.Gauge
 	mu                 sync.Mutex
 }
 
+// rotationLabels are the labels of the rotation analytics gauges.
+var rotationLabels = []string{"source", "namespace", "issuer"}
+
 // NewCollector creates and registers metrics on the given registerer.
 func NewCollector(reg prometheus.Registerer) *Collector {
 	c := &Collector{
@@ -81,6 +94,60 @@ func NewCollector(reg prometheus.Registerer) *Collector {
 			Help:      "Duration of each discoverer's Discover() call in seconds.",
 			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10},
 		}, []string{"source"}),
+
+		historySize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "trustwatch",
+			Name:      "history_db_size_bytes",
+			Help:      "Size of the history database in bytes.",
+		}),
+
+		historyFree: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "trustwatch",
+			Name:      "history_db_free_bytes",
+			Help:      "Bytes of free pages in the history database, reclaimable by VACUUM.",
+		}),
+
+		historyRows: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "trustwatch",
+			Name:      "history_rows",
+			Help:      "Number of rows in each history database table.",
+		}, []string{"table"}),
+
+		rotationSLO: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "trustwatch",
+			Name:      "rotation_slo_ratio",
+			Help:      "Share of ended certificates rotated with the required fraction of lifetime left, over the analytics window.",
+		}, rotationLabels),
+
+		rotationSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "trustwatch",
+			Name:      "rotation_success_ratio",
+			Help:      "Share of ended certificates rotated before expiry, over the analytics window.",
+		}, rotationLabels),
+
+		rotationRemaining: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "trustwatch",
+			Name:      "rotation_remaining_lifetime_ratio",
+			Help:      "Mean fraction of certificate lifetime left at rotation, over the analytics window.",
+		}, rotationLabels),
+
+		rotationNearMisses: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "trustwatch",
+			Name:      "rotation_near_misses",
+			Help:      "Rotations with less than the near-miss threshold of lifetime left, over the analytics window.",
+		}, rotationLabels),
+
+		findingTimeOpen: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "trustwatch",
+			Name:      "finding_time_open_seconds",
+			Help:      "Mean time resolved warn and critical findings stayed open, over the analytics window.",
+		}, rotationLabels),
+
+		historyDeleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "trustwatch",
+			Name:      "history_compacted_rows_total",
+			Help:      "Rows deleted from the history database by retention compaction.",
+		}, []string{"table"}),
 	}
 
 	reg.MustRegister(c.certNotAfter)
@@ -92,6 +159,15 @@ func NewCollector(reg prometheus.Registerer) *Collector {
 	reg.MustRegister(c.discoveryErrors)
 	reg.MustRegister(c.chainErrors)
 	reg.MustRegister(c.discovererDuration)
+	reg.MustRegister(c.historySize)
+	reg.MustRegister(c.historyFree)
+	reg.MustRegister(c.historyRows)
+	reg.MustRegister(c.historyDeleted)
+	reg.MustRegister(c.rotationSLO)
+	reg.MustRegister(c.rotationSuccess)
+	reg.MustRegister(c.rotationRemaining)
+	reg.MustRegister(c.rotationNearMisses)
+	reg.MustRegister(c.findingTimeOpen)
 
 	return c
 }
@@ -101,6 +177,50 @@ func (c *Collector) ObserveDiscovererDuration(source string, d time.Duration) {
 	c.discovererDuration.WithLabelValues(source).Observe(d.Seconds())
 }
 
+// UpdateHistory records the history database size and per-table row counts.
+func (c *Collector) UpdateHistory(sizeBytes, freeBytes int64, rows map[string]int64) {
+	c.historySize.Set(float64(sizeBytes))
+	c.historyFree.Set(float64(freeBytes))
+	for table, n := range rows {
+		c.historyRows.WithLabelValues(table).Set(float64(n))
+	}
+}
+
+// AddHistoryCompacted counts rows deleted from a history table by compaction.
+func (c *Collector) AddHistoryCompacted(table string, n int64) {
+	c.historyDeleted.WithLabelValues(table).Add(float64(n))
+}
+
+// UpdateAnalytics replaces the rotation analytics gauges with one series per
+// report group. Ratios without data are left unset.
+func (c *Collector) UpdateAnalytics(r *analytics.Report) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, g := range []*prometheus.GaugeVec{c.rotationSLO, c.rotationSuccess, c.rotationRemaining, c.rotationNearMisses, c.findingTimeOpen} {
+		g.Reset()
+	}
+	for i := range r.Groups {
+		g := &r.Groups[i]
+		labels := prometheus.Labels{"source": string(g.Source), "namespace": g.Namespace, "issuer": g.Issuer}
+		for gauge, v := range map[*prometheus.GaugeVec]*float64{
+			c.rotationSLO:       g.SLORatio,
+			c.rotationSuccess:   g.SuccessRatio,
+			c.rotationRemaining: g.MeanRemaining,
+		} {
+			if v != nil {
+				gauge.With(labels).Set(*v)
+			}
+		}
+		if g.Rotations+g.Expired > 0 {
+			c.rotationNearMisses.With(labels).Set(float64(g.NearMisses))
+		}
+		if g.ResolvedFindings > 0 {
+			c.findingTimeOpen.With(labels).Set(g.MeanTimeOpen.Seconds())
+		}
+	}
+}
+
 // Update replaces all metric values from the given snapshot.
 func (c *Collector) Update(snap store.Snapshot, scanDuration time.Duration) {
 	c.mu.Lock()
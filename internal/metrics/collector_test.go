@@ -7,6 +7,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 
+	"github.com/ppiankov/trustwatch/internal/analytics"
 	"github.com/ppiankov/trustwatch/internal/store"
 )
 
@@ -234,6 +235,60 @@ func TestObserveDiscovererDuration(t *testing.T) {
 	}
 }
 
+func TestUpdateHistory(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c := NewCollector(reg)
+
+	c.UpdateHistory(4096, 1024, map[string]int64{"snapshots": 10, "findings": 2000})
+	c.AddHistoryCompacted("findings", 150)
+	c.AddHistoryCompacted("findings", 50)
+
+	if got := testutil.ToFloat64(c.historySize); got != 4096 {
+		t.Errorf("history_db_size_bytes = %v, want 4096", got)
+	}
+	if got := testutil.ToFloat64(c.historyFree); got != 1024 {
+		t.Errorf("history_db_free_bytes = %v, want 1024", got)
+	}
+	if got := testutil.ToFloat64(c.historyRows.WithLabelValues("findings")); got != 2000 {
+		t.Errorf("history_rows{table=findings} = %v, want 2000", got)
+	}
+	if got := testutil.ToFloat64(c.historyDeleted.WithLabelValues("findings")); got != 200 {
+		t.Errorf("history_compacted_rows_total{table=findings} = %v, want 200", got)
+	}
+}
+
+func TestUpdateAnalytics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c := NewCollector(reg)
+
+	slo, success := 0.5, 0.75
+	c.UpdateAnalytics(&analytics.Report{Groups: []analytics.Group{
+		{
+			Source: store.SourceCertManager, Namespace: "payments", Issuer: "CN=LE",
+			SLORatio: &slo, SuccessRatio: &success, Rotations: 3, Expired: 1, NearMisses: 2,
+			ResolvedFindings: 2, MeanTimeOpen: time.Hour,
+		},
+		{Source: store.SourceTLSSecret, Namespace: "web", OpenFindings: 1},
+	}})
+
+	labels := []string{string(store.SourceCertManager), "payments", "CN=LE"}
+	if got := testutil.ToFloat64(c.rotationSLO.WithLabelValues(labels...)); got != 0.5 {
+		t.Errorf("rotation_slo_ratio = %v, want 0.5", got)
+	}
+	if got := testutil.ToFloat64(c.rotationNearMisses.WithLabelValues(labels...)); got != 2 {
+		t.Errorf("rotation_near_misses = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(c.findingTimeOpen.WithLabelValues(labels...)); got != 3600 {
+		t.Errorf("finding_time_open_seconds = %v, want 3600", got)
+	}
+	// Groups without rotations or resolved findings export no series.
+	for name, vec := range map[string]*prometheus.GaugeVec{"slo": c.rotationSLO, "near misses": c.rotationNearMisses, "time open": c.findingTimeOpen} {
+		if n := testutil.CollectAndCount(vec); n != 1 {
+			t.Errorf("%s series = %d, want 1", name, n)
+		}
+	}
+}
+
 func TestUpdate_DiscoveryErrors(t *testing.T) {
 	reg := prometheus.NewRegistry()
 	c := NewCollector(reg)
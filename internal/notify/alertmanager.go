@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// alertmanagerAlertName is the alertname label on every alert trustwatch pushes.
+const alertmanagerAlertName = "TrustwatchFinding"
+
+// amAlert is a single alert in an Alertmanager v2 POST /api/v2/alerts request.
+type amAlert struct {
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt,omitzero"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// pushAlertmanager sends the full set of firing alerts to every Alertmanager
+// webhook, plus resolved alerts for label sets that fired in prev but not in
+// curr. It runs on every scan so that firing alerts are refreshed before
+// Alertmanager's resolve timeout expires them.
+func (n *Notifier) pushAlertmanager(prev, curr store.Snapshot) {
+	var hooks []*config.WebhookConfig
+	for i := range n.webhooks {
+		if n.webhooks[i].Type == "alertmanager" {
+			hooks = append(hooks, &n.webhooks[i])
+		}
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	alerts := n.alertmanagerAlerts(prev, curr, now)
+	if len(alerts) == 0 {
+		return
+	}
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		slog.Warn("notification: alertmanager marshal error", "err", err)
+		return
+	}
+	summary := amSummary(alerts, now)
+	for _, wh := range hooks {
+		n.enqueue(wh, "/api/v2/alerts", summary, body)
+	}
+}
+
+// alertmanagerAlerts builds firing alerts for curr and resolved alerts for
+// label sets only present in prev.
+func (n *Notifier) alertmanagerAlerts(prev, curr store.Snapshot, now time.Time) []amAlert {
+	var endsAt time.Time
+	if n.scanInterval > 0 {
+		// Outlive a few missed scans; the next push extends it again.
+		endsAt = now.Add(3 * n.scanInterval)
+	}
+
+	var alerts []amAlert
+	firing := make(map[string]bool)
+	for i := range curr.Findings {
+		f := &curr.Findings[i]
+		if !n.severities[f.Severity] {
+			continue
+		}
+		labels := amLabels(f)
+		firing[labelKey(labels)] = true
+		alerts = append(alerts, amAlert{
+			Labels:      labels,
+			Annotations: amAnnotations(f),
+			StartsAt:    startsAt(curr.At, now),
+			EndsAt:      endsAt,
+		})
+	}
+	for i := range prev.Findings {
+		f := &prev.Findings[i]
+		if !n.severities[f.Severity] {
+			continue
+		}
+		labels := amLabels(f)
+		key := labelKey(labels)
+		if firing[key] {
+			continue
+		}
+		firing[key] = true // report each resolved label set once
+		alerts = append(alerts, amAlert{
+			Labels:      labels,
+			Annotations: amAnnotations(f),
+			StartsAt:    startsAt(prev.At, now),
+			EndsAt:      now,
+		})
+	}
+	return alerts
+}
+
+// amLabels returns the identifying labels for a finding. Empty values are omitted.
+func amLabels(f *store.CertFinding) map[string]string {
+	labels := map[string]string{
+		"alertname": alertmanagerAlertName,
+		"source":    string(f.Source),
+		"severity":  string(f.Severity),
+	}
+	for k, v := range map[string]string{
+		"namespace":   f.Namespace,
+		"name":        f.Name,
+		"cluster":     f.Cluster,
+		"findingType": f.FindingType,
+		"policyName":  f.PolicyName,
+	} {
+		if v != "" {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// amAnnotations returns the descriptive annotations for a finding.
+func amAnnotations(f *store.CertFinding) map[string]string {
+	ann := map[string]string{"summary": pdSummary(f)}
+	if f.Notes != "" {
+		ann["notes"] = f.Notes
+	}
+	if f.Remediation != "" {
+		ann["remediation"] = f.Remediation
+	}
+	if !f.NotAfter.IsZero() {
+		ann["notAfter"] = f.NotAfter.UTC().Format(time.RFC3339)
+	}
+	return ann
+}
+
+// labelKey returns a stable identity for a label set.
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%q,", k, labels[k])
+	}
+	return b.String()
+}
+
+// startsAt returns the snapshot time, falling back to now for snapshots without one.
+func startsAt(at, now time.Time) time.Time {
+	if at.IsZero() {
+		return now
+	}
+	return at.UTC()
+}
+
+func amSummary(alerts []amAlert, now time.Time) string {
+	var resolved int
+	for i := range alerts {
+		if !alerts[i].EndsAt.IsZero() && !alerts[i].EndsAt.After(now) {
+			resolved++
+		}
+	}
+	return fmt.Sprintf("%d firing, %d resolved alert(s)", len(alerts)-resolved, resolved)
+}
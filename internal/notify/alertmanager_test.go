@@ -0,0 +1,204 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// fakeAlertmanager records the alert batches posted to /api/v2/alerts.
+type fakeAlertmanager struct {
+	batches [][]amAlert
+	mu      sync.Mutex
+}
+
+func (f *fakeAlertmanager) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v2/alerts" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body) //nolint:errcheck // test helper
+		var alerts []amAlert
+		if err := json.Unmarshal(body, &alerts); err != nil {
+			t.Errorf("invalid alerts JSON: %v", err)
+		}
+		f.mu.Lock()
+		f.batches = append(f.batches, alerts)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (f *fakeAlertmanager) last(t *testing.T) []amAlert {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.batches) == 0 {
+		t.Fatal("expected Alertmanager to receive alerts")
+	}
+	return f.batches[len(f.batches)-1]
+}
+
+func alertmanagerConfig(url string) config.NotificationConfig {
+	return config.NotificationConfig{
+		Enabled:    true,
+		Webhooks:   []config.WebhookConfig{{URL: url + "/", Type: "alertmanager"}},
+		Severities: []string{"critical", "warn"},
+		Cooldown:   time.Hour,
+	}
+}
+
+func TestAlertmanager_PushesFiringAlerts(t *testing.T) {
+	am := &fakeAlertmanager{}
+	srv := httptest.NewServer(am.handler(t))
+	defer srv.Close()
+
+	n := New(alertmanagerConfig(srv.URL), WithScanInterval(2*time.Minute))
+
+	f := criticalFinding("web-tls", "prod")
+	f.Cluster = "east"
+	f.FindingType = "MANAGED_EXPIRY"
+	f.Notes = "expires soon"
+	f.Remediation = "renew the certificate"
+	curr := store.Snapshot{At: time.Now(), Findings: []store.CertFinding{f}}
+
+	n.Notify(store.Snapshot{}, curr)
+
+	alerts := am.last(t)
+	if len(alerts) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(alerts))
+	}
+	a := alerts[0]
+	wantLabels := map[string]string{
+		"alertname":   alertmanagerAlertName,
+		"source":      string(store.SourceTLSSecret),
+		"namespace":   "prod",
+		"name":        "web-tls",
+		"cluster":     "east",
+		"severity":    "critical",
+		"findingType": "MANAGED_EXPIRY",
+	}
+	for k, v := range wantLabels {
+		if a.Labels[k] != v {
+			t.Errorf("label %s = %q, want %q", k, a.Labels[k], v)
+		}
+	}
+	if _, ok := a.Labels["policyName"]; ok {
+		t.Error("empty labels should be omitted")
+	}
+	if a.Annotations["notes"] != "expires soon" || a.Annotations["remediation"] != "renew the certificate" {
+		t.Errorf("annotations = %v", a.Annotations)
+	}
+	if a.Annotations["notAfter"] != f.NotAfter.UTC().Format(time.RFC3339) {
+		t.Errorf("notAfter annotation = %q", a.Annotations["notAfter"])
+	}
+	if a.StartsAt.IsZero() {
+		t.Error("expected startsAt")
+	}
+	if !a.EndsAt.After(time.Now().Add(5 * time.Minute)) {
+		t.Errorf("firing endsAt = %s, want a few scan intervals ahead", a.EndsAt)
+	}
+}
+
+func TestAlertmanager_ResendsEveryScan(t *testing.T) {
+	am := &fakeAlertmanager{}
+	srv := httptest.NewServer(am.handler(t))
+	defer srv.Close()
+
+	n := New(alertmanagerConfig(srv.URL))
+	curr := newCriticalSnapshot()
+
+	n.Notify(store.Snapshot{}, curr)
+	n.Notify(curr, curr) // unchanged and inside the cooldown
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	if len(am.batches) != 2 {
+		t.Fatalf("expected a push on every scan, got %d", len(am.batches))
+	}
+	if !am.batches[1][0].EndsAt.IsZero() {
+		t.Error("without a scan interval, firing alerts should leave endsAt to Alertmanager")
+	}
+}
+
+func TestAlertmanager_ResolvesClearedAndRelabeledFindings(t *testing.T) {
+	am := &fakeAlertmanager{}
+	srv := httptest.NewServer(am.handler(t))
+	defer srv.Close()
+
+	n := New(alertmanagerConfig(srv.URL))
+	prev := store.Snapshot{
+		At:       time.Now().Add(-time.Minute),
+		Findings: []store.CertFinding{warnFinding("escalating", "ns"), criticalFinding("gone", "ns")},
+	}
+	curr := store.Snapshot{
+		At:       time.Now(),
+		Findings: []store.CertFinding{criticalFinding("escalating", "ns")},
+	}
+
+	n.Notify(prev, curr)
+
+	resolved := map[string]store.Severity{}
+	firing := 0
+	for _, a := range am.last(t) {
+		if a.EndsAt.IsZero() {
+			firing++
+			continue
+		}
+		if a.EndsAt.After(time.Now()) {
+			t.Errorf("resolved alert %v has future endsAt", a.Labels)
+		}
+		resolved[a.Labels["name"]] = store.Severity(a.Labels["severity"])
+	}
+	if firing != 1 {
+		t.Errorf("expected 1 firing alert, got %d", firing)
+	}
+	if resolved["gone"] != store.SeverityCritical {
+		t.Error("expected the cleared finding to be resolved")
+	}
+	if resolved["escalating"] != store.SeverityWarn {
+		t.Error("expected the old warn label set of the escalated finding to be resolved")
+	}
+}
+
+func TestAlertmanager_FailedPushNotRetried(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	n := New(alertmanagerConfig(srv.URL))
+	n.Notify(store.Snapshot{}, newCriticalSnapshot())
+
+	counts, err := n.Outbox().Counts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[StatusDead] != 1 || counts[StatusFailed] != 0 {
+		t.Errorf("counts = %v, want the push dead-lettered without retry", counts)
+	}
+}
+
+func TestAlertmanager_NothingToSend(t *testing.T) {
+	am := &fakeAlertmanager{}
+	srv := httptest.NewServer(am.handler(t))
+	defer srv.Close()
+
+	cfg := alertmanagerConfig(srv.URL)
+	cfg.Severities = []string{"critical"}
+	n := New(cfg)
+	n.Notify(store.Snapshot{}, store.Snapshot{At: time.Now(), Findings: []store.CertFinding{warnFinding("w", "ns")}})
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	if len(am.batches) != 0 {
+		t.Errorf("expected no push for filtered findings, got %d", len(am.batches))
+	}
+}
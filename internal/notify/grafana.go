@@ -1,11 +1,9 @@
 package notify
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"net/http"
 	"strings"
 	"time"
 
@@ -13,6 +11,9 @@ import (
 	"github.com/ppiankov/trustwatch/internal/store"
 )
 
+// grafanaAnnotationsPath is Grafana's annotation API under the configured URL.
+const grafanaAnnotationsPath = "/api/annotations"
+
 // grafanaAnnotation is the payload for Grafana's POST /api/annotations endpoint.
 type grafanaAnnotation struct {
 	Text         string   `json:"text"`
@@ -37,26 +38,7 @@ func (n *Notifier) sendGrafana(wh *config.WebhookConfig, findings []store.CertFi
 		return
 	}
 
-	url := strings.TrimRight(wh.URL, "/") + "/api/annotations"
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body)) //nolint:noctx // fire-and-forget notification
-	if err != nil {
-		slog.Warn("notification: grafana request error", "err", err)
-		return
-	}
-	req.Header.Set("Content-Type", "application/json")
-	if wh.APIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+wh.APIKey)
-	}
-
-	resp, err := n.client.Do(req)
-	if err != nil {
-		slog.Warn("notification: grafana delivery failed", "url", url, "err", err)
-		return
-	}
-	defer resp.Body.Close() //nolint:errcheck // read-only close
-	if resp.StatusCode >= 300 {
-		slog.Warn("notification: grafana returned non-2xx", "url", url, "status", resp.StatusCode)
-	}
+	n.enqueue(wh, grafanaAnnotationsPath, buildSummary(findings), body)
 }
 
 func grafanaTags(findings []store.CertFinding) []string {
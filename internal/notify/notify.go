@@ -3,32 +3,80 @@ package notify
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/ppiankov/trustwatch/internal/config"
 	"github.com/ppiankov/trustwatch/internal/store"
 )
 
-const httpTimeout = 10 * time.Second
+const (
+	httpTimeout = 10 * time.Second
+
+	defaultMaxAttempts  = 5
+	defaultRetryBackoff = 30 * time.Second
+	maxBackoff          = time.Hour
+	flushBatch          = 100
+	claimBatch          = 10
+	pollInterval        = 15 * time.Second
+	outboxRetention     = 7 * 24 * time.Hour
+)
 
 // Notifier sends alerts for findings that cross severity thresholds.
+// Rendered messages go through an Outbox and are retried with exponential
+// backoff until delivered or dead-lettered.
 type Notifier struct {
-	severities map[store.Severity]bool
-	sent       map[string]time.Time
-	client     *http.Client
-	webhooks   []config.WebhookConfig
-	cooldown   time.Duration
-	mu         sync.Mutex
+	outbox        Outbox
+	templates     map[string]*template.Template
+	router        *router
+	nowFn         func() time.Time
+	severities    map[store.Severity]bool
+	sent          map[string]time.Time
+	digests       map[string]time.Time
+	openSince     map[string]time.Time
+	reminded      map[string]time.Time
+	tickets       map[string]time.Time
+	ticketsQueued map[string]bool
+	client        *http.Client
+	clients       map[string]*http.Client
+	webhooks      []config.WebhookConfig
+	milestones    []milestone
+	cooldown      time.Duration
+	scanInterval  time.Duration
+	retryBackoff  time.Duration
+	maxAttempts   int
+	mu            sync.Mutex
+	flushMu       sync.Mutex
+	clientMu      sync.Mutex
+}
+
+// Option configures a Notifier.
+type Option func(*Notifier)
+
+// WithOutbox sets a persistent outbox. The default is an in-memory outbox.
+func WithOutbox(o Outbox) Option {
+	return func(n *Notifier) {
+		n.outbox = o
+	}
+}
+
+// WithScanInterval sets how often Notify is called. Alertmanager alerts are
+// given an endsAt a few intervals ahead so they stay firing between scans.
+func WithScanInterval(d time.Duration) Option {
+	return func(n *Notifier) {
+		n.scanInterval = d
+	}
 }
 
 // New creates a Notifier from notification config. Returns nil if not enabled or no webhooks.
-func New(cfg config.NotificationConfig) *Notifier {
+func New(cfg config.NotificationConfig, opts ...Option) *Notifier {
 	if !cfg.Enabled || len(cfg.Webhooks) == 0 {
 		return nil
 	}
@@ -47,18 +95,100 @@ func New(cfg config.NotificationConfig) *Notifier {
 	if cooldown == 0 {
 		cooldown = time.Hour
 	}
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	retryBackoff := cfg.RetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = defaultRetryBackoff
+	}
+
+	n := &Notifier{
+		webhooks:      cfg.Webhooks,
+		severities:    sevs,
+		cooldown:      cooldown,
+		maxAttempts:   maxAttempts,
+		retryBackoff:  retryBackoff,
+		sent:          make(map[string]time.Time),
+		digests:       make(map[string]time.Time),
+		openSince:     make(map[string]time.Time),
+		reminded:      make(map[string]time.Time),
+		tickets:       make(map[string]time.Time),
+		ticketsQueued: make(map[string]bool),
+		milestones:    parseMilestones(cfg.Reminders),
+		templates:     make(map[string]*template.Template),
+		client:        &http.Client{Timeout: httpTimeout},
+		clients:       make(map[string]*http.Client),
+		router:        newRouter(&cfg),
+		nowFn:         time.Now,
+	}
+	for i := range cfg.Webhooks {
+		tmpl, err := cfg.Webhooks[i].ParseTemplate()
+		if err != nil {
+			slog.Error("notification: invalid template, using default payload", "webhook", cfg.Webhooks[i].ID(), "err", err)
+			continue
+		}
+		if tmpl != nil {
+			n.templates[cfg.Webhooks[i].ID()] = tmpl
+		}
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	if n.outbox == nil {
+		n.outbox = NewMemoryOutbox()
+	}
 
-	return &Notifier{
-		webhooks:   cfg.Webhooks,
-		severities: sevs,
-		cooldown:   cooldown,
-		sent:       make(map[string]time.Time),
-		client:     &http.Client{Timeout: httpTimeout},
+	cooldowns, err := n.outbox.Cooldowns()
+	if err != nil {
+		slog.Warn("notification: loading cooldown state", "err", err)
 	}
+	now := time.Now()
+	var expired []string
+	for key, at := range cooldowns {
+		switch {
+		case strings.HasPrefix(key, digestKeyPrefix):
+			n.digests[key] = at
+			continue
+		case strings.HasPrefix(key, openKeyPrefix):
+			n.openSince[key] = at
+			continue
+		case strings.HasPrefix(key, reminderKeyPrefix):
+			n.reminded[key] = at
+			continue
+		case strings.HasPrefix(key, ticketKeyPrefix):
+			n.tickets[key] = at
+			continue
+		}
+		if now.Sub(at) < cooldown {
+			n.sent[key] = at
+		} else {
+			expired = append(expired, key)
+		}
+	}
+	if len(expired) > 0 {
+		if err := n.outbox.DeleteCooldowns(expired); err != nil {
+			slog.Warn("notification: deleting expired cooldown state", "err", err)
+		}
+	}
+	if n.hasTickets() {
+		n.loadQueuedTickets()
+	}
+	return n
+}
+
+// Outbox returns the outbox that holds queued and delivered messages.
+func (n *Notifier) Outbox() Outbox {
+	return n.outbox
 }
 
-// findingKey returns a deduplication key for a finding.
+// findingKey returns a deduplication key for a finding. Federated findings are
+// prefixed with their cluster so the same object in two clusters stays distinct.
 func findingKey(f *store.CertFinding) string {
+	if f.Cluster != "" {
+		return fmt.Sprintf("%s/%s/%s/%s", f.Cluster, f.Source, f.Namespace, f.Name)
+	}
 	return fmt.Sprintf("%s/%s/%s", f.Source, f.Namespace, f.Name)
 }
 
@@ -94,55 +224,108 @@ func (n *Notifier) Notify(prev, curr store.Snapshot) {
 
 		newFindings = append(newFindings, *f)
 		n.sent[key] = now
+		if err := n.outbox.SaveCooldown(key, now); err != nil {
+			slog.Warn("notification: saving cooldown state", "key", key, "err", err)
+		}
+	}
+	// An expired cooldown no longer suppresses anything, so drop it; this is
+	// how the state of resolved findings is cleaned up.
+	var expired []string
+	for key, at := range n.sent {
+		if now.Sub(at) >= n.cooldown {
+			delete(n.sent, key)
+			expired = append(expired, key)
+		}
 	}
 	n.mu.Unlock()
+	if len(expired) > 0 {
+		if err := n.outbox.DeleteCooldowns(expired); err != nil {
+			slog.Warn("notification: deleting expired cooldown state", "err", err)
+		}
+	}
 
-	resolvedKeys := n.computeResolved(prev, curr)
+	resolved := n.computeResolved(prev, curr)
 
-	if len(newFindings) == 0 && len(resolvedKeys) == 0 {
-		return
+	if len(newFindings) > 0 || len(resolved) > 0 {
+		n.dispatch(newTemplateData(&curr, newFindings, resolved))
 	}
-
-	n.dispatch(newFindings, resolvedKeys)
+	if len(n.milestones) > 0 || n.hasTickets() {
+		n.trackOpen(&curr)
+	}
+	if len(n.milestones) > 0 {
+		notified := make(map[string]bool, len(newFindings))
+		for i := range newFindings {
+			notified[findingKey(&newFindings[i])] = true
+		}
+		if reminders := n.checkReminders(&curr, notified); len(reminders) > 0 {
+			n.sendReminders(&curr, reminders)
+		}
+	}
+	n.sendPagerDutyChanges(&curr)
+	n.pushAlertmanager(prev, curr)
+	n.sendDigests(&curr)
+	n.syncTickets(&curr)
+	n.Flush(context.Background())
 }
 
-// computeResolved returns dedup keys for findings in prev (matching severity) absent from curr.
-func (n *Notifier) computeResolved(prev, curr store.Snapshot) []string {
+// computeResolved returns findings in prev (matching severity) that curr no
+// longer reports at a matching severity: they are either gone or were
+// downgraded, as when a renewed certificate drops back to info.
+func (n *Notifier) computeResolved(prev, curr store.Snapshot) []store.CertFinding {
 	currKeys := make(map[string]bool, len(curr.Findings))
 	for i := range curr.Findings {
-		currKeys[findingKey(&curr.Findings[i])] = true
+		if n.severities[curr.Findings[i].Severity] {
+			currKeys[findingKey(&curr.Findings[i])] = true
+		}
 	}
-	var resolved []string
+	var resolved []store.CertFinding
 	for i := range prev.Findings {
 		f := &prev.Findings[i]
 		if !n.severities[f.Severity] {
 			continue
 		}
-		if key := findingKey(f); !currKeys[key] {
-			resolved = append(resolved, key)
+		if !currKeys[findingKey(f)] {
+			resolved = append(resolved, *f)
 		}
 	}
 	return resolved
 }
 
-// dispatch sends new findings and resolve events to all configured webhooks.
-func (n *Notifier) dispatch(newFindings []store.CertFinding, resolvedKeys []string) {
+// dispatch queues new findings and resolve events for all configured webhooks,
+// each narrowed to the findings routed to it.
+func (n *Notifier) dispatch(all *TemplateData) {
 	for i := range n.webhooks {
 		wh := &n.webhooks[i]
-		if len(newFindings) > 0 {
+		data := n.routedData(wh, all)
+		if data == nil {
+			continue
+		}
+		if tmpl := n.templates[wh.ID()]; tmpl != nil {
+			n.sendTemplate(wh, tmpl, data)
+			continue
+		}
+		if len(data.Findings) > 0 {
 			switch wh.Type {
+			case "alertmanager":
+				// pushed on every scan by pushAlertmanager
+			case "ticket":
+				// filed by syncTickets once a finding has been open long enough
 			case "slack":
-				n.sendSlack(wh.URL, newFindings)
+				n.sendSlack(wh, data.Findings)
 			case "pagerduty":
-				n.sendPagerDuty(wh, newFindings)
+				n.sendPagerDuty(wh, data.Findings)
 			case "grafana":
-				n.sendGrafana(wh, newFindings)
+				n.sendGrafana(wh, data.Findings)
+			case "smtp":
+				if wh.SMTP != nil && wh.SMTP.DigestPeriod() == 0 {
+					n.sendEmail(wh, data)
+				}
 			default:
-				n.sendGeneric(wh.URL, newFindings)
+				n.sendGeneric(wh, data.Findings)
 			}
 		}
-		if wh.Type == "pagerduty" && len(resolvedKeys) > 0 {
-			n.resolvePagerDuty(wh, resolvedKeys)
+		if wh.Type == "pagerduty" && len(data.Resolved) > 0 {
+			n.resolvePagerDuty(wh, data.Resolved)
 		}
 	}
 }
@@ -154,9 +337,10 @@ func isEscalation(prev, curr store.Severity) bool {
 
 // GenericPayload is the JSON body sent to generic webhooks.
 type GenericPayload struct {
-	Timestamp time.Time        `json:"timestamp"`
-	Summary   string           `json:"summary"`
-	Findings  []GenericFinding `json:"findings"`
+	Timestamp time.Time         `json:"timestamp"`
+	Summary   string            `json:"summary"`
+	Findings  []GenericFinding  `json:"findings"`
+	Reminders []GenericReminder `json:"reminders,omitempty"`
 }
 
 // GenericFinding is a single finding in the generic webhook payload.
@@ -169,7 +353,16 @@ type GenericFinding struct {
 	ProbeOK   bool             `json:"probeOk"`
 }
 
-func (n *Notifier) sendGeneric(webhookURL string, findings []store.CertFinding) {
+func (n *Notifier) sendGeneric(wh *config.WebhookConfig, findings []store.CertFinding) {
+	body, err := genericBody(findings)
+	if err != nil {
+		slog.Warn("notification: marshal error", "err", err)
+		return
+	}
+	n.enqueue(wh, "", buildSummary(findings), body)
+}
+
+func genericBody(findings []store.CertFinding) ([]byte, error) {
 	payload := GenericPayload{
 		Timestamp: time.Now().UTC(),
 		Summary:   buildSummary(findings),
@@ -185,14 +378,7 @@ func (n *Notifier) sendGeneric(webhookURL string, findings []store.CertFinding)
 			ProbeOK:   findings[i].ProbeOK,
 		}
 	}
-
-	body, err := json.Marshal(payload)
-	if err != nil {
-		slog.Warn("notification: marshal error", "err", err)
-		return
-	}
-
-	n.post(webhookURL, "application/json", body)
+	return json.Marshal(payload)
 }
 
 // SlackPayload is the JSON body sent to Slack incoming webhooks.
@@ -212,7 +398,16 @@ type SlackText struct {
 	Text string `json:"text"`
 }
 
-func (n *Notifier) sendSlack(webhookURL string, findings []store.CertFinding) {
+func (n *Notifier) sendSlack(wh *config.WebhookConfig, findings []store.CertFinding) {
+	body, err := slackBody(findings)
+	if err != nil {
+		slog.Warn("notification: slack marshal error", "err", err)
+		return
+	}
+	n.enqueue(wh, "", buildSummary(findings), body)
+}
+
+func slackBody(findings []store.CertFinding) ([]byte, error) {
 	blocks := []SlackBlock{
 		{
 			Type: "header",
@@ -251,26 +446,207 @@ func (n *Notifier) sendSlack(webhookURL string, findings []store.CertFinding) {
 		},
 	})
 
-	payload := SlackPayload{Blocks: blocks}
-	body, err := json.Marshal(payload)
-	if err != nil {
-		slog.Warn("notification: slack marshal error", "err", err)
-		return
+	return json.Marshal(SlackPayload{Blocks: blocks})
+}
+
+// enqueue stores a rendered JSON message in the outbox for delivery. endpoint
+// is a path under the webhook URL ("" for the URL itself) or a fixed absolute URL.
+func (n *Notifier) enqueue(wh *config.WebhookConfig, endpoint, summary string, body []byte) {
+	msg := n.newMessage(wh, endpoint, summary, body)
+	if err := n.outbox.Enqueue(msg); err != nil {
+		slog.Warn("notification: queueing message", "target", msg.Target, "err", err)
 	}
+}
+
+// newMessage returns a pending message for wh that is due immediately. Only
+// the webhook ID and endpoint are stored; the webhook URL, which may embed a
+// token, is resolved from the config at delivery.
+func (n *Notifier) newMessage(wh *config.WebhookConfig, endpoint, summary string, body []byte) *Message {
+	now := time.Now().UTC()
+	return &Message{
+		CreatedAt:   now,
+		NextAttempt: now,
+		Body:        body,
+		Kind:        wh.Type,
+		Webhook:     wh.ID(),
+		Endpoint:    endpoint,
+		Target:      targetHost(requestURL(wh, endpoint)),
+		ContentType: contentType(wh),
+		Summary:     summary,
+		Status:      StatusPending,
+	}
+}
 
-	n.post(webhookURL, "application/json", body)
+// Run retries due messages and prunes old delivered messages until ctx is canceled.
+func (n *Notifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	pruneTicker := time.NewTicker(time.Hour)
+	defer pruneTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.Flush(ctx)
+		case <-pruneTicker.C:
+			if pruned, err := n.outbox.Prune(time.Now().Add(-outboxRetention)); err != nil {
+				slog.Warn("notification: pruning outbox", "err", err)
+			} else if pruned > 0 {
+				slog.Debug("notification: pruned outbox", "messages", pruned)
+			}
+		}
+	}
 }
 
-func (n *Notifier) post(webhookURL, contentType string, body []byte) {
-	resp, err := n.client.Post(webhookURL, contentType, bytes.NewReader(body)) //nolint:noctx // fire-and-forget notification
+// Flush attempts delivery of every message that is due. Failed messages are
+// rescheduled with exponential backoff and dead-lettered after the maximum
+// number of attempts. Messages are claimed claimBatch at a time, so a batch
+// is delivered well within its ClaimLease even when every attempt times out.
+func (n *Notifier) Flush(ctx context.Context) {
+	n.flushMu.Lock()
+	defer n.flushMu.Unlock()
+
+	for {
+		due, err := n.outbox.Due(time.Now().UTC(), claimBatch)
+		if err != nil {
+			slog.Warn("notification: reading outbox", "err", err)
+			return
+		}
+		for i := range due {
+			n.attempt(ctx, &due[i])
+		}
+		if len(due) < claimBatch || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// attempt delivers one claimed message and records the outcome.
+func (n *Notifier) attempt(ctx context.Context, msg *Message) {
+	sendErr := n.deliver(ctx, msg)
+	now := time.Now().UTC()
+	msg.Attempts++
+	switch {
+	case sendErr == nil:
+		msg.Status = StatusSent
+		msg.SentAt = &now
+		msg.LastError = ""
+	case msg.Kind == "alertmanager":
+		// Superseded by the next scan's push, so never retried.
+		msg.Status = StatusDead
+		msg.LastError = sendErr.Error()
+		slog.Warn("notification: alertmanager push failed, next scan re-sends",
+			"id", msg.ID, "target", msg.Target, "err", sendErr)
+	case msg.Attempts >= n.maxAttempts:
+		msg.Status = StatusDead
+		msg.LastError = sendErr.Error()
+		if msg.Kind == "ticket" {
+			n.releaseTicket(msg)
+		}
+		slog.Error("notification: giving up after repeated failures",
+			"id", msg.ID, "target", msg.Target, "attempts", msg.Attempts, "err", sendErr)
+	default:
+		msg.Status = StatusFailed
+		msg.LastError = sendErr.Error()
+		msg.NextAttempt = now.Add(backoff(n.retryBackoff, msg.Attempts))
+		slog.Warn("notification: delivery failed, will retry",
+			"id", msg.ID, "target", msg.Target, "attempts", msg.Attempts, "next", msg.NextAttempt, "err", sendErr)
+	}
+	if err := n.outbox.Update(msg); err != nil {
+		slog.Warn("notification: updating outbox", "id", msg.ID, "err", err)
+	}
+}
+
+// deliver posts a single message, sends it by SMTP for email channels, or
+// applies it to an issue tracker for ticket channels.
+func (n *Notifier) deliver(ctx context.Context, msg *Message) error {
+	wh := n.webhookFor(msg)
+	switch msg.Kind {
+	case "smtp":
+		return deliverMail(ctx, wh, msg)
+	case "ticket":
+		return n.deliverTicket(ctx, wh, msg)
+	}
+	target := requestURL(wh, msg.Endpoint)
+	if target == "" {
+		return fmt.Errorf("webhook %q is no longer configured", msg.Webhook)
+	}
+	method := http.MethodPost
+	if wh != nil && wh.Method != "" {
+		method = strings.ToUpper(wh.Method)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(msg.Body))
 	if err != nil {
-		slog.Warn("notification: webhook delivery failed", "url", webhookURL, "err", err)
-		return
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", msg.ContentType)
+	if wh != nil {
+		applyHeaders(req, wh)
+		if wh.Signed() {
+			sign(req, wh.SigningSecret, msg.Body, n.nowFn())
+		}
+	}
+
+	client, err := n.httpClient(wh)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
 	}
 	defer resp.Body.Close() //nolint:errcheck // read-only close
 	if resp.StatusCode >= 300 {
-		slog.Warn("notification: webhook returned non-2xx", "url", webhookURL, "status", resp.StatusCode)
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookFor returns the configured webhook a message was queued for, or nil
+// if it has since been removed from the config.
+func (n *Notifier) webhookFor(msg *Message) *config.WebhookConfig {
+	for i := range n.webhooks {
+		if n.webhooks[i].ID() == msg.Webhook && n.webhooks[i].Type == msg.Kind {
+			return &n.webhooks[i]
+		}
+	}
+	return nil
+}
+
+// requestURL resolves a message endpoint against the webhook's URL. Absolute
+// endpoints, such as PagerDuty's, need no webhook; it returns "" when a
+// relative endpoint's webhook has been removed from the config.
+func requestURL(wh *config.WebhookConfig, endpoint string) string {
+	switch {
+	case strings.Contains(endpoint, "://"):
+		return endpoint
+	case wh == nil:
+		return ""
+	case endpoint == "" && wh.Type != "ticket":
+		return wh.URL
+	default:
+		return wh.APIURL() + endpoint
+	}
+}
+
+// applyHeaders adds configured headers and credentials to a request. They are
+// applied at delivery time so they are never written to the outbox.
+func applyHeaders(req *http.Request, wh *config.WebhookConfig) {
+	for k, v := range wh.Headers {
+		req.Header.Set(k, v)
+	}
+	if wh.Type == "grafana" && wh.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+wh.APIKey)
+	}
+}
+
+// contentType returns the Content-Type for a webhook's requests.
+func contentType(wh *config.WebhookConfig) string {
+	if wh.ContentType != "" {
+		return wh.ContentType
 	}
+	return "application/json"
 }
 
 func buildSummary(findings []store.CertFinding) string {
@@ -134,6 +134,39 @@ func TestNotifier_CooldownSuppresses(t *testing.T) {
 	}
 }
 
+func TestNotifier_FederatedFindingsAreDistinct(t *testing.T) {
+	calls := 0
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	east := criticalFinding("api", "payments")
+	east.Cluster = "east"
+	west := east
+	west.Cluster = "west"
+	n := New(testConfig(srv.URL))
+
+	// The same object in a second cluster is a new finding, not a repeat.
+	n.Notify(store.Snapshot{}, store.Snapshot{At: time.Now(), Findings: []store.CertFinding{east}})
+	n.Notify(store.Snapshot{Findings: []store.CertFinding{east}}, store.Snapshot{At: time.Now(), Findings: []store.CertFinding{east, west}})
+	mu.Lock()
+	if calls != 2 {
+		t.Errorf("expected 2 webhook calls, got %d", calls)
+	}
+	mu.Unlock()
+
+	// Fixing it in one cluster resolves only that cluster's finding.
+	resolved := n.computeResolved(store.Snapshot{Findings: []store.CertFinding{east, west}}, store.Snapshot{Findings: []store.CertFinding{west}})
+	if len(resolved) != 1 || findingKey(&resolved[0]) != "east/k8s.tlsSecret/payments/api" {
+		t.Errorf("resolved = %+v, want only the east finding", resolved)
+	}
+}
+
 func TestNotifier_SeverityFilter(t *testing.T) {
 	callCount := 0
 	var mu sync.Mutex
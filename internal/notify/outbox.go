@@ -0,0 +1,254 @@
+package notify
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Status is the delivery state of an outbox message.
+type Status string
+
+// Message delivery states.
+const (
+	StatusPending Status = "pending" // queued, not yet attempted
+	StatusFailed  Status = "failed"  // last attempt failed, retry scheduled
+	StatusSending Status = "sending" // claimed for delivery; reclaimed once its lease expires
+	StatusSent    Status = "sent"    // delivered
+	StatusDead    Status = "dead"    // gave up after the maximum number of attempts
+)
+
+// ValidStatus reports whether s is a known message status.
+func ValidStatus(s Status) bool {
+	switch s {
+	case StatusPending, StatusFailed, StatusSending, StatusSent, StatusDead:
+		return true
+	}
+	return false
+}
+
+// Message is a rendered notification waiting in, or recorded by, the outbox.
+type Message struct {
+	SentAt      *time.Time `json:"sentAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	NextAttempt time.Time  `json:"nextAttempt"`
+	Kind        string     `json:"kind"`   // webhook type: slack, generic, pagerduty, grafana
+	Webhook     string     `json:"-"`      // configured webhook ID, used to resolve the URL and credentials at delivery
+	Endpoint    string     `json:"-"`      // path under the webhook URL, a fixed absolute URL, or mailto: for email
+	Target      string     `json:"target"` // request host, safe to display
+	ContentType string     `json:"-"`
+	Summary     string     `json:"summary"`
+	Status      Status     `json:"status"`
+	LastError   string     `json:"lastError,omitempty"`
+	Body        []byte     `json:"-"`
+	ID          int64      `json:"id"`
+	Attempts    int        `json:"attempts"`
+}
+
+// Outbox stores notifications until they are delivered and remembers
+// per-finding cooldowns, so neither is lost when trustwatch restarts.
+type Outbox interface {
+	// Enqueue stores a new pending message and assigns its ID.
+	Enqueue(msg *Message) error
+	// Due claims up to limit pending, failed, or lease-expired sending
+	// messages whose next attempt is at or before now, oldest first. Claimed
+	// messages are marked sending until now+ClaimLease, so processes sharing
+	// the outbox never deliver the same message concurrently.
+	Due(now time.Time, limit int) ([]Message, error)
+	// Update records the outcome of a delivery attempt.
+	Update(msg *Message) error
+	// List returns messages newest first, filtered by status when status is non-empty.
+	List(status Status, limit int) ([]Message, error)
+	// Counts returns the number of messages in each status.
+	Counts() (map[Status]int, error)
+	// Cooldowns returns when each finding key was last notified.
+	Cooldowns() (map[string]time.Time, error)
+	// SaveCooldown records when a finding key was last notified.
+	SaveCooldown(key string, at time.Time) error
+	// DeleteCooldowns removes the given keys from the cooldown state.
+	DeleteCooldowns(keys []string) error
+	// Prune deletes sent and dead messages created before the given time.
+	Prune(before time.Time) (int, error)
+}
+
+// ClaimLease is how long a message claimed by Due stays reserved for the
+// claiming process. A process that dies mid-delivery leaves its messages in
+// sending, and they become due again when the lease runs out.
+const ClaimLease = 10 * time.Minute
+
+// memoryOutboxLimit caps the delivered and dead messages kept by MemoryOutbox.
+const memoryOutboxLimit = 500
+
+// MemoryOutbox is an in-process Outbox used when no history database is configured.
+// Its contents are lost on restart.
+type MemoryOutbox struct {
+	cooldowns map[string]time.Time
+	messages  []Message
+	nextID    int64
+	mu        sync.Mutex
+}
+
+// NewMemoryOutbox creates an empty in-memory outbox.
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{cooldowns: make(map[string]time.Time)}
+}
+
+// Enqueue implements Outbox.
+func (o *MemoryOutbox) Enqueue(msg *Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.nextID++
+	msg.ID = o.nextID
+	o.messages = append(o.messages, *msg)
+	o.trim()
+	return nil
+}
+
+// trim drops the oldest finished messages beyond memoryOutboxLimit.
+func (o *MemoryOutbox) trim() {
+	finished := 0
+	for i := range o.messages {
+		if isFinished(o.messages[i].Status) {
+			finished++
+		}
+	}
+	if finished <= memoryOutboxLimit {
+		return
+	}
+	drop := finished - memoryOutboxLimit
+	kept := o.messages[:0]
+	for i := range o.messages {
+		if drop > 0 && isFinished(o.messages[i].Status) {
+			drop--
+			continue
+		}
+		kept = append(kept, o.messages[i])
+	}
+	o.messages = kept
+}
+
+// Due implements Outbox.
+func (o *MemoryOutbox) Due(now time.Time, limit int) ([]Message, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var due []Message
+	for i := range o.messages {
+		m := &o.messages[i]
+		if isFinished(m.Status) || m.NextAttempt.After(now) {
+			continue
+		}
+		m.Status = StatusSending
+		m.NextAttempt = now.Add(ClaimLease)
+		due = append(due, *m)
+		if len(due) == limit {
+			break
+		}
+	}
+	return due, nil
+}
+
+// Update implements Outbox.
+func (o *MemoryOutbox) Update(msg *Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := range o.messages {
+		if o.messages[i].ID == msg.ID {
+			o.messages[i] = *msg
+			break
+		}
+	}
+	o.trim()
+	return nil
+}
+
+// List implements Outbox.
+func (o *MemoryOutbox) List(status Status, limit int) ([]Message, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := []Message{}
+	for i := len(o.messages) - 1; i >= 0 && len(out) < limit; i-- {
+		if status == "" || o.messages[i].Status == status {
+			out = append(out, o.messages[i])
+		}
+	}
+	return out, nil
+}
+
+// Counts implements Outbox.
+func (o *MemoryOutbox) Counts() (map[Status]int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	counts := make(map[Status]int)
+	for i := range o.messages {
+		counts[o.messages[i].Status]++
+	}
+	return counts, nil
+}
+
+// Cooldowns implements Outbox.
+func (o *MemoryOutbox) Cooldowns() (map[string]time.Time, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := make(map[string]time.Time, len(o.cooldowns))
+	for k, v := range o.cooldowns {
+		out[k] = v
+	}
+	return out, nil
+}
+
+// SaveCooldown implements Outbox.
+func (o *MemoryOutbox) SaveCooldown(key string, at time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.cooldowns[key] = at
+	return nil
+}
+
+// DeleteCooldowns implements Outbox.
+func (o *MemoryOutbox) DeleteCooldowns(keys []string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, k := range keys {
+		delete(o.cooldowns, k)
+	}
+	return nil
+}
+
+// Prune implements Outbox.
+func (o *MemoryOutbox) Prune(before time.Time) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	kept := o.messages[:0]
+	for i := range o.messages {
+		if isFinished(o.messages[i].Status) && o.messages[i].CreatedAt.Before(before) {
+			continue
+		}
+		kept = append(kept, o.messages[i])
+	}
+	pruned := len(o.messages) - len(kept)
+	o.messages = kept
+	return pruned, nil
+}
+
+func isFinished(s Status) bool {
+	return s == StatusSent || s == StatusDead
+}
+
+// backoff returns the delay before the next attempt after the given number of
+// failed attempts, doubling from base and capped at maxBackoff.
+func backoff(base time.Duration, attempts int) time.Duration {
+	d := base
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// targetHost returns the host of a webhook URL for display.
+func targetHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "invalid-url"
+	}
+	return u.Host
+}
@@ -0,0 +1,341 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func newCriticalSnapshot() store.Snapshot {
+	return store.Snapshot{
+		At:       time.Now(),
+		Findings: []store.CertFinding{criticalFinding("my-cert", "default")},
+	}
+}
+
+func TestNotifier_RetriesFailedDelivery(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.RetryBackoff = time.Millisecond
+	n := New(cfg)
+
+	n.Notify(store.Snapshot{}, newCriticalSnapshot())
+
+	failed, err := n.Outbox().List(StatusFailed, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 {
+		t.Fatalf("expected 1 failed message after first attempt, got %d", len(failed))
+	}
+	if failed[0].LastError == "" || failed[0].Attempts != 1 {
+		t.Errorf("failed message = %+v, want attempts=1 and an error", failed[0])
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	n.Flush(context.Background())
+
+	sent, err := n.Outbox().List(StatusSent, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 sent message after retry, got %d", len(sent))
+	}
+	if sent[0].Attempts != 2 || sent[0].SentAt == nil || sent[0].LastError != "" {
+		t.Errorf("sent message = %+v, want attempts=2, sentAt set, no error", sent[0])
+	}
+}
+
+func TestNotifier_DeadLettersAfterMaxAttempts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.RetryBackoff = time.Millisecond
+	cfg.MaxAttempts = 2
+	n := New(cfg)
+
+	n.Notify(store.Snapshot{}, newCriticalSnapshot())
+	time.Sleep(5 * time.Millisecond)
+	n.Flush(context.Background())
+
+	counts, err := n.Outbox().Counts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[StatusDead] != 1 || counts[StatusFailed] != 0 {
+		t.Errorf("counts = %v, want 1 dead", counts)
+	}
+
+	// Dead messages are not retried.
+	time.Sleep(5 * time.Millisecond)
+	n.Flush(context.Background())
+	dead, err := n.Outbox().List(StatusDead, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].Attempts != 2 {
+		t.Errorf("dead = %+v, want one message with 2 attempts", dead)
+	}
+}
+
+func TestNotifier_CooldownSurvivesRestart(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	outbox := NewMemoryOutbox()
+	New(testConfig(srv.URL), WithOutbox(outbox)).Notify(store.Snapshot{}, newCriticalSnapshot())
+
+	// A fresh notifier sharing the outbox has no previous snapshot but
+	// must still respect the cooldown recorded by the first one.
+	New(testConfig(srv.URL), WithOutbox(outbox)).Notify(store.Snapshot{}, newCriticalSnapshot())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 {
+		t.Errorf("expected 1 webhook call across restart, got %d", calls)
+	}
+}
+
+func TestNotifier_RestartAfterCooldownExpired(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.Cooldown = time.Millisecond
+	outbox := NewMemoryOutbox()
+	persisted := newCriticalSnapshot()
+	New(cfg, WithOutbox(outbox)).Notify(store.Snapshot{}, persisted)
+	time.Sleep(5 * time.Millisecond)
+
+	// serve seeds the previous snapshot from history on startup, so a
+	// finding that is still open is not new once the cooldown has passed.
+	New(cfg, WithOutbox(outbox)).Notify(persisted, newCriticalSnapshot())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 {
+		t.Errorf("expected 1 webhook call across restart, got %d", calls)
+	}
+}
+
+func TestNotifier_OutboxOmitsWebhookURL(t *testing.T) {
+	var gotToken string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotToken = r.URL.Query().Get("token")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	outbox := NewMemoryOutbox()
+	New(testConfig(srv.URL+"/hook?token=s3cret"), WithOutbox(outbox)).Notify(store.Snapshot{}, newCriticalSnapshot())
+
+	if gotToken != "s3cret" {
+		t.Errorf("delivered token = %q, want the configured one", gotToken)
+	}
+	msgs, err := outbox.List("", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+	if m := msgs[0]; strings.Contains(m.Webhook+m.Endpoint+m.Target, "s3cret") {
+		t.Errorf("outbox message stores the webhook URL: %+v", m)
+	}
+
+	// A message whose webhook was removed from the config is not sent anywhere.
+	msg := msgs[0]
+	msg.Webhook = "removed"
+	if err := New(testConfig(srv.URL)).deliver(context.Background(), &msg); err == nil {
+		t.Error("expected an error delivering to a removed webhook")
+	}
+}
+
+func TestNotifier_DeletesExpiredCooldowns(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.Cooldown = time.Millisecond
+	outbox := NewMemoryOutbox()
+	n := New(cfg, WithOutbox(outbox))
+	n.Notify(store.Snapshot{}, newCriticalSnapshot())
+	if cooldowns, _ := outbox.Cooldowns(); len(cooldowns) != 1 { //nolint:errcheck // memory outbox never errors
+		t.Fatalf("expected 1 cooldown after notifying, got %v", cooldowns)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	n.Notify(newCriticalSnapshot(), store.Snapshot{At: time.Now()})
+	if cooldowns, _ := outbox.Cooldowns(); len(cooldowns) != 0 { //nolint:errcheck // memory outbox never errors
+		t.Errorf("expected the resolved finding's cooldown to be deleted, got %v", cooldowns)
+	}
+}
+
+func TestNotifier_QueuedMessageDeliveredAfterRestart(t *testing.T) {
+	var mu sync.Mutex
+	up := false
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !up {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.RetryBackoff = time.Millisecond
+	outbox := NewMemoryOutbox()
+	New(cfg, WithOutbox(outbox)).Notify(store.Snapshot{}, newCriticalSnapshot())
+
+	mu.Lock()
+	up = true
+	mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	New(cfg, WithOutbox(outbox)).Flush(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 {
+		t.Errorf("expected queued message to be delivered by the new notifier, got %d calls", calls)
+	}
+}
+
+func TestMemoryOutbox_TrimsFinishedMessages(t *testing.T) {
+	o := NewMemoryOutbox()
+	for range memoryOutboxLimit + 10 {
+		if err := o.Enqueue(&Message{Status: StatusSent}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := o.Enqueue(&Message{Status: StatusPending}); err != nil {
+		t.Fatal(err)
+	}
+
+	counts, _ := o.Counts() //nolint:errcheck // memory outbox never errors
+	if counts[StatusSent] != memoryOutboxLimit {
+		t.Errorf("sent = %d, want %d", counts[StatusSent], memoryOutboxLimit)
+	}
+	if counts[StatusPending] != 1 {
+		t.Errorf("pending = %d, want 1", counts[StatusPending])
+	}
+
+	latest, _ := o.List("", 1) //nolint:errcheck // memory outbox never errors
+	if len(latest) != 1 || latest[0].Status != StatusPending {
+		t.Errorf("List newest = %+v, want the pending message", latest)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{10, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(30*time.Second, tt.attempts); got != tt.want {
+			t.Errorf("backoff(30s, %d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestTargetHost(t *testing.T) {
+	if got := targetHost("https://hooks.slack.com/services/T/B/secret"); got != "hooks.slack.com" {
+		t.Errorf("targetHost = %q, want hooks.slack.com", got)
+	}
+	if got := targetHost("::not a url"); got != "invalid-url" {
+		t.Errorf("targetHost = %q, want invalid-url", got)
+	}
+}
+
+func TestMemoryOutbox_Prune(t *testing.T) {
+	o := NewMemoryOutbox()
+	old := time.Now().Add(-48 * time.Hour)
+	for _, m := range []Message{
+		{CreatedAt: old, Status: StatusSent},
+		{CreatedAt: old, Status: StatusDead},
+		{CreatedAt: old, Status: StatusFailed},
+		{CreatedAt: time.Now(), Status: StatusSent},
+	} {
+		if err := o.Enqueue(&m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruned, err := o.Prune(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 2 {
+		t.Errorf("pruned = %d, want 2", pruned)
+	}
+	counts, _ := o.Counts() //nolint:errcheck // memory outbox never errors
+	if counts[StatusFailed] != 1 || counts[StatusSent] != 1 {
+		t.Errorf("counts after prune = %v, want 1 failed and 1 recent sent", counts)
+	}
+}
+
+func TestMemoryOutbox_DueClaims(t *testing.T) {
+	o := NewMemoryOutbox()
+	now := time.Now()
+	if err := o.Enqueue(&Message{NextAttempt: now, Status: StatusPending}); err != nil {
+		t.Fatal(err)
+	}
+	due, _ := o.Due(now, 10) //nolint:errcheck // memory outbox never errors
+	if len(due) != 1 || due[0].Status != StatusSending {
+		t.Fatalf("Due = %+v, want the message claimed as sending", due)
+	}
+	if again, _ := o.Due(now, 10); len(again) != 0 { //nolint:errcheck // memory outbox never errors
+		t.Errorf("claimed message returned twice: %+v", again)
+	}
+	if expired, _ := o.Due(now.Add(ClaimLease), 10); len(expired) != 1 { //nolint:errcheck // memory outbox never errors
+		t.Errorf("expected the message to be reclaimed after its lease, got %+v", expired)
+	}
+}
@@ -7,11 +7,15 @@ import (
 	"time"
 
 	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/drift"
 	"github.com/ppiankov/trustwatch/internal/store"
 )
 
-// pagerDutyEventsURL is the PagerDuty Events API v2 endpoint (var for testing).
-var pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue" //nolint:gosec // not a credential
+// PagerDuty Events API v2 endpoints (vars for testing).
+var (
+	pagerDutyEventsURL  = "https://events.pagerduty.com/v2/enqueue"        //nolint:gosec // not a credential
+	pagerDutyChangesURL = "https://events.pagerduty.com/v2/change/enqueue" //nolint:gosec // not a credential
+)
 
 // pdEvent is a PagerDuty Events API v2 request body.
 type pdEvent struct {
@@ -23,10 +27,28 @@ type pdEvent struct {
 
 // pdPayload is the payload section of a PagerDuty trigger event.
 type pdPayload struct {
-	Timestamp time.Time `json:"timestamp"`
-	Summary   string    `json:"summary"`
-	Source    string    `json:"source"`
-	Severity  string    `json:"severity"`
+	Timestamp     time.Time         `json:"timestamp"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+}
+
+// pdChangeEvent is a PagerDuty Change Events API request body.
+type pdChangeEvent struct {
+	Payload    pdChangePayload `json:"payload"`
+	RoutingKey string          `json:"routing_key"`
+}
+
+// pdChangePayload is the payload section of a PagerDuty change event.
+type pdChangePayload struct {
+	Timestamp     time.Time         `json:"timestamp"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
 }
 
 func (n *Notifier) sendPagerDuty(wh *config.WebhookConfig, findings []store.CertFinding) {
@@ -36,19 +58,103 @@ func (n *Notifier) sendPagerDuty(wh *config.WebhookConfig, findings []store.Cert
 			RoutingKey:  wh.RoutingKey,
 			EventAction: "trigger",
 			DedupKey:    findingKey(f),
-			Payload: &pdPayload{
-				Summary:   pdSummary(f),
-				Source:    "trustwatch",
-				Severity:  pdSeverity(f.Severity),
-				Timestamp: time.Now().UTC(),
-			},
+			Payload:     pdFindingPayload(f, pdSummary(f)),
 		}
 
 		body, err := json.Marshal(event)
 		if err != nil {
 			continue
 		}
-		n.post(pagerDutyEventsURL, "application/json", body)
+		n.enqueue(wh, pagerDutyEventsURL, event.Payload.Summary, body)
+	}
+}
+
+// pdFindingPayload builds a trigger payload for f. The component, group, and
+// class fields let PagerDuty group alerts by object, namespace, and source.
+func pdFindingPayload(f *store.CertFinding, summary string) *pdPayload {
+	component := f.Name
+	if f.Namespace != "" {
+		component = f.Namespace + "/" + f.Name
+	}
+	return &pdPayload{
+		Summary:       summary,
+		Source:        "trustwatch",
+		Severity:      pdFindingSeverity(f),
+		Timestamp:     time.Now().UTC(),
+		Component:     component,
+		Group:         f.Namespace,
+		Class:         string(f.Source),
+		CustomDetails: pdDetails(f),
+	}
+}
+
+// pdDetails returns the finding fields shown in the incident's custom details.
+func pdDetails(f *store.CertFinding) map[string]string {
+	details := map[string]string{
+		"source":   string(f.Source),
+		"severity": string(f.Severity),
+	}
+	add := func(k, v string) {
+		if v != "" {
+			details[k] = v
+		}
+	}
+	if !f.NotAfter.IsZero() {
+		details["notAfter"] = f.NotAfter.UTC().Format(time.RFC3339)
+	}
+	add("namespace", f.Namespace)
+	add("name", f.Name)
+	add("cluster", f.Cluster)
+	add("target", f.Target)
+	add("issuer", f.Issuer)
+	add("serial", f.Serial)
+	add("findingType", f.FindingType)
+	add("owner", f.Owner)
+	add("probeError", f.ProbeErr)
+	add("chainErrors", strings.Join(f.ChainErrors, "; "))
+	add("notes", f.Notes)
+	add("remediation", f.Remediation)
+	return details
+}
+
+// sendPagerDutyChanges sends a change event for every certificate rotation
+// that drift detection reported in curr, so rotations appear on the service's
+// incident timeline.
+func (n *Notifier) sendPagerDutyChanges(curr *store.Snapshot) {
+	var rotated []store.CertFinding
+	for i := range curr.Findings {
+		if curr.Findings[i].FindingType == drift.FindingSerialChanged {
+			rotated = append(rotated, curr.Findings[i])
+		}
+	}
+	if len(rotated) == 0 {
+		return
+	}
+	for i := range n.webhooks {
+		wh := &n.webhooks[i]
+		if wh.Type != "pagerduty" {
+			continue
+		}
+		for _, f := range n.routed(wh, rotated) {
+			where := f.Name
+			if f.Namespace != "" {
+				where = f.Namespace + "/" + f.Name
+			}
+			event := pdChangeEvent{
+				RoutingKey: wh.RoutingKey,
+				Payload: pdChangePayload{
+					Summary:       fmt.Sprintf("Certificate rotated: %s — %s", where, f.Source),
+					Source:        "trustwatch",
+					Timestamp:     curr.At.UTC(),
+					CustomDetails: pdDetails(&f),
+				},
+			}
+			body, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			n.enqueue(wh, pagerDutyChangesURL, event.Payload.Summary, body)
+		}
 	}
 }
 
@@ -64,7 +170,7 @@ func (n *Notifier) resolvePagerDuty(wh *config.WebhookConfig, keys []string) {
 		if err != nil {
 			continue
 		}
-		n.post(pagerDutyEventsURL, "application/json", body)
+		n.enqueue(wh, pagerDutyEventsURL, "resolve "+key, body)
 	}
 }
 
@@ -77,6 +183,16 @@ func pdSummary(f *store.CertFinding) string {
 		strings.ToUpper(string(f.Severity)), where, string(f.Source))
 }
 
+// pdFindingSeverity maps a finding to a PagerDuty severity. Endpoints that
+// could not be probed are reported as error, since their certificate state is
+// unknown rather than known to be expiring.
+func pdFindingSeverity(f *store.CertFinding) string {
+	if !f.ProbeOK && f.Severity != store.SeverityInfo {
+		return "error"
+	}
+	return pdSeverity(f.Severity)
+}
+
 func pdSeverity(s store.Severity) string {
 	switch s {
 	case store.SeverityCritical:
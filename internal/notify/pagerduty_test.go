@@ -252,3 +252,118 @@ func TestPdSeverity(t *testing.T) {
 		}
 	}
 }
+
+// pdCapture points both PagerDuty endpoints at a test server and returns the
+// decoded requests by URL path.
+func pdCapture(t *testing.T) func() map[string][]map[string]any {
+	t.Helper()
+	var mu sync.Mutex
+	got := make(map[string][]map[string]any)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid JSON: %v", err)
+		}
+		mu.Lock()
+		got[r.URL.Path] = append(got[r.URL.Path], body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+
+	origEvents, origChanges := pagerDutyEventsURL, pagerDutyChangesURL
+	t.Cleanup(func() { pagerDutyEventsURL, pagerDutyChangesURL = origEvents, origChanges })
+	pagerDutyEventsURL = srv.URL + "/v2/enqueue"
+	pagerDutyChangesURL = srv.URL + "/v2/change/enqueue"
+	return func() map[string][]map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return got
+	}
+}
+
+func TestPagerDuty_CustomDetails(t *testing.T) {
+	requests := pdCapture(t)
+	n := New(pagerDutyConfig("key"))
+
+	f := criticalFinding("my-cert", "default")
+	f.Issuer = "CN=Test CA"
+	f.Serial = "42"
+	f.Remediation = "renew it"
+	n.Notify(store.Snapshot{}, store.Snapshot{At: time.Now(), Findings: []store.CertFinding{f}})
+
+	events := requests()["/v2/enqueue"]
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	payload := events[0]["payload"].(map[string]any)
+	details := payload["custom_details"].(map[string]any)
+	for k, want := range map[string]string{
+		"notAfter":    f.NotAfter.UTC().Format(time.RFC3339),
+		"issuer":      "CN=Test CA",
+		"serial":      "42",
+		"remediation": "renew it",
+	} {
+		if details[k] != want {
+			t.Errorf("custom_details[%s] = %v, want %q", k, details[k], want)
+		}
+	}
+	if payload["component"] != "default/my-cert" || payload["class"] != string(store.SourceTLSSecret) {
+		t.Errorf("unexpected component/class: %v", payload)
+	}
+}
+
+func TestPagerDuty_ResolveOnDowngrade(t *testing.T) {
+	requests := pdCapture(t)
+	n := New(pagerDutyConfig("key"))
+
+	f := criticalFinding("my-cert", "default")
+	renewed := f
+	renewed.Severity = store.SeverityInfo
+	renewed.NotAfter = time.Now().Add(90 * 24 * time.Hour)
+	n.Notify(store.Snapshot{Findings: []store.CertFinding{f}}, store.Snapshot{At: time.Now(), Findings: []store.CertFinding{renewed}})
+
+	events := requests()["/v2/enqueue"]
+	if len(events) != 1 || events[0]["event_action"] != "resolve" || events[0]["dedup_key"] != findingKey(&f) {
+		t.Errorf("expected a resolve for the renewed certificate, got %v", events)
+	}
+}
+
+func TestPagerDuty_ChangeEventOnRotation(t *testing.T) {
+	requests := pdCapture(t)
+	n := New(pagerDutyConfig("key"))
+
+	cert := store.CertFinding{Name: "api-tls", Namespace: "payments", Source: store.SourceTLSSecret, Severity: store.SeverityInfo, Serial: "2", ProbeOK: true}
+	rotation := store.CertFinding{
+		Name: "api-tls", Namespace: "payments", Source: store.SourceTLSSecret, Severity: store.SeverityInfo,
+		FindingType: "SERIAL_CHANGED", Notes: "serial changed from 1 to 2", ProbeOK: true,
+	}
+	n.Notify(store.Snapshot{}, store.Snapshot{At: time.Now(), Findings: []store.CertFinding{cert, rotation}})
+
+	got := requests()
+	if len(got["/v2/enqueue"]) != 0 {
+		t.Errorf("rotation must not trigger an incident: %v", got["/v2/enqueue"])
+	}
+	changes := got["/v2/change/enqueue"]
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change event, got %d", len(changes))
+	}
+	payload := changes[0]["payload"].(map[string]any)
+	if changes[0]["routing_key"] != "key" || payload["summary"] != "Certificate rotated: payments/api-tls — k8s.tlsSecret" {
+		t.Errorf("unexpected change event: %v", changes[0])
+	}
+	if payload["custom_details"].(map[string]any)["notes"] != "serial changed from 1 to 2" {
+		t.Errorf("custom_details = %v", payload["custom_details"])
+	}
+}
+
+func TestPdFindingSeverity(t *testing.T) {
+	failed := store.CertFinding{Severity: store.SeverityCritical, ProbeErr: "connection refused"}
+	if got := pdFindingSeverity(&failed); got != "error" {
+		t.Errorf("probe failure = %q, want error", got)
+	}
+	ok := criticalFinding("a", "b")
+	if got := pdFindingSeverity(&ok); got != "critical" {
+		t.Errorf("expiring = %q, want critical", got)
+	}
+}
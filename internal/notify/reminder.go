@@ -0,0 +1,414 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/discovery"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+const (
+	// openKeyPrefix marks when a finding was first seen at a notified severity.
+	openKeyPrefix = "open:"
+	// reminderKeyPrefix marks milestones already reminded for a certificate,
+	// as reminder:<finding key>|<notAfter unix>|<milestone>.
+	reminderKeyPrefix = "reminder:"
+)
+
+// milestone is a reminder point before certificate expiry.
+type milestone struct {
+	label  string
+	before time.Duration
+}
+
+// parseMilestones parses configured milestones, largest first. Invalid
+// entries are rejected by config validation and skipped here.
+func parseMilestones(labels []string) []milestone {
+	var out []milestone
+	for _, l := range labels {
+		d, err := config.ParseMilestone(l)
+		if err != nil {
+			slog.Warn("notification: ignoring reminder milestone", "err", err)
+			continue
+		}
+		out = append(out, milestone{label: l, before: d})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].before > out[j].before })
+	return out
+}
+
+// Reminder is an expiry countdown reminder for a finding that reached a milestone.
+type Reminder struct {
+	OpenSince      time.Time         // first scan that reported the finding at a notified severity
+	Milestone      string            // the milestone reached, e.g. "7d" or "expired"
+	Finding        store.CertFinding // the finding as of the current scan
+	OpenFor        time.Duration     // how long the finding has been open
+	RenewalPending bool              // a cert-manager renewal for the certificate has not completed
+}
+
+// Text describes the reminder in one line.
+func (r *Reminder) Text() string {
+	f := &r.Finding
+	where := f.Name
+	if f.Namespace != "" {
+		where = f.Namespace + "/" + f.Name
+	}
+	raised := r.OpenSince.Add(r.OpenFor)
+	left := f.NotAfter.Sub(raised)
+	expiry := "expires in " + formatAge(left)
+	if left <= 0 {
+		expiry = "expired " + formatAge(-left) + " ago"
+	}
+	parts := []string{
+		fmt.Sprintf("%s %s (%s reminder)", where, expiry, r.Milestone),
+		"open for " + formatAge(r.OpenFor),
+	}
+	if r.RenewalPending {
+		parts = append(parts, "cert-manager renewal pending")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatAge renders a duration in days and hours, e.g. "6d 23h".
+func formatAge(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d/time.Hour) % 24
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	}
+}
+
+// trackOpen records when each finding was first seen at a notified severity
+// and drops the state of findings that cleared.
+func (n *Notifier) trackOpen(curr *store.Snapshot) {
+	now := n.nowFn()
+	var save, stale []string
+	present := make(map[string]bool)
+
+	n.mu.Lock()
+	for i := range curr.Findings {
+		f := &curr.Findings[i]
+		if !n.severities[f.Severity] {
+			continue
+		}
+		openKey := openKeyPrefix + findingKey(f)
+		present[openKey] = true
+		if _, ok := n.openSince[openKey]; !ok {
+			n.openSince[openKey] = now
+			save = append(save, openKey)
+		}
+	}
+	for k := range n.openSince {
+		if !present[k] {
+			stale = append(stale, k)
+			delete(n.openSince, k)
+		}
+	}
+	n.mu.Unlock()
+
+	for _, k := range save {
+		if err := n.outbox.SaveCooldown(k, now); err != nil {
+			slog.Warn("notification: saving open state", "key", k, "err", err)
+		}
+	}
+	if err := n.outbox.DeleteCooldowns(stale); err != nil {
+		slog.Warn("notification: clearing open state", "err", err)
+	}
+}
+
+// checkReminders returns reminders for certificates that reached a milestone
+// they were not yet reminded about. Findings notified in this scan (notified)
+// only record their milestone, so a new finding is not followed by a reminder
+// for the same milestone. State for certificates that cleared or were replaced
+// is dropped. trackOpen must run first.
+func (n *Notifier) checkReminders(curr *store.Snapshot, notified map[string]bool) []Reminder {
+	now := n.nowFn()
+	pending := renewalPending(curr.Findings)
+	var reminders []Reminder
+	var save []string
+	presentCert := make(map[string]bool)
+
+	n.mu.Lock()
+	for i := range curr.Findings {
+		f := &curr.Findings[i]
+		if !n.severities[f.Severity] {
+			continue
+		}
+		key := findingKey(f)
+		since, ok := n.openSince[openKeyPrefix+key]
+		if !ok {
+			since = now
+		}
+
+		if f.NotAfter.IsZero() {
+			continue
+		}
+		m, ok := n.milestoneFor(f.NotAfter.Sub(now))
+		if !ok {
+			continue
+		}
+		cert := key + "|" + strconv.FormatInt(f.NotAfter.Unix(), 10)
+		presentCert[cert] = true
+		remKey := reminderKeyPrefix + cert + "|" + m.label
+		if _, done := n.reminded[remKey]; done {
+			continue
+		}
+		n.reminded[remKey] = now
+		save = append(save, remKey)
+		if notified[key] {
+			continue
+		}
+		reminders = append(reminders, Reminder{
+			Finding:        *f,
+			Milestone:      m.label,
+			OpenSince:      since,
+			OpenFor:        now.Sub(since),
+			RenewalPending: pending(f),
+		})
+	}
+
+	var stale []string
+	for k := range n.reminded {
+		cert := strings.TrimPrefix(k, reminderKeyPrefix)
+		if idx := strings.LastIndex(cert, "|"); idx >= 0 {
+			cert = cert[:idx]
+		}
+		if !presentCert[cert] {
+			stale = append(stale, k)
+			delete(n.reminded, k)
+		}
+	}
+	n.mu.Unlock()
+
+	for _, k := range save {
+		if err := n.outbox.SaveCooldown(k, now); err != nil {
+			slog.Warn("notification: saving reminder state", "key", k, "err", err)
+		}
+	}
+	if err := n.outbox.DeleteCooldowns(stale); err != nil {
+		slog.Warn("notification: clearing reminder state", "err", err)
+	}
+	return reminders
+}
+
+// milestoneFor returns the smallest milestone that the remaining lifetime has reached.
+func (n *Notifier) milestoneFor(remaining time.Duration) (milestone, bool) {
+	var found milestone
+	ok := false
+	for _, m := range n.milestones {
+		if remaining <= m.before {
+			found, ok = m, true
+		}
+	}
+	return found, ok
+}
+
+// renewalPending returns a check for whether a finding's certificate belongs to
+// a cert-manager Certificate that is not Ready, matched by name for Certificate
+// findings and by serial for the Secrets and endpoints serving the same cert.
+func renewalPending(findings []store.CertFinding) func(*store.CertFinding) bool {
+	notReady := make(map[string]bool)
+	for i := range findings {
+		f := &findings[i]
+		if f.Source == store.SourceCertManagerRenewal && f.FindingType == discovery.FindingRequestPending {
+			notReady[f.Namespace+"/"+f.Name] = true
+		}
+	}
+	bySerial := make(map[string]string)
+	for i := range findings {
+		f := &findings[i]
+		if f.Source == store.SourceCertManager && f.Serial != "" {
+			bySerial[f.Serial] = f.Namespace + "/" + f.Name
+		}
+	}
+	return func(f *store.CertFinding) bool {
+		if len(notReady) == 0 {
+			return false
+		}
+		if f.Source == store.SourceCertManager {
+			return notReady[f.Namespace+"/"+f.Name]
+		}
+		cert, ok := bySerial[f.Serial]
+		return ok && f.Serial != "" && notReady[cert]
+	}
+}
+
+// sendReminders queues reminders for every webhook they are routed to.
+// Alertmanager is skipped because firing alerts are re-sent on every scan,
+// and ticket webhooks track findings through their issues.
+func (n *Notifier) sendReminders(curr *store.Snapshot, reminders []Reminder) {
+	for i := range n.webhooks {
+		wh := &n.webhooks[i]
+		if wh.Type == "alertmanager" || wh.Type == "ticket" {
+			continue
+		}
+		routed := n.routedReminders(wh, reminders)
+		if len(routed) == 0 {
+			continue
+		}
+		if tmpl := n.templates[wh.ID()]; tmpl != nil {
+			data := newTemplateData(curr, nil, nil)
+			data.Summary = reminderSummary(routed)
+			data.Reminders = routed
+			n.sendTemplate(wh, tmpl, data)
+			continue
+		}
+		switch wh.Type {
+		case "slack":
+			n.enqueueReminders(wh, "", routed, slackReminderBody)
+		case "pagerduty":
+			n.sendPagerDutyReminders(wh, routed)
+		case "grafana":
+			n.enqueueReminders(wh, grafanaAnnotationsPath, routed, grafanaReminderBody)
+		case "smtp":
+			if wh.SMTP != nil && wh.SMTP.DigestPeriod() == 0 {
+				n.emailReminders(wh, curr, routed)
+			}
+		default:
+			n.enqueueReminders(wh, "", routed, genericReminderBody)
+		}
+	}
+}
+
+func (n *Notifier) routedReminders(wh *config.WebhookConfig, reminders []Reminder) []Reminder {
+	if n.router == nil {
+		return reminders
+	}
+	var out []Reminder
+	for i := range reminders {
+		if len(n.routed(wh, []store.CertFinding{reminders[i].Finding})) > 0 {
+			out = append(out, reminders[i])
+		}
+	}
+	return out
+}
+
+func reminderSummary(reminders []Reminder) string {
+	return fmt.Sprintf("%d expiry reminder(s)", len(reminders))
+}
+
+func (n *Notifier) enqueueReminders(wh *config.WebhookConfig, endpoint string, reminders []Reminder, render func([]Reminder) ([]byte, error)) {
+	body, err := render(reminders)
+	if err != nil {
+		slog.Warn("notification: rendering reminders", "webhook", wh.ID(), "err", err)
+		return
+	}
+	n.enqueue(wh, endpoint, reminderSummary(reminders), body)
+}
+
+// GenericReminder is a single reminder in the generic webhook payload.
+type GenericReminder struct {
+	OpenSince time.Time `json:"openSince"`
+	Milestone string    `json:"milestone"`
+	GenericFinding
+	RenewalPending bool `json:"renewalPending"`
+}
+
+func genericReminderBody(reminders []Reminder) ([]byte, error) {
+	payload := GenericPayload{
+		Timestamp: time.Now().UTC(),
+		Summary:   reminderSummary(reminders),
+		Findings:  []GenericFinding{},
+		Reminders: make([]GenericReminder, len(reminders)),
+	}
+	for i := range reminders {
+		f := &reminders[i].Finding
+		payload.Reminders[i] = GenericReminder{
+			GenericFinding: GenericFinding{
+				Name:      f.Name,
+				Namespace: f.Namespace,
+				Source:    f.Source,
+				Severity:  f.Severity,
+				NotAfter:  f.NotAfter,
+				ProbeOK:   f.ProbeOK,
+			},
+			OpenSince:      reminders[i].OpenSince,
+			Milestone:      reminders[i].Milestone,
+			RenewalPending: reminders[i].RenewalPending,
+		}
+	}
+	return json.Marshal(payload)
+}
+
+func slackReminderBody(reminders []Reminder) ([]byte, error) {
+	blocks := []SlackBlock{{
+		Type: "header",
+		Text: &SlackText{Type: "plain_text", Text: "trustwatch: " + reminderSummary(reminders)},
+	}}
+	for i := range reminders {
+		blocks = append(blocks, SlackBlock{
+			Type: "section",
+			Text: &SlackText{
+				Type: "mrkdwn",
+				Text: fmt.Sprintf("[%s] %s", strings.ToUpper(string(reminders[i].Finding.Severity)), reminders[i].Text()),
+			},
+		})
+	}
+	blocks = append(blocks, SlackBlock{
+		Type: "context",
+		Text: &SlackText{
+			Type: "mrkdwn",
+			Text: fmt.Sprintf("Source: trustwatch | %s", time.Now().UTC().Format(time.RFC3339)),
+		},
+	})
+	return json.Marshal(SlackPayload{Blocks: blocks})
+}
+
+func grafanaReminderBody(reminders []Reminder) ([]byte, error) {
+	lines := []string{"trustwatch: " + reminderSummary(reminders)}
+	findings := make([]store.CertFinding, 0, len(reminders))
+	for i := range reminders {
+		lines = append(lines, "- "+reminders[i].Text())
+		findings = append(findings, reminders[i].Finding)
+	}
+	return json.Marshal(grafanaAnnotation{
+		Time: time.Now().UnixMilli(),
+		Tags: append(grafanaTags(findings), "reminder"),
+		Text: strings.Join(lines, "\n"),
+	})
+}
+
+// sendPagerDutyReminders re-triggers each finding's incident with the reminder
+// as summary; PagerDuty appends it to the open incident via the dedup key.
+func (n *Notifier) sendPagerDutyReminders(wh *config.WebhookConfig, reminders []Reminder) {
+	for i := range reminders {
+		f := &reminders[i].Finding
+		event := pdEvent{
+			RoutingKey:  wh.RoutingKey,
+			EventAction: "trigger",
+			DedupKey:    findingKey(f),
+			Payload:     pdFindingPayload(f, "Reminder: "+reminders[i].Text()),
+		}
+		body, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		n.enqueue(wh, pagerDutyEventsURL, event.Payload.Summary, body)
+	}
+}
+
+func (n *Notifier) emailReminders(wh *config.WebhookConfig, curr *store.Snapshot, reminders []Reminder) {
+	findings := make([]store.CertFinding, 0, len(reminders))
+	lines := make([]string, 0, len(reminders))
+	for i := range reminders {
+		findings = append(findings, reminders[i].Finding)
+		lines = append(lines, reminders[i].Text()+".")
+	}
+	var cluster string
+	if curr.Metadata != nil {
+		cluster = curr.Metadata.Cluster
+	}
+	n.queueEmail(wh, wh.SMTP.To, "trustwatch: "+reminderSummary(reminders), strings.Join(lines, " "), cluster, curr.At, findings)
+}
@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/discovery"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func reminderConfig(url string) *Notifier {
+	cfg := testConfig(url)
+	cfg.Reminders = []string{"1d", "30d", "7d", "expired"}
+	return New(cfg)
+}
+
+func expiringIn(name string, d time.Duration) store.CertFinding {
+	f := warnFinding(name, "payments")
+	f.NotAfter = time.Now().Add(d).Truncate(time.Second)
+	return f
+}
+
+func TestMilestoneFor(t *testing.T) {
+	n := reminderConfig("http://unused")
+	tests := []struct {
+		want      string
+		remaining time.Duration
+		ok        bool
+	}{
+		{remaining: 40 * 24 * time.Hour},
+		{remaining: 20 * 24 * time.Hour, want: "30d", ok: true},
+		{remaining: 5 * 24 * time.Hour, want: "7d", ok: true},
+		{remaining: 2 * time.Hour, want: "1d", ok: true},
+		{remaining: -time.Hour, want: "expired", ok: true},
+	}
+	for _, tt := range tests {
+		m, ok := n.milestoneFor(tt.remaining)
+		if ok != tt.ok || m.label != tt.want {
+			t.Errorf("milestoneFor(%s) = %q, %v; want %q, %v", tt.remaining, m.label, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNotifier_RemindersOncePerMilestone(t *testing.T) {
+	srv, requests := captureServer(t)
+	n := reminderConfig(srv.URL)
+	start := time.Now()
+	n.nowFn = func() time.Time { return start }
+
+	f := expiringIn("api", 20*24*time.Hour)
+	snap := store.Snapshot{At: start, Findings: []store.CertFinding{f}}
+
+	// First scan: new-finding notification only; the 30d milestone is recorded.
+	n.Notify(store.Snapshot{}, snap)
+	if got := len(requests()); got != 1 {
+		t.Fatalf("expected 1 request for the new finding, got %d", got)
+	}
+
+	// Unchanged scans at the same milestone send nothing.
+	n.Notify(snap, snap)
+	if got := len(requests()); got != 1 {
+		t.Fatalf("expected no reminder within the same milestone, got %d requests", got)
+	}
+
+	// Two weeks later the cert is inside the 7d milestone: one reminder.
+	n.nowFn = func() time.Time { return start.Add(14 * 24 * time.Hour) }
+	n.Notify(snap, snap)
+	n.Notify(snap, snap)
+	reqs := requests()
+	if len(reqs) != 2 {
+		t.Fatalf("expected exactly one reminder, got %d", len(reqs)-1)
+	}
+	var payload GenericPayload
+	if err := json.Unmarshal(reqs[1].body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Reminders) != 1 || payload.Reminders[0].Milestone != "7d" || payload.Summary != "1 expiry reminder(s)" {
+		t.Fatalf("unexpected reminder payload: %s", reqs[1].body)
+	}
+	if !payload.Reminders[0].OpenSince.Equal(start) {
+		t.Errorf("openSince = %s, want first scan time %s", payload.Reminders[0].OpenSince, start)
+	}
+
+	// A renewed certificate (new NotAfter) starts its milestones afresh.
+	f.NotAfter = start.Add(14*24*time.Hour + 25*24*time.Hour)
+	renewed := store.Snapshot{At: start, Findings: []store.CertFinding{f}}
+	n.Notify(snap, renewed)
+	if got := len(requests()); got != 3 {
+		t.Errorf("expected a 30d reminder for the renewed cert, got %d requests", got)
+	}
+}
+
+func TestNotifier_ReminderStatePersists(t *testing.T) {
+	srv, requests := captureServer(t)
+	outbox := NewMemoryOutbox()
+	cfg := testConfig(srv.URL)
+	cfg.Reminders = []string{"7d"}
+
+	f := expiringIn("api", 5*24*time.Hour)
+	snap := store.Snapshot{At: time.Now(), Findings: []store.CertFinding{f}}
+	New(cfg, WithOutbox(outbox)).Notify(snap, snap)
+	if got := len(requests()); got != 1 {
+		t.Fatalf("expected a 7d reminder for an existing finding, got %d requests", got)
+	}
+
+	// A restarted notifier remembers the milestone.
+	New(cfg, WithOutbox(outbox)).Notify(snap, snap)
+	if got := len(requests()); got != 1 {
+		t.Errorf("reminder re-sent after restart, got %d requests", got)
+	}
+
+	// Once the finding clears, its state is dropped.
+	New(cfg, WithOutbox(outbox)).Notify(snap, store.Snapshot{At: time.Now()})
+	state, err := outbox.Cooldowns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range state {
+		if strings.HasPrefix(k, reminderKeyPrefix) || strings.HasPrefix(k, openKeyPrefix) {
+			t.Errorf("stale reminder state %q after the finding cleared", k)
+		}
+	}
+}
+
+func TestRenewalPending(t *testing.T) {
+	cert := store.CertFinding{Source: store.SourceCertManager, Namespace: "payments", Name: "api", Serial: "42"}
+	secret := store.CertFinding{Source: store.SourceTLSSecret, Namespace: "payments", Name: "api-tls", Serial: "42"}
+	other := store.CertFinding{Source: store.SourceTLSSecret, Namespace: "payments", Name: "web-tls", Serial: "7"}
+	pendingReq := store.CertFinding{Source: store.SourceCertManagerRenewal, Namespace: "payments", Name: "api", FindingType: discovery.FindingRequestPending}
+
+	pending := renewalPending([]store.CertFinding{cert, secret, other, pendingReq})
+	if !pending(&cert) || !pending(&secret) {
+		t.Error("expected the Certificate and its Secret to have a pending renewal")
+	}
+	if pending(&other) {
+		t.Error("unrelated secret reported as renewal pending")
+	}
+	if renewalPending([]store.CertFinding{cert, secret})(&secret) {
+		t.Error("no renewal is pending without a REQUEST_PENDING finding")
+	}
+}
+
+func TestReminderText(t *testing.T) {
+	now := time.Now()
+	r := Reminder{
+		Finding:        expiringIn("api", 6*24*time.Hour+time.Hour),
+		OpenSince:      now.Add(-23 * 24 * time.Hour),
+		Milestone:      "7d",
+		OpenFor:        23 * 24 * time.Hour,
+		RenewalPending: true,
+	}
+	got := r.Text()
+	for _, want := range []string{"payments/api expires in 6d", "(7d reminder)", "open for 23d 0h", "cert-manager renewal pending"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Text() = %q, missing %q", got, want)
+		}
+	}
+}
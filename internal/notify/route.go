@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"path"
+	"slices"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// router decides which named webhooks receive a finding. Without routes or a
+// fallback every webhook receives every finding.
+type router struct {
+	routes   []config.RouteConfig
+	fallback []string
+}
+
+func newRouter(cfg *config.NotificationConfig) *router {
+	if len(cfg.Routes) == 0 && len(cfg.Fallback) == 0 {
+		return nil
+	}
+	return &router{routes: cfg.Routes, fallback: cfg.Fallback}
+}
+
+// targets returns the webhook names a finding is routed to: the names from its
+// trustwatch.dev/notify annotation plus those of matching routes. The fallback
+// is added for findings with neither an owner nor an annotation, even when a
+// route matched, and for findings nothing else claims.
+func (r *router) targets(f *store.CertFinding) []string {
+	names := append([]string(nil), f.Notify...)
+	for i := range r.routes {
+		if !matchRoute(&r.routes[i].Match, f) {
+			continue
+		}
+		names = append(names, r.routes[i].Webhooks...)
+		if !r.routes[i].Continue {
+			break
+		}
+	}
+	if len(names) == 0 || (f.Owner == "" && len(f.Notify) == 0) {
+		names = append(names, r.fallback...)
+	}
+	return names
+}
+
+// matchRoute reports whether f satisfies every non-empty field of m.
+func matchRoute(m *config.RouteMatch, f *store.CertFinding) bool {
+	return matchGlob(m.Namespaces, f.Namespace) &&
+		matchExact(m.Sources, string(f.Source)) &&
+		matchExact(m.Severities, string(f.Severity)) &&
+		matchExact(m.Clusters, f.Cluster) &&
+		matchExact(m.FindingTypes, f.FindingType) &&
+		matchGlob(m.Owners, f.Owner)
+}
+
+func matchExact(values []string, v string) bool {
+	return len(values) == 0 || slices.Contains(values, v)
+}
+
+func matchGlob(patterns []string, v string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, err := path.Match(p, v); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// routed returns the findings that should be sent to wh. Alertmanager does its
+// own routing, so it always receives everything.
+func (n *Notifier) routed(wh *config.WebhookConfig, findings []store.CertFinding) []store.CertFinding {
+	if n.router == nil || wh.Type == "alertmanager" {
+		return findings
+	}
+	var out []store.CertFinding
+	for i := range findings {
+		if slices.Contains(n.router.targets(&findings[i]), wh.Name) {
+			out = append(out, findings[i])
+		}
+	}
+	return out
+}
+
+// routedData narrows data to the new and resolved findings routed to wh. It
+// returns nil when nothing is left to send.
+func (n *Notifier) routedData(wh *config.WebhookConfig, data *TemplateData) *TemplateData {
+	if n.router == nil {
+		return data
+	}
+	findings := n.routed(wh, data.Findings)
+	resolved := n.routed(wh, data.resolved)
+	if len(findings) == 0 && len(resolved) == 0 {
+		return nil
+	}
+	out := *data
+	out.Findings = findings
+	out.Summary = buildSummary(findings)
+	out.resolved = resolved
+	out.Resolved = make([]string, 0, len(resolved))
+	for i := range resolved {
+		out.Resolved = append(out.Resolved, findingKey(&resolved[i]))
+	}
+	return &out
+}
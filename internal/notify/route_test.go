@@ -0,0 +1,148 @@
+package notify
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func TestMatchRoute(t *testing.T) {
+	f := criticalFinding("api", "payments-prod")
+	f.Owner = "payments"
+	f.Cluster = "east"
+	f.FindingType = "CHAIN_INVALID"
+
+	tests := []struct {
+		name  string
+		match config.RouteMatch
+		want  bool
+	}{
+		{name: "empty matches all", want: true},
+		{name: "namespace glob", match: config.RouteMatch{Namespaces: []string{"payments-*"}}, want: true},
+		{name: "namespace mismatch", match: config.RouteMatch{Namespaces: []string{"search"}}},
+		{name: "source", match: config.RouteMatch{Sources: []string{string(store.SourceTLSSecret)}}, want: true},
+		{name: "severity mismatch", match: config.RouteMatch{Severities: []string{"warn"}}},
+		{name: "cluster", match: config.RouteMatch{Clusters: []string{"west", "east"}}, want: true},
+		{name: "finding type mismatch", match: config.RouteMatch{FindingTypes: []string{"MANAGED_EXPIRY"}}},
+		{name: "owner and severity", match: config.RouteMatch{Owners: []string{"pay*"}, Severities: []string{"critical"}}, want: true},
+		{name: "one field fails", match: config.RouteMatch{Owners: []string{"payments"}, Clusters: []string{"west"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchRoute(&tt.match, &f); got != tt.want {
+				t.Errorf("matchRoute = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouter_Targets(t *testing.T) {
+	r := newRouter(&config.NotificationConfig{
+		Routes: []config.RouteConfig{
+			{Match: config.RouteMatch{Severities: []string{"critical"}}, Webhooks: []string{"oncall"}, Continue: true},
+			{Match: config.RouteMatch{Owners: []string{"payments"}}, Webhooks: []string{"payments"}},
+			{Match: config.RouteMatch{Namespaces: []string{"payments"}}, Webhooks: []string{"never"}},
+		},
+		Fallback: []string{"platform"},
+	})
+
+	owned := criticalFinding("api", "payments")
+	owned.Owner = "payments"
+	if got := r.targets(&owned); !slices.Equal(got, []string{"oncall", "payments"}) {
+		t.Errorf("continue then stop: got %v", got)
+	}
+
+	annotated := warnFinding("web", "search")
+	annotated.Notify = []string{"search"}
+	if got := r.targets(&annotated); !slices.Equal(got, []string{"search"}) {
+		t.Errorf("notify annotation: got %v", got)
+	}
+
+	unowned := warnFinding("web", "default")
+	if got := r.targets(&unowned); !slices.Equal(got, []string{"platform"}) {
+		t.Errorf("fallback: got %v", got)
+	}
+
+	// An unowned finding still reaches the fallback when a route matched it.
+	paged := criticalFinding("api", "default")
+	if got := r.targets(&paged); !slices.Equal(got, []string{"oncall", "platform"}) {
+		t.Errorf("unowned with a matching route: got %v", got)
+	}
+
+	if newRouter(&config.NotificationConfig{}) != nil {
+		t.Error("expected no router without routes or fallback")
+	}
+}
+
+func TestNotifier_Routing(t *testing.T) {
+	paySrv, payRequests := captureServer(t)
+	platSrv, platRequests := captureServer(t)
+	amSrv, amRequests := captureServer(t)
+
+	cfg := config.NotificationConfig{
+		Enabled: true,
+		Webhooks: []config.WebhookConfig{
+			{Name: "payments", URL: paySrv.URL},
+			{Name: "platform", URL: platSrv.URL},
+			{Name: "am", URL: amSrv.URL, Type: "alertmanager"},
+		},
+		Routes: []config.RouteConfig{
+			{Match: config.RouteMatch{Owners: []string{"payments"}}, Webhooks: []string{"payments"}},
+		},
+		Fallback:   []string{"platform"},
+		Severities: []string{"critical", "warn"},
+		Cooldown:   time.Hour,
+	}
+	n := New(cfg)
+
+	owned := criticalFinding("api", "payments")
+	owned.Owner = "payments"
+	unowned := warnFinding("web", "default")
+	curr := store.Snapshot{At: time.Now(), Findings: []store.CertFinding{owned, unowned}}
+	n.Notify(store.Snapshot{}, curr)
+
+	pay := payRequests()
+	if len(pay) != 1 || !strings.Contains(string(pay[0].body), `"name":"api"`) || strings.Contains(string(pay[0].body), `"name":"web"`) {
+		t.Errorf("payments webhook got %d request(s): %s", len(pay), pay)
+	}
+	plat := platRequests()
+	if len(plat) != 1 || !strings.Contains(string(plat[0].body), `"name":"web"`) || strings.Contains(string(plat[0].body), `"name":"api"`) {
+		t.Errorf("fallback webhook got %d request(s): %s", len(plat), plat)
+	}
+	if len(amRequests()) != 1 {
+		t.Error("alertmanager must receive all findings regardless of routes")
+	}
+}
+
+func TestNotifier_RoutingResolved(t *testing.T) {
+	cfg := config.NotificationConfig{
+		Enabled: true,
+		Webhooks: []config.WebhookConfig{
+			{Name: "payments", Type: "pagerduty", RoutingKey: "pay"},
+			{Name: "platform", Type: "pagerduty", RoutingKey: "plat"},
+		},
+		Routes: []config.RouteConfig{
+			{Match: config.RouteMatch{Namespaces: []string{"payments"}}, Webhooks: []string{"payments"}},
+		},
+		Fallback:   []string{"platform"},
+		Severities: []string{"critical", "warn"},
+	}
+	n := New(cfg)
+
+	owned := criticalFinding("api", "payments")
+	owned.Owner = "payments"
+	data := newTemplateData(&store.Snapshot{At: time.Now()}, nil,
+		[]store.CertFinding{owned, criticalFinding("db", "default")})
+	pay := n.routedData(&cfg.Webhooks[0], data)
+	if pay == nil || !slices.Equal(pay.Resolved, []string{"k8s.tlsSecret/payments/api"}) {
+		t.Errorf("payments resolved = %+v", pay)
+	}
+	plat := n.routedData(&cfg.Webhooks[1], data)
+	if plat == nil || !slices.Equal(plat.Resolved, []string{"k8s.tlsSecret/default/db"}) {
+		t.Errorf("platform resolved = %+v", plat)
+	}
+}
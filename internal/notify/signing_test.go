@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func TestNotifier_SignedWebhook(t *testing.T) {
+	srv, requests := captureServer(t)
+
+	cfg := testConfig(srv.URL)
+	cfg.Webhooks[0].SigningSecret = "hmac-s3cret"
+	n := New(cfg)
+	now := time.Now()
+	n.nowFn = func() time.Time { return now }
+
+	n.Notify(store.Snapshot{}, store.Snapshot{At: now, Findings: []store.CertFinding{criticalFinding("api", "payments")}})
+
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(reqs))
+	}
+	r := reqs[0]
+	if err := VerifySignature("hmac-s3cret", r.header, r.body, now, 5*time.Minute); err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+	if err := VerifySignature("wrong", r.header, r.body, now, 5*time.Minute); err == nil {
+		t.Error("expected mismatch for the wrong secret")
+	}
+	if err := VerifySignature("hmac-s3cret", r.header, append(r.body, ' '), now, 5*time.Minute); err == nil {
+		t.Error("expected mismatch for a modified body")
+	}
+	if err := VerifySignature("hmac-s3cret", r.header, r.body, now.Add(time.Hour), 5*time.Minute); err == nil {
+		t.Error("expected a replayed request to be rejected")
+	}
+
+	msgs, err := n.Outbox().List("", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 outbox message, got %d", len(msgs))
+	}
+	if got := r.header.Get(SignatureHeader); got == "" || string(msgs[0].Body) != string(r.body) {
+		t.Errorf("signature %q must cover the exact outbox body", got)
+	}
+}
+
+func TestNotifier_UnsignedWebhook(t *testing.T) {
+	srv, requests := captureServer(t)
+
+	n := New(testConfig(srv.URL))
+	n.Notify(store.Snapshot{}, store.Snapshot{At: time.Now(), Findings: []store.CertFinding{criticalFinding("api", "payments")}})
+
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(reqs))
+	}
+	if reqs[0].header.Get(SignatureHeader) != "" || reqs[0].header.Get(TimestampHeader) != "" {
+		t.Error("unsigned webhooks must not carry signature headers")
+	}
+}
@@ -0,0 +1,259 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/report"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+const (
+	smtpTimeout = 30 * time.Second
+
+	// digestKeyPrefix marks digest send times in the outbox cooldown state.
+	digestKeyPrefix = "digest:"
+)
+
+// sendEmail queues an email with new findings for each recipient.
+func (n *Notifier) sendEmail(wh *config.WebhookConfig, data *TemplateData) {
+	title := fmt.Sprintf("trustwatch: %d new finding(s)", len(data.Findings))
+	n.queueEmail(wh, wh.SMTP.To, title, data.Summary+".", data.Cluster, data.SnapshotAt, data.Findings)
+}
+
+// sendDigests queues one digest per recipient address and period with the
+// open warn and critical findings routed to any digest-mode smtp webhook that
+// lists the address, so an address on several webhooks still receives a single
+// digest. It is sent through the first such webhook, and the period runs from
+// the recipient's previous digest rather than a fixed time of day.
+func (n *Notifier) sendDigests(curr *store.Snapshot) {
+	var open []store.CertFinding
+	for j := range curr.Findings {
+		if sev := curr.Findings[j].Severity; sev == store.SeverityCritical || sev == store.SeverityWarn {
+			open = append(open, curr.Findings[j])
+		}
+	}
+
+	type digest struct {
+		wh       *config.WebhookConfig
+		to       string
+		included map[string]bool
+	}
+	digests := make(map[string]*digest)
+	var keys []string
+	for i := range n.webhooks {
+		wh := &n.webhooks[i]
+		if wh.Type != "smtp" || wh.SMTP == nil || wh.SMTP.DigestPeriod() == 0 {
+			continue
+		}
+		routed := n.routed(wh, open)
+		for _, to := range wh.SMTP.To {
+			key := digestKey(wh.SMTP.Digest, to)
+			d, ok := digests[key]
+			if !ok {
+				d = &digest{wh: wh, to: to, included: make(map[string]bool)}
+				digests[key] = d
+				keys = append(keys, key)
+			}
+			for k := range routed {
+				d.included[fingerprint(&routed[k])] = true
+			}
+		}
+	}
+
+	var cluster string
+	if curr.Metadata != nil {
+		cluster = curr.Metadata.Cluster
+	}
+	now := time.Now()
+	for _, key := range keys {
+		d := digests[key]
+		n.mu.Lock()
+		last, sent := n.digests[key]
+		due := !sent || now.Sub(last) >= d.wh.SMTP.DigestPeriod()
+		if due {
+			n.digests[key] = now
+		}
+		n.mu.Unlock()
+		if !due {
+			continue
+		}
+		if err := n.outbox.SaveCooldown(key, now); err != nil {
+			slog.Warn("notification: saving digest state", "webhook", d.wh.ID(), "to", d.to, "err", err)
+		}
+
+		var findings []store.CertFinding
+		for j := range open {
+			if d.included[fingerprint(&open[j])] {
+				findings = append(findings, open[j])
+			}
+		}
+		if len(findings) == 0 {
+			continue
+		}
+		title := "trustwatch daily certificate digest"
+		if d.wh.SMTP.Digest == config.DigestWeekly {
+			title = "trustwatch weekly certificate digest"
+		}
+		intro := fmt.Sprintf("%d open finding(s): %s.", len(findings), buildSummary(findings))
+		n.queueEmail(d.wh, []string{d.to}, title, intro, cluster, curr.At, findings)
+	}
+}
+
+// digestKey returns the cooldown key for a recipient's digests of the given
+// period. Addresses compare case-insensitively.
+func digestKey(period, to string) string {
+	return digestKeyPrefix + period + ":" + strings.ToLower(to)
+}
+
+// queueEmail renders findings and queues one email per recipient in to.
+func (n *Notifier) queueEmail(wh *config.WebhookConfig, to []string, title, intro, cluster string, at time.Time, findings []store.CertFinding) {
+	subject := title
+	if cluster != "" {
+		subject = fmt.Sprintf("[%s] %s", cluster, title)
+	}
+	htmlBody, textBody, err := report.Email(&report.EmailInput{
+		At:          at,
+		Title:       title,
+		ClusterName: cluster,
+		Intro:       intro,
+		Findings:    findings,
+	})
+	if err != nil {
+		slog.Warn("notification: rendering email", "webhook", wh.ID(), "err", err)
+		return
+	}
+
+	for _, to := range to {
+		body, err := buildMail(wh.SMTP.From, to, subject, htmlBody, textBody, time.Now())
+		if err != nil {
+			slog.Warn("notification: building email", "webhook", wh.ID(), "to", to, "err", err)
+			continue
+		}
+		msg := n.newMessage(wh, "mailto:"+to, fmt.Sprintf("%s to %s", subject, to), body)
+		msg.Target = wh.SMTP.Host
+		msg.ContentType = "message/rfc822"
+		if err := n.outbox.Enqueue(msg); err != nil {
+			slog.Warn("notification: queueing email", "to", to, "err", err)
+		}
+	}
+}
+
+// buildMail assembles a multipart/alternative message with plain-text and HTML parts.
+func buildMail(from, to, subject string, htmlBody, textBody []byte, date time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", textBody},
+		{"text/html; charset=utf-8", htmlBody},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// deliverMail sends a queued email. Server credentials come from the current
+// config rather than the outbox.
+func deliverMail(ctx context.Context, wh *config.WebhookConfig, msg *Message) error {
+	if wh == nil || wh.SMTP == nil {
+		return fmt.Errorf("smtp webhook %q is no longer configured", msg.Webhook)
+	}
+	s := wh.SMTP
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("parsing from address: %w", err)
+	}
+	to, err := mail.ParseAddress(strings.TrimPrefix(msg.Endpoint, "mailto:"))
+	if err != nil {
+		return fmt.Errorf("parsing recipient: %w", err)
+	}
+
+	dialer := net.Dialer{Timeout: httpTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.Address())
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		conn.Close() //nolint:errcheck // best-effort cleanup
+		return err
+	}
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close() //nolint:errcheck // best-effort cleanup
+		return err
+	}
+	defer c.Close() //nolint:errcheck // Quit below closes on success
+
+	if s.StartTLS != config.StartTLSDisabled {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: s.Host, MinVersion: tls.VersionTLS12}); err != nil {
+				return fmt.Errorf("starttls: %w", err)
+			}
+		} else if s.StartTLS != config.StartTLSOpportunistic {
+			return fmt.Errorf("%s does not offer STARTTLS (set startTLS: opportunistic or disabled to send in cleartext)", s.Address())
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return fmt.Errorf("MAIL FROM: %w", err)
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("RCPT TO: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("DATA: %w", err)
+	}
+	if _, err := w.Write(msg.Body); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("finishing message: %w", err)
+	}
+	return c.Quit()
+}
@@ -0,0 +1,342 @@
+package notify
+
+import (
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+type receivedMail struct {
+	auth string
+	from string
+	data string
+	to   []string
+}
+
+// fakeSMTP is a minimal SMTP server that records received messages. It does
+// not offer STARTTLS.
+type fakeSMTP struct {
+	ln   net.Listener
+	mail []receivedMail
+	mu   sync.Mutex
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{ln: ln}
+	t.Cleanup(func() { ln.Close() }) //nolint:errcheck // test cleanup
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) received() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail(nil), s.mail...)
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close() //nolint:errcheck // test server
+	tp := textproto.NewConn(conn)
+	reply := func(line string) { tp.PrintfLine("%s", line) } //nolint:errcheck // test server
+	reply("220 localhost ESMTP fake")
+	var m receivedMail
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			fields := strings.Fields(line)
+			if len(fields) == 3 {
+				decoded, _ := base64.StdEncoding.DecodeString(fields[2]) //nolint:errcheck // test server
+				m.auth = string(decoded)
+			}
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			m.from = strings.TrimSuffix(strings.TrimPrefix(line[len("MAIL FROM:"):], "<"), ">")
+			reply("250 OK")
+		case "RCPT":
+			m.to = append(m.to, strings.TrimSuffix(strings.TrimPrefix(line[len("RCPT TO:"):], "<"), ">"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			lines, err := tp.ReadDotLines()
+			if err != nil {
+				return
+			}
+			m.data = strings.Join(lines, "\n")
+			s.mu.Lock()
+			s.mail = append(s.mail, m)
+			s.mu.Unlock()
+			m = receivedMail{auth: m.auth}
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func smtpConfig(port int, digest string) config.NotificationConfig {
+	return config.NotificationConfig{
+		Enabled: true,
+		Webhooks: []config.WebhookConfig{{
+			Name: "email",
+			Type: "smtp",
+			SMTP: &config.SMTPConfig{
+				Host:     "127.0.0.1",
+				Port:     port,
+				From:     "trustwatch <trustwatch@example.com>",
+				To:       []string{"ops@example.com", "sec@example.com"},
+				Username: "bot",
+				Password: "s3cret",
+				StartTLS: config.StartTLSOpportunistic,
+				Digest:   digest,
+			},
+		}},
+		Severities: []string{"critical", "warn"},
+		Cooldown:   time.Hour,
+	}
+}
+
+func TestNotifier_SMTPImmediate(t *testing.T) {
+	srv := newFakeSMTP(t)
+	n := New(smtpConfig(srv.port(), ""))
+
+	curr := store.Snapshot{
+		At:       time.Now(),
+		Metadata: &store.Metadata{Cluster: "prod-east"},
+		Findings: []store.CertFinding{criticalFinding("api", "payments")},
+	}
+	n.Notify(store.Snapshot{}, curr)
+
+	got := srv.received()
+	if len(got) != 2 {
+		t.Fatalf("expected one email per recipient, got %d", len(got))
+	}
+	for i, want := range []string{"ops@example.com", "sec@example.com"} {
+		m := got[i]
+		if m.from != "trustwatch@example.com" || len(m.to) != 1 || m.to[0] != want {
+			t.Errorf("envelope = %s -> %v, want -> %s", m.from, m.to, want)
+		}
+		if m.auth != "\x00bot\x00s3cret" {
+			t.Errorf("auth = %q", m.auth)
+		}
+		for _, part := range []string{
+			"Subject: [prod-east] trustwatch: 1 new finding(s)",
+			"To: " + want,
+			"Content-Type: multipart/alternative",
+			"Content-Type: text/plain; charset=utf-8",
+			"Content-Type: text/html; charset=utf-8",
+			"payments",
+		} {
+			if !strings.Contains(m.data, part) {
+				t.Errorf("message to %s missing %q", want, part)
+			}
+		}
+	}
+
+	// Credentials are used at send time and never stored in the outbox.
+	msgs, err := n.Outbox().List("", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range msgs {
+		if msgs[i].Status != StatusSent || msgs[i].Target != "127.0.0.1" {
+			t.Errorf("message %d: status %s target %s", i, msgs[i].Status, msgs[i].Target)
+		}
+		if strings.Contains(string(msgs[i].Body), "s3cret") {
+			t.Error("outbox body must not contain the SMTP password")
+		}
+	}
+}
+
+func TestNotifier_SMTPDigest(t *testing.T) {
+	srv := newFakeSMTP(t)
+	outbox := NewMemoryOutbox()
+	cfg := smtpConfig(srv.port(), config.DigestDaily)
+	cfg.Webhooks[0].SMTP.To = []string{"ops@example.com"}
+
+	curr := store.Snapshot{
+		At: time.Now(),
+		Findings: []store.CertFinding{
+			criticalFinding("api", "payments"),
+			warnFinding("web", "frontend"),
+			{Name: "ok", Namespace: "default", Source: store.SourceTLSSecret, Severity: store.SeverityInfo},
+		},
+	}
+	n := New(cfg, WithOutbox(outbox))
+	// The digest includes findings that were already open in the previous scan.
+	n.Notify(curr, curr)
+	n.Notify(curr, curr)
+
+	got := srv.received()
+	if len(got) != 1 {
+		t.Fatalf("expected a single digest per period, got %d", len(got))
+	}
+	data := got[0].data
+	if !strings.Contains(data, "daily certificate digest") || !strings.Contains(data, "2 open finding(s)") {
+		t.Errorf("unexpected digest:\n%s", data)
+	}
+	if !strings.Contains(data, "frontend") || strings.Contains(data, "default/ok") {
+		t.Error("digest must include warn findings and exclude info findings")
+	}
+
+	// The last digest time survives a restart.
+	New(cfg, WithOutbox(outbox)).Notify(curr, curr)
+	if len(srv.received()) != 1 {
+		t.Error("digest re-sent after restart within the period")
+	}
+
+	// An elapsed period sends the next digest.
+	if err := outbox.SaveCooldown(digestKey(config.DigestDaily, "ops@example.com"), time.Now().Add(-25*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	New(cfg, WithOutbox(outbox)).Notify(curr, curr)
+	if len(srv.received()) != 2 {
+		t.Errorf("expected a second digest after the period elapsed, got %d", len(srv.received()))
+	}
+}
+
+func TestNotifier_SMTPDigestPerRecipient(t *testing.T) {
+	srv := newFakeSMTP(t)
+	cfg := smtpConfig(srv.port(), config.DigestDaily)
+	cfg.Webhooks[0].SMTP.To = []string{"ops@example.com", "sec@example.com"}
+	payments := cfg.Webhooks[0]
+	payments.Name = "payments-email"
+	payments.SMTP = &config.SMTPConfig{}
+	*payments.SMTP = *cfg.Webhooks[0].SMTP
+	payments.SMTP.To = []string{"payments@example.com", "OPS@example.com"}
+	frontend := payments
+	frontend.Name = "frontend-email"
+	frontend.SMTP = &config.SMTPConfig{}
+	*frontend.SMTP = *payments.SMTP
+	frontend.SMTP.To = []string{"payments@example.com"}
+	cfg.Webhooks = append(cfg.Webhooks, payments, frontend)
+	cfg.Routes = []config.RouteConfig{
+		{Match: config.RouteMatch{Namespaces: []string{"payments"}}, Webhooks: []string{"payments-email"}, Continue: true},
+		{Match: config.RouteMatch{Namespaces: []string{"frontend"}}, Webhooks: []string{"frontend-email"}, Continue: true},
+		{Match: config.RouteMatch{Namespaces: []string{"payments"}}, Webhooks: []string{"email"}},
+	}
+
+	curr := store.Snapshot{
+		At:       time.Now(),
+		Findings: []store.CertFinding{criticalFinding("api", "payments"), warnFinding("web", "frontend")},
+	}
+	New(cfg).Notify(curr, curr)
+
+	// Each address gets one digest holding the findings routed to any
+	// webhook that lists it, however many webhooks that is.
+	got := srv.received()
+	bodies := make(map[string]string)
+	for _, m := range got {
+		bodies[strings.ToLower(m.to[0])] = m.data
+	}
+	if len(got) != 3 || len(bodies) != 3 {
+		t.Fatalf("expected one digest for each of 3 recipients, got %d", len(got))
+	}
+	if p := bodies["payments@example.com"]; !strings.Contains(p, "2 open finding(s)") || !strings.Contains(p, "frontend") {
+		t.Errorf("payments digest must combine both webhooks' findings:\n%s", p)
+	}
+	for _, to := range []string{"ops@example.com", "sec@example.com"} {
+		if b := bodies[to]; !strings.Contains(b, "1 open finding(s)") || strings.Contains(b, "frontend") {
+			t.Errorf("%s digest must hold only the payments finding:\n%s", to, b)
+		}
+	}
+}
+
+func TestNotifier_SMTPDigestCadenceFollowsLastSend(t *testing.T) {
+	srv := newFakeSMTP(t)
+	outbox := NewMemoryOutbox()
+	cfg := smtpConfig(srv.port(), config.DigestDaily)
+	cfg.Webhooks[0].SMTP.To = []string{"ops@example.com"}
+	curr := store.Snapshot{At: time.Now(), Findings: []store.CertFinding{criticalFinding("api", "payments")}}
+
+	// The period runs from the previous digest, not from a time of day: a
+	// digest last sent 23 hours ago is not yet due.
+	if err := outbox.SaveCooldown(digestKey(config.DigestDaily, "ops@example.com"), time.Now().Add(-23*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	New(cfg, WithOutbox(outbox)).Notify(curr, curr)
+	if n := len(srv.received()); n != 0 {
+		t.Fatalf("digest sent %d time(s) before the period elapsed", n)
+	}
+	cooldowns, _ := outbox.Cooldowns() //nolint:errcheck // memory outbox never errors
+	if time.Since(cooldowns[digestKey(config.DigestDaily, "ops@example.com")]) < 23*time.Hour {
+		t.Error("a digest that was not due must not move the cadence")
+	}
+}
+
+func TestNotifier_SMTPRequiresStartTLS(t *testing.T) {
+	srv := newFakeSMTP(t)
+	cfg := smtpConfig(srv.port(), "")
+	cfg.Webhooks[0].SMTP.StartTLS = ""
+	cfg.MaxAttempts = 1
+	n := New(cfg)
+
+	n.Notify(store.Snapshot{}, store.Snapshot{At: time.Now(), Findings: []store.CertFinding{criticalFinding("api", "payments")}})
+
+	if got := srv.received(); len(got) != 0 {
+		t.Fatalf("expected no mail without STARTTLS, got %d", len(got))
+	}
+	msgs, err := n.Outbox().List(StatusDead, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || !strings.Contains(msgs[0].LastError, "STARTTLS") {
+		t.Errorf("expected dead-lettered messages with a STARTTLS error, got %+v", msgs)
+	}
+}
+
+func TestBuildMail(t *testing.T) {
+	date := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	body, err := buildMail("a@example.com", "b@example.com", "Zertifikat läuft ab", []byte("<p>html</p>"), []byte("text"), date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(body)
+	for _, want := range []string{
+		"Subject: =?utf-8?q?Zertifikat_l=C3=A4uft_ab?=\r\n",
+		"Date: " + date.Format(time.RFC1123Z) + "\r\n",
+		"MIME-Version: 1.0\r\n",
+		"<p>html</p>",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+	if !strings.Contains(msg, "\r\n\r\n--") {
+		t.Error("headers must be separated from the body by a blank line")
+	}
+}
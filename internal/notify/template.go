@@ -0,0 +1,159 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"text/template"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// TemplateData is the data model passed to webhook body templates.
+//
+// Each finding exposes the store.CertFinding fields, e.g. .Name, .Namespace,
+// .Source, .Severity, .NotAfter, .FindingType, .Notes, .Remediation, and
+// .Cluster. Resolved holds the keys of findings that cleared since the
+// previous scan: "source/namespace/name", or "cluster/source/namespace/name"
+// for findings labeled with a cluster. Reminders is only set on expiry reminder
+// messages, which carry no new findings. Templates can call json, upper, lower,
+// join, and until (time remaining until a timestamp).
+type TemplateData struct {
+	Timestamp  time.Time           // when the notification was rendered (UTC)
+	SnapshotAt time.Time           // scan time of the snapshot that triggered it
+	Cluster    string              // cluster name from snapshot metadata, if set
+	Summary    string              // e.g. "2 critical, 1 warn finding(s)"
+	Findings   []store.CertFinding // new or escalated findings
+	Resolved   []string            // [cluster/]source/namespace/name keys of findings that cleared
+	Reminders  []Reminder          // expiry milestone reminders (sent separately from new findings)
+	resolved   []store.CertFinding // the cleared findings, for routing
+}
+
+func newTemplateData(curr *store.Snapshot, findings, resolved []store.CertFinding) *TemplateData {
+	data := &TemplateData{
+		Timestamp:  time.Now().UTC(),
+		SnapshotAt: curr.At,
+		Summary:    buildSummary(findings),
+		Findings:   findings,
+		Resolved:   make([]string, 0, len(resolved)),
+		resolved:   resolved,
+	}
+	for i := range resolved {
+		data.Resolved = append(data.Resolved, findingKey(&resolved[i]))
+	}
+	if curr.Metadata != nil {
+		data.Cluster = curr.Metadata.Cluster
+	}
+	return data
+}
+
+// Render executes a webhook's body template. It returns an error if the
+// webhook has no template.
+func Render(wh *config.WebhookConfig, data *TemplateData) ([]byte, error) {
+	tmpl, err := wh.ParseTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	if tmpl == nil {
+		return nil, fmt.Errorf("webhook %q has no template", wh.ID())
+	}
+	return execute(tmpl, data)
+}
+
+func execute(tmpl *template.Template, data *TemplateData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (n *Notifier) sendTemplate(wh *config.WebhookConfig, tmpl *template.Template, data *TemplateData) {
+	body, err := execute(tmpl, data)
+	if err != nil {
+		slog.Warn("notification: rendering template", "webhook", wh.ID(), "err", err)
+		return
+	}
+	summary := data.Summary
+	if len(data.Resolved) > 0 {
+		summary = fmt.Sprintf("%s, %d resolved", summary, len(data.Resolved))
+	}
+	n.enqueue(wh, "", summary, body)
+}
+
+// Preview renders the request body a webhook would receive for data: its
+// template when set, otherwise the built-in generic or Slack payload.
+func Preview(wh *config.WebhookConfig, data *TemplateData) ([]byte, error) {
+	if wh.Template != "" {
+		return Render(wh, data)
+	}
+	switch wh.Type {
+	case "", "generic":
+		return genericBody(data.Findings)
+	case "slack":
+		return slackBody(data.Findings)
+	case "ticket":
+		if wh.Ticket == nil {
+			return nil, fmt.Errorf("ticket webhook has no ticket section")
+		}
+		return ticketPreview(wh, data.Findings)
+	default:
+		return nil, fmt.Errorf("preview is not available for %s webhooks", wh.Type)
+	}
+}
+
+// SendTest delivers data to every configured webhook once, ignoring severity
+// filters and cooldowns, and returns the first delivery error.
+func (n *Notifier) SendTest(ctx context.Context, data *TemplateData) error {
+	n.dispatch(data)
+	n.fileTestTickets(data.Findings)
+	n.Flush(ctx)
+	msgs, err := n.outbox.List("", flushBatch)
+	if err != nil {
+		return err
+	}
+	for i := range msgs {
+		if msgs[i].Status != StatusSent {
+			return fmt.Errorf("delivery to %s failed: %s", msgs[i].Target, msgs[i].LastError)
+		}
+	}
+	return nil
+}
+
+// SampleTemplateData returns representative data for previewing templates.
+func SampleTemplateData(cluster string) *TemplateData {
+	now := time.Now().UTC().Truncate(time.Second)
+	findings := []store.CertFinding{
+		{
+			Name:        "api-tls",
+			Namespace:   "payments",
+			Cluster:     cluster,
+			Source:      store.SourceTLSSecret,
+			Severity:    store.SeverityCritical,
+			NotAfter:    now.Add(5 * 24 * time.Hour),
+			Notes:       "certificate expires in 5 days",
+			Remediation: "renew the certificate or check the cert-manager Certificate",
+			ProbeOK:     true,
+		},
+		{
+			Name:      "ingress-web",
+			Namespace: "web",
+			Cluster:   cluster,
+			Source:    store.SourceIngressTLS,
+			Severity:  store.SeverityWarn,
+			NotAfter:  now.Add(20 * 24 * time.Hour),
+			ProbeOK:   true,
+		},
+	}
+	return &TemplateData{
+		Timestamp:  now,
+		SnapshotAt: now,
+		Cluster:    cluster,
+		Summary:    buildSummary(findings),
+		Findings:   findings,
+		Resolved:   []string{findingKey(&store.CertFinding{Cluster: cluster, Source: store.SourceTLSSecret, Namespace: "legacy", Name: "old-cert"})},
+	}
+}
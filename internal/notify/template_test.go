@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+const teamsTemplate = `{"title": "trustwatch {{ .Cluster }}", "text": {{ json .Summary }}, ` +
+	`"names": [{{ range $i, $f := .Findings }}{{ if $i }}, {{ end }}"{{ upper (print $f.Severity) }} {{ $f.Namespace }}/{{ $f.Name }}"{{ end }}], ` +
+	`"resolved": {{ len .Resolved }}}`
+
+type capturedRequest struct {
+	header http.Header
+	method string
+	body   []byte
+}
+
+func captureServer(t *testing.T) (*httptest.Server, func() []capturedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var reqs []capturedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body) //nolint:errcheck // test helper
+		mu.Lock()
+		reqs = append(reqs, capturedRequest{method: r.Method, header: r.Header.Clone(), body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []capturedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]capturedRequest(nil), reqs...)
+	}
+}
+
+func TestNotifier_TemplatedWebhook(t *testing.T) {
+	srv, requests := captureServer(t)
+
+	cfg := testConfig(srv.URL)
+	cfg.Webhooks[0] = config.WebhookConfig{
+		Name:        "teams",
+		URL:         srv.URL,
+		Template:    teamsTemplate,
+		Method:      "put",
+		ContentType: "application/vnd.test+json",
+		Headers:     map[string]string{"X-Token": "s3cret"},
+	}
+	n := New(cfg)
+
+	curr := store.Snapshot{
+		At:       time.Now(),
+		Metadata: &store.Metadata{Cluster: "prod-east"},
+		Findings: []store.CertFinding{criticalFinding("api", "payments")},
+	}
+	n.Notify(store.Snapshot{}, curr)
+
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(reqs))
+	}
+	r := reqs[0]
+	if r.method != http.MethodPut {
+		t.Errorf("method = %s, want PUT", r.method)
+	}
+	if got := r.header.Get("Content-Type"); got != "application/vnd.test+json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := r.header.Get("X-Token"); got != "s3cret" {
+		t.Errorf("X-Token = %q", got)
+	}
+	var payload struct {
+		Title    string   `json:"title"`
+		Text     string   `json:"text"`
+		Names    []string `json:"names"`
+		Resolved int      `json:"resolved"`
+	}
+	if err := json.Unmarshal(r.body, &payload); err != nil {
+		t.Fatalf("rendered body is not JSON: %v\n%s", err, r.body)
+	}
+	if payload.Title != "trustwatch prod-east" || payload.Text != "1 critical finding(s)" {
+		t.Errorf("payload = %+v", payload)
+	}
+	if len(payload.Names) != 1 || payload.Names[0] != "CRITICAL payments/api" {
+		t.Errorf("names = %v", payload.Names)
+	}
+
+	// Headers are applied at send time and never stored in the outbox.
+	msgs, err := n.Outbox().List("", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(msgs[0].Body), "s3cret") {
+		t.Error("outbox body must not contain header values")
+	}
+}
+
+func TestNotifier_TemplatedWebhookReceivesResolved(t *testing.T) {
+	srv, requests := captureServer(t)
+
+	cfg := testConfig(srv.URL)
+	cfg.Webhooks[0].Template = teamsTemplate
+	n := New(cfg)
+
+	prev := store.Snapshot{At: time.Now(), Findings: []store.CertFinding{criticalFinding("old", "ns")}}
+	n.Notify(prev, store.Snapshot{At: time.Now()})
+
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("expected a templated request for resolved findings, got %d", len(reqs))
+	}
+	if !strings.Contains(string(reqs[0].body), `"resolved": 1`) {
+		t.Errorf("body = %s", reqs[0].body)
+	}
+}
+
+func TestRender_SampleData(t *testing.T) {
+	wh := &config.WebhookConfig{Name: "bot", Template: `{{ range .Findings }}{{ .Name }} {{ .Remediation }};{{ end }}{{ join .Resolved "," }}`}
+	body, err := Render(wh, SampleTemplateData("lab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "api-tls renew the certificate or check the cert-manager Certificate;ingress-web ;lab/k8s.tlsSecret/legacy/old-cert"
+	if string(body) != want {
+		t.Errorf("Render = %q, want %q", body, want)
+	}
+
+	wh.Template = "{{ .Missing }}"
+	if _, err := Render(wh, SampleTemplateData("lab")); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+func TestPreview(t *testing.T) {
+	data := SampleTemplateData("")
+	body, err := Preview(&config.WebhookConfig{Type: "slack"}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slack SlackPayload
+	if err := json.Unmarshal(body, &slack); err != nil || len(slack.Blocks) != 4 {
+		t.Errorf("slack preview = %s (err %v), want header, 2 findings, context", body, err)
+	}
+
+	if _, err := Preview(&config.WebhookConfig{Type: "pagerduty"}, data); err == nil {
+		t.Error("expected preview error for pagerduty")
+	}
+}
@@ -0,0 +1,620 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+const (
+	// ticketKeyPrefix marks findings with a filed ticket, as
+	// ticket:<webhook>|<fingerprint>|<severity at the last update>.
+	ticketKeyPrefix = "ticket:"
+
+	// ticketLabel is added to every ticket so trustwatch can find its own issues.
+	ticketLabel = "trustwatch"
+
+	ticketOpen    = "open"
+	ticketComment = "comment"
+	ticketClose   = "close"
+
+	githubPageSize = 100
+	githubMaxPages = 10
+
+	// queuedTicketScan bounds how many undelivered ticket operations are read
+	// back from the outbox on startup.
+	queuedTicketScan = 1000
+)
+
+// ticketOp is a queued issue tracker operation. Delivery looks the issue up
+// by fingerprint first, so retried or repeated operations are idempotent.
+type ticketOp struct {
+	Action      string   `json:"action"`
+	Fingerprint string   `json:"fingerprint"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Comment     string   `json:"comment,omitempty"`
+	Severity    string   `json:"severity,omitempty"` // recorded once the operation is delivered
+	Labels      []string `json:"labels,omitempty"`
+}
+
+// fingerprint returns a stable identifier for a finding across scans and
+// restarts. The finding type and violated policy are part of it, so each
+// problem on one object gets its own ticket; untyped findings keep the
+// object key alone.
+func fingerprint(f *store.CertFinding) string {
+	key := findingKey(f)
+	if f.FindingType != "" {
+		key += "/" + f.FindingType
+	}
+	if f.PolicyName != "" {
+		key += "/" + f.PolicyName
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// hasTickets reports whether any ticket webhook is configured.
+func (n *Notifier) hasTickets() bool {
+	for i := range n.webhooks {
+		if n.webhooks[i].Type == "ticket" && n.webhooks[i].Ticket != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ticketKey identifies a finding's ticket on the webhook with the given ID.
+func ticketKey(webhookID, fp string) string {
+	return ticketKeyPrefix + webhookID + "|" + fp
+}
+
+// syncTickets files tickets for findings open longer than each ticket
+// webhook's threshold, comments when a ticketed finding changes severity, and
+// closes tickets whose finding resolved. trackOpen must run first.
+func (n *Notifier) syncTickets(curr *store.Snapshot) {
+	now := n.nowFn()
+	for i := range n.webhooks {
+		wh := &n.webhooks[i]
+		if wh.Type != "ticket" || wh.Ticket == nil {
+			continue
+		}
+		var open []store.CertFinding
+		for j := range curr.Findings {
+			if n.severities[curr.Findings[j].Severity] {
+				open = append(open, curr.Findings[j])
+			}
+		}
+		open = n.routed(wh, open)
+
+		var ops []ticketOp
+		present := make(map[string]bool, len(open))
+
+		// Ticket state is recorded when an operation is delivered, so a
+		// finding whose last operation is still queued is left alone.
+		n.mu.Lock()
+		filed := n.filedTickets(wh)
+		for j := range open {
+			f := &open[j]
+			fp := fingerprint(f)
+			present[fp] = true
+			if n.ticketsQueued[ticketKey(wh.ID(), fp)] {
+				continue
+			}
+			since, ok := n.openSince[openKeyPrefix+findingKey(f)]
+			if !ok {
+				continue
+			}
+			priorSev, ticketed := filed[fp]
+			switch {
+			case !ticketed:
+				if now.Sub(since) < wh.Ticket.OpenDelay() {
+					continue
+				}
+				ops = append(ops, newTicketOp(wh, ticketOpen, f, since, now))
+			case priorSev != f.Severity:
+				op := newTicketOp(wh, ticketComment, f, since, now)
+				op.Comment = fmt.Sprintf("Severity changed from %s to %s.", priorSev, f.Severity)
+				ops = append(ops, op)
+			}
+		}
+		for fp := range filed {
+			if present[fp] || n.ticketsQueued[ticketKey(wh.ID(), fp)] {
+				continue
+			}
+			ops = append(ops, ticketOp{
+				Action:      ticketClose,
+				Fingerprint: fp,
+				Comment:     "trustwatch no longer reports this finding; closing.",
+			})
+		}
+		for k := range ops {
+			n.ticketsQueued[ticketKey(wh.ID(), ops[k].Fingerprint)] = true
+		}
+		n.mu.Unlock()
+
+		n.enqueueTicketOps(wh, ops)
+	}
+}
+
+// filedTickets returns the severity last recorded on each of wh's tickets,
+// keyed by fingerprint. The caller must hold n.mu.
+func (n *Notifier) filedTickets(wh *config.WebhookConfig) map[string]store.Severity {
+	prefix := ticketKeyPrefix + wh.ID() + "|"
+	out := make(map[string]store.Severity)
+	for k := range n.tickets {
+		rest, ok := strings.CutPrefix(k, prefix)
+		if !ok {
+			continue
+		}
+		fp, sev, ok := strings.Cut(rest, "|")
+		if !ok {
+			continue
+		}
+		out[fp] = store.Severity(sev)
+	}
+	return out
+}
+
+// recordTicket updates the ticket state once op has been applied to the
+// tracker: an open or comment records the finding's current severity, and a
+// close forgets the ticket.
+func (n *Notifier) recordTicket(webhookID string, op *ticketOp) {
+	prefix := ticketKey(webhookID, op.Fingerprint) + "|"
+	now := n.nowFn()
+	var stale []string
+	n.mu.Lock()
+	delete(n.ticketsQueued, ticketKey(webhookID, op.Fingerprint))
+	for k := range n.tickets {
+		if strings.HasPrefix(k, prefix) {
+			delete(n.tickets, k)
+			stale = append(stale, k)
+		}
+	}
+	key := prefix + op.Severity
+	if op.Action != ticketClose {
+		n.tickets[key] = now
+	}
+	n.mu.Unlock()
+
+	if err := n.outbox.DeleteCooldowns(stale); err != nil {
+		slog.Warn("notification: clearing ticket state", "err", err)
+	}
+	if op.Action != ticketClose {
+		if err := n.outbox.SaveCooldown(key, now); err != nil {
+			slog.Warn("notification: saving ticket state", "key", key, "err", err)
+		}
+	}
+}
+
+// releaseTicket forgets that msg's ticket operation is queued, so the next
+// sync plans it again. Used when the operation is dead-lettered.
+func (n *Notifier) releaseTicket(msg *Message) {
+	var op ticketOp
+	if err := json.Unmarshal(msg.Body, &op); err != nil {
+		return
+	}
+	n.mu.Lock()
+	delete(n.ticketsQueued, ticketKey(msg.Webhook, op.Fingerprint))
+	n.mu.Unlock()
+}
+
+// loadQueuedTickets marks ticket operations left undelivered by a previous
+// run as queued, so they are not planned a second time.
+func (n *Notifier) loadQueuedTickets() {
+	for _, status := range []Status{StatusPending, StatusFailed, StatusSending} {
+		msgs, err := n.outbox.List(status, queuedTicketScan)
+		if err != nil {
+			slog.Warn("notification: loading queued ticket operations", "err", err)
+			return
+		}
+		for i := range msgs {
+			var op ticketOp
+			if msgs[i].Kind != "ticket" || json.Unmarshal(msgs[i].Body, &op) != nil {
+				continue
+			}
+			n.ticketsQueued[ticketKey(msgs[i].Webhook, op.Fingerprint)] = true
+		}
+	}
+}
+
+func (n *Notifier) enqueueTicketOps(wh *config.WebhookConfig, ops []ticketOp) {
+	for i := range ops {
+		body, err := json.Marshal(&ops[i])
+		if err != nil {
+			continue
+		}
+		summary := fmt.Sprintf("%s ticket %s", ops[i].Action, ops[i].Fingerprint)
+		if ops[i].Title != "" {
+			summary += ": " + ops[i].Title
+		}
+		n.enqueue(wh, "", summary, body)
+	}
+}
+
+// newTicketOp describes f as an issue. The title leaves out severity and
+// relative times so it stays accurate for the life of the ticket.
+func newTicketOp(wh *config.WebhookConfig, action string, f *store.CertFinding, since, now time.Time) ticketOp {
+	where := f.Name
+	if f.Namespace != "" {
+		where = f.Namespace + "/" + f.Name
+	}
+	if f.Cluster != "" {
+		where = f.Cluster + ": " + where
+	}
+	problem := "open finding"
+	switch {
+	case f.PolicyName != "":
+		problem = f.FindingType + " " + f.PolicyName
+	case f.FindingType != "":
+		problem = f.FindingType
+	case len(f.ChainErrors) > 0:
+		problem = "certificate chain invalid"
+	case !f.ProbeOK:
+		problem = "probe failed"
+	case !f.NotAfter.IsZero():
+		problem = "certificate expires " + f.NotAfter.UTC().Format(time.DateOnly)
+	}
+
+	lines := []string{
+		fmt.Sprintf("trustwatch has reported this finding since %s (open for %s).",
+			since.UTC().Format(time.RFC3339), formatAge(now.Sub(since))),
+		"",
+		"- Severity: " + string(f.Severity),
+		"- Source: " + string(f.Source),
+	}
+	add := func(label, v string) {
+		if v != "" {
+			lines = append(lines, fmt.Sprintf("- %s: %s", label, v))
+		}
+	}
+	add("Cluster", f.Cluster)
+	add("Namespace", f.Namespace)
+	add("Name", f.Name)
+	add("Target", f.Target)
+	if !f.NotAfter.IsZero() {
+		add("Not after", f.NotAfter.UTC().Format(time.RFC3339))
+	}
+	add("Issuer", f.Issuer)
+	add("Owner", f.Owner)
+	add("Finding type", f.FindingType)
+	add("Policy", f.PolicyName)
+	add("Probe error", f.ProbeErr)
+	add("Chain errors", strings.Join(f.ChainErrors, "; "))
+	add("Notes", f.Notes)
+	add("Remediation", f.Remediation)
+
+	return ticketOp{
+		Action:      action,
+		Fingerprint: fingerprint(f),
+		Severity:    string(f.Severity),
+		Title:       fmt.Sprintf("[trustwatch] %s (%s): %s", where, f.Source, problem),
+		Description: strings.Join(lines, "\n"),
+		Labels:      append([]string{ticketLabel}, wh.Ticket.Labels...),
+	}
+}
+
+// fileTestTickets queues ticket creation for findings regardless of how long
+// they have been open. Used by notify test.
+func (n *Notifier) fileTestTickets(findings []store.CertFinding) {
+	now := time.Now()
+	for i := range n.webhooks {
+		wh := &n.webhooks[i]
+		if wh.Type != "ticket" || wh.Ticket == nil {
+			continue
+		}
+		ops := make([]ticketOp, 0, len(findings))
+		for j := range findings {
+			ops = append(ops, newTicketOp(wh, ticketOpen, &findings[j], now, now))
+		}
+		n.enqueueTicketOps(wh, ops)
+	}
+}
+
+// ticketPreview renders the operations that would open tickets for findings.
+func ticketPreview(wh *config.WebhookConfig, findings []store.CertFinding) ([]byte, error) {
+	now := time.Now()
+	ops := make([]ticketOp, 0, len(findings))
+	for i := range findings {
+		ops = append(ops, newTicketOp(wh, ticketOpen, &findings[i], now, now))
+	}
+	return json.MarshalIndent(ops, "", "  ")
+}
+
+// ticketTracker is an issue tracker API.
+type ticketTracker interface {
+	// find returns the ID of the open issue carrying fingerprint, if any.
+	find(ctx context.Context, fingerprint string) (id string, found bool, err error)
+	create(ctx context.Context, op *ticketOp) error
+	comment(ctx context.Context, id, text string) error
+	close(ctx context.Context, id, text string) error
+}
+
+// deliverTicket applies a queued ticket operation. Opening an issue that
+// already exists, or closing one that does not, is a no-op.
+func (n *Notifier) deliverTicket(ctx context.Context, wh *config.WebhookConfig, msg *Message) error {
+	if wh == nil || wh.Ticket == nil {
+		return fmt.Errorf("ticket webhook %q is no longer configured", msg.Webhook)
+	}
+	var op ticketOp
+	if err := json.Unmarshal(msg.Body, &op); err != nil {
+		return fmt.Errorf("decoding ticket operation: %w", err)
+	}
+	tr, err := n.tracker(wh)
+	if err != nil {
+		return err
+	}
+	if err := applyTicket(ctx, tr, &op); err != nil {
+		return err
+	}
+	n.recordTicket(msg.Webhook, &op)
+	return nil
+}
+
+// applyTicket looks up op's issue and opens, comments on, or closes it.
+func applyTicket(ctx context.Context, tr ticketTracker, op *ticketOp) error {
+	id, found, err := tr.find(ctx, op.Fingerprint)
+	if err != nil {
+		return fmt.Errorf("finding ticket: %w", err)
+	}
+	switch op.Action {
+	case ticketOpen:
+		if found {
+			return nil
+		}
+		return tr.create(ctx, op)
+	case ticketComment:
+		if !found {
+			// The issue was closed or deleted by hand; file it again.
+			op.Description += "\n\n" + op.Comment
+			return tr.create(ctx, op)
+		}
+		return tr.comment(ctx, id, op.Comment)
+	case ticketClose:
+		if !found {
+			return nil
+		}
+		return tr.close(ctx, id, op.Comment)
+	default:
+		return fmt.Errorf("unknown ticket action %q", op.Action)
+	}
+}
+
+func (n *Notifier) tracker(wh *config.WebhookConfig) (ticketTracker, error) {
+	client, err := n.httpClient(wh)
+	if err != nil {
+		return nil, err
+	}
+	api := &trackerClient{client: client, wh: wh}
+	if wh.Ticket.Provider == config.TicketJira {
+		return &jiraTracker{api: api}, nil
+	}
+	return &githubTracker{api: api}, nil
+}
+
+// trackerClient sends authenticated JSON requests to an issue tracker.
+type trackerClient struct {
+	client *http.Client
+	wh     *config.WebhookConfig
+}
+
+// errNotFound is returned for 404 responses.
+var errNotFound = errors.New("not found")
+
+func (c *trackerClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.wh.APIURL()+path, body)
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	t := c.wh.Ticket
+	switch {
+	case t.Provider == config.TicketGitHub:
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		req.Header.Set("Authorization", "Bearer "+t.Token)
+	case t.Username != "":
+		req.SetBasicAuth(t.Username, t.Token)
+	default:
+		req.Header.Set("Authorization", "Bearer "+t.Token)
+	}
+	applyHeaders(req, c.wh)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // read-only close
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s %s: %w", method, path, errNotFound)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s returned status %d", method, path, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s response: %w", path, err)
+	}
+	return nil
+}
+
+// githubTracker files GitHub issues. Issues carry the trustwatch label and
+// the fingerprint in a hidden comment in their body.
+type githubTracker struct {
+	api *trackerClient
+}
+
+func githubMarker(fp string) string {
+	return "<!-- trustwatch-fingerprint: " + fp + " -->"
+}
+
+func (g *githubTracker) repoPath() string {
+	return "/repos/" + g.api.wh.Ticket.Repo + "/issues"
+}
+
+func (g *githubTracker) find(ctx context.Context, fp string) (string, bool, error) {
+	marker := githubMarker(fp)
+	for page := 1; page <= githubMaxPages; page++ {
+		q := url.Values{
+			"state":    {"open"},
+			"labels":   {ticketLabel},
+			"per_page": {strconv.Itoa(githubPageSize)},
+			"page":     {strconv.Itoa(page)},
+		}
+		var issues []struct {
+			PullRequest *json.RawMessage `json:"pull_request"`
+			Body        string           `json:"body"`
+			Number      int              `json:"number"`
+		}
+		if err := g.api.do(ctx, http.MethodGet, g.repoPath()+"?"+q.Encode(), nil, &issues); err != nil {
+			return "", false, err
+		}
+		for i := range issues {
+			if issues[i].PullRequest == nil && strings.Contains(issues[i].Body, marker) {
+				return strconv.Itoa(issues[i].Number), true, nil
+			}
+		}
+		if len(issues) < githubPageSize {
+			break
+		}
+	}
+	return "", false, nil
+}
+
+func (g *githubTracker) create(ctx context.Context, op *ticketOp) error {
+	return g.api.do(ctx, http.MethodPost, g.repoPath(), map[string]any{
+		"title":  op.Title,
+		"body":   op.Description + "\n\n" + githubMarker(op.Fingerprint),
+		"labels": op.Labels,
+	}, nil)
+}
+
+func (g *githubTracker) comment(ctx context.Context, id, text string) error {
+	return g.api.do(ctx, http.MethodPost, g.repoPath()+"/"+id+"/comments", map[string]string{"body": text}, nil)
+}
+
+func (g *githubTracker) close(ctx context.Context, id, text string) error {
+	if err := g.comment(ctx, id, text); err != nil {
+		return err
+	}
+	return g.api.do(ctx, http.MethodPatch, g.repoPath()+"/"+id, map[string]string{
+		"state":        "closed",
+		"state_reason": "completed",
+	}, nil)
+}
+
+// jiraTracker files Jira issues through the REST API v2. Issues carry the
+// trustwatch label plus a per-finding trustwatch-<fingerprint> label.
+type jiraTracker struct {
+	api *trackerClient
+}
+
+func jiraLabel(fp string) string {
+	return ticketLabel + "-" + fp
+}
+
+func (j *jiraTracker) find(ctx context.Context, fp string) (string, bool, error) {
+	q := url.Values{
+		"jql":        {fmt.Sprintf("project = %q AND labels = %q AND statusCategory != Done", j.api.wh.Ticket.Project, jiraLabel(fp))},
+		"fields":     {"status"},
+		"maxResults": {"1"},
+	}
+	var result struct {
+		Issues []struct {
+			Key string `json:"key"`
+		} `json:"issues"`
+	}
+	// Jira Cloud serves /search/jql; Data Center only has /search.
+	err := j.api.do(ctx, http.MethodGet, "/rest/api/2/search/jql?"+q.Encode(), nil, &result)
+	if errors.Is(err, errNotFound) {
+		err = j.api.do(ctx, http.MethodGet, "/rest/api/2/search?"+q.Encode(), nil, &result)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if len(result.Issues) == 0 {
+		return "", false, nil
+	}
+	return result.Issues[0].Key, true, nil
+}
+
+func (j *jiraTracker) create(ctx context.Context, op *ticketOp) error {
+	t := j.api.wh.Ticket
+	issueType := t.IssueType
+	if issueType == "" {
+		issueType = "Task"
+	}
+	return j.api.do(ctx, http.MethodPost, "/rest/api/2/issue", map[string]any{
+		"fields": map[string]any{
+			"project":     map[string]string{"key": t.Project},
+			"issuetype":   map[string]string{"name": issueType},
+			"summary":     op.Title,
+			"description": op.Description + "\n\ntrustwatch-fingerprint: " + op.Fingerprint,
+			"labels":      append(append([]string(nil), op.Labels...), jiraLabel(op.Fingerprint)),
+		},
+	}, nil)
+}
+
+func (j *jiraTracker) comment(ctx context.Context, id, text string) error {
+	return j.api.do(ctx, http.MethodPost, "/rest/api/2/issue/"+id+"/comment", map[string]string{"body": text}, nil)
+}
+
+func (j *jiraTracker) close(ctx context.Context, id, text string) error {
+	if err := j.comment(ctx, id, text); err != nil {
+		return err
+	}
+	var result struct {
+		Transitions []struct {
+			To struct {
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"to"`
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	}
+	if err := j.api.do(ctx, http.MethodGet, "/rest/api/2/issue/"+id+"/transitions", nil, &result); err != nil {
+		return err
+	}
+	want := j.api.wh.Ticket.CloseTransition
+	for i := range result.Transitions {
+		tr := &result.Transitions[i]
+		if (want != "" && strings.EqualFold(tr.Name, want)) || (want == "" && tr.To.StatusCategory.Key == "done") {
+			return j.api.do(ctx, http.MethodPost, "/rest/api/2/issue/"+id+"/transitions", map[string]any{
+				"transition": map[string]string{"id": tr.ID},
+			}, nil)
+		}
+	}
+	if want != "" {
+		return fmt.Errorf("issue %s has no transition named %q", id, want)
+	}
+	return fmt.Errorf("issue %s has no transition to a done status", id)
+}
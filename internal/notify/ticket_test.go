@@ -0,0 +1,408 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// fakeGitHub is an in-memory GitHub Issues API.
+type fakeGitHub struct {
+	issues   map[int]*fakeIssue
+	comments map[int][]string
+	auth     []string
+	mu       sync.Mutex
+	down     bool // answer every request with 503
+}
+
+type fakeIssue struct {
+	Title  string   `json:"title"`
+	Body   string   `json:"body"`
+	State  string   `json:"state"`
+	Labels []string `json:"labels"`
+	Number int      `json:"number"`
+}
+
+func newFakeGitHub(t *testing.T) (*fakeGitHub, *httptest.Server) {
+	t.Helper()
+	gh := &fakeGitHub{issues: make(map[int]*fakeIssue), comments: make(map[int][]string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/acme/infra/issues", func(w http.ResponseWriter, r *http.Request) {
+		gh.mu.Lock()
+		defer gh.mu.Unlock()
+		gh.auth = append(gh.auth, r.Header.Get("Authorization"))
+		out := []fakeIssue{}
+		for _, is := range gh.issues {
+			if is.State == r.URL.Query().Get("state") && strings.Contains(strings.Join(is.Labels, ","), r.URL.Query().Get("labels")) {
+				out = append(out, *is)
+			}
+		}
+		json.NewEncoder(w).Encode(out) //nolint:errcheck // test server
+	})
+	mux.HandleFunc("POST /repos/acme/infra/issues", func(w http.ResponseWriter, r *http.Request) {
+		gh.mu.Lock()
+		defer gh.mu.Unlock()
+		var is fakeIssue
+		json.NewDecoder(r.Body).Decode(&is) //nolint:errcheck // test server
+		is.Number = len(gh.issues) + 1
+		is.State = "open"
+		gh.issues[is.Number] = &is
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("POST /repos/acme/infra/issues/{n}/comments", func(w http.ResponseWriter, r *http.Request) {
+		gh.mu.Lock()
+		defer gh.mu.Unlock()
+		num, _ := strconv.Atoi(r.PathValue("n")) //nolint:errcheck // test server
+		var c struct{ Body string }
+		json.NewDecoder(r.Body).Decode(&c) //nolint:errcheck // test server
+		gh.comments[num] = append(gh.comments[num], c.Body)
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("PATCH /repos/acme/infra/issues/{n}", func(w http.ResponseWriter, r *http.Request) {
+		gh.mu.Lock()
+		defer gh.mu.Unlock()
+		num, _ := strconv.Atoi(r.PathValue("n")) //nolint:errcheck // test server
+		var p struct{ State string }
+		json.NewDecoder(r.Body).Decode(&p) //nolint:errcheck // test server
+		gh.issues[num].State = p.State
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gh.mu.Lock()
+		down := gh.down
+		gh.mu.Unlock()
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return gh, srv
+}
+
+func (gh *fakeGitHub) setDown(down bool) {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	gh.down = down
+}
+
+func (gh *fakeGitHub) snapshot() (issues []fakeIssue, comments map[int][]string) {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	for i := 1; i <= len(gh.issues); i++ {
+		issues = append(issues, *gh.issues[i])
+	}
+	comments = make(map[int][]string)
+	for k, v := range gh.comments {
+		comments[k] = append([]string(nil), v...)
+	}
+	return issues, comments
+}
+
+func ticketConfig(wh config.WebhookConfig) config.NotificationConfig {
+	return config.NotificationConfig{
+		Enabled:    true,
+		Webhooks:   []config.WebhookConfig{wh},
+		Severities: []string{"critical", "warn"},
+		Cooldown:   time.Hour,
+	}
+}
+
+func TestNotifier_GitHubTickets(t *testing.T) {
+	gh, srv := newFakeGitHub(t)
+	n := New(ticketConfig(config.WebhookConfig{
+		Name: "issues", URL: srv.URL, Type: "ticket",
+		Ticket: &config.TicketConfig{Provider: "github", Repo: "acme/infra", Token: "ghp_test", Labels: []string{"certs"}},
+	}))
+	start := time.Now()
+	n.nowFn = func() time.Time { return start }
+
+	f := warnFinding("api", "payments")
+	snap := store.Snapshot{At: start, Findings: []store.CertFinding{f}}
+	n.Notify(store.Snapshot{}, snap)
+	if issues, _ := gh.snapshot(); len(issues) != 0 {
+		t.Fatalf("ticket filed before the finding was open a week: %+v", issues)
+	}
+
+	// Past the default threshold the ticket is filed once.
+	n.nowFn = func() time.Time { return start.Add(8 * 24 * time.Hour) }
+	n.Notify(snap, snap)
+	n.Notify(snap, snap)
+	issues, _ := gh.snapshot()
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	is := issues[0]
+	if !strings.Contains(is.Title, "payments/api") || !strings.Contains(is.Body, githubMarker(fingerprint(&f))) {
+		t.Errorf("unexpected issue: %+v", is)
+	}
+	if strings.Join(is.Labels, ",") != "trustwatch,certs" {
+		t.Errorf("labels = %v", is.Labels)
+	}
+	if gh.auth[0] != "Bearer ghp_test" {
+		t.Errorf("authorization = %q", gh.auth[0])
+	}
+
+	// Escalation comments on the existing issue.
+	esc := f
+	esc.Severity = store.SeverityCritical
+	escSnap := store.Snapshot{At: start, Findings: []store.CertFinding{esc}}
+	n.Notify(snap, escSnap)
+	issues, comments := gh.snapshot()
+	if len(issues) != 1 || len(comments[1]) != 1 || !strings.Contains(comments[1][0], "from warn to critical") {
+		t.Fatalf("expected a severity comment, got issues=%d comments=%v", len(issues), comments)
+	}
+
+	// Resolution closes it.
+	n.Notify(escSnap, store.Snapshot{At: start})
+	issues, comments = gh.snapshot()
+	if issues[0].State != "closed" || len(comments[1]) != 2 {
+		t.Errorf("expected the issue closed with a comment, got state=%s comments=%v", issues[0].State, comments[1])
+	}
+	state, err := n.outbox.Cooldowns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range state {
+		if strings.HasPrefix(k, ticketKeyPrefix) {
+			t.Errorf("stale ticket state %q", k)
+		}
+	}
+}
+
+func TestNotifier_TicketStateRecordedOnDelivery(t *testing.T) {
+	gh, srv := newFakeGitHub(t)
+	cfg := ticketConfig(config.WebhookConfig{
+		Name: "issues", URL: srv.URL, Type: "ticket",
+		Ticket: &config.TicketConfig{Provider: "github", Repo: "acme/infra", Token: "t", OpenAfter: time.Hour},
+	})
+	cfg.RetryBackoff = time.Millisecond
+	outbox := NewMemoryOutbox()
+	n := New(cfg, WithOutbox(outbox))
+	start := time.Now()
+	n.nowFn = func() time.Time { return start }
+	snap := store.Snapshot{At: start, Findings: []store.CertFinding{warnFinding("api", "payments")}}
+	n.Notify(store.Snapshot{}, snap)
+
+	hasState := func() bool {
+		state, _ := outbox.Cooldowns() //nolint:errcheck // memory outbox never errors
+		for k := range state {
+			if strings.HasPrefix(k, ticketKeyPrefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// While the tracker is down nothing is recorded and the open is not queued twice.
+	gh.setDown(true)
+	n.nowFn = func() time.Time { return start.Add(2 * time.Hour) }
+	n.Notify(snap, snap)
+	n.Notify(snap, snap)
+	if hasState() {
+		t.Error("ticket state recorded before delivery")
+	}
+	if counts, _ := outbox.Counts(); counts[StatusFailed] != 1 { //nolint:errcheck // memory outbox never errors
+		t.Errorf("expected one queued ticket operation, got %v", counts)
+	}
+
+	// A restarted notifier sees the queued operation and does not plan another.
+	restarted := New(cfg, WithOutbox(outbox))
+	restarted.nowFn = n.nowFn
+	restarted.Notify(snap, snap)
+	if counts, _ := outbox.Counts(); counts[StatusFailed] != 1 || counts[StatusPending] != 0 { //nolint:errcheck // memory outbox never errors
+		t.Errorf("restart queued a duplicate ticket operation: %v", counts)
+	}
+
+	// The retry that reaches the tracker records the ticket.
+	gh.setDown(false)
+	time.Sleep(5 * time.Millisecond)
+	restarted.Flush(context.Background())
+	if issues, _ := gh.snapshot(); len(issues) != 1 {
+		t.Fatalf("expected 1 issue after recovery, got %d", len(issues))
+	}
+	if !hasState() {
+		t.Error("ticket state not recorded after delivery")
+	}
+}
+
+func TestNotifier_TicketPerFindingType(t *testing.T) {
+	gh, srv := newFakeGitHub(t)
+	n := New(ticketConfig(config.WebhookConfig{
+		Name: "issues", URL: srv.URL, Type: "ticket",
+		Ticket: &config.TicketConfig{Provider: "github", Repo: "acme/infra", Token: "t"},
+	}))
+	start := time.Now()
+	n.nowFn = func() time.Time { return start }
+
+	// An expiring Secret that also violates a policy.
+	expiry := warnFinding("api-tls", "payments")
+	violation := expiry
+	violation.FindingType = "POLICY_VIOLATION"
+	violation.PolicyName = "min-key-size"
+	both := store.Snapshot{At: start, Findings: []store.CertFinding{expiry, violation}}
+	n.Notify(store.Snapshot{}, both)
+	n.nowFn = func() time.Time { return start.Add(8 * 24 * time.Hour) }
+	n.Notify(both, both)
+
+	issues, _ := gh.snapshot()
+	if len(issues) != 2 {
+		t.Fatalf("expected one issue per finding type, got %d: %+v", len(issues), issues)
+	}
+	if !strings.Contains(issues[0].Title+issues[1].Title, "min-key-size") {
+		t.Errorf("policy issue title does not name the policy: %q, %q", issues[0].Title, issues[1].Title)
+	}
+
+	// Fixing the policy violation closes its issue only.
+	expiryOnly := store.Snapshot{At: start, Findings: []store.CertFinding{expiry}}
+	n.Notify(both, expiryOnly)
+	issues, comments := gh.snapshot()
+	for i, is := range issues {
+		wantClosed := strings.Contains(is.Body, githubMarker(fingerprint(&violation)))
+		if (is.State == "closed") != wantClosed {
+			t.Errorf("issue %q state = %s, closing comments %v", is.Title, is.State, comments[i+1])
+		}
+	}
+}
+
+func TestDeliverTicket_Idempotent(t *testing.T) {
+	gh, srv := newFakeGitHub(t)
+	cfg := ticketConfig(config.WebhookConfig{
+		URL: srv.URL, Type: "ticket",
+		Ticket: &config.TicketConfig{Provider: "github", Repo: "acme/infra", Token: "t"},
+	})
+	n := New(cfg)
+	f := criticalFinding("api", "payments")
+	n.fileTestTickets([]store.CertFinding{f})
+	n.fileTestTickets([]store.CertFinding{f})
+	n.Flush(context.Background())
+
+	if issues, _ := gh.snapshot(); len(issues) != 1 {
+		t.Errorf("expected one issue for a repeated open, got %d", len(issues))
+	}
+	msgs, err := n.outbox.List(StatusSent, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Errorf("expected both operations delivered, got %d", len(msgs))
+	}
+}
+
+// fakeJira is an in-memory Jira Data Center REST API v2 (no /search/jql).
+type fakeJira struct {
+	labels      map[string][]string
+	status      map[string]string
+	comments    map[string][]string
+	transitions []string
+	mu          sync.Mutex
+}
+
+func newFakeJira(t *testing.T) (*fakeJira, *httptest.Server) {
+	t.Helper()
+	j := &fakeJira{labels: make(map[string][]string), status: make(map[string]string), comments: make(map[string][]string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rest/api/2/search", func(w http.ResponseWriter, r *http.Request) {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		if u, _, ok := r.BasicAuth(); !ok || u != "bot@acme.io" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		jql := r.URL.Query().Get("jql")
+		var issues []map[string]string
+		for key, labels := range j.labels {
+			for _, l := range labels {
+				if strings.Contains(jql, `labels = "`+l+`"`) && j.status[key] != "Done" {
+					issues = append(issues, map[string]string{"key": key})
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"issues": issues}) //nolint:errcheck // test server
+	})
+	mux.HandleFunc("POST /rest/api/2/issue", func(w http.ResponseWriter, r *http.Request) {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		var req struct {
+			Fields struct {
+				Project struct{ Key string } `json:"project"`
+				Labels  []string             `json:"labels"`
+			} `json:"fields"`
+		}
+		json.NewDecoder(r.Body).Decode(&req) //nolint:errcheck // test server
+		key := req.Fields.Project.Key + "-" + strconv.Itoa(len(j.labels)+1)
+		j.labels[key] = req.Fields.Labels
+		j.status[key] = "To Do"
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("POST /rest/api/2/issue/{key}/comment", func(w http.ResponseWriter, r *http.Request) {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		var c struct{ Body string }
+		json.NewDecoder(r.Body).Decode(&c) //nolint:errcheck // test server
+		j.comments[r.PathValue("key")] = append(j.comments[r.PathValue("key")], c.Body)
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("GET /rest/api/2/issue/{key}/transitions", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"transitions":[
+			{"id":"11","name":"Start","to":{"statusCategory":{"key":"indeterminate"}}},
+			{"id":"31","name":"Done","to":{"statusCategory":{"key":"done"}}}]}`)) //nolint:errcheck // test server
+	})
+	mux.HandleFunc("POST /rest/api/2/issue/{key}/transitions", func(w http.ResponseWriter, r *http.Request) {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		var req struct {
+			Transition struct{ ID string } `json:"transition"`
+		}
+		json.NewDecoder(r.Body).Decode(&req) //nolint:errcheck // test server
+		j.transitions = append(j.transitions, req.Transition.ID)
+		j.status[r.PathValue("key")] = "Done"
+		w.WriteHeader(http.StatusNoContent)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return j, srv
+}
+
+func TestNotifier_JiraTickets(t *testing.T) {
+	j, srv := newFakeJira(t)
+	n := New(ticketConfig(config.WebhookConfig{
+		Name: "jira", URL: srv.URL, Type: "ticket",
+		Ticket: &config.TicketConfig{Provider: "jira", Project: "OPS", Username: "bot@acme.io", Token: "t", OpenAfter: time.Hour},
+	}))
+	start := time.Now()
+	n.nowFn = func() time.Time { return start }
+
+	f := criticalFinding("api", "payments")
+	snap := store.Snapshot{At: start, Findings: []store.CertFinding{f}}
+	n.Notify(store.Snapshot{}, snap)
+	n.nowFn = func() time.Time { return start.Add(2 * time.Hour) }
+	n.Notify(snap, snap)
+
+	j.mu.Lock()
+	labels := j.labels["OPS-1"]
+	created := len(j.labels)
+	j.mu.Unlock()
+	if created != 1 || strings.Join(labels, ",") != "trustwatch,"+jiraLabel(fingerprint(&f)) {
+		t.Fatalf("expected one labelled issue, got %d with labels %v", created, labels)
+	}
+
+	n.Notify(snap, store.Snapshot{At: start})
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status["OPS-1"] != "Done" || len(j.transitions) != 1 || j.transitions[0] != "31" {
+		t.Errorf("expected transition 31 to done, got status=%s transitions=%v", j.status["OPS-1"], j.transitions)
+	}
+	if len(j.comments["OPS-1"]) != 1 {
+		t.Errorf("expected a closing comment, got %v", j.comments["OPS-1"])
+	}
+}
@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+)
+
+// httpClient returns the client used to deliver to a webhook. Webhooks with
+// tls settings get their own client, built on first use and cached; a build
+// failure is returned as a delivery error so the message is retried.
+func (n *Notifier) httpClient(wh *config.WebhookConfig) (*http.Client, error) {
+	if wh == nil || wh.TLS == nil {
+		return n.client, nil
+	}
+	n.clientMu.Lock()
+	defer n.clientMu.Unlock()
+	if c, ok := n.clients[wh.ID()]; ok {
+		return c, nil
+	}
+	tlsCfg, err := wh.TLS.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("tls settings: %w", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+	c := &http.Client{Timeout: httpTimeout, Transport: transport}
+	n.clients[wh.ID()] = c
+	return c, nil
+}
@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+// writeClientCert writes a self-signed client certificate and key to dir and
+// returns the parsed certificate and the file paths.
+func writeClientCert(t *testing.T, dir string) (cert *x509.Certificate, certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "trustwatch"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return cert, certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotifier_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCert, certFile, keyFile := writeClientCert(t, dir)
+
+	var gotCN string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCN = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.WriteHeader(http.StatusOK)
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs, MinVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", srv.Certificate().Raw)
+
+	findings := []store.CertFinding{criticalFinding("api", "payments")}
+
+	// Without a client certificate the handshake is refused and the message stays queued.
+	cfg := testConfig(srv.URL)
+	cfg.Webhooks[0].TLS = &config.ClientTLSConfig{CAFile: caFile}
+	n := New(cfg)
+	n.Notify(store.Snapshot{}, store.Snapshot{At: time.Now(), Findings: findings})
+	msgs, err := n.Outbox().List(StatusFailed, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 failed message without a client certificate, got %d", len(msgs))
+	}
+
+	cfg.Webhooks[0].TLS = &config.ClientTLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
+	n = New(cfg)
+	n.Notify(store.Snapshot{}, store.Snapshot{At: time.Now(), Findings: findings})
+	if gotCN != "trustwatch" {
+		t.Fatalf("server saw client certificate %q, want trustwatch", gotCN)
+	}
+	msgs, err = n.Outbox().List(StatusSent, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 {
+		t.Errorf("expected 1 sent message, got %d", len(msgs))
+	}
+}
+
+func TestClientTLSConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, certFile, _ := writeClientCert(t, dir)
+
+	tests := []struct {
+		tls  *config.ClientTLSConfig
+		name string
+	}{
+		{name: "missing ca file", tls: &config.ClientTLSConfig{CAFile: filepath.Join(dir, "missing.pem")}},
+		{name: "ca file without certificates", tls: &config.ClientTLSConfig{CAFile: notPEM}},
+		{name: "key does not match", tls: &config.ClientTLSConfig{CertFile: certFile, KeyFile: notPEM}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.tls.TLSConfig(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"github.com/ppiankov/trustwatch/internal/store"
 )
 
+// FindingPolicyViolation is the finding type of a violated TrustPolicy rule.
+const FindingPolicyViolation = "POLICY_VIOLATION"
+
 // Engine evaluates policy rules against findings and produces violations.
 type Engine struct {
 	policies []TrustPolicy
@@ -39,10 +42,11 @@ func (e *Engine) Evaluate(findings []store.CertFinding) []store.CertFinding {
 						Namespace:   f.Namespace,
 						Source:      store.SourcePolicy,
 						Severity:    sev,
-						FindingType: "POLICY_VIOLATION",
+						FindingType: FindingPolicyViolation,
 						PolicyName:  p.Name,
 						Notes:       r.Name + ": " + reason,
 						ProbeOK:     true,
+						Object:      f.Object,
 					})
 				}
 			}
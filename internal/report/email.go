@@ -0,0 +1,66 @@
+package report
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+var (
+	emailHTMLTmpl = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/email.html", "templates/style.html"))
+	emailTextTmpl = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/email.txt"))
+)
+
+// EmailInput describes a notification email.
+type EmailInput struct {
+	At          time.Time // scan time of the findings
+	Title       string    // heading and subject, e.g. "Daily certificate digest"
+	ClusterName string
+	Intro       string // optional sentence shown above the findings
+	Findings    []store.CertFinding
+}
+
+type emailData struct {
+	Title         string
+	ClusterName   string
+	Intro         string
+	ScanTime      string
+	Findings      []reportRow
+	CriticalCount int
+	WarnCount     int
+	TotalCount    int
+}
+
+// Email renders findings as HTML and plain-text email bodies using the report styling.
+func Email(in *EmailInput) (htmlBody, textBody []byte, err error) {
+	findings := sortFindings(in.Findings)
+	data := emailData{
+		Title:       in.Title,
+		ClusterName: in.ClusterName,
+		Intro:       in.Intro,
+		ScanTime:    in.At.UTC().Format("2006-01-02 15:04 UTC"),
+		TotalCount:  len(findings),
+		Findings:    make([]reportRow, 0, len(findings)),
+	}
+	for i := range findings {
+		switch findings[i].Severity {
+		case store.SeverityCritical:
+			data.CriticalCount++
+		case store.SeverityWarn:
+			data.WarnCount++
+		}
+		data.Findings = append(data.Findings, buildRow(&findings[i], in.At))
+	}
+
+	var h, t bytes.Buffer
+	if err := emailHTMLTmpl.Execute(&h, data); err != nil {
+		return nil, nil, err
+	}
+	if err := emailTextTmpl.Execute(&t, data); err != nil {
+		return nil, nil, err
+	}
+	return h.Bytes(), t.Bytes(), nil
+}
@@ -0,0 +1,56 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func TestEmail(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	in := &EmailInput{
+		At:          now,
+		Title:       "Daily certificate digest",
+		ClusterName: "prod",
+		Intro:       "2 open finding(s).",
+		Findings: []store.CertFinding{
+			{Name: "web", Namespace: "shop", Source: store.SourceTLSSecret, Severity: store.SeverityWarn, NotAfter: now.Add(20 * 24 * time.Hour)},
+			{Name: "api", Namespace: "pay", Source: store.SourceIngressTLS, Severity: store.SeverityCritical,
+				NotAfter: now.Add(3 * 24 * time.Hour), Remediation: "renew <now>"},
+		},
+	}
+
+	htmlBody, textBody, err := Email(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := string(htmlBody)
+	for _, want := range []string{"<h1>Daily certificate digest</h1>", "Critical: 1", "pay/api", "renew &lt;now&gt;", ".badge-critical"} {
+		if !strings.Contains(h, want) {
+			t.Errorf("HTML body missing %q", want)
+		}
+	}
+	if strings.Index(h, "pay/api") > strings.Index(h, "shop/web") {
+		t.Error("critical findings should be listed first")
+	}
+
+	text := string(textBody)
+	for _, want := range []string{"Cluster: prod", "[CRITICAL] pay/api (k8s.ingressTLS) - expires in 3d 0h", "Remediation: renew <now>", "[WARN] shop/web"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text body missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestEmail_NoFindings(t *testing.T) {
+	_, textBody, err := Email(&EmailInput{At: time.Now(), Title: "Weekly certificate digest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(textBody), "No findings.") {
+		t.Errorf("text body = %s", textBody)
+	}
+}
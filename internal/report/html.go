@@ -14,13 +14,49 @@ import (
 	"github.com/ppiankov/trustwatch/internal/store"
 )
 
-//go:embed templates/report.html
+//go:embed templates/*.html templates/*.txt
 var templateFS embed.FS
 
-var reportTmpl = template.Must(template.ParseFS(templateFS, "templates/report.html"))
+var reportTmpl = template.Must(template.ParseFS(templateFS, "templates/report.html", "templates/style.html"))
+
+// Option adds an optional section to a report.
+type Option func(*reportData)
+
+// RotationSLO is the rotation analytics shown in a report, typically
+// converted from an analytics.Report.
+type RotationSLO struct {
+	Since        time.Time
+	Groups       []RotationStats
+	Total        RotationStats
+	NearMiss     time.Duration
+	MinRemaining float64
+}
+
+// RotationStats is the rotation SLO of one source, namespace, and issuer.
+// Nil ratios mean there was nothing to measure.
+type RotationStats struct {
+	SLORatio      *float64
+	SuccessRatio  *float64
+	MeanRemaining *float64
+	Source        string
+	Namespace     string
+	Issuer        string
+	MeanTimeOpen  time.Duration
+	Rotations     int
+	Expired       int
+	NearMisses    int
+	OpenFindings  int
+}
+
+// WithRotationSLO adds a rotation SLO section computed from history.
+func WithRotationSLO(r *RotationSLO) Option {
+	return func(d *reportData) {
+		d.Analytics = buildAnalytics(r)
+	}
+}
 
 // Generate renders a scan snapshot as a self-contained HTML report.
-func Generate(snap store.Snapshot, clusterName string) ([]byte, error) {
+func Generate(snap store.Snapshot, clusterName string, opts ...Option) ([]byte, error) {
 	findings := sortFindings(snap.Findings)
 
 	var critCount, warnCount, infoCount int
@@ -46,6 +82,26 @@ func Generate(snap store.Snapshot, clusterName string) ([]byte, error) {
 		InfoCount:     infoCount,
 		TotalCount:    len(findings),
 		Findings:      rows,
+		CoverageGaps:  snap.CoverageGaps(),
+	}
+	if md := snap.Metadata; md != nil {
+		data.Version = md.Version
+		data.Context = md.Context
+		data.KubernetesVersion = md.KubernetesVersion
+		data.Tunnel = md.Tunnel
+		if md.WarnBefore > 0 || md.CritBefore > 0 {
+			data.Thresholds = fmt.Sprintf("warn %s / crit %s", formatThreshold(md.WarnBefore), formatThreshold(md.CritBefore))
+		}
+		if md.ScanDuration > 0 {
+			data.ScanDuration = md.ScanDuration.Round(time.Millisecond).String()
+		}
+		if data.ClusterName == "" {
+			data.ClusterName = md.Cluster
+		}
+		data.Scope = describeScope(md.Scope)
+	}
+	for _, opt := range opts {
+		opt(&data)
 	}
 
 	var buf bytes.Buffer
@@ -56,13 +112,43 @@ func Generate(snap store.Snapshot, clusterName string) ([]byte, error) {
 }
 
 type reportData struct {
-	ScanTime      string
-	ClusterName   string
-	Findings      []reportRow
-	CriticalCount int
-	WarnCount     int
-	InfoCount     int
-	TotalCount    int
+	Analytics         *analyticsSection
+	ScanTime          string
+	ClusterName       string
+	Context           string
+	Version           string
+	KubernetesVersion string
+	Thresholds        string
+	ScanDuration      string
+	Scope             string
+	Findings          []reportRow
+	CoverageGaps      []string
+	CriticalCount     int
+	WarnCount         int
+	InfoCount         int
+	TotalCount        int
+	Tunnel            bool
+}
+
+// describeScope summarizes the namespace scope of a scan for the report header.
+func describeScope(scope *store.Scope) string {
+	if scope == nil {
+		return ""
+	}
+	if len(scope.Include) == 0 && len(scope.Exclude) == 0 && scope.NamespaceSelector == "" {
+		return "all namespaces"
+	}
+	parts := []string{fmt.Sprintf("%d namespace(s)", len(scope.Namespaces))}
+	if len(scope.Include) > 0 {
+		parts = append(parts, "include "+strings.Join(scope.Include, ", "))
+	}
+	if len(scope.Exclude) > 0 {
+		parts = append(parts, "exclude "+strings.Join(scope.Exclude, ", "))
+	}
+	if scope.NamespaceSelector != "" {
+		parts = append(parts, "selector "+scope.NamespaceSelector)
+	}
+	return strings.Join(parts, "; ")
 }
 
 type reportRow struct {
@@ -88,6 +174,70 @@ type reportRow struct {
 	Remediation      string
 }
 
+// analyticsSection is the rotation SLO table of a report.
+type analyticsSection struct {
+	Rows         []analyticsRow
+	Since        string
+	MinRemaining string
+	NearMiss     string
+	Total        analyticsRow
+}
+
+type analyticsRow struct {
+	Source        string
+	Namespace     string
+	Issuer        string
+	SLO           string
+	Success       string
+	MeanRemaining string
+	MeanTimeOpen  string
+	Rotations     int
+	Expired       int
+	NearMisses    int
+	OpenFindings  int
+}
+
+func buildAnalytics(r *RotationSLO) *analyticsSection {
+	sec := &analyticsSection{
+		Since:        r.Since.UTC().Format("2006-01-02"),
+		MinRemaining: formatPercent(&r.MinRemaining),
+		NearMiss:     formatThreshold(r.NearMiss),
+		Total:        buildAnalyticsRow(&r.Total),
+		Rows:         make([]analyticsRow, 0, len(r.Groups)),
+	}
+	for i := range r.Groups {
+		sec.Rows = append(sec.Rows, buildAnalyticsRow(&r.Groups[i]))
+	}
+	return sec
+}
+
+func buildAnalyticsRow(g *RotationStats) analyticsRow {
+	row := analyticsRow{
+		Source:        g.Source,
+		Namespace:     g.Namespace,
+		Issuer:        g.Issuer,
+		SLO:           formatPercent(g.SLORatio),
+		Success:       formatPercent(g.SuccessRatio),
+		MeanRemaining: formatPercent(g.MeanRemaining),
+		Rotations:     g.Rotations,
+		Expired:       g.Expired,
+		NearMisses:    g.NearMisses,
+		OpenFindings:  g.OpenFindings,
+	}
+	if g.MeanTimeOpen > 0 {
+		row.MeanTimeOpen = formatExpiresIn(time.Time{}.Add(g.MeanTimeOpen), time.Time{})
+	}
+	return row
+}
+
+// formatPercent renders a ratio as a whole percentage, or "-" when there is no data.
+func formatPercent(r *float64) string {
+	if r == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", *r*100)
+}
+
 func buildRow(f *store.CertFinding, now time.Time) reportRow {
 	where := f.Name
 	if f.Namespace != "" {
@@ -165,6 +315,14 @@ func formatExpiresIn(notAfter, now time.Time) string {
 	}
 }
 
+// formatThreshold renders whole-day thresholds as days (720h → 30d).
+func formatThreshold(d time.Duration) string {
+	if d > 0 && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
 func sortFindings(findings []store.CertFinding) []store.CertFinding {
 	sorted := make([]store.CertFinding, len(findings))
 	copy(sorted, findings)
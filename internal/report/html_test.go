@@ -216,3 +216,87 @@ func TestGenerate_ExpiredCert(t *testing.T) {
 		t.Error("expected EXPIRED in report for expired cert")
 	}
 }
+
+func TestGenerate_Metadata(t *testing.T) {
+	snap := store.Snapshot{
+		At:     time.Now().UTC(),
+		Errors: map[string]string{"istio": "forbidden"},
+		Metadata: &store.Metadata{
+			Cluster:           "prod",
+			Version:           "1.4.0",
+			KubernetesVersion: "v1.31.2",
+			WarnBefore:        720 * time.Hour,
+			CritBefore:        336 * time.Hour,
+			Scope: &store.Scope{
+				Exclude:    []string{"kube-*"},
+				Namespaces: []string{"app", "web"},
+				Denied:     map[string][]string{"secrets": {"web"}},
+			},
+		},
+	}
+
+	html, err := Generate(snap, "")
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	body := string(html)
+	for _, want := range []string{
+		"Cluster: <strong>prod</strong>",
+		"v1.31.2",
+		"warn 30d / crit 14d",
+		"2 namespace(s); exclude kube-*",
+		"Partial coverage",
+		"istio failed: forbidden",
+		"secrets: 1 namespace(s) not accessible (web)",
+		"Generated by trustwatch 1.4.0",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected report to contain %q", want)
+		}
+	}
+}
+
+func TestGenerate_FullCoverageHasNoBanner(t *testing.T) {
+	snap := store.Snapshot{At: time.Now().UTC(), Metadata: &store.Metadata{}}
+
+	html, err := Generate(snap, "")
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if strings.Contains(string(html), "Partial coverage") {
+		t.Error("expected no coverage banner for a complete scan")
+	}
+}
+
+func TestGenerate_RotationSLO(t *testing.T) {
+	snap := store.Snapshot{At: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)}
+	slo, success := 0.5, 1.0
+	html, err := Generate(snap, "", WithRotationSLO(&RotationSLO{
+		Since: snap.At.Add(-90 * 24 * time.Hour),
+		Groups: []RotationStats{{
+			Source: "certmanager", Namespace: "payments", Issuer: "CN=LE",
+			SLORatio: &slo, SuccessRatio: &success, Rotations: 4, NearMisses: 1, MeanTimeOpen: 50 * time.Hour,
+		}},
+		Total:        RotationStats{SLORatio: &slo, Rotations: 4},
+		NearMiss:     72 * time.Hour,
+		MinRemaining: 1.0 / 3,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(html)
+	for _, want := range []string{"Rotation SLO", "Since 2026-06-03", "at least 33%", "less than 3d", "CN=LE", "50%", "100%", "2d 2h"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q", want)
+		}
+	}
+
+	plain, err := Generate(snap, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(plain), "Rotation SLO") {
+		t.Error("rotation SLO section rendered without analytics")
+	}
+}
@@ -40,12 +40,29 @@ func DetectRole(f *store.CertFinding) CertRole {
 	return RoleLeaf
 }
 
+// Key identifies a finding across scans as source/namespace/name, the key
+// used for observed rotation intervals.
+func Key(f *store.CertFinding) string {
+	return fmt.Sprintf("%s/%s/%s", f.Source, f.Namespace, f.Name)
+}
+
 // Check analyzes findings for excessive rotation and returns new EXCESSIVE_ROTATION findings.
 func Check(findings []store.CertFinding) []store.CertFinding {
+	return CheckObserved(findings, nil)
+}
+
+// CheckObserved is Check with rotation intervals observed in history, keyed
+// by Key. A certificate replaced sooner than its configured duration is judged
+// by how often it actually rotates.
+func CheckObserved(findings []store.CertFinding, observed map[string]time.Duration) []store.CertFinding {
 	var results []store.CertFinding
 	for i := range findings {
 		f := &findings[i]
-		if f.CertDuration <= 0 {
+		duration, basis := f.CertDuration, "duration"
+		if d, ok := observed[Key(f)]; ok && d > 0 && (duration <= 0 || d < duration) {
+			duration, basis = d, "observed rotation interval"
+		}
+		if duration <= 0 {
 			continue
 		}
 
@@ -60,14 +77,14 @@ func Check(findings []store.CertFinding) []store.CertFinding {
 			continue // no minimum for leaf certs
 		}
 
-		if f.CertDuration < minDur {
+		if duration < minDur {
 			results = append(results, store.CertFinding{
 				Source:      f.Source,
 				Severity:    store.SeverityWarn,
 				FindingType: FindingExcessiveRotation,
 				Name:        f.Name,
 				Namespace:   f.Namespace,
-				Notes:       fmt.Sprintf("duration %s below minimum %s for %s", f.CertDuration, minDur, role),
+				Notes:       fmt.Sprintf("%s %s below minimum %s for %s", basis, duration.Round(time.Minute), minDur, role),
 			})
 		}
 	}
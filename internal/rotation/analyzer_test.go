@@ -1,6 +1,7 @@
 package rotation
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -193,3 +194,37 @@ func TestCheck_BoundaryExact(t *testing.T) {
 		t.Errorf("expected no findings at exact threshold, got %d", len(results))
 	}
 }
+
+func TestCheckObserved_ShortRotationCadence(t *testing.T) {
+	findings := []store.CertFinding{
+		{
+			Source:       store.SourceCertManager,
+			Name:         "issuer-cert",
+			Namespace:    "cert-manager",
+			IsCA:         true,
+			CertDuration: 8760 * time.Hour, // configured for a year, rotated weekly
+			ProbeOK:      true,
+		},
+		{
+			Source:    store.SourceLinkerd,
+			Name:      "linkerd-identity-issuer",
+			Namespace: "linkerd",
+			Notes:     "identity issuer",
+			ProbeOK:   true,
+		},
+	}
+	observed := map[string]time.Duration{
+		"certmanager/cert-manager/issuer-cert":         7 * 24 * time.Hour,
+		"mesh.linkerd/linkerd/linkerd-identity-issuer": 60 * 24 * time.Hour,
+	}
+	results := CheckObserved(findings, observed)
+	if len(results) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(results))
+	}
+	if results[0].Name != "issuer-cert" || !strings.Contains(results[0].Notes, "observed rotation interval 168h0m0s") {
+		t.Errorf("unexpected finding: %+v", results[0])
+	}
+	if len(Check(findings)) != 0 {
+		t.Error("Check without observed intervals should use CertDuration only")
+	}
+}
@@ -3,6 +3,9 @@ package store
 
 import (
 	"crypto/x509"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -39,13 +42,17 @@ const (
 	SourceGCPManagedCert     SourceKind = "cloud.gcp.cert"
 	SourceAzureKeyVault      SourceKind = "cloud.azure.keyvault"
 	SourceCT                 SourceKind = "ct"
+	SourceServing            SourceKind = "trustwatch.serving"
 )
 
 // CertFinding represents a single trust surface observation.
 type CertFinding struct {
 	NotAfter           time.Time         `json:"notAfter"`
+	NotBefore          time.Time         `json:"notBefore,omitzero"`
+	RenewalTime        time.Time         `json:"renewalTime,omitzero"` // when cert-manager will renew the certificate
 	RawIssuer          *x509.Certificate `json:"-"`
 	RawCert            *x509.Certificate `json:"-"`
+	Object             *ObjectRef        `json:"object,omitempty"` // Kubernetes object behind the finding
 	SignatureAlgorithm string            `json:"signatureAlgorithm,omitempty"`
 	FindingType        string            `json:"findingType,omitempty"`
 	Namespace          string            `json:"namespace,omitempty"`
@@ -64,12 +71,14 @@ type CertFinding struct {
 	KeyAlgorithm       string            `json:"keyAlgorithm,omitempty"`
 	Subject            string            `json:"subject,omitempty"`
 	Remediation        string            `json:"remediation,omitempty"`
+	Owner              string            `json:"owner,omitempty"` // team from trustwatch.dev/owner or a team label
 	TLSVersion         string            `json:"tlsVersion,omitempty"`
 	ChainErrors        []string          `json:"chainErrors,omitempty"`
 	DNSNames           []string          `json:"dnsNames,omitempty"`
 	IssuerChain        []string          `json:"issuerChain,omitempty"`
 	RevocationIssues   []string          `json:"revocationIssues,omitempty"`
 	PostureIssues      []string          `json:"postureIssues,omitempty"`
+	Notify             []string          `json:"notify,omitempty"` // webhook names from trustwatch.dev/notify
 	OCSPStaple         []byte            `json:"-"`
 	CertDuration       time.Duration     `json:"certDuration,omitempty"`
 	ChainLen           int               `json:"chainLen,omitempty"`
@@ -79,9 +88,106 @@ type CertFinding struct {
 	IsCA               bool              `json:"isCA,omitempty"`
 }
 
+// ObjectRef identifies the Kubernetes object a finding was discovered on.
+type ObjectRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	UID        string `json:"uid,omitempty"`
+}
+
 // Snapshot is a point-in-time collection of findings.
 type Snapshot struct {
 	At       time.Time         `json:"at"`
+	Metadata *Metadata         `json:"metadata,omitempty"`
 	Errors   map[string]string `json:"errors,omitempty"`
 	Findings []CertFinding     `json:"findings"`
 }
+
+// Metadata describes how a snapshot was produced.
+type Metadata struct {
+	Discoverers       map[string]DiscovererRun `json:"discoverers,omitempty"`       // per-discoverer outcome, keyed by discoverer name
+	Remotes           map[string]*Metadata     `json:"remotes,omitempty"`           // metadata of federated remote snapshots, keyed by cluster
+	Scope             *Scope                   `json:"scope,omitempty"`             // namespace scope the scan covered
+	Cluster           string                   `json:"cluster,omitempty"`           // cluster name label
+	Context           string                   `json:"context,omitempty"`           // kubeconfig context
+	Version           string                   `json:"version,omitempty"`           // trustwatch version
+	KubernetesVersion string                   `json:"kubernetesVersion,omitempty"` // API server version
+	WarnBefore        time.Duration            `json:"warnBefore,omitempty"`
+	CritBefore        time.Duration            `json:"critBefore,omitempty"`
+	ScanDuration      time.Duration            `json:"scanDuration,omitempty"`
+	Tunnel            bool                     `json:"tunnel,omitempty"` // probes were routed through the SOCKS5 relay
+}
+
+// DiscovererRun records how a single discoverer performed during a scan.
+type DiscovererRun struct {
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Findings int           `json:"findings"`
+}
+
+// Scope records the namespace scope a scan covered.
+type Scope struct {
+	LabelSelectors    map[string]string   `json:"labelSelectors,omitempty"`    // resource label selector per discoverer
+	Denied            map[string][]string `json:"denied,omitempty"`            // namespaces skipped per discoverer because RBAC denied list access
+	NamespaceSelector string              `json:"namespaceSelector,omitempty"` // label selector applied to Namespace objects
+	Include           []string            `json:"include,omitempty"`           // configured names or glob patterns
+	Exclude           []string            `json:"exclude,omitempty"`           // configured names or glob patterns
+	Namespaces        []string            `json:"namespaces"`                  // resolved namespaces
+}
+
+// CoverageGaps describes the parts of the trust surface a snapshot did not cover:
+// failed discoverers, namespaces skipped for RBAC, and gaps reported by federated
+// remotes. An empty result means the scan covered everything it was scoped to.
+func (s *Snapshot) CoverageGaps() []string {
+	var gaps []string
+	for _, name := range sortedKeys(s.Errors) {
+		gaps = append(gaps, fmt.Sprintf("%s failed: %s", name, s.Errors[name]))
+	}
+	if s.Metadata == nil {
+		return gaps
+	}
+	gaps = append(gaps, s.Metadata.deniedGaps()...)
+	for _, cluster := range sortedKeys(s.Metadata.Remotes) {
+		remote := s.Metadata.Remotes[cluster]
+		if remote == nil {
+			continue
+		}
+		for _, gap := range remote.deniedGaps() {
+			gaps = append(gaps, cluster+": "+gap)
+		}
+		for _, name := range sortedKeys(remote.Discoverers) {
+			if errMsg := remote.Discoverers[name].Error; errMsg != "" {
+				gaps = append(gaps, fmt.Sprintf("%s: %s failed: %s", cluster, name, errMsg))
+			}
+		}
+	}
+	return gaps
+}
+
+// deniedGaps summarizes namespaces skipped because of RBAC.
+func (m *Metadata) deniedGaps() []string {
+	if m.Scope == nil {
+		return nil
+	}
+	var gaps []string
+	for _, name := range sortedKeys(m.Scope.Denied) {
+		denied := m.Scope.Denied[name]
+		if len(denied) == 0 {
+			continue
+		}
+		gaps = append(gaps, fmt.Sprintf("%s: %d namespace(s) not accessible (%s)", name, len(denied), strings.Join(denied, ", ")))
+	}
+	return gaps
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -77,3 +77,37 @@ func TestSeverityConstants(t *testing.T) {
 		}
 	}
 }
+
+func TestSnapshotCoverageGaps(t *testing.T) {
+	s := Snapshot{
+		Errors: map[string]string{"webhooks": "timeout"},
+		Metadata: &Metadata{
+			Scope: &Scope{Denied: map[string][]string{"secrets": {"team-a", "team-b"}}},
+			Remotes: map[string]*Metadata{
+				"staging": {Discoverers: map[string]DiscovererRun{
+					"istio":   {Error: "forbidden"},
+					"secrets": {Findings: 3},
+				}},
+			},
+		},
+	}
+
+	want := []string{
+		"webhooks failed: timeout",
+		"secrets: 2 namespace(s) not accessible (team-a, team-b)",
+		"staging: istio failed: forbidden",
+	}
+	got := s.CoverageGaps()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d gaps, got %v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("gap %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if gaps := (&Snapshot{}).CoverageGaps(); len(gaps) != 0 {
+		t.Errorf("expected no gaps for a complete snapshot, got %v", gaps)
+	}
+}
@@ -0,0 +1,54 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/calendar"
+	"github.com/ppiankov/trustwatch/internal/config"
+)
+
+// CalendarHandler returns the certificates due for renewal or expiry in the
+// current snapshot, bucketed by week. The horizon parameter (default 90d)
+// sets how far ahead to look, group selects owner or namespace, and
+// format=ics returns an iCalendar feed for calendar subscriptions. The
+// source, severity, and namespace filters apply as for /api/v1/snapshot.
+func CalendarHandler(getSnapshot SnapshotFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		opts := calendar.Options{GroupBy: q.Get("group")}
+		if opts.GroupBy != "" && opts.GroupBy != calendar.GroupByOwner && opts.GroupBy != calendar.GroupByNamespace {
+			http.Error(w, "group must be owner or namespace", http.StatusBadRequest)
+			return
+		}
+		if v := q.Get("horizon"); v != "" {
+			horizon, err := config.ParseDays(v)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			opts.Horizon = horizon
+		}
+		format := q.Get("format")
+		if format != "" && format != "json" && format != "ics" {
+			http.Error(w, "format must be json or ics", http.StatusBadRequest)
+			return
+		}
+
+		snap := getSnapshot()
+		cal := calendar.Build(filterFindings(snap.Findings, r), opts, time.Now())
+		if format == "ics" {
+			w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+			w.Header().Set("Content-Disposition", `inline; filename="trustwatch.ics"`)
+			if err := cal.WriteICS(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(cal); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
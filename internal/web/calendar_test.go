@@ -0,0 +1,54 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/calendar"
+	"github.com/ppiankov/trustwatch/internal/store"
+)
+
+func TestCalendarHandler(t *testing.T) {
+	now := time.Now()
+	findings := []store.CertFinding{
+		{Source: store.SourceCertManager, Namespace: "payments", Name: "api", Cluster: "prod", Owner: "team-a",
+			ProbeOK: true, NotAfter: now.Add(20 * 24 * time.Hour), RenewalTime: now.Add(10 * 24 * time.Hour)},
+		{Source: store.SourceIngressTLS, Namespace: "web", Name: "site", Cluster: "staging",
+			ProbeOK: true, NotAfter: now.Add(60 * 24 * time.Hour)},
+	}
+	handler := CalendarHandler(fixedSnapshot(findings))
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/v1/calendar?horizon=30d&group=owner", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var cal calendar.Calendar
+	if err := json.NewDecoder(w.Body).Decode(&cal); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if cal.Total != 1 || cal.GroupBy != calendar.GroupByOwner {
+		t.Errorf("total = %d, groupBy = %q; want 1 and owner", cal.Total, cal.GroupBy)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/v1/calendar?format=ics", http.NoBody))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("content-type = %q, want text/calendar", ct)
+	}
+	if n := strings.Count(w.Body.String(), "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("events = %d, want 2", n)
+	}
+
+	for _, query := range []string{"horizon=soon", "group=team", "format=xml"} {
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/api/v1/calendar?"+query, http.NoBody))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+	}
+}
@@ -2,10 +2,17 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/ppiankov/trustwatch/internal/analytics"
+	"github.com/ppiankov/trustwatch/internal/config"
+	"github.com/ppiankov/trustwatch/internal/drift"
 	"github.com/ppiankov/trustwatch/internal/history"
+	"github.com/ppiankov/trustwatch/internal/store"
 )
 
 // HistoryHandler returns the most recent snapshot summaries as JSON.
@@ -62,3 +69,273 @@ func TrendHandler(hs *history.Store) http.HandlerFunc {
 		}
 	}
 }
+
+// LineageHandler returns certificate lineages as JSON. With name and source it
+// returns the single lineage of that finding (404 if it was never observed),
+// in the cluster given by cluster when findings are federated; otherwise every
+// lineage matching the optional cluster, source and namespace parameters.
+// since (RFC 3339) ignores older observations.
+func LineageHandler(hs *history.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		filter := history.LineageFilter{
+			Cluster:   q.Get("cluster"),
+			Source:    q.Get("source"),
+			Namespace: q.Get("namespace"),
+			Name:      q.Get("name"),
+		}
+		if v := q.Get("since"); v != "" {
+			since, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "since must be an RFC 3339 timestamp", http.StatusBadRequest)
+				return
+			}
+			filter.Since = since
+		}
+
+		lineages, err := hs.Lineages(filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var body any = lineages
+		if filter.Name != "" && filter.Source != "" {
+			if len(lineages) == 0 {
+				http.Error(w, "no certificates observed for this finding", http.StatusNotFound)
+				return
+			}
+			body = lineages[0]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// QueryHandler returns a page of the findings recorded between the from and
+// to timestamps (RFC 3339, either may be omitted) as JSON. source, namespace,
+// cluster, severity, findingType, and issuer (a substring) filter the
+// findings and accept comma-separated values; limit and offset page through
+// the results.
+func QueryHandler(hs *history.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hq, err := parseHistoryQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		q := r.URL.Query()
+		for name, dst := range map[string]*int{"limit": &hq.Limit, "offset": &hq.Offset} {
+			if v := q.Get(name); v != "" {
+				n, convErr := strconv.Atoi(v)
+				if convErr != nil || n < 0 {
+					http.Error(w, name+" must be a non-negative integer", http.StatusBadRequest)
+					return
+				}
+				*dst = n
+			}
+		}
+
+		page, err := hs.Query(hq)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// QueryCountsHandler returns the findings matching the QueryHandler filters
+// counted by severity per interval (default 1h; e.g. 15m, 1d) as JSON. Each
+// bucket counts the last snapshot taken in it.
+func QueryCountsHandler(hs *history.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hq, err := parseHistoryQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		interval := time.Hour
+		if v := r.URL.Query().Get("interval"); v != "" {
+			if interval, err = config.ParseDays(v); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		buckets, err := hs.CountBySeverity(hq, interval)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(buckets); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// parseHistoryQuery reads the time range and finding filters shared by the query endpoints.
+func parseHistoryQuery(r *http.Request) (history.Query, error) {
+	q := r.URL.Query()
+	hq := history.Query{
+		Sources:      splitParam(q.Get("source")),
+		Namespaces:   splitParam(q.Get("namespace")),
+		Clusters:     splitParam(q.Get("cluster")),
+		Severities:   splitParam(q.Get("severity")),
+		FindingTypes: splitParam(q.Get("findingType")),
+		Issuers:      splitParam(q.Get("issuer")),
+	}
+	for name, dst := range map[string]*time.Time{"from": &hq.From, "to": &hq.To} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return hq, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+			}
+			*dst = t
+		}
+	}
+	if !hq.From.IsZero() && !hq.To.IsZero() && hq.To.Before(hq.From) {
+		return hq, fmt.Errorf("to must not be before from")
+	}
+	return hq, nil
+}
+
+// AnalyticsHandler returns rotation SLO analytics as JSON. source and
+// namespace narrow the groups of the report returned by cached, which serve
+// refreshes with its history maintenance. The window parameter (a Go
+// duration such as 720h) overrides the configured window and computes a
+// report from history, as does any request before the first refresh.
+func AnalyticsHandler(hs *history.Store, opts analytics.Options, cached func() *analytics.Report) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		opts := opts
+		opts.Source = q.Get("source")
+		opts.Namespace = q.Get("namespace")
+		rep := cached()
+		if v := q.Get("window"); v != "" {
+			window, err := time.ParseDuration(v)
+			if err != nil || window <= 0 {
+				http.Error(w, "window must be a positive duration such as 720h", http.StatusBadRequest)
+				return
+			}
+			opts.Window, rep = window, nil
+		}
+
+		if rep != nil {
+			rep = rep.Filter(opts.Source, opts.Namespace)
+		} else {
+			var err error
+			if rep, err = analytics.FromHistory(hs, opts, time.Now()); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rep); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// DiffHandler returns the change set between two history snapshots. The from and
+// to parameters accept a snapshot ID or an RFC 3339 timestamp (the latest snapshot
+// at or before it); to defaults to the latest snapshot. Timestamps and the
+// default resolve within the snapshots saved for cluster, the server's own
+// stream, unless the cluster parameter names another, e.g. an imported one.
+// format=markdown or format=table returns plain text instead of JSON.
+func DiffHandler(hs *history.Store, cluster string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("from") == "" {
+			http.Error(w, "from query parameter is required", http.StatusBadRequest)
+			return
+		}
+		format := q.Get("format")
+		if format != "" && format != "json" && format != "markdown" && format != "table" {
+			http.Error(w, "format must be json, markdown, or table", http.StatusBadRequest)
+			return
+		}
+		cluster := cluster
+		if q.Has("cluster") {
+			cluster = q.Get("cluster")
+		}
+
+		from, err := lookupSnapshot(hs, cluster, q.Get("from"))
+		if err != nil {
+			http.Error(w, "from: "+err.Error(), lookupStatus(err))
+			return
+		}
+		to, err := lookupSnapshot(hs, cluster, q.Get("to"))
+		if err != nil {
+			http.Error(w, "to: "+err.Error(), lookupStatus(err))
+			return
+		}
+
+		cs := drift.Diff(from, to)
+		switch format {
+		case "markdown":
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			err = cs.WriteMarkdown(w)
+		case "table":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			err = cs.WriteTable(w)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			err = json.NewEncoder(w).Encode(cs)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// Errors returned by lookupSnapshot, mapped to status codes by lookupStatus.
+var (
+	errBadSnapshotRef = errors.New("not a snapshot ID or RFC 3339 timestamp")
+	errNoSnapshot     = errors.New("no snapshot found")
+)
+
+// lookupStatus returns 400 for an unparseable reference, 404 when no snapshot
+// matches, and 500 for store errors.
+func lookupStatus(err error) int {
+	switch {
+	case errors.Is(err, errBadSnapshotRef):
+		return http.StatusBadRequest
+	case errors.Is(err, errNoSnapshot):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// lookupSnapshot resolves a snapshot ID, an RFC 3339 timestamp, or "" (latest);
+// timestamps and latest are looked up in cluster's stream.
+func lookupSnapshot(hs *history.Store, cluster, ref string) (*store.Snapshot, error) {
+	var snap *store.Snapshot
+	var err error
+	switch id, idErr := strconv.ParseInt(ref, 10, 64); {
+	case ref == "":
+		snap, err = hs.GetLatest(cluster)
+	case idErr == nil:
+		snap, err = hs.GetSnapshot(id)
+	default:
+		at, timeErr := time.Parse(time.RFC3339, ref)
+		if timeErr != nil {
+			return nil, fmt.Errorf("%q: %w", ref, errBadSnapshotRef)
+		}
+		snap, err = hs.GetAt(at, cluster)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, fmt.Errorf("%w for %q", errNoSnapshot, ref)
+	}
+	return snap, nil
+}
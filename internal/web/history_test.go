@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ppiankov/trustwatch/internal/analytics"
+	"github.com/ppiankov/trustwatch/internal/drift"
 	"github.com/ppiankov/trustwatch/internal/history"
 	"github.com/ppiankov/trustwatch/internal/store"
 )
@@ -147,3 +151,257 @@ func TestTrendHandler_NoData(t *testing.T) {
 		t.Errorf("expected 0 points, got %d", len(points))
 	}
 }
+
+func TestDiffHandler(t *testing.T) {
+	hs := openTestHistory(t)
+	base := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i, serial := range []string{"AA", "BB"} {
+		snap := store.Snapshot{
+			At: base.Add(time.Duration(i) * 7 * 24 * time.Hour),
+			Findings: []store.CertFinding{
+				{Name: "cert-a", Namespace: "default", Source: store.SourceTLSSecret, Serial: serial, ProbeOK: true},
+			},
+		}
+		if err := hs.Save(snap); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/diff?from=2026-05-02T00:00:00Z", http.NoBody)
+	w := httptest.NewRecorder()
+	DiffHandler(hs, "")(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var cs drift.ChangeSet
+	if err := json.NewDecoder(w.Body).Decode(&cs); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if len(cs.Changes) != 1 || cs.Changes[0].Kind != drift.ChangeSerialRotated {
+		t.Fatalf("expected one serial rotation, got %+v", cs.Changes)
+	}
+	if cs.Changes[0].From != "AA" || cs.Changes[0].To != "BB" {
+		t.Errorf("expected AA → BB, got %s → %s", cs.Changes[0].From, cs.Changes[0].To)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/diff?from=1&to=2&format=markdown", http.NoBody)
+	w = httptest.NewRecorder()
+	DiffHandler(hs, "")(w, req)
+	if ct := w.Header().Get("Content-Type"); ct != "text/markdown; charset=utf-8" {
+		t.Errorf("content-type = %q, want text/markdown", ct)
+	}
+	if !strings.Contains(w.Body.String(), "### Serial rotated") {
+		t.Errorf("expected markdown section, got:\n%s", w.Body.String())
+	}
+}
+
+func TestDiffHandler_ErrorStatus(t *testing.T) {
+	hs := openTestHistory(t)
+	for query, want := range map[string]int{
+		"":                           http.StatusBadRequest,
+		"?from=yesterday":            http.StatusBadRequest,
+		"?from=1&format=xml":         http.StatusBadRequest,
+		"?from=42":                   http.StatusNotFound,
+		"?from=2020-01-01T00:00:00Z": http.StatusNotFound,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/diff"+query, http.NoBody)
+		w := httptest.NewRecorder()
+		DiffHandler(hs, "")(w, req)
+		if w.Code != want {
+			t.Errorf("%q: status = %d, want %d", query, w.Code, want)
+		}
+	}
+
+	// A failing store is a server error, not a bad reference.
+	if err := hs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/diff?from=1", http.NoBody)
+	w := httptest.NewRecorder()
+	DiffHandler(hs, "")(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("closed store: status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestLineageHandler(t *testing.T) {
+	hs := openTestHistory(t)
+	base := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i, serial := range []string{"AA", "AA", "BB"} {
+		snap := store.Snapshot{
+			At: base.Add(time.Duration(i) * 24 * time.Hour),
+			Findings: []store.CertFinding{
+				{Name: "cert-a", Namespace: "default", Source: store.SourceTLSSecret, Serial: serial, ProbeOK: true},
+				{Name: "cert-b", Namespace: "other", Source: store.SourceTLSSecret, Serial: "CC", ProbeOK: true},
+			},
+		}
+		if err := hs.Save(snap); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/lineage?source=k8s.tlsSecret&namespace=default&name=cert-a", http.NoBody)
+	w := httptest.NewRecorder()
+	LineageHandler(hs)(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var l history.Lineage
+	if err := json.NewDecoder(w.Body).Decode(&l); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if l.Rotations != 1 || len(l.Generations) != 2 || l.Generations[0].Serial != "AA" || l.Generations[0].Observations != 2 {
+		t.Errorf("unexpected lineage: %+v", l)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/lineage?namespace=other", http.NoBody)
+	w = httptest.NewRecorder()
+	LineageHandler(hs)(w, req)
+	var all []history.Lineage
+	if err := json.NewDecoder(w.Body).Decode(&all); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if len(all) != 1 || all[0].Name != "cert-b" || all[0].Rotations != 0 {
+		t.Errorf("unexpected lineages: %+v", all)
+	}
+
+	for query, want := range map[string]int{
+		"source=k8s.tlsSecret&name=missing": http.StatusNotFound,
+		"since=yesterday":                   http.StatusBadRequest,
+	} {
+		w = httptest.NewRecorder()
+		LineageHandler(hs)(w, httptest.NewRequest(http.MethodGet, "/api/v1/lineage?"+query, http.NoBody))
+		if w.Code != want {
+			t.Errorf("%s: status = %d, want %d", query, w.Code, want)
+		}
+	}
+}
+
+func TestAnalyticsHandler(t *testing.T) {
+	hs := openTestHistory(t)
+	now := time.Now().UTC()
+	for i, serial := range []string{"AA", "BB"} {
+		issued := now.Add(time.Duration(i-2) * 20 * 24 * time.Hour)
+		snap := store.Snapshot{
+			At: issued,
+			Findings: []store.CertFinding{{
+				Name: "cert-a", Namespace: "default", Source: store.SourceTLSSecret, Serial: serial, Issuer: "CN=CA",
+				NotBefore: issued, NotAfter: issued.Add(30 * 24 * time.Hour), ProbeOK: true,
+			}},
+		}
+		if err := hs.Save(snap); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	var cached atomic.Pointer[analytics.Report]
+	handler := AnalyticsHandler(hs, analytics.Options{}, cached.Load)
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/v1/analytics?namespace=default", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var rep analytics.Report
+	if err := json.NewDecoder(w.Body).Decode(&rep); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if len(rep.Groups) != 1 || rep.Total.Rotations != 1 || rep.Total.SLOMet != 1 {
+		t.Errorf("unexpected report: %+v", rep)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/v1/analytics?window=240h", http.NoBody))
+	if err := json.NewDecoder(w.Body).Decode(&rep); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if rep.Total.Rotations != 0 {
+		t.Errorf("a 10-day window should exclude the rotation 20 days ago: %+v", rep.Total)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/v1/analytics?window=soon", http.NoBody))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	// Once maintenance has stored a report, requests filter it instead of
+	// reading history; a custom window still computes.
+	cached.Store(&analytics.Report{Groups: []analytics.Group{
+		{Source: store.SourceTLSSecret, Namespace: "default", Expired: 1},
+		{Source: store.SourceTLSSecret, Namespace: "other", Expired: 2},
+	}})
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/v1/analytics?namespace=other", http.NoBody))
+	if err := json.NewDecoder(w.Body).Decode(&rep); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if len(rep.Groups) != 1 || rep.Total.Expired != 2 {
+		t.Errorf("cached report filtered to other = %+v", rep)
+	}
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/v1/analytics?window=2160h", http.NoBody))
+	if err := json.NewDecoder(w.Body).Decode(&rep); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if rep.Total.Rotations != 1 || rep.Total.Expired != 0 {
+		t.Errorf("window override should compute from history: %+v", rep.Total)
+	}
+}
+
+func TestQueryHandlers(t *testing.T) {
+	hs := openTestHistory(t)
+	base := time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		snap := store.Snapshot{
+			At: base.Add(time.Duration(i) * time.Hour),
+			Findings: []store.CertFinding{
+				{Name: "api", Namespace: "payments", Cluster: "prod", Source: store.SourceCertManager, Severity: store.SeverityWarn, ProbeOK: true},
+				{Name: "web", Namespace: "web", Cluster: "staging", Source: store.SourceIngressTLS, Severity: store.SeverityInfo, ProbeOK: true},
+			},
+		}
+		if err := hs.Save(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	QueryHandler(hs)(w, httptest.NewRequest(http.MethodGet,
+		"/api/v1/query?from=2026-06-01T10:30:00Z&cluster=prod,dev&limit=1", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var page history.Page
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if page.Total != 2 || len(page.Records) != 1 || page.NextOffset != 1 || page.Records[0].Finding.Cluster != "prod" {
+		t.Errorf("page = %+v", page)
+	}
+
+	w = httptest.NewRecorder()
+	QueryCountsHandler(hs)(w, httptest.NewRequest(http.MethodGet, "/api/v1/query/counts?interval=1d", http.NoBody))
+	var buckets []history.Bucket
+	if err := json.NewDecoder(w.Body).Decode(&buckets); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if len(buckets) != 1 || buckets[0].Total != 2 || buckets[0].Counts[store.SeverityWarn] != 1 {
+		t.Errorf("buckets = %+v", buckets)
+	}
+
+	for _, target := range []string{
+		"/api/v1/query?from=yesterday",
+		"/api/v1/query?from=2026-06-02T00:00:00Z&to=2026-06-01T00:00:00Z",
+		"/api/v1/query?limit=-1",
+		"/api/v1/query/counts?interval=0",
+	} {
+		w = httptest.NewRecorder()
+		handler := QueryHandler(hs)
+		if strings.HasPrefix(target, "/api/v1/query/counts") {
+			handler = QueryCountsHandler(hs)
+		}
+		handler(w, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", target, w.Code)
+		}
+	}
+}
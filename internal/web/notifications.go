@@ -0,0 +1,51 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ppiankov/trustwatch/internal/notify"
+)
+
+// NotificationsResponse is the JSON body returned by NotificationsHandler.
+type NotificationsResponse struct {
+	Counts        map[notify.Status]int `json:"counts"`
+	Notifications []notify.Message      `json:"notifications"`
+}
+
+// NotificationsHandler returns queued, delivered, and failed notifications from
+// the outbox, newest first. The status parameter filters by pending, failed,
+// sent, or dead.
+func NotificationsHandler(o notify.Outbox) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := notify.Status(r.URL.Query().Get("status"))
+		if status != "" && !notify.ValidStatus(status) {
+			http.Error(w, "status must be pending, failed, sent, or dead", http.StatusBadRequest)
+			return
+		}
+
+		limit := 50
+		if q := r.URL.Query().Get("limit"); q != "" {
+			if n, err := strconv.Atoi(q); err == nil && n > 0 {
+				limit = n
+			}
+		}
+
+		msgs, err := o.List(status, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		counts, err := o.Counts()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(NotificationsResponse{Counts: counts, Notifications: msgs}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/trustwatch/internal/notify"
+)
+
+func TestNotificationsHandler(t *testing.T) {
+	o := notify.NewMemoryOutbox()
+	now := time.Now().UTC()
+	for _, st := range []notify.Status{notify.StatusSent, notify.StatusDead, notify.StatusPending} {
+		msg := &notify.Message{
+			CreatedAt: now,
+			Kind:      "slack",
+			Webhook:   "slack-secret",
+			Endpoint:  "/services/T/B/secret",
+			Target:    "hooks.slack.com",
+			Status:    st,
+		}
+		if err := o.Enqueue(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/notifications", http.NoBody)
+	w := httptest.NewRecorder()
+	NotificationsHandler(o)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "secret") {
+		t.Error("response must not expose webhook URLs")
+	}
+	var resp NotificationsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if len(resp.Notifications) != 3 || resp.Notifications[0].Status != notify.StatusPending {
+		t.Errorf("notifications = %+v, want 3 newest first", resp.Notifications)
+	}
+	if resp.Counts[notify.StatusDead] != 1 {
+		t.Errorf("counts = %v, want 1 dead", resp.Counts)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/notifications?status=dead", http.NoBody)
+	w = httptest.NewRecorder()
+	NotificationsHandler(o)(w, req)
+	resp = NotificationsResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if len(resp.Notifications) != 1 || resp.Notifications[0].Status != notify.StatusDead {
+		t.Errorf("filtered notifications = %+v, want 1 dead", resp.Notifications)
+	}
+}
+
+func TestNotificationsHandler_BadStatus(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/notifications?status=bogus", http.NoBody)
+	w := httptest.NewRecorder()
+	NotificationsHandler(notify.NewMemoryOutbox())(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}